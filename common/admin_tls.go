@@ -0,0 +1,86 @@
+package common
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AdminTLSClientCert 客户端证书对应的用户，Role 为该证书允许的最高角色
+type AdminTLSClientCert struct {
+	UserId int
+	Role   int
+}
+
+// AdminTLSClientCerts 客户端证书 SHA-256 指纹到用户的映射
+var AdminTLSClientCerts = make(map[string]AdminTLSClientCert)
+
+// AdminTLSEnabled 是否启用了 mTLS 管理端口，启用后管理接口只能通过该端口访问
+var AdminTLSEnabled = false
+
+// ParseAdminTLSClientCerts 解析 ADMIN_TLS_CLIENT_CERTS，格式为 "指纹=用户ID:角色"，多个以逗号分隔，
+// 例如 "ab12...=1:100,cd34...=2:10"
+func ParseAdminTLSClientCerts(value string) (map[string]AdminTLSClientCert, error) {
+	certs := make(map[string]AdminTLSClientCert)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		fingerprint, identity, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid client cert mapping: %s", item)
+		}
+		userIdStr, roleStr, ok := strings.Cut(identity, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid client cert mapping: %s", item)
+		}
+		userId, err := strconv.Atoi(strings.TrimSpace(userIdStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id in client cert mapping: %s", item)
+		}
+		role, err := strconv.Atoi(strings.TrimSpace(roleStr))
+		if err != nil || !IsValidateRole(role) {
+			return nil, fmt.Errorf("invalid role in client cert mapping: %s", item)
+		}
+		certs[normalizeCertFingerprint(fingerprint)] = AdminTLSClientCert{
+			UserId: userId,
+			Role:   role,
+		}
+	}
+	return certs, nil
+}
+
+// normalizeCertFingerprint 兼容带冒号、大写的指纹写法
+func normalizeCertFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+}
+
+// CertFingerprint 返回证书 DER 编码的 SHA-256 指纹
+func CertFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAdminTLSConfig 构造要求并校验客户端证书的 TLS 配置
+func NewAdminTLSConfig(clientCAFile string) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("no valid certificate found in client CA file")
+	}
+	return &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  pool,
+		MinVersion: tls.VersionTLS12,
+	}, nil
+}
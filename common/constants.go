@@ -113,6 +113,9 @@ var IsMasterNode bool
 var requestInterval int
 var RequestInterval time.Duration
 
+// ChannelTestConcurrency 批量测试渠道时的并发数，同一上游的渠道仍按 RequestInterval 依次测试
+var ChannelTestConcurrency int
+
 var SyncFrequency int // unit is second
 
 var BatchUpdateEnabled = false
@@ -166,6 +169,9 @@ var (
 
 	CriticalRateLimitNum            = 20
 	CriticalRateLimitDuration int64 = 20 * 60
+
+	ExportRateLimitNum            = 10
+	ExportRateLimitDuration int64 = 60
 )
 
 var RateLimitKeyExpirationDuration = 20 * time.Minute
@@ -193,6 +199,8 @@ const (
 	ChannelStatusEnabled          = 1 // don't use 0, 0 is the default value!
 	ChannelStatusManuallyDisabled = 2 // also don't use 0
 	ChannelStatusAutoDisabled     = 3
+	ChannelStatusQuarantined      = 4 // 隔离：只接收测试流量与少量重试请求
+	ChannelStatusDraining         = 5 // 排空：不再接收新请求，处理中的请求结束后删除
 )
 
 const (
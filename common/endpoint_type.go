@@ -2,6 +2,26 @@ package common
 
 import "one-api/constant"
 
+// ChannelTypeSupportsRerank Gemini 与 Vertex AI 渠道没有对应的 rerank 接口，路由 rerank 请求时跳过
+func ChannelTypeSupportsRerank(channelType int) bool {
+	switch channelType {
+	case constant.ChannelTypeGemini, constant.ChannelTypeVertexAi:
+		return false
+	}
+	return true
+}
+
+// ChannelTypeSupportsClaudeMessages 这些渠道使用各自的原生协议且响应无法转换为 Claude 格式，路由 /v1/messages 请求时跳过
+func ChannelTypeSupportsClaudeMessages(channelType int) bool {
+	switch channelType {
+	case constant.ChannelTypeBaidu, constant.ChannelCloudflare, constant.ChannelTypeCohere, constant.ChannelTypeCoze,
+		constant.ChannelTypeDify, constant.ChannelTypeJimeng, constant.ChannelTypeJina, constant.ChannelTypeMokaAI,
+		constant.ChannelTypePaLM, constant.ChannelTypeTencent, constant.ChannelTypeXunfei, constant.ChannelTypeZhipu:
+		return false
+	}
+	return true
+}
+
 // GetEndpointTypesByChannelType 获取渠道最优先端点类型（所有的渠道都支持 OpenAI 端点）
 func GetEndpointTypesByChannelType(channelType int, modelName string) []constant.EndpointType {
 	var endpointTypes []constant.EndpointType
@@ -25,6 +25,18 @@ func GetEnvOrDefaultString(env string, defaultValue string) string {
 	return os.Getenv(env)
 }
 
+func GetEnvOrDefaultFloat(env string, defaultValue float64) float64 {
+	if env == "" || os.Getenv(env) == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(os.Getenv(env), 64)
+	if err != nil {
+		SysError(fmt.Sprintf("failed to parse %s: %s, using default value: %f", env, err.Error(), defaultValue))
+		return defaultValue
+	}
+	return f
+}
+
 func GetEnvOrDefaultBool(env string, defaultValue bool) bool {
 	if env == "" || os.Getenv(env) == "" {
 		return defaultValue
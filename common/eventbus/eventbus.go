@@ -0,0 +1,141 @@
+// Package eventbus 将请求完成后的用量事件异步发布到 Kafka（REST Proxy）或 NATS，供外部实时统计使用
+package eventbus
+
+import (
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	EventTypeConsume = "consume"
+	EventTypeError   = "error"
+)
+
+const (
+	publisherKafka = "kafka"
+	publisherNats  = "nats"
+
+	eventQueueSize = 10000
+	maxBatchSize   = 100
+	flushInterval  = time.Second
+)
+
+// Event 一次请求的用量事件，默认不包含日志内容
+type Event struct {
+	Type             string `json:"type"`
+	RequestId        string `json:"request_id,omitempty"`
+	CreatedAt        int64  `json:"created_at"`
+	UserId           int    `json:"user_id"`
+	Username         string `json:"username,omitempty"`
+	TokenId          int    `json:"token_id"`
+	TokenName        string `json:"token_name,omitempty"`
+	ChannelId        int    `json:"channel_id"`
+	ModelName        string `json:"model_name"`
+	Group            string `json:"group,omitempty"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Quota            int    `json:"quota"`
+	UseTimeSeconds   int    `json:"use_time_seconds"`
+	IsStream         bool   `json:"is_stream"`
+	Content          string `json:"content,omitempty"`
+}
+
+// publisher 将一批已编码的事件发送到消息系统
+type publisher interface {
+	publish(events [][]byte) error
+}
+
+var (
+	enabled        bool
+	includeContent bool
+	events         chan []byte
+	dropped        atomic.Int64
+)
+
+// Init 根据环境变量初始化事件发布，EVENT_BUS_TYPE 为空时不启用
+func Init() error {
+	busType := strings.ToLower(strings.TrimSpace(common.GetEnvOrDefaultString("EVENT_BUS_TYPE", "")))
+	if busType == "" {
+		return nil
+	}
+	url := common.GetEnvOrDefaultString("EVENT_BUS_URL", "")
+	topic := common.GetEnvOrDefaultString("EVENT_BUS_TOPIC", "new-api.events")
+	if url == "" {
+		return fmt.Errorf("EVENT_BUS_URL is required when EVENT_BUS_TYPE is set")
+	}
+	var p publisher
+	switch busType {
+	case publisherKafka:
+		p = newKafkaPublisher(url, topic)
+	case publisherNats:
+		natsPublisher, err := newNatsPublisher(url, topic)
+		if err != nil {
+			return err
+		}
+		p = natsPublisher
+	default:
+		return fmt.Errorf("unsupported EVENT_BUS_TYPE: %s", busType)
+	}
+	includeContent = common.GetEnvOrDefaultBool("EVENT_BUS_INCLUDE_CONTENT", false)
+	events = make(chan []byte, eventQueueSize)
+	enabled = true
+	go run(p)
+	common.SysLog(fmt.Sprintf("event bus enabled, type: %s, topic: %s", busType, topic))
+	return nil
+}
+
+// Enabled 是否启用了事件发布
+func Enabled() bool {
+	return enabled
+}
+
+// Publish 将事件放入发送队列，队列已满时丢弃，不阻塞请求
+func Publish(event Event) {
+	if !enabled {
+		return
+	}
+	if !includeContent {
+		event.Content = ""
+	}
+	data, err := common.Marshal(event)
+	if err != nil {
+		return
+	}
+	select {
+	case events <- data:
+	default:
+		dropped.Add(1)
+	}
+}
+
+func run(p publisher) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	batch := make([][]byte, 0, maxBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := p.publish(batch); err != nil {
+			common.SysError(fmt.Sprintf("failed to publish %d events: %s", len(batch), err.Error()))
+		}
+		batch = batch[:0]
+		if n := dropped.Swap(0); n > 0 {
+			common.SysError(fmt.Sprintf("event bus queue is full, %d events dropped", n))
+		}
+	}
+	for {
+		select {
+		case event := <-events:
+			batch = append(batch, event)
+			if len(batch) >= maxBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package eventbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// kafkaPublisher 通过 Kafka REST Proxy（v2 API）写入事件，url 形如 http://rest-proxy:8082
+type kafkaPublisher struct {
+	endpoint string
+	client   *http.Client
+}
+
+func newKafkaPublisher(baseUrl string, topic string) *kafkaPublisher {
+	return &kafkaPublisher{
+		endpoint: strings.TrimSuffix(baseUrl, "/") + "/topics/" + url.PathEscape(topic),
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *kafkaPublisher) publish(events [][]byte) error {
+	type record struct {
+		Value json.RawMessage `json:"value"`
+	}
+	records := make([]record, 0, len(events))
+	for _, event := range events {
+		records = append(records, record{Value: event})
+	}
+	body, err := json.Marshal(map[string]any{"records": records})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, p.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("kafka rest proxy returned status %d: %s", resp.StatusCode, respBody)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
@@ -2,9 +2,11 @@ package common
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"one-api/constant"
 
 	"github.com/gin-gonic/gin"
 )
@@ -24,6 +26,10 @@ func IOCopyBytesGracefully(c *gin.Context, src *http.Response, data []byte) {
 		return
 	}
 
+	// 错误响应保持上游原样返回
+	if src == nil || (src.StatusCode >= 200 && src.StatusCode < 300) {
+		data = InjectRequestMetadata(c, data)
+	}
 	body := io.NopCloser(bytes.NewBuffer(data))
 
 	// We shouldn't set the header before we parse the response body, because the parse part may fail.
@@ -55,3 +61,36 @@ func IOCopyBytesGracefully(c *gin.Context, src *http.Response, data []byte) {
 		LogError(c, fmt.Sprintf("failed to copy response body: %s", err.Error()))
 	}
 }
+
+// InjectRequestMetadata 将请求中携带的 metadata 回显到 JSON 响应体中，流式响应只在首个数据块中回显
+func InjectRequestMetadata(c *gin.Context, data []byte) []byte {
+	metadata, ok := GetContextKeyType[map[string]interface{}](c, constant.ContextKeyRequestMetadata)
+	if !ok || len(metadata) == 0 || GetContextKeyBool(c, constant.ContextKeyRequestMetadataEchoed) {
+		return data
+	}
+	if len(data) == 0 || data[0] != '{' {
+		return data
+	}
+	// 只解析顶层字段判断是否已有 metadata，其余内容保持原始字节，不经过 map 重新序列化
+	var body map[string]json.RawMessage
+	if err := Unmarshal(data, &body); err != nil {
+		return data
+	}
+	if _, exists := body["metadata"]; exists {
+		return data
+	}
+	metadataJson, err := Marshal(metadata)
+	if err != nil {
+		return data
+	}
+	rest := bytes.TrimLeft(data[1:], " \t\r\n")
+	newData := make([]byte, 0, len(data)+len(metadataJson)+len(`{"metadata":,`))
+	newData = append(newData, `{"metadata":`...)
+	newData = append(newData, metadataJson...)
+	if len(rest) > 0 && rest[0] != '}' {
+		newData = append(newData, ',')
+	}
+	newData = append(newData, rest...)
+	SetContextKey(c, constant.ContextKeyRequestMetadataEchoed, true)
+	return newData
+}
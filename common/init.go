@@ -82,6 +82,7 @@ func InitEnv() {
 	// Initialize variables with GetEnvOrDefault
 	SyncFrequency = GetEnvOrDefault("SYNC_FREQUENCY", 60)
 	BatchUpdateInterval = GetEnvOrDefault("BATCH_UPDATE_INTERVAL", 5)
+	ChannelTestConcurrency = GetEnvOrDefault("CHANNEL_TEST_CONCURRENCY", 1)
 	RelayTimeout = GetEnvOrDefault("RELAY_TIMEOUT", 0)
 
 	// Initialize string variables with GetEnvOrDefaultString
@@ -97,6 +98,11 @@ func InitEnv() {
 	GlobalWebRateLimitNum = GetEnvOrDefault("GLOBAL_WEB_RATE_LIMIT", 60)
 	GlobalWebRateLimitDuration = int64(GetEnvOrDefault("GLOBAL_WEB_RATE_LIMIT_DURATION", 180))
 
+	// 高频日志的采样、限流与截断
+	LogSampleRate = GetEnvOrDefaultFloat("LOG_SAMPLE_RATE", 1)
+	LogRateLimitPerMinute = GetEnvOrDefault("LOG_RATE_LIMIT_PER_MINUTE", 60)
+	LogMaxLength = GetEnvOrDefault("LOG_MAX_LENGTH", 4096)
+
 	initConstantEnv()
 }
 
@@ -104,6 +110,8 @@ func initConstantEnv() {
 	constant.StreamingTimeout = GetEnvOrDefault("STREAMING_TIMEOUT", 120)
 	constant.DifyDebug = GetEnvOrDefaultBool("DIFY_DEBUG", true)
 	constant.MaxFileDownloadMB = GetEnvOrDefault("MAX_FILE_DOWNLOAD_MB", 20)
+	// MaxRequestBodyMB 校验 JSON 请求体时允许的最大体积，0 表示不限制
+	constant.MaxRequestBodyMB = GetEnvOrDefault("MAX_REQUEST_BODY_MB", 64)
 	// ForceStreamOption 覆盖请求参数，强制返回usage信息
 	constant.ForceStreamOption = GetEnvOrDefaultBool("FORCE_STREAM_OPTION", true)
 	constant.GetMediaToken = GetEnvOrDefaultBool("GET_MEDIA_TOKEN", true)
@@ -0,0 +1,105 @@
+package common
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// jsonScanner 逐字符跟踪 JSON 文档的嵌套深度，用于找到第一个完整文档的结束位置
+type jsonScanner struct {
+	started  bool
+	done     bool
+	depth    int
+	inString bool
+	escaped  bool
+}
+
+// feed 处理一个字符，返回该字符是否属于第一个 JSON 文档
+func (s *jsonScanner) feed(ch byte) bool {
+	if s.done {
+		return false
+	}
+	if !s.started {
+		if ch != '{' && ch != '[' {
+			return false
+		}
+		s.started = true
+	}
+	if s.inString {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case ch == '\\':
+			s.escaped = true
+		case ch == '"':
+			s.inString = false
+		}
+		return true
+	}
+	switch ch {
+	case '"':
+		s.inString = true
+	case '{', '[':
+		s.depth++
+	case '}', ']':
+		s.depth--
+		if s.depth == 0 {
+			s.done = true
+		}
+	}
+	return true
+}
+
+// ExtractFirstJSON 提取文本中第一个完整且合法的 JSON 文档，丢弃前后的说明文字与代码块标记。
+// 返回值 repaired 表示内容是否被修改，找不到合法 JSON 时原样返回。
+func ExtractFirstJSON(text string) (result string, repaired bool) {
+	scanner := &jsonScanner{}
+	start := -1
+	for i := 0; i < len(text); i++ {
+		if scanner.feed(text[i]) && start < 0 {
+			start = i
+		}
+		if scanner.done {
+			candidate := text[start : i+1]
+			if !json.Valid([]byte(candidate)) {
+				return text, false
+			}
+			return candidate, candidate != strings.TrimSpace(text)
+		}
+	}
+	return text, false
+}
+
+// JsonStreamRepairer 流式场景下只放行第一个 JSON 文档的内容，按 choice 分别跟踪
+type JsonStreamRepairer struct {
+	scanners map[int]*jsonScanner
+	Repaired bool
+}
+
+func NewJsonStreamRepairer() *JsonStreamRepairer {
+	return &JsonStreamRepairer{
+		scanners: make(map[int]*jsonScanner),
+	}
+}
+
+// Filter 过滤一段增量内容，返回应当下发给客户端的部分
+func (r *JsonStreamRepairer) Filter(index int, delta string) string {
+	scanner, ok := r.scanners[index]
+	if !ok {
+		scanner = &jsonScanner{}
+		r.scanners[index] = scanner
+	}
+	var kept strings.Builder
+	for i := 0; i < len(delta); i++ {
+		if scanner.feed(delta[i]) {
+			kept.WriteByte(delta[i])
+		} else if !isJsonWhitespace(delta[i]) {
+			r.Repaired = true
+		}
+	}
+	return kept.String()
+}
+
+func isJsonWhitespace(ch byte) bool {
+	return ch == ' ' || ch == '\n' || ch == '\r' || ch == '\t'
+}
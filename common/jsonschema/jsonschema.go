@@ -0,0 +1,176 @@
+// Package jsonschema 实现 JSON Schema 的常用子集，用于在转发前校验请求体：
+// type、properties、required、additionalProperties、items、enum、minimum、maximum、minItems、minLength
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+type Schema struct {
+	Type                 any                `json:"type,omitempty"` // 单个类型名或类型名数组
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Enum                 []any              `json:"enum,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+}
+
+// ValidationError 校验失败的位置与原因，Path 形如 $.messages[0].role
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func Parse(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// Validate 校验 encoding/json 解码得到的值，返回第一个不满足的约束
+func (s *Schema) Validate(value any) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if name, ok := item.(string); ok {
+				types = append(types, name)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func typeOf(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func matchType(expected string, actual string) bool {
+	return expected == actual || (expected == "number" && actual == "integer")
+}
+
+func (s *Schema) validate(path string, value any) error {
+	if types := s.types(); len(types) > 0 {
+		actual := typeOf(value)
+		matched := false
+		for _, expected := range types {
+			if matchType(expected, actual) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("expected %s, got %s", strings.Join(types, " or "), actual)}
+		}
+	}
+	if len(s.Enum) > 0 && !enumContains(s.Enum, value) {
+		allowed, _ := json.Marshal(s.Enum)
+		return &ValidationError{Path: path, Message: fmt.Sprintf("value must be one of %s", allowed)}
+	}
+
+	switch v := value.(type) {
+	case float64:
+		if s.Minimum != nil && v < *s.Minimum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be >= %v", *s.Minimum)}
+		}
+		if s.Maximum != nil && v > *s.Maximum {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must be <= %v", *s.Maximum)}
+		}
+	case string:
+		if s.MinLength != nil && len([]rune(v)) < *s.MinLength {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("length must be >= %d", *s.MinLength)}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			return &ValidationError{Path: path, Message: fmt.Sprintf("must contain at least %d items", *s.MinItems)}
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]any:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: path, Message: fmt.Sprintf("missing required field %q", name)}
+			}
+		}
+		// 按字段名排序，保证多个字段不合法时返回的错误稳定
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return &ValidationError{Path: path, Message: fmt.Sprintf("unknown field %q", name)}
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, v[name]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// enumContains 仅支持标量枚举值，数组与对象不相等
+func enumContains(enum []any, value any) bool {
+	switch value.(type) {
+	case []any, map[string]any:
+		return false
+	}
+	for _, item := range enum {
+		switch item.(type) {
+		case []any, map[string]any:
+			continue
+		}
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
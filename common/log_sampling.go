@@ -0,0 +1,80 @@
+package common
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// 高频日志的类别，同一类别共享限流额度
+const (
+	LogCategoryChannelTest = "channel_test"
+	LogCategoryGeminiCache = "gemini_cache"
+)
+
+// LogSampleRate 按类别输出的日志的采样率，取值 0~1，1 表示全部输出
+var LogSampleRate = 1.0
+
+// LogRateLimitPerMinute 每个类别每分钟最多输出的日志条数，0 表示不限制
+var LogRateLimitPerMinute = 60
+
+// LogMaxLength 按类别输出的日志的最大长度（字节），超出部分截断，0 表示不截断
+var LogMaxLength = 4096
+
+type logCategoryState struct {
+	windowStart int64
+	count       int
+	dropped     int
+}
+
+var (
+	logCategoryLock   sync.Mutex
+	logCategoryStates = make(map[string]*logCategoryState)
+)
+
+// allowCategoryLog 按分钟窗口限流，返回是否输出以及此前被丢弃的条数
+func allowCategoryLog(category string) (bool, int) {
+	logCategoryLock.Lock()
+	defer logCategoryLock.Unlock()
+	state, ok := logCategoryStates[category]
+	if !ok {
+		state = &logCategoryState{}
+		logCategoryStates[category] = state
+	}
+	window := time.Now().Unix() / 60
+	if state.windowStart != window {
+		state.windowStart = window
+		state.count = 0
+	}
+	if (LogSampleRate < 1 && rand.Float64() >= LogSampleRate) ||
+		(LogRateLimitPerMinute > 0 && state.count >= LogRateLimitPerMinute) {
+		state.dropped++
+		return false, 0
+	}
+	state.count++
+	dropped := state.dropped
+	state.dropped = 0
+	return true, dropped
+}
+
+// TruncateLog 将日志截断到 LogMaxLength
+func TruncateLog(s string) string {
+	if LogMaxLength <= 0 || len(s) <= LogMaxLength {
+		return s
+	}
+	return fmt.Sprintf("%s...(truncated, %d bytes total)", s[:LogMaxLength], len(s))
+}
+
+// SysLogSampled 用于热点路径的系统日志，按类别采样、限流并截断，被丢弃的条数在下一条输出时附带
+func SysLogSampled(category string, s string) {
+	ok, dropped := allowCategoryLog(category)
+	if !ok {
+		return
+	}
+	s = TruncateLog(s)
+	if dropped > 0 {
+		s = fmt.Sprintf("%s (%d %s logs suppressed)", s, dropped, category)
+	}
+	SysLog(s)
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 	"unsafe"
 )
 
@@ -190,3 +191,14 @@ func MaskSensitiveInfo(str string) string {
 
 	return str
 }
+
+// TruncateUTF8 将字符串截断到不超过 maxBytes 字节，且不会截断多字节字符
+func TruncateUTF8(str string, maxBytes int) string {
+	if maxBytes <= 0 || len(str) <= maxBytes {
+		return str
+	}
+	for maxBytes > 0 && !utf8.RuneStart(str[maxBytes]) {
+		maxBytes--
+	}
+	return str[:maxBytes]
+}
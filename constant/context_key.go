@@ -15,6 +15,9 @@ const (
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenEnableGeminiCache ContextKey = "token_enable_gemini_cache"
+	ContextKeyTokenMaxResponseTokens ContextKey = "token_max_response_tokens"
+	ContextKeyTokenMaxResponseBytes  ContextKey = "token_max_response_bytes"
+	ContextKeyTokenAllowedEndpoints  ContextKey = "token_allowed_endpoints"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -44,4 +47,28 @@ const (
 	ContextKeyUserName    ContextKey = "username"
 
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
+
+	ContextKeyRequestMetadata       ContextKey = "request_metadata"
+	ContextKeyRequestMetadataEchoed ContextKey = "request_metadata_echoed"
+
+	// ContextKeyStreamResumable 流式响应会被缓存以便断线续传，客户端断开后仍需读完上游
+	ContextKeyStreamResumable ContextKey = "stream_resumable"
+
+	// ContextKeyPromptLanguage 开启语言路由时检测到的提示词语言
+	ContextKeyPromptLanguage ContextKey = "prompt_language"
+
+	// ContextKeyLogId 本次请求最后写入的消费或错误日志 id
+	ContextKeyLogId ContextKey = "log_id"
+
+	// ContextKeyRelayAttempts 本次请求中失败并已重试的上游请求
+	ContextKeyRelayAttempts ContextKey = "relay_attempts"
+
+	// ContextKeyPendingQuota 本次请求预扣且尚未结算的额度
+	ContextKeyPendingQuota ContextKey = "pending_quota"
+
+	// ContextKeyRequestHasTools 开启工具路由时，请求包含工具定义
+	ContextKeyRequestHasTools ContextKey = "request_has_tools"
+
+	// ContextKeyPassedThroughHeaders 已透传给客户端的上游响应头，重试时先清除
+	ContextKeyPassedThroughHeaders ContextKey = "passed_through_headers"
 )
@@ -3,6 +3,7 @@ package constant
 var StreamingTimeout int
 var DifyDebug bool
 var MaxFileDownloadMB int
+var MaxRequestBodyMB int
 var ForceStreamOption bool
 var GetMediaToken bool
 var GetMediaTokenNotStream bool
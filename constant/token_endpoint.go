@@ -0,0 +1,34 @@
+package constant
+
+// 令牌可限制访问的接口类别
+const (
+	TokenEndpointChat        = "chat" // chat/completions、completions、edits
+	TokenEndpointResponses   = "responses"
+	TokenEndpointMessages    = "messages" // Claude messages
+	TokenEndpointEmbeddings  = "embeddings"
+	TokenEndpointImages      = "images"
+	TokenEndpointAudio       = "audio"
+	TokenEndpointModerations = "moderations"
+	TokenEndpointRerank      = "rerank"
+	TokenEndpointRealtime    = "realtime"
+	TokenEndpointGemini      = "gemini" // Gemini 原生接口
+	TokenEndpointMidjourney  = "midjourney"
+	TokenEndpointSuno        = "suno"
+	TokenEndpointVideo       = "video"
+)
+
+var TokenEndpoints = []string{
+	TokenEndpointChat,
+	TokenEndpointResponses,
+	TokenEndpointMessages,
+	TokenEndpointEmbeddings,
+	TokenEndpointImages,
+	TokenEndpointAudio,
+	TokenEndpointModerations,
+	TokenEndpointRerank,
+	TokenEndpointRealtime,
+	TokenEndpointGemini,
+	TokenEndpointMidjourney,
+	TokenEndpointSuno,
+	TokenEndpointVideo,
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	"one-api/relay"
+	"one-api/relay/channel"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdaptorFeatures 渠道类型的适配器实现了哪些格式转换，未实现的转换在请求时会返回 501
+type AdaptorFeatures struct {
+	ChannelType int             `json:"channel_type"`
+	ChannelName string          `json:"channel_name"`
+	Features    map[string]bool `json:"features"`
+}
+
+var (
+	adaptorFeatures     []AdaptorFeatures
+	adaptorFeaturesOnce sync.Once
+)
+
+// isNotImplementedError 适配器对未实现的转换统一返回 not implemented / not supported
+func isNotImplementedError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.HasPrefix(msg, "not implemented") || strings.HasPrefix(msg, "not supported")
+}
+
+// probeConversion 以空请求调用转换方法，返回错误以外的结果（包括因空请求 panic）都视为已实现
+func probeConversion(convert func() error) (supported bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			supported = true
+		}
+	}()
+	err := convert()
+	return err == nil || !isNotImplementedError(err)
+}
+
+func probeAdaptorFeatures(channelType int, apiType int) map[string]bool {
+	newProbe := func(relayMode int) (*gin.Context, *relaycommon.RelayInfo) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+		info := &relaycommon.RelayInfo{
+			ChannelType: channelType,
+			RelayMode:   relayMode,
+		}
+		return c, info
+	}
+	probe := func(relayMode int, convert func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error) bool {
+		return probeConversion(func() error {
+			c, info := newProbe(relayMode)
+			adaptor := relay.GetAdaptor(apiType)
+			adaptor.Init(info)
+			return convert(adaptor, c, info)
+		})
+	}
+	return map[string]bool{
+		"chat": probe(relayconstant.RelayModeChatCompletions, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertOpenAIRequest(c, info, &dto.GeneralOpenAIRequest{})
+			return err
+		}),
+		"claude": probe(relayconstant.RelayModeChatCompletions, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertClaudeRequest(c, info, &dto.ClaudeRequest{})
+			return err
+		}),
+		"gemini": probe(relayconstant.RelayModeGemini, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertGeminiRequest(c, info, &dto.GeminiChatRequest{})
+			return err
+		}),
+		"responses": probe(relayconstant.RelayModeResponses, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertOpenAIResponsesRequest(c, info, dto.OpenAIResponsesRequest{})
+			return err
+		}),
+		"audio": probe(relayconstant.RelayModeAudioSpeech, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertAudioRequest(c, info, dto.AudioRequest{})
+			return err
+		}),
+		"rerank": probe(relayconstant.RelayModeRerank, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertRerankRequest(c, info.RelayMode, dto.RerankRequest{})
+			return err
+		}),
+		"embeddings": probe(relayconstant.RelayModeEmbeddings, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertEmbeddingRequest(c, info, dto.EmbeddingRequest{})
+			return err
+		}),
+		"image": probe(relayconstant.RelayModeImagesGenerations, func(adaptor channel.Adaptor, c *gin.Context, info *relaycommon.RelayInfo) error {
+			_, err := adaptor.ConvertImageRequest(c, info, dto.ImageRequest{})
+			return err
+		}),
+	}
+}
+
+// GetAdaptorFeatures 返回各渠道类型适配器支持的转换矩阵，结果由适配器实现探测得出
+func GetAdaptorFeatures(c *gin.Context) {
+	adaptorFeaturesOnce.Do(func() {
+		for channelType := 1; channelType < constant.ChannelTypeDummy; channelType++ {
+			apiType, ok := common.ChannelType2APIType(channelType)
+			if !ok || apiType == constant.APITypeAIProxyLibrary {
+				continue
+			}
+			adaptor := relay.GetAdaptor(apiType)
+			if adaptor == nil {
+				continue
+			}
+			adaptor.Init(&relaycommon.RelayInfo{ChannelType: channelType})
+			adaptorFeatures = append(adaptorFeatures, AdaptorFeatures{
+				ChannelType: channelType,
+				ChannelName: adaptor.GetChannelName(),
+				Features:    probeAdaptorFeatures(channelType, apiType),
+			})
+		}
+	})
+	common.ApiSuccess(c, adaptorFeatures)
+}
@@ -16,10 +16,12 @@ import (
 	"one-api/middleware"
 	"one-api/model"
 	"one-api/relay"
+	"one-api/relay/channel"
 	relaycommon "one-api/relay/common"
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
+	"one-api/setting/operation_setting"
 	"one-api/types"
 	"strconv"
 	"strings"
@@ -28,15 +30,83 @@ import (
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 type testResult struct {
 	context     *gin.Context
 	localErr    error
 	newAPIError *types.NewAPIError
+	streamStats *testStreamStats
+	usage       *dto.Usage
+	quota       int
+}
+
+// testOptions 覆盖默认测试请求的提示词与输出长度，用于基准测试
+type testOptions struct {
+	Prompt    string
+	MaxTokens uint
+	DryRun    bool // 仍请求上游，但不记录消费日志
+	KeyIndex  *int // 多 Key 渠道指定测试的 key 下标，为空时按渠道的轮询策略选择
+}
+
+// testStreamStats 流式测试的耗时统计，单位为秒
+type testStreamStats struct {
+	FirstTokenTime float64 `json:"first_token_time"`
+	Duration       float64 `json:"duration"`
+	Chunks         int     `json:"chunks"`
 }
 
 func testChannel(channel *model.Channel, testModel string, testType string) testResult {
+	testModel = getChannelTestModel(channel, testModel)
+	tik := time.Now()
+	result := testChannelWithOptions(channel, testModel, testType, nil)
+	recordChannelTestResult(channel, testModel, testType, result, time.Since(tik).Milliseconds())
+	return result
+}
+
+// getChannelTestModel 未指定测试模型时依次使用渠道的测试模型、第一个模型
+func getChannelTestModel(channel *model.Channel, testModel string) string {
+	if testModel != "" {
+		return testModel
+	}
+	if channel.TestModel != nil && *channel.TestModel != "" {
+		return *channel.TestModel
+	}
+	if len(channel.GetModels()) > 0 {
+		return channel.GetModels()[0]
+	}
+	return "gpt-4o-mini"
+}
+
+// recordChannelTestResult 保存测试记录，供仪表盘查看历史
+func recordChannelTestResult(channel *model.Channel, testModel string, testType string, result testResult, latency int64) {
+	testType = strings.ToLower(strings.TrimSpace(testType))
+	if testType == "" {
+		testType = "text"
+	}
+	record := &model.ChannelTestResult{
+		ChannelId:   channel.Id,
+		ModelName:   testModel,
+		TestType:    testType,
+		Success:     result.localErr == nil && result.newAPIError == nil,
+		Latency:     latency,
+		CreatedTime: common.GetTimestamp(),
+	}
+	if result.newAPIError != nil {
+		record.ErrorCode = string(result.newAPIError.GetErrorCode())
+		record.ErrorMessage = result.newAPIError.Error()
+	} else if result.localErr != nil {
+		record.ErrorMessage = result.localErr.Error()
+	}
+	gopool.Go(func() {
+		if err := record.Insert(); err != nil {
+			common.SysError("failed to save channel test result: " + err.Error())
+		}
+	})
+}
+
+func testChannelWithOptions(channel *model.Channel, testModel string, testType string, options *testOptions) testResult {
 	tik := time.Now()
 	if channel.Type == constant.ChannelTypeMidjourney {
 		return testResult{localErr: errors.New("midjourney channel test is not supported")}
@@ -59,6 +129,12 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
+	common.SetContextKey(c, constant.ContextKeyRequestStartTime, tik)
+
+	testType = strings.ToLower(strings.TrimSpace(testType))
+	if testType == "" {
+		testType = "text"
+	}
 
 	requestPath := "/v1/chat/completions"
 	isEmbeddingModel := func(m string) bool {
@@ -68,7 +144,9 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 			strings.Contains(m, "bge-") ||
 			strings.Contains(lm, "embed")
 	}
-	if isEmbeddingModel(testModel) || channel.Type == constant.ChannelTypeMokaAI {
+	if testType == "image" {
+		requestPath = "/v1/images/generations"
+	} else if isEmbeddingModel(testModel) || channel.Type == constant.ChannelTypeMokaAI {
 		requestPath = "/v1/embeddings"
 	}
 
@@ -79,21 +157,7 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 		Header: make(http.Header),
 	}
 
-	if testModel == "" {
-		if channel.TestModel != nil && *channel.TestModel != "" {
-			testModel = *channel.TestModel
-		} else {
-			if len(channel.GetModels()) > 0 {
-				testModel = channel.GetModels()[0]
-			} else {
-				testModel = "gpt-4o-mini"
-			}
-		}
-	}
-	testType = strings.ToLower(strings.TrimSpace(testType))
-	if testType == "" {
-		testType = "text"
-	}
+	testModel = getChannelTestModel(channel, testModel)
 
 	// user cache
 	cache, err := model.GetUserCache(1)
@@ -112,6 +176,19 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 	if newAPIError != nil {
 		return testResult{context: c, localErr: newAPIError, newAPIError: newAPIError}
 	}
+	if options != nil && options.KeyIndex != nil {
+		keys := channel.GetKeys()
+		keyIndex := *options.KeyIndex
+		if keyIndex < 0 || keyIndex >= len(keys) {
+			return testResult{context: c, localErr: fmt.Errorf("key index %d out of range", keyIndex)}
+		}
+		common.SetContextKey(c, constant.ContextKeyChannelKey, keys[keyIndex])
+		common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, keyIndex)
+	} else if testKey, keyIndex, ok := channel.GetTestKey(); ok {
+		// 多Key渠道优先使用测试专用key，避免健康检查消耗生产key的速率限制
+		common.SetContextKey(c, constant.ContextKeyChannelKey, testKey)
+		common.SetContextKey(c, constant.ContextKeyChannelMultiKeyIndex, keyIndex)
+	}
 
 	info := relaycommon.GenRelayInfo(c)
 
@@ -131,21 +208,45 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 		return testResult{context: c, localErr: err, newAPIError: types.NewError(err, types.ErrorCodeInvalidApiType)}
 	}
 
-	request := buildTestRequest(testModel, testType)
+	var request *dto.GeneralOpenAIRequest
+	var imageRequest *dto.ImageRequest
+	maxTokens := 0
+	if testType == "image" {
+		imageRequest = buildTestImageRequest(testModel)
+	} else {
+		request = buildTestRequest(testModel, testType)
+		if err := applyTestRequestTemplate(request, adaptor, info.ChannelSetting); err != nil {
+			return testResult{context: c, localErr: err, newAPIError: types.NewError(err, types.ErrorCodeInvalidRequest)}
+		}
+		if options != nil {
+			applyTestOptions(request, options)
+		}
+		maxTokens = int(request.GetMaxTokens())
+	}
 
 	logInfo := *info
 	logInfo.ApiKey = ""
-	common.SysLog(fmt.Sprintf("testing channel %d with model %s (type=%s), info %+v", channel.Id, testModel, testType, logInfo))
+	common.SysLogSampled(common.LogCategoryChannelTest, fmt.Sprintf("testing channel %d with model %s (type=%s), info %+v", channel.Id, testModel, testType, logInfo))
 
-	priceData, err := helper.ModelPriceHelper(c, info, 0, int(request.GetMaxTokens()))
+	priceData, err := helper.ModelPriceHelper(c, info, 0, maxTokens)
 	if err != nil {
 		return testResult{context: c, localErr: err, newAPIError: types.NewError(err, types.ErrorCodeModelPriceError)}
 	}
 
+	if request != nil && request.Stream {
+		info.IsStream = true
+		info.ShouldIncludeUsage = true
+		if !info.SupportStreamOptions {
+			request.StreamOptions = nil
+		}
+	}
+
 	adaptor.Init(info)
 
 	var convertedRequest any
-	if info.RelayMode == relayconstant.RelayModeEmbeddings {
+	if imageRequest != nil {
+		convertedRequest, err = adaptor.ConvertImageRequest(c, info, *imageRequest)
+	} else if info.RelayMode == relayconstant.RelayModeEmbeddings {
 		embeddingRequest := dto.EmbeddingRequest{
 			Input: request.Input,
 			Model: request.Model,
@@ -166,6 +267,7 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 	requestBody := bytes.NewBuffer(jsonData)
 	c.Request.Body = io.NopCloser(requestBody)
 
+	requestStart := time.Now()
 	resp, err := adaptor.DoRequest(c, info, requestBody)
 	if err != nil {
 		return testResult{context: c, localErr: err, newAPIError: types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)}
@@ -195,8 +297,29 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 	if err != nil {
 		return testResult{context: c, localErr: err, newAPIError: types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)}
 	}
+	if imageRequest != nil {
+		if err := validateTestImage(respBody); err != nil {
+			return testResult{context: c, localErr: err, newAPIError: types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)}
+		}
+		// 与图片生成一致，上游未返回用量时按生成张数计
+		if usage.PromptTokens == 0 {
+			usage.PromptTokens = imageRequest.N
+		}
+	}
 	info.PromptTokens = usage.PromptTokens
 
+	var streamStats *testStreamStats
+	if info.IsStream {
+		streamStats, err = validateTestStream(string(respBody))
+		if err != nil {
+			return testResult{context: c, localErr: err, newAPIError: types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)}
+		}
+		streamStats.Duration = time.Since(requestStart).Seconds()
+		if info.HasSendResponse() {
+			streamStats.FirstTokenTime = info.FirstResponseTime.Sub(requestStart).Seconds()
+		}
+	}
+
 	quota := 0
 	if !priceData.UsePrice {
 		quota = usage.PromptTokens + int(math.Round(float64(usage.CompletionTokens)*priceData.CompletionRatio))
@@ -217,25 +340,141 @@ func testChannel(channel *model.Channel, testModel string, testType string) test
 		priceData.ModelRatio, priceData.GroupRatioInfo.GroupRatio, priceData.CompletionRatio,
 		usage.PromptTokensDetails.CachedTokens, priceData.CacheRatio, priceData.ModelPrice, priceData.GroupRatioInfo.GroupSpecialRatio,
 	)
-	model.RecordConsumeLog(c, 1, model.RecordConsumeLogParams{
-		ChannelId:        channel.Id,
-		PromptTokens:     usage.PromptTokens,
-		CompletionTokens: usage.CompletionTokens,
-		ModelName:        info.OriginModelName,
-		TokenName:        "模型测试",
-		Quota:            quota,
-		Content:          "模型测试",
-		UseTimeSeconds:   int(consumedTime),
-		IsStream:         info.IsStream,
-		Group:            info.UsingGroup,
-		Other:            other,
-	})
+	if options == nil || !options.DryRun {
+		model.RecordConsumeLog(c, 1, model.RecordConsumeLogParams{
+			ChannelId:        channel.Id,
+			PromptTokens:     usage.PromptTokens,
+			CompletionTokens: usage.CompletionTokens,
+			ModelName:        info.OriginModelName,
+			TokenName:        "模型测试",
+			Quota:            quota,
+			Content:          "模型测试",
+			UseTimeSeconds:   int(consumedTime),
+			IsStream:         info.IsStream,
+			Group:            info.UsingGroup,
+			Other:            other,
+		})
+	}
+
+	if imageRequest != nil {
+		// 图片响应可能包含很长的 base64，只记录大小
+		common.SysLogSampled(common.LogCategoryChannelTest, fmt.Sprintf("testing channel #%d, image response: %d bytes", channel.Id, len(respBody)))
+	} else {
+		common.SysLogSampled(common.LogCategoryChannelTest, fmt.Sprintf("testing channel #%d, response: \n%s", channel.Id, string(respBody)))
+	}
+
+	return testResult{context: c, localErr: nil, newAPIError: nil, streamStats: streamStats, usage: usage, quota: quota}
+}
 
-	common.SysLog(fmt.Sprintf("testing channel #%d, response: \n%s", channel.Id, string(respBody)))
+// applyTestRequestTemplate 先应用适配器的测试模板，再合并渠道设置中的覆盖字段
+func applyTestRequestTemplate(request *dto.GeneralOpenAIRequest, adaptor channel.Adaptor, setting dto.ChannelSettings) error {
+	if templater, ok := adaptor.(channel.TestRequestTemplater); ok {
+		templater.ApplyTestRequestTemplate(request)
+	}
+	if len(setting.TestRequestTemplate) > 0 {
+		if err := common.Unmarshal(setting.TestRequestTemplate, request); err != nil {
+			return fmt.Errorf("invalid test request template: %w", err)
+		}
+	}
+	return nil
+}
+
+func applyTestOptions(request *dto.GeneralOpenAIRequest, options *testOptions) {
+	if options.Prompt != "" && len(request.Messages) > 0 {
+		request.Messages = []dto.Message{
+			{
+				Role:    "user",
+				Content: options.Prompt,
+			},
+		}
+	}
+	if options.MaxTokens > 0 {
+		if request.MaxCompletionTokens > 0 {
+			request.MaxCompletionTokens = options.MaxTokens
+		} else {
+			request.MaxTokens = options.MaxTokens
+		}
+	}
+}
+
+// validateTestStream 校验流式响应的 SSE 分帧：每个事件都是合法的 chunk，以 [DONE] 结束，且包含最终的 usage
+func validateTestStream(body string) (*testStreamStats, error) {
+	stats := &testStreamStats{}
+	done := false
+	hasUsage := false
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		if !strings.HasPrefix(line, "data:") {
+			return nil, fmt.Errorf("invalid stream line: %s", line)
+		}
+		if done {
+			return nil, errors.New("stream data received after [DONE]")
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk dto.ChatCompletionsStreamResponse
+		if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+			return nil, fmt.Errorf("invalid stream chunk: %s", data)
+		}
+		stats.Chunks++
+		if chunk.Usage != nil && chunk.Usage.TotalTokens > 0 {
+			hasUsage = true
+		}
+	}
+	if stats.Chunks == 0 {
+		return nil, errors.New("stream contains no chunks")
+	}
+	if !done {
+		return nil, errors.New("stream is not terminated with [DONE]")
+	}
+	if !hasUsage {
+		return nil, errors.New("stream does not contain a usage chunk")
+	}
+	return stats, nil
+}
 
-	return testResult{context: c, localErr: nil, newAPIError: nil}
+// validateTestImage 校验图片生成响应中至少包含一张图片（URL 或 base64）
+func validateTestImage(body []byte) error {
+	var imageResponse dto.ImageResponse
+	if err := common.Unmarshal(body, &imageResponse); err != nil {
+		return fmt.Errorf("invalid image response: %s", err.Error())
+	}
+	for _, data := range imageResponse.Data {
+		if data.Url != "" || data.B64Json != "" {
+			return nil
+		}
+	}
+	return errors.New("no image returned in response")
 }
 
+// buildTestImageRequest 构造最小的图片生成请求，尽量选择模型支持的最小尺寸与最低质量
+func buildTestImageRequest(modelName string) *dto.ImageRequest {
+	req := &dto.ImageRequest{
+		Model:  modelName,
+		Prompt: "A small red circle on a white background.",
+		N:      1,
+	}
+	switch {
+	case strings.HasPrefix(modelName, "dall-e-2"):
+		req.Size = "256x256"
+	case strings.HasPrefix(modelName, "dall-e"):
+		req.Size = "1024x1024"
+	case strings.HasPrefix(modelName, "gpt-image"):
+		req.Size = "1024x1024"
+		req.Quality = "low"
+	}
+	return req
+}
+
+// testVisionImage 16x16 纯红色 PNG，用于测试渠道的多模态链路
+const testVisionImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAABAAAAAQCAIAAACQkWg2AAAAFklEQVR42mP4z8BAEmIY1TCqYfhqAACQ+f8B8u7oVwAAAABJRU5ErkJggg=="
+
 func buildTestRequest(modelName string, testType string) *dto.GeneralOpenAIRequest {
 	req := &dto.GeneralOpenAIRequest{
 		Model:  "",
@@ -319,6 +558,36 @@ func buildTestRequest(modelName string, testType string) *dto.GeneralOpenAIReque
 		}
 		req.Messages = append(req.Messages, sys, user)
 
+	case "vision":
+		user := dto.Message{
+			Role: "user",
+		}
+		user.SetMediaContent([]dto.MediaContent{
+			{
+				Type: dto.ContentTypeText,
+				Text: "What color is this image? Answer in one word.",
+			},
+			{
+				Type: dto.ContentTypeImageURL,
+				ImageUrl: map[string]any{
+					"url":    testVisionImage,
+					"detail": "low",
+				},
+			},
+		})
+		req.Messages = append(req.Messages, user)
+
+	case "stream":
+		req.Stream = true
+		req.StreamOptions = &dto.StreamOptions{
+			IncludeUsage: true,
+		}
+		msg := dto.Message{
+			Role:    "user",
+			Content: "hi",
+		}
+		req.Messages = append(req.Messages, msg)
+
 	default: // "text"
 		msg := dto.Message{
 			Role:    "user",
@@ -346,10 +615,17 @@ func TestChannel(c *gin.Context) {
 	}
 
 	testModel := c.Query("model")
-	testType := strings.ToLower(c.Query("type")) // "", "text", "json", "function"
+	testType := strings.ToLower(c.Query("type")) // "", "text", "json", "function", "vision", "stream", "image"
+	// dry_run 仍请求上游，但不记录消费日志、测试历史与响应时间
+	dryRun := c.Query("dry_run") == "true"
 	tik := time.Now()
 
-	result := testChannel(channel, testModel, testType)
+	var result testResult
+	if dryRun {
+		result = testChannelWithOptions(channel, testModel, testType, &testOptions{DryRun: true})
+	} else {
+		result = testChannel(channel, testModel, testType)
+	}
 	if result.localErr != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"success": false,
@@ -361,7 +637,9 @@ func TestChannel(c *gin.Context) {
 
 	tok := time.Now()
 	milliseconds := tok.Sub(tik).Milliseconds()
-	go channel.UpdateResponseTime(milliseconds)
+	if !dryRun {
+		go channel.UpdateResponseTime(milliseconds)
+	}
 	consumedTime := float64(milliseconds) / 1000.0
 	if result.newAPIError != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -371,17 +649,288 @@ func TestChannel(c *gin.Context) {
 		})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
 		"message": "",
 		"time":    consumedTime,
+	}
+	if result.streamStats != nil {
+		response["stream"] = result.streamStats
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+const (
+	// channelUpdateTestTimeout 保存渠道时等待测试结果的最长时间，超时的测试在后台继续执行
+	channelUpdateTestTimeout = 10 * time.Second
+	// channelUpdateTestMaxModels 保存渠道时最多测试的模型数
+	channelUpdateTestMaxModels = 5
+)
+
+// channelUpdateTestModels 返回保存渠道后需要测试的模型：新增的模型，
+// 密钥或代理地址变更、或只删除了模型时测试渠道配置的测试模型
+func channelUpdateTestModels(origin *model.Channel, updated *model.Channel) []string {
+	models := make([]string, 0)
+	if origin.Key != updated.Key || origin.GetBaseURL() != updated.GetBaseURL() {
+		models = append(models, getChannelTestModel(updated, ""))
+	}
+	originModels := origin.GetModels()
+	for _, m := range updated.GetModels() {
+		if !lo.Contains(originModels, m) {
+			models = append(models, m)
+		}
+	}
+	if len(models) == 0 {
+		models = append(models, getChannelTestModel(updated, ""))
+	}
+	models = lo.Uniq(models)
+	if len(models) > channelUpdateTestMaxModels {
+		models = models[:channelUpdateTestMaxModels]
+	}
+	return models
+}
+
+// testUpdatedChannel 使用保存后的渠道配置并发测试变更的模型，便于在保存时发现填写错误。
+// 最多等待 channelUpdateTestTimeout，未返回的模型标记为超时
+func testUpdatedChannel(origin *model.Channel, channelId int) []channelModelTestResult {
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		return []channelModelTestResult{{Success: false, Message: err.Error()}}
+	}
+	models := channelUpdateTestModels(origin, channel)
+	results := make([]channelModelTestResult, len(models))
+	for i, testModel := range models {
+		results[i] = channelModelTestResult{Model: testModel, Message: "测试超时，结果未在保存时返回"}
+	}
+	var lock sync.Mutex
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i, testModel := range models {
+		wg.Add(1)
+		gopool.Go(func() {
+			defer wg.Done()
+			tik := time.Now()
+			result := testChannel(channel, testModel, "")
+			milliseconds := time.Since(tik).Milliseconds()
+			item := channelModelTestResult{
+				Model:   testModel,
+				Success: true,
+				Time:    float64(milliseconds) / 1000.0,
+			}
+			if result.localErr != nil {
+				item.Success = false
+				item.Message = result.localErr.Error()
+				item.Time = 0
+			} else if result.newAPIError != nil {
+				item.Success = false
+				item.Message = result.newAPIError.Error()
+			} else {
+				channel.UpdateResponseTime(milliseconds)
+			}
+			lock.Lock()
+			results[i] = item
+			lock.Unlock()
+		})
+	}
+	gopool.Go(func() {
+		wg.Wait()
+		close(done)
 	})
+	select {
+	case <-done:
+	case <-time.After(channelUpdateTestTimeout):
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return append([]channelModelTestResult(nil), results...)
+}
+
+// channelModelTestConcurrency 单个渠道测试全部模型时的并发数
+const channelModelTestConcurrency = 5
+
+type channelModelTestResult struct {
+	Model   string  `json:"model"`
+	Success bool    `json:"success"`
+	Message string  `json:"message"`
+	Time    float64 `json:"time"`
+}
+
+// TestChannelAllModels 并发测试渠道的所有模型，用于发现上游已下线的模型
+// POST /api/channel/:id/test_all_models
+func TestChannelAllModels(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	models := channel.GetModels()
+	if len(models) == 0 {
+		common.ApiErrorMsg(c, "渠道未配置模型")
+		return
+	}
+	testType := strings.ToLower(c.Query("type"))
+
+	results := make([]channelModelTestResult, len(models))
+	modelQueue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(channelModelTestConcurrency, len(models)); i++ {
+		wg.Add(1)
+		gopool.Go(func() {
+			defer wg.Done()
+			for idx := range modelQueue {
+				tik := time.Now()
+				result := testChannel(channel, models[idx], testType)
+				consumedTime := float64(time.Since(tik).Milliseconds()) / 1000.0
+				item := channelModelTestResult{
+					Model:   models[idx],
+					Success: true,
+					Time:    consumedTime,
+				}
+				if result.localErr != nil {
+					item.Success = false
+					item.Message = result.localErr.Error()
+					item.Time = 0
+				} else if result.newAPIError != nil {
+					item.Success = false
+					item.Message = result.newAPIError.Error()
+				}
+				results[idx] = item
+			}
+		})
+	}
+	for idx := range models {
+		modelQueue <- idx
+	}
+	close(modelQueue)
+	wg.Wait()
+
+	common.ApiSuccess(c, results)
+}
+
+// channelKeyTestConcurrency 多 Key 渠道逐个测试 key 时的并发数
+const channelKeyTestConcurrency = 5
+
+type channelKeyTestResult struct {
+	Index    int     `json:"index"`
+	Status   int     `json:"status"` // 测试前 key 的状态
+	Success  bool    `json:"success"`
+	Message  string  `json:"message"`
+	Time     float64 `json:"time"`
+	Disabled bool    `json:"disabled"` // 本次测试后被禁用
+}
+
+// TestChannelKeys 逐个测试多 Key 渠道的所有 key，disable_failed=true 时只禁用测试失败的 key 而不是整个渠道
+// POST /api/channel/:id/test_keys?model=&type=&disable_failed=
+func TestChannelKeys(c *gin.Context) {
+	channelId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !channel.ChannelInfo.IsMultiKey {
+		common.ApiErrorMsg(c, "该渠道不是多 Key 渠道")
+		return
+	}
+	keys := channel.GetKeys()
+	if len(keys) == 0 {
+		common.ApiErrorMsg(c, "渠道未配置 key")
+		return
+	}
+	testModel := c.Query("model")
+	testType := strings.ToLower(c.Query("type"))
+	disableFailed := c.Query("disable_failed") == "true"
+
+	results := make([]channelKeyTestResult, len(keys))
+	failedErrors := make([]*types.NewAPIError, len(keys))
+	keyQueue := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < min(channelKeyTestConcurrency, len(keys)); i++ {
+		wg.Add(1)
+		gopool.Go(func() {
+			defer wg.Done()
+			for idx := range keyQueue {
+				keyIndex := idx
+				tik := time.Now()
+				result := testChannelWithOptions(channel, testModel, testType, &testOptions{KeyIndex: &keyIndex})
+				item := channelKeyTestResult{
+					Index:   idx,
+					Status:  common.ChannelStatusEnabled,
+					Success: true,
+					Time:    float64(time.Since(tik).Milliseconds()) / 1000.0,
+				}
+				if status, ok := channel.ChannelInfo.MultiKeyStatusList[idx]; ok {
+					item.Status = status
+				}
+				if result.localErr != nil {
+					item.Success = false
+					item.Message = result.localErr.Error()
+					item.Time = 0
+				} else if result.newAPIError != nil {
+					item.Success = false
+					item.Message = result.newAPIError.Error()
+					failedErrors[idx] = result.newAPIError
+				}
+				results[idx] = item
+			}
+		})
+	}
+	for idx := range keys {
+		keyQueue <- idx
+	}
+	close(keyQueue)
+	wg.Wait()
+
+	// 禁用 key 会读写整个渠道信息，测试全部完成后再依次处理，避免并发更新互相覆盖
+	if disableFailed {
+		for idx, newAPIError := range failedErrors {
+			if newAPIError == nil || results[idx].Status != common.ChannelStatusEnabled {
+				continue
+			}
+			// 限流、过载等瞬时错误不能说明 key 失效
+			category := service.ClassifyChannelError(channel.Type, newAPIError)
+			if category == "" || service.IsTransientChannelErrorCategory(category) {
+				continue
+			}
+			reason := fmt.Sprintf("key test failed: %s", newAPIError.Error())
+			if model.UpdateChannelStatus(channel.Id, keys[idx], common.ChannelStatusAutoDisabled, reason) {
+				results[idx].Disabled = true
+			}
+		}
+	}
+
+	common.ApiSuccess(c, results)
+}
+
+// GetChannelTestResults 分页查询渠道测试历史
+// GET /api/channel/test_results?channel_id=&model_name=
+func GetChannelTestResults(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	results, total, err := model.GetChannelTestResults(channelId, c.Query("model_name"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(results)
+	common.ApiSuccess(c, pageInfo)
 }
 
 var testAllChannelsLock sync.Mutex
 var testAllChannelsRunning bool = false
 
-func testAllChannels(notify bool) error {
+// testAllChannels 批量测试渠道，filter 不为空时只测试其返回 true 的渠道
+func testAllChannels(notify bool, filter func(channel *model.Channel) bool) error {
 	testAllChannelsLock.Lock()
 	if testAllChannelsRunning {
 		testAllChannelsLock.Unlock()
@@ -391,9 +940,18 @@ func testAllChannels(notify bool) error {
 	testAllChannelsLock.Unlock()
 
 	channels, getChannelErr := model.GetAllChannels(0, 0, true, false)
-	if getChannelErr != nil {
+	if getChannelErr == nil && filter != nil {
+		channels = lo.Filter(channels, func(channel *model.Channel, _ int) bool {
+			return filter(channel)
+		})
+	}
+	if getChannelErr != nil || len(channels) == 0 {
+		testAllChannelsLock.Lock()
+		testAllChannelsRunning = false
+		testAllChannelsLock.Unlock()
 		return getChannelErr
 	}
+	common.SysLog(fmt.Sprintf("testing %d channels", len(channels)))
 	var disableThreshold = int64(common.ChannelDisableThreshold * 1000)
 	if disableThreshold == 0 {
 		disableThreshold = 10000000
@@ -406,51 +964,39 @@ func testAllChannels(notify bool) error {
 			testAllChannelsLock.Unlock()
 		}()
 
+		// 同一上游的渠道共用一把锁，依次测试并保持 RequestInterval 间隔，不同上游并行测试
+		upstreamLocks := make(map[string]*sync.Mutex)
 		for _, channel := range channels {
-			isChannelEnabled := channel.Status == common.ChannelStatusEnabled
-			tik := time.Now()
-			result := testChannel(channel, "", "")
-			tok := time.Now()
-			milliseconds := tok.Sub(tik).Milliseconds()
-
-			shouldBanChannel := false
-			newAPIError := result.newAPIError
-			if newAPIError != nil {
-				shouldBanChannel = service.ShouldDisableChannel(channel.Type, result.newAPIError)
+			upstream := getChannelUpstream(channel)
+			if _, ok := upstreamLocks[upstream]; !ok {
+				upstreamLocks[upstream] = &sync.Mutex{}
 			}
+		}
 
-			if common.AutomaticDisableChannelEnabled && !shouldBanChannel {
-				if milliseconds > disableThreshold {
-					err := fmt.Errorf("响应时间 %.2fs 超过阈值 %.2fs", float64(milliseconds)/1000.0, float64(disableThreshold)/1000.0)
-					newAPIError = types.NewOpenAIError(err, types.ErrorCodeChannelResponseTimeExceeded, http.StatusRequestTimeout)
-					shouldBanChannel = true
+		channelQueue := make(chan *model.Channel)
+		var wg sync.WaitGroup
+		for i := 0; i < max(common.ChannelTestConcurrency, 1); i++ {
+			wg.Add(1)
+			gopool.Go(func() {
+				defer wg.Done()
+				for channel := range channelQueue {
+					lock := upstreamLocks[getChannelUpstream(channel)]
+					lock.Lock()
+					testChannelAndUpdateStatus(channel, disableThreshold)
+					time.Sleep(common.RequestInterval)
+					lock.Unlock()
 				}
+			})
+		}
+		for _, channel := range channels {
+			// 排空中的渠道即将删除，不参与测试，避免测试结果改变其状态
+			if channel.Status == common.ChannelStatusDraining {
+				continue
 			}
-
-			// disable
-			if isChannelEnabled && shouldBanChannel && channel.GetAutoBan() {
-				go processChannelError(
-					result.context,
-					*types.NewChannelError(
-						channel.Id,
-						channel.Type,
-						channel.Name,
-						channel.ChannelInfo.IsMultiKey,
-						common.GetContextKeyString(result.context, constant.ContextKeyChannelKey),
-						channel.GetAutoBan(),
-					),
-					newAPIError,
-				)
-			}
-
-			// enable
-			if !isChannelEnabled && service.ShouldEnableChannel(newAPIError, channel.Status) {
-				service.EnableChannel(channel.Id, common.GetContextKeyString(result.context, constant.ContextKeyChannelKey), channel.Name)
-			}
-
-			channel.UpdateResponseTime(milliseconds)
-			time.Sleep(common.RequestInterval)
+			channelQueue <- channel
 		}
+		close(channelQueue)
+		wg.Wait()
 
 		if notify {
 			service.NotifyRootUser(dto.NotifyTypeChannelTest, "通道测试完成", "所有通道测试已完成")
@@ -459,8 +1005,93 @@ func testAllChannels(notify bool) error {
 	return nil
 }
 
+// getChannelUpstream 返回渠道的上游地址，用于批量测试时按上游限速
+func getChannelUpstream(channel *model.Channel) string {
+	if channel.GetBaseURL() != "" {
+		return channel.GetBaseURL()
+	}
+	if baseURL := constant.ChannelBaseURLs[channel.Type]; baseURL != "" {
+		return baseURL
+	}
+	return fmt.Sprintf("type:%d", channel.Type)
+}
+
+// testChannelAndUpdateStatus 测试单个渠道，并根据结果自动禁用或启用渠道
+func testChannelAndUpdateStatus(channel *model.Channel, disableThreshold int64) {
+	isChannelEnabled := channel.Status == common.ChannelStatusEnabled
+	tik := time.Now()
+	result := testChannel(channel, "", "")
+	tok := time.Now()
+	milliseconds := tok.Sub(tik).Milliseconds()
+
+	shouldBanChannel := false
+	newAPIError := result.newAPIError
+	if newAPIError != nil {
+		// 是否禁用由 processChannelError 计入滚动窗口后决定，这里只判断错误类型，避免重复计数
+		// 限流、过载等瞬时错误不说明渠道损坏，仍按响应时间判断
+		category := service.ClassifyChannelError(channel.Type, result.newAPIError)
+		shouldBanChannel = category != "" && !service.IsTransientChannelErrorCategory(category)
+	}
+
+	disableSetting := operation_setting.GetChannelDisableSetting()
+	if disableSetting.LatencyPercentileEnabled && result.localErr == nil && newAPIError == nil {
+		service.RecordChannelLatency(channel.Id, milliseconds, disableSetting.LatencyWindowSize)
+	}
+	if common.AutomaticDisableChannelEnabled && !shouldBanChannel {
+		if disableSetting.LatencyPercentileEnabled {
+			// 单次慢响应不禁用，最近样本的 p95 超过阈值才禁用
+			p95, samples := service.GetChannelLatencyP95(channel.Id)
+			if samples >= disableSetting.LatencyMinSamples && p95 > disableThreshold {
+				err := fmt.Errorf("最近 %d 次响应时间 p95 %.2fs 超过阈值 %.2fs", samples, float64(p95)/1000.0, float64(disableThreshold)/1000.0)
+				newAPIError = types.NewOpenAIError(err, types.ErrorCodeChannelResponseTimeExceeded, http.StatusRequestTimeout)
+				shouldBanChannel = true
+			}
+		} else if milliseconds > disableThreshold {
+			err := fmt.Errorf("响应时间 %.2fs 超过阈值 %.2fs", float64(milliseconds)/1000.0, float64(disableThreshold)/1000.0)
+			newAPIError = types.NewOpenAIError(err, types.ErrorCodeChannelResponseTimeExceeded, http.StatusRequestTimeout)
+			shouldBanChannel = true
+		}
+	}
+
+	// disable
+	isChannelQuarantined := channel.Status == common.ChannelStatusQuarantined
+	if (isChannelEnabled || isChannelQuarantined) && shouldBanChannel && channel.GetAutoBan() {
+		go processChannelError(
+			result.context,
+			*types.NewChannelError(
+				channel.Id,
+				channel.Type,
+				channel.Name,
+				channel.ChannelInfo.IsMultiKey,
+				common.GetContextKeyString(result.context, constant.ContextKeyChannelKey),
+				channel.GetAutoBan(),
+			),
+			newAPIError,
+		)
+	}
+
+	// enable
+	if !isChannelEnabled && service.ShouldEnableChannel(newAPIError, channel.Status) {
+		usingKey := common.GetContextKeyString(result.context, constant.ContextKeyChannelKey)
+		event := &service.ChannelStatusEvent{
+			ModelName: common.GetContextKeyString(result.context, constant.ContextKeyOriginalModel),
+			Latency:   milliseconds,
+		}
+		if operation_setting.GetChannelWarmUpSetting().ShouldWarmUp() {
+			go warmUpAndEnableChannel(channel.Id, usingKey, event)
+		} else {
+			service.EnableChannel(channel.Id, usingKey, channel.Name, event)
+		}
+	}
+
+	channel.UpdateResponseTime(milliseconds)
+	if result.localErr == nil && newAPIError == nil {
+		tuneChannelPriority(channel)
+	}
+}
+
 func TestAllChannels(c *gin.Context) {
-	err := testAllChannels(true)
+	err := testAllChannels(true, nil)
 	if err != nil {
 		common.ApiError(c, err)
 		return
@@ -473,10 +1104,33 @@ func AutomaticallyTestChannels(frequency int) {
 		common.SysLog("CHANNEL_TEST_FREQUENCY is not set or invalid, skipping automatic channel test")
 		return
 	}
+	// 每分钟检查一次，只测试距上次测试已超过各自间隔的渠道
 	for {
-		time.Sleep(time.Duration(frequency) * time.Minute)
-		common.SysLog("testing all channels")
-		_ = testAllChannels(false)
-		common.SysLog("channel test finished")
+		time.Sleep(time.Minute)
+		now := common.GetTimestamp()
+		_ = testAllChannels(false, func(channel *model.Channel) bool {
+			testFrequency, ok := getChannelTestFrequency(channel, frequency)
+			return ok && now-channel.TestTime >= int64(testFrequency)*60
+		})
+	}
+}
+
+// getChannelTestFrequency 返回渠道的自动测试间隔（分钟），依次使用渠道设置、标签设置、全局设置，
+// 渠道或标签被排除时返回 false
+func getChannelTestFrequency(channel *model.Channel, defaultFrequency int) (int, bool) {
+	setting := channel.GetSetting()
+	if setting.TestDisabled {
+		return 0, false
+	}
+	if setting.TestFrequency > 0 {
+		return setting.TestFrequency, true
+	}
+	testSetting := operation_setting.GetChannelTestSetting()
+	if testSetting.IsTagExcluded(channel.GetTag()) {
+		return 0, false
+	}
+	if tagFrequency := testSetting.GetTagFrequency(channel.GetTag()); tagFrequency > 0 {
+		return tagFrequency, true
 	}
+	return defaultFrequency, true
 }
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/model"
+	"one-api/setting/operation_setting"
 	"strconv"
 	"strings"
 
@@ -550,18 +551,37 @@ func AddChannel(c *gin.Context) {
 	return
 }
 
+// DeleteChannel 渠道先进入排空状态，处理中的请求结束后再删除；force=true 时直接删除
 func DeleteChannel(c *gin.Context) {
 	id, _ := strconv.Atoi(c.Param("id"))
-	channel := model.Channel{Id: id}
-	err := channel.Delete()
+	impacts, err := model.GetChannelDeleteImpact([]int{id})
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
-	model.InitChannelCache()
+	if c.Query("force") == "true" {
+		channel := model.Channel{Id: id}
+		err = channel.Delete()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		model.InitChannelCache()
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": "",
+			"data":    impacts,
+		})
+		return
+	}
+	if err = startChannelDeletion([]int{id}); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "",
+		"message": "渠道排空中，处理中的请求结束后自动删除",
+		"data":    impacts,
 	})
 	return
 }
@@ -682,12 +702,11 @@ func DeleteChannelBatch(c *gin.Context) {
 		})
 		return
 	}
-	err = model.BatchDeleteChannels(channelBatch.Ids)
+	err = startChannelDeletion(channelBatch.Ids)
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
-	model.InitChannelCache()
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -798,22 +817,54 @@ func UpdateChannel(c *gin.Context) {
 			// 覆盖模式：直接使用新密钥（默认行为，不需要特殊处理）
 		}
 	}
+	// 开启预热时，手动启用的渠道先保持原状态，预热完成后再启用
+	warmUp := channel.Status == common.ChannelStatusEnabled && originChannel.Status != common.ChannelStatusEnabled &&
+		!originChannel.ChannelInfo.IsMultiKey && operation_setting.GetChannelWarmUpSetting().ShouldWarmUp()
+	if warmUp {
+		channel.Status = originChannel.Status
+	}
 	err = channel.Update()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
 	model.InitChannelCache()
+	if channel.Key != "" && channelKeysRemoved(originChannel.GetKeys(), channel.GetKeys()) {
+		go purgeChannelPromptCaches(originChannel)
+	}
+	message := ""
+	var testResults []channelModelTestResult
+	if warmUp {
+		go warmUpAndEnableChannel(channel.Id, "", nil)
+		message = "渠道预热中，预热完成后自动启用"
+	} else if operation_setting.GetChannelTestSetting().TestOnUpdate && channelConnectionChanged(originChannel, &channel.Channel) {
+		testResults = testUpdatedChannel(originChannel, channel.Id)
+	}
 	channel.Key = ""
 	clearChannelInfo(&channel.Channel)
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"success": true,
-		"message": "",
+		"message": message,
 		"data":    channel,
-	})
+	}
+	if testResults != nil {
+		response["test"] = testResults
+	}
+	c.JSON(http.StatusOK, response)
 	return
 }
 
+// channelConnectionChanged 判断本次修改是否变更了渠道的密钥、代理地址或模型列表
+func channelConnectionChanged(origin *model.Channel, updated *model.Channel) bool {
+	if updated.Key != "" && updated.Key != origin.Key {
+		return true
+	}
+	if updated.BaseURL != nil && *updated.BaseURL != origin.GetBaseURL() {
+		return true
+	}
+	return updated.Models != "" && updated.Models != origin.Models
+}
+
 func FetchModels(c *gin.Context) {
 	var req struct {
 		BaseURL string `json:"base_url"`
@@ -1013,8 +1064,8 @@ func CopyChannel(c *gin.Context) {
 // MultiKeyManageRequest represents the request for multi-key management operations
 type MultiKeyManageRequest struct {
 	ChannelId int    `json:"channel_id"`
-	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_disabled_keys", "get_key_status"
-	KeyIndex  *int   `json:"key_index,omitempty"` // for disable_key and enable_key actions
+	Action    string `json:"action"`              // "disable_key", "enable_key", "delete_disabled_keys", "get_key_status", "set_test_key"
+	KeyIndex  *int   `json:"key_index,omitempty"` // for disable_key, enable_key and set_test_key actions
 	Page      int    `json:"page,omitempty"`      // for get_key_status pagination
 	PageSize  int    `json:"page_size,omitempty"` // for get_key_status pagination
 	Status    *int   `json:"status,omitempty"`    // for get_key_status filtering: 1=enabled, 2=manual_disabled, 3=auto_disabled, nil=all
@@ -1031,6 +1082,8 @@ type MultiKeyStatusResponse struct {
 	EnabledCount        int `json:"enabled_count"`
 	ManualDisabledCount int `json:"manual_disabled_count"`
 	AutoDisabledCount   int `json:"auto_disabled_count"`
+	// 渠道测试专用key索引
+	TestKeyIndex *int `json:"test_key_index,omitempty"`
 }
 
 type KeyStatus struct {
@@ -1182,6 +1235,7 @@ func ManageMultiKeys(c *gin.Context) {
 				EnabledCount:        enabledCount,        // Overall statistics
 				ManualDisabledCount: manualDisabledCount, // Overall statistics
 				AutoDisabledCount:   autoDisabledCount,   // Overall statistics
+				TestKeyIndex:        channel.ChannelInfo.MultiKeyTestKeyIndex,
 			},
 		})
 		return
@@ -1342,6 +1396,37 @@ func ManageMultiKeys(c *gin.Context) {
 		})
 		return
 
+	case "set_test_key":
+		// 未指定索引时取消测试专用key
+		if request.KeyIndex != nil {
+			keyIndex := *request.KeyIndex
+			if keyIndex < 0 || keyIndex >= channel.ChannelInfo.MultiKeySize {
+				c.JSON(http.StatusOK, gin.H{
+					"success": false,
+					"message": "密钥索引超出范围",
+				})
+				return
+			}
+		}
+		channel.ChannelInfo.MultiKeyTestKeyIndex = request.KeyIndex
+
+		err = channel.Update()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+
+		model.InitChannelCache()
+		message := "已取消测试专用密钥"
+		if request.KeyIndex != nil {
+			message = "已设置测试专用密钥"
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"message": message,
+		})
+		return
+
 	case "delete_disabled_keys":
 		keys := channel.GetKeys()
 		var remainingKeys []string
@@ -1349,6 +1434,7 @@ func ManageMultiKeys(c *gin.Context) {
 		var newStatusList = make(map[int]int)
 		var newDisabledTime = make(map[int]int64)
 		var newDisabledReason = make(map[int]string)
+		var newTestKeyIndex *int
 
 		newIndex := 0
 		for i, key := range keys {
@@ -1364,6 +1450,9 @@ func ManageMultiKeys(c *gin.Context) {
 				deletedCount++
 			} else {
 				remainingKeys = append(remainingKeys, key)
+				if testKeyIndex := channel.ChannelInfo.MultiKeyTestKeyIndex; testKeyIndex != nil && *testKeyIndex == i {
+					newTestKeyIndex = common.GetPointer(newIndex)
+				}
 				// 保留非自动禁用密钥的状态信息，重新索引
 				if status != 1 {
 					newStatusList[newIndex] = status
@@ -1396,6 +1485,7 @@ func ManageMultiKeys(c *gin.Context) {
 		channel.ChannelInfo.MultiKeyStatusList = newStatusList
 		channel.ChannelInfo.MultiKeyDisabledTime = newDisabledTime
 		channel.ChannelInfo.MultiKeyDisabledReason = newDisabledReason
+		channel.ChannelInfo.MultiKeyTestKeyIndex = newTestKeyIndex
 
 		err = channel.Update()
 		if err != nil {
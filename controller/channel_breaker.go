@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetChannelBreakers 返回当前节点各渠道与模型的熔断状态
+// GET /api/channel/breakers
+func GetChannelBreakers(c *gin.Context) {
+	states := model.GetChannelBreakerStates()
+	open := 0
+	for _, state := range states {
+		if state.State == model.ChannelBreakerOpen {
+			open++
+		}
+	}
+	common.ApiSuccess(c, gin.H{
+		"open":     open,
+		"breakers": states,
+	})
+}
+
+// ResetChannelBreakers 手动关闭熔断，channel_id 与 model 为空时清除全部
+// DELETE /api/channel/breakers?channel_id=1&model=gpt-4o
+func ResetChannelBreakers(c *gin.Context) {
+	channelId := 0
+	if value := c.Query("channel_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		channelId = id
+	}
+	common.ApiSuccess(c, model.ResetChannelBreakers(channelId, c.Query("model")))
+}
@@ -0,0 +1,121 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 正在排空的渠道，避免同一渠道被重复排空
+var drainingChannels sync.Map
+
+// drainAndDeleteChannel 等待渠道处理中的请求结束后删除渠道，超时后直接删除
+func drainAndDeleteChannel(channelId int) {
+	if _, loaded := drainingChannels.LoadOrStore(channelId, struct{}{}); loaded {
+		return
+	}
+	defer drainingChannels.Delete(channelId)
+
+	setting := operation_setting.GetChannelDrainSetting()
+	deadline := time.Now().Add(time.Duration(setting.TimeoutSeconds) * time.Second)
+	for service.GetChannelInFlight(channelId) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Second)
+	}
+	if inFlight := service.GetChannelInFlight(channelId); inFlight > 0 {
+		common.SysLog(fmt.Sprintf("channel #%d drain timed out with %d requests in flight, deleting anyway", channelId, inFlight))
+	}
+
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get draining channel #%d: %s", channelId, err.Error()))
+		return
+	}
+	if channel.Status != common.ChannelStatusDraining {
+		// 排空期间渠道被重新启用，取消删除
+		common.SysLog(fmt.Sprintf("channel #%d is no longer draining, deletion cancelled", channelId))
+		return
+	}
+	if setting.RetestReplacement {
+		retestReplacementChannels(channel)
+	}
+	if err = channel.Delete(); err != nil {
+		common.SysError(fmt.Sprintf("failed to delete drained channel #%d: %s", channelId, err.Error()))
+		return
+	}
+	model.InitChannelCache()
+	common.SysLog(fmt.Sprintf("channel #%d (%s) drained and deleted", channelId, channel.Name))
+}
+
+// retestReplacementChannels 对渠道的每个模型测试一个接替渠道，结果只记录日志
+func retestReplacementChannels(channel *model.Channel) {
+	for _, modelName := range channel.GetModels() {
+		replacementId := model.GetReplacementChannelId(modelName, []int{channel.Id})
+		if replacementId == 0 {
+			common.SysLog(fmt.Sprintf("channel #%d: model %s has no replacement channel", channel.Id, modelName))
+			continue
+		}
+		replacement, err := model.GetChannelById(replacementId, true)
+		if err != nil {
+			continue
+		}
+		result := testChannelWithOptions(replacement, modelName, "", nil)
+		if result.localErr != nil {
+			common.SysLog(fmt.Sprintf("channel #%d: replacement channel #%d failed test for model %s: %s", channel.Id, replacementId, modelName, result.localErr.Error()))
+		} else if result.newAPIError != nil {
+			common.SysLog(fmt.Sprintf("channel #%d: replacement channel #%d failed test for model %s: %s", channel.Id, replacementId, modelName, result.newAPIError.Error()))
+		}
+	}
+}
+
+// startChannelDeletion 将渠道标记为排空中，后台等待处理中的请求结束后删除
+func startChannelDeletion(channelIds []int) error {
+	for _, id := range channelIds {
+		if err := model.SetChannelDraining(id); err != nil {
+			return err
+		}
+	}
+	model.InitChannelCache()
+	for _, id := range channelIds {
+		go drainAndDeleteChannel(id)
+	}
+	return nil
+}
+
+// ResumeDrainingChannels 服务重启后继续删除上次未完成排空的渠道
+func ResumeDrainingChannels() {
+	ids, err := model.GetDrainingChannelIds()
+	if err != nil {
+		common.SysError("failed to get draining channels: " + err.Error())
+		return
+	}
+	for _, id := range ids {
+		go drainAndDeleteChannel(id)
+	}
+}
+
+// GetChannelDeleteImpact 返回删除渠道后失去最后一个可用渠道的模型
+func GetChannelDeleteImpact(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	impacts, err := model.GetChannelDeleteImpact([]int{id})
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "",
+		"data":    impacts,
+	})
+}
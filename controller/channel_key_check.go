@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/service"
+	"one-api/types"
+	"time"
+)
+
+// buildKeyCheckRequest 构造供应商的鉴权探测请求，只访问模型列表等不消耗额度的接口。
+// 不支持探测的渠道类型返回 nil
+func buildKeyCheckRequest(channel *model.Channel, key string) (*http.Request, error) {
+	baseURL := constant.ChannelBaseURLs[channel.Type]
+	if channel.GetBaseURL() != "" {
+		baseURL = channel.GetBaseURL()
+	}
+	var url string
+	headers := make(http.Header)
+	switch channel.Type {
+	case constant.ChannelTypeGemini:
+		url = fmt.Sprintf("%s/v1beta/models?pageSize=1", baseURL)
+		headers.Set("x-goog-api-key", key)
+	case constant.ChannelTypeAnthropic:
+		url = fmt.Sprintf("%s/v1/models?limit=1", baseURL)
+		headers.Set("x-api-key", key)
+		headers.Set("anthropic-version", "2023-06-01")
+	case constant.ChannelTypeOpenAI, constant.ChannelTypeOpenRouter, constant.ChannelTypeDeepSeek,
+		constant.ChannelTypeMoonshot, constant.ChannelTypeMistral, constant.ChannelTypeXai,
+		constant.ChannelTypeSiliconFlow:
+		url = fmt.Sprintf("%s/v1/models", baseURL)
+		headers.Set("Authorization", "Bearer "+key)
+	default:
+		return nil, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = headers
+	return req, nil
+}
+
+// checkChannelKey 探测单个 key，返回上游的错误响应；网络错误不视为 key 失效
+func checkChannelKey(channel *model.Channel, key string) *types.NewAPIError {
+	req, err := buildKeyCheckRequest(channel, key)
+	if err != nil || req == nil {
+		return nil
+	}
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		common.SysError(fmt.Sprintf("channel #%d key check request failed: %s", channel.Id, err.Error()))
+		return nil
+	}
+	if resp.StatusCode == http.StatusOK {
+		common.CloseResponseBodyGracefully(resp)
+		return nil
+	}
+	return service.RelayErrorHandler(resp, true)
+}
+
+// checkChannelKeys 探测渠道的所有启用中的 key，鉴权类错误计入自动禁用的滚动窗口
+func checkChannelKeys(channel *model.Channel) {
+	keys := []string{channel.Key}
+	if channel.ChannelInfo.IsMultiKey {
+		keys = channel.GetKeys()
+	}
+	for i, key := range keys {
+		if channel.ChannelInfo.IsMultiKey {
+			if status, ok := channel.ChannelInfo.MultiKeyStatusList[i]; ok && status != common.ChannelStatusEnabled {
+				continue
+			}
+		}
+		newAPIError := checkChannelKey(channel, key)
+		if newAPIError == nil {
+			continue
+		}
+		common.SysLog(fmt.Sprintf("channel #%d key #%d check failed (status code: %d): %s", channel.Id, i, newAPIError.StatusCode, newAPIError.Error()))
+		if service.ShouldDisableChannel(channel.Id, channel.Type, newAPIError) {
+			channelError := types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, key, channel.GetAutoBan())
+			service.DisableChannel(*channelError, newAPIError.Error(), &service.ChannelStatusEvent{
+				ErrorCode: string(newAPIError.GetErrorCode()),
+			})
+			if !channel.ChannelInfo.IsMultiKey {
+				return
+			}
+		}
+	}
+}
+
+func checkAllChannelKeys() error {
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		return err
+	}
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusEnabled || !channel.GetAutoBan() {
+			continue
+		}
+		checkChannelKeys(channel)
+		time.Sleep(common.RequestInterval)
+	}
+	return nil
+}
+
+// AutomaticallyCheckChannelKeys 定期探测渠道 key 是否仍然有效，不发起生成请求，可以比完整测试更频繁地运行
+func AutomaticallyCheckChannelKeys(frequency int) {
+	if frequency <= 0 {
+		common.SysLog("CHANNEL_KEY_CHECK_FREQUENCY is not set or invalid, skipping channel key check")
+		return
+	}
+	for {
+		time.Sleep(time.Duration(frequency) * time.Minute)
+		common.SysLog("checking all channel keys")
+		if err := checkAllChannelKeys(); err != nil {
+			common.SysError("failed to check channel keys: " + err.Error())
+		}
+		common.SysLog("channel key check finished")
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type channelKeyUsageItem struct {
+	KeyIndex     int   `json:"key_index"`
+	Status       int   `json:"status"`
+	UsedQuota    int   `json:"used_quota"`
+	RequestCount int   `json:"request_count"`
+	UpdatedTime  int64 `json:"updated_time"`
+}
+
+// GetChannelKeyUsages 返回多 key 渠道中每个 key 的用量与状态
+// GET /api/channel/:id/key_usage
+func GetChannelKeyUsages(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !channel.ChannelInfo.IsMultiKey {
+		common.ApiErrorMsg(c, "仅支持多 key 渠道")
+		return
+	}
+	usages, err := model.GetChannelKeyUsages(channel.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	items := make([]channelKeyUsageItem, 0)
+	for i, key := range channel.GetKeys() {
+		item := channelKeyUsageItem{
+			KeyIndex: i,
+			Status:   common.ChannelStatusEnabled,
+		}
+		if status, ok := channel.ChannelInfo.MultiKeyStatusList[i]; ok {
+			item.Status = status
+		}
+		if usage, ok := usages[model.HashChannelKey(key)]; ok {
+			item.UsedQuota = usage.UsedQuota
+			item.RequestCount = usage.RequestCount
+			item.UpdatedTime = usage.UpdatedTime
+		}
+		items = append(items, item)
+	}
+	common.ApiSuccess(c, items)
+}
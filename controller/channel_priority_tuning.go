@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+)
+
+// tuneChannelPriority 根据最近的测试延迟调整渠道优先级，每次调整幅度与累计下调量都有上限，调整记录写入系统日志
+func tuneChannelPriority(channel *model.Channel) {
+	setting := operation_setting.GetChannelPriorityTuningSetting()
+	if !setting.Enabled || setting.SampleSize <= 0 {
+		return
+	}
+	latencies, err := model.GetRecentChannelTestLatencies(channel.Id, setting.SampleSize)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get test latencies of channel #%d: %s", channel.Id, err.Error()))
+		return
+	}
+	if len(latencies) < setting.SampleSize {
+		return
+	}
+	var total int64
+	for _, latency := range latencies {
+		total += latency
+	}
+	avgLatency := total / int64(len(latencies))
+	delta := setting.GetAdjustment(avgLatency, channel.ChannelInfo.PriorityAdjustment)
+	if delta == 0 {
+		return
+	}
+	oldPriority := channel.GetPriority()
+	if err = model.AdjustChannelPriority(channel, delta); err != nil {
+		common.SysError(fmt.Sprintf("failed to adjust priority of channel #%d: %s", channel.Id, err.Error()))
+		return
+	}
+	model.InitChannelCache()
+	content := fmt.Sprintf("渠道「%s」（#%d）最近 %d 次测试平均延迟 %dms，优先级自动从 %d 调整为 %d（累计调整 %d）",
+		channel.Name, channel.Id, len(latencies), avgLatency, oldPriority, channel.GetPriority(), channel.ChannelInfo.PriorityAdjustment)
+	common.SysLog(content)
+	model.RecordLog(0, model.LogTypeSystem, content)
+}
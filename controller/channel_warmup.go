@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"sync"
+	"time"
+)
+
+// 正在预热的渠道，避免同一渠道被重复预热
+var warmingUpChannels sync.Map
+
+// warmUpAndEnableChannel 渠道启用前先发送若干预热请求，填充上游缓存与路由后才启用并加入负载均衡
+// 预热请求失败不影响启用，只记录日志
+func warmUpAndEnableChannel(channelId int, usingKey string, event *service.ChannelStatusEvent) {
+	if _, loaded := warmingUpChannels.LoadOrStore(channelId, struct{}{}); loaded {
+		return
+	}
+	defer warmingUpChannels.Delete(channelId)
+
+	channel, err := model.GetChannelById(channelId, true)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get channel #%d for warm-up: %s", channelId, err.Error()))
+		return
+	}
+	setting := operation_setting.GetChannelWarmUpSetting()
+	testModel := getChannelTestModel(channel, "")
+	failed := 0
+	for i := 0; i < setting.Requests; i++ {
+		if i > 0 && setting.IntervalSeconds > 0 {
+			time.Sleep(time.Duration(setting.IntervalSeconds) * time.Second)
+		}
+		result := testChannelWithOptions(channel, testModel, "", nil)
+		if result.localErr != nil {
+			failed++
+			common.SysLog(fmt.Sprintf("channel #%d warm-up request %d failed: %s", channelId, i+1, result.localErr.Error()))
+		} else if result.newAPIError != nil {
+			failed++
+			common.SysLog(fmt.Sprintf("channel #%d warm-up request %d failed: %s", channelId, i+1, result.newAPIError.Error()))
+		}
+	}
+	common.SysLog(fmt.Sprintf("channel #%d warm-up finished, %d/%d requests succeeded", channelId, setting.Requests-failed, setting.Requests))
+	service.EnableChannel(channelId, usingKey, channel.Name, event)
+}
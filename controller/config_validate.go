@@ -0,0 +1,156 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting"
+	"one-api/setting/ratio_setting"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+const (
+	ConfigIssueMissingPrice    = "missing_price"
+	ConfigIssueInvalidMapping  = "invalid_mapping"
+	ConfigIssueUnusedMapping   = "unused_mapping"
+	ConfigIssueUnknownUpstream = "unknown_upstream_model"
+	ConfigIssueMissingGroup    = "missing_group_ratio"
+	ConfigIssueEmptyGroup      = "group_without_channel"
+)
+
+// ConfigValidationIssue 一条配置检查结果
+type ConfigValidationIssue struct {
+	Type        string `json:"type"`
+	ChannelId   int    `json:"channel_id,omitempty"`
+	ChannelName string `json:"channel_name,omitempty"`
+	Model       string `json:"model,omitempty"`
+	Group       string `json:"group,omitempty"`
+	Message     string `json:"message"`
+}
+
+// ValidateConfig 交叉检查渠道模型、模型映射、价格倍率与分组倍率，返回可操作的警告
+// GET /api/config/validate
+func ValidateConfig(c *gin.Context) {
+	channels, err := model.GetAllChannels(0, 0, true, false)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	issues := make([]ConfigValidationIssue, 0)
+	channelGroups := make(map[string]bool)
+	checkedModels := make(map[string]bool)
+	for _, channel := range channels {
+		if channel.Status != common.ChannelStatusEnabled {
+			continue
+		}
+		models := channel.GetModels()
+		for _, modelName := range models {
+			if checkedModels[modelName] {
+				continue
+			}
+			checkedModels[modelName] = true
+			if _, ok := ratio_setting.GetModelPrice(modelName, false); ok || ratio_setting.HasModelRatio(modelName) {
+				continue
+			}
+			issues = append(issues, ConfigValidationIssue{
+				Type:        ConfigIssueMissingPrice,
+				ChannelId:   channel.Id,
+				ChannelName: channel.Name,
+				Model:       modelName,
+				Message:     fmt.Sprintf("模型 %s 未设置价格或倍率，请求将被拒绝；开启自用模式或用户接受未设置倍率时按默认倍率 37.5 计费", modelName),
+			})
+		}
+		issues = append(issues, validateChannelModelMapping(channel, models)...)
+
+		for _, group := range channel.GetGroups() {
+			channelGroups[group] = true
+		}
+	}
+
+	groupRatios := ratio_setting.GetGroupRatioCopy()
+	groups := lo.Keys(channelGroups)
+	sort.Strings(groups)
+	for _, group := range groups {
+		if _, ok := groupRatios[group]; !ok {
+			issues = append(issues, ConfigValidationIssue{
+				Type:    ConfigIssueMissingGroup,
+				Group:   group,
+				Message: fmt.Sprintf("渠道分组 %s 未设置分组倍率，将按倍率 1 计费", group),
+			})
+		}
+	}
+	usableGroups := lo.Keys(setting.GetUserUsableGroupsCopy())
+	sort.Strings(usableGroups)
+	for _, group := range usableGroups {
+		if _, ok := groupRatios[group]; !ok && !channelGroups[group] {
+			issues = append(issues, ConfigValidationIssue{
+				Type:    ConfigIssueMissingGroup,
+				Group:   group,
+				Message: fmt.Sprintf("用户可选分组 %s 未设置分组倍率，将按倍率 1 计费", group),
+			})
+		}
+		if !channelGroups[group] {
+			issues = append(issues, ConfigValidationIssue{
+				Type:    ConfigIssueEmptyGroup,
+				Group:   group,
+				Message: fmt.Sprintf("用户可选分组 %s 没有启用的渠道，选择该分组的请求将无可用渠道", group),
+			})
+		}
+	}
+
+	common.ApiSuccess(c, gin.H{
+		"issues": issues,
+		"total":  len(issues),
+	})
+}
+
+// validateChannelModelMapping 检查渠道模型映射：映射源不在模型列表中不会生效，映射目标应为该渠道类型已知的上游模型
+func validateChannelModelMapping(channel *model.Channel, models []string) []ConfigValidationIssue {
+	mappingStr := channel.GetModelMapping()
+	if mappingStr == "" || mappingStr == "{}" {
+		return nil
+	}
+	issues := make([]ConfigValidationIssue, 0)
+	modelMapping := make(map[string]string)
+	if err := json.Unmarshal([]byte(mappingStr), &modelMapping); err != nil {
+		return append(issues, ConfigValidationIssue{
+			Type:        ConfigIssueInvalidMapping,
+			ChannelId:   channel.Id,
+			ChannelName: channel.Name,
+			Message:     fmt.Sprintf("渠道 %s 的模型映射不是合法的 JSON：%s", channel.Name, err.Error()),
+		})
+	}
+	knownModels := channelId2Models[channel.Type]
+	sources := lo.Keys(modelMapping)
+	sort.Strings(sources)
+	for _, source := range sources {
+		target := modelMapping[source]
+		if !lo.Contains(models, source) {
+			issues = append(issues, ConfigValidationIssue{
+				Type:        ConfigIssueUnusedMapping,
+				ChannelId:   channel.Id,
+				ChannelName: channel.Name,
+				Model:       source,
+				Message:     fmt.Sprintf("渠道 %s 映射了模型 %s，但该模型不在渠道模型列表中，映射不会生效", channel.Name, source),
+			})
+		}
+		// 映射目标仍是映射源时为链式映射，由后续映射决定最终上游模型
+		if _, chained := modelMapping[target]; chained || len(knownModels) == 0 {
+			continue
+		}
+		if !lo.Contains(knownModels, target) {
+			issues = append(issues, ConfigValidationIssue{
+				Type:        ConfigIssueUnknownUpstream,
+				ChannelId:   channel.Id,
+				ChannelName: channel.Name,
+				Model:       source,
+				Message:     fmt.Sprintf("渠道 %s 将 %s 映射为 %s，但 %s 不在该渠道类型的已知上游模型中，请确认上游支持该模型", channel.Name, source, target, target),
+			})
+		}
+	}
+	return issues
+}
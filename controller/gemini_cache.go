@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/relay/channel/gemini"
+	"one-api/service/promptcache"
+	"one-api/setting/model_setting"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type geminiCacheItem struct {
+	Name            string `json:"name"`
+	Model           string `json:"model"`
+	DisplayName     string `json:"display_name"`
+	CreateTime      string `json:"create_time"`
+	ExpireTime      string `json:"expire_time"`
+	TtlSeconds      int64  `json:"ttl_seconds"`
+	TotalTokenCount int    `json:"total_token_count"`
+	KeyIndex        int    `json:"key_index"`
+}
+
+func getGeminiChannelKeys(c *gin.Context) ([]string, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	channel, err := model.GetChannelById(id, true)
+	if err != nil {
+		common.ApiError(c, err)
+		return nil, false
+	}
+	if channel.Type != constant.ChannelTypeGemini {
+		common.ApiErrorMsg(c, "仅支持 Gemini 渠道")
+		return nil, false
+	}
+	if channel.ChannelInfo.IsMultiKey {
+		return channel.GetKeys(), true
+	}
+	return []string{channel.Key}, true
+}
+
+// GetChannelGeminiCaches 列出渠道 key 在上游的 cachedContents
+// GET /api/channel/:id/gemini_caches
+func GetChannelGeminiCaches(c *gin.Context) {
+	keys, ok := getGeminiChannelKeys(c)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	items := make([]geminiCacheItem, 0)
+	for i, key := range keys {
+		caches, err := gemini.ListGeminiCaches(key)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		for _, cache := range caches {
+			item := geminiCacheItem{
+				Name:            cache.Name,
+				Model:           cache.Model,
+				DisplayName:     cache.DisplayName,
+				CreateTime:      cache.CreateTime,
+				ExpireTime:      cache.ExpireTime,
+				TotalTokenCount: cache.UsageMetadata.TotalTokenCount,
+				KeyIndex:        i,
+			}
+			if expireTime, err := time.Parse(time.RFC3339Nano, cache.ExpireTime); err == nil && expireTime.After(now) {
+				item.TtlSeconds = int64(expireTime.Sub(now).Seconds())
+			}
+			items = append(items, item)
+		}
+	}
+	common.ApiSuccess(c, items)
+}
+
+// DeleteChannelGeminiCache 删除上游的 cachedContent
+// DELETE /api/channel/:id/gemini_caches?name=cachedContents/xxx&key_index=0
+func DeleteChannelGeminiCache(c *gin.Context) {
+	name := c.Query("name")
+	if !strings.HasPrefix(name, "cachedContents/") || strings.Contains(strings.TrimPrefix(name, "cachedContents/"), "/") {
+		common.ApiErrorMsg(c, "无效的缓存名称")
+		return
+	}
+	keys, ok := getGeminiChannelKeys(c)
+	if !ok {
+		return
+	}
+	keyIndex, _ := strconv.Atoi(c.DefaultQuery("key_index", "0"))
+	if keyIndex < 0 || keyIndex >= len(keys) {
+		common.ApiError(c, errors.New("key_index out of range"))
+		return
+	}
+	if err := gemini.DeleteGeminiCache(keys[keyIndex], name); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// purgeChannelPromptCaches 渠道 key 变更后，旧 key 创建的缓存无法再使用，清理绑定到该渠道的缓存记录
+func purgeChannelPromptCaches(channel *model.Channel) {
+	if cache, ok := promptcache.Get(promptcache.ProviderAnthropic); ok {
+		if _, err := cache.InvalidateChannel(channel.Id); err != nil {
+			common.SysError(fmt.Sprintf("failed to purge claude prompt caches of channel #%d: %s", channel.Id, err.Error()))
+		}
+	}
+	count, err := gemini.PurgeChannelGeminiCaches(channel.Id, channel.GetKeys())
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to purge gemini caches of channel #%d: %s", channel.Id, err.Error()))
+		return
+	}
+	if count > 0 {
+		common.SysLog(fmt.Sprintf("channel #%d key changed, purged %d gemini caches", channel.Id, count))
+	}
+}
+
+// channelKeysRemoved 判断更新后是否有旧 key 被移除，仅追加 key 时旧缓存仍然可用
+func channelKeysRemoved(oldKeys []string, newKeys []string) bool {
+	for _, key := range oldKeys {
+		if !slices.Contains(newKeys, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// AutomaticallyReconcileGeminiCaches 按 CacheJanitorInterval 定期核对 Gemini 缓存记录与上游缓存，间隔为 0 时暂停核对
+func AutomaticallyReconcileGeminiCaches() {
+	for {
+		interval := time.Duration(model_setting.GetGeminiSettings().CacheJanitorInterval) * time.Second
+		if interval <= 0 || !model_setting.GetGeminiSettings().EnableCache {
+			time.Sleep(time.Minute)
+			continue
+		}
+		time.Sleep(interval)
+		// 同一轮内每个渠道只查询一次
+		channelKeys := make(map[int][]string)
+		_, err := gemini.ReconcileGeminiCaches(func(channelID int) []string {
+			if keys, ok := channelKeys[channelID]; ok {
+				return keys
+			}
+			var keys []string
+			channel, err := model.GetChannelById(channelID, true)
+			if err == nil && channel.Type == constant.ChannelTypeGemini {
+				keys = channel.GetKeys()
+			}
+			channelKeys[channelID] = keys
+			return keys
+		}, interval)
+		if err != nil {
+			common.SysError("failed to reconcile gemini caches: " + err.Error())
+		}
+	}
+}
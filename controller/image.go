@@ -1,9 +1,27 @@
 package controller
 
 import (
+	"net/http"
+	"one-api/service"
+	"os"
+
 	"github.com/gin-gonic/gin"
 )
 
 func GetImage(c *gin.Context) {
 
 }
+
+// GetStoredImage 返回本地存储的生成图片
+func GetStoredImage(c *gin.Context) {
+	path, err := service.GetStoredImagePath(c.Param("name"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if _, err = os.Stat(path); err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.File(path)
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -121,6 +122,42 @@ func GetLogsStat(c *gin.Context) {
 	return
 }
 
+// GetLogsCacheSavingsStat 按用户、模型汇总提示缓存命中与节省的额度，默认统计最近 30 天
+func GetLogsCacheSavingsStat(c *gin.Context) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp == 0 {
+		startTimestamp = common.GetTimestamp() - 30*24*3600
+	}
+	if endTimestamp != 0 && endTimestamp < startTimestamp {
+		common.ApiErrorMsg(c, "结束时间不能早于开始时间")
+		return
+	}
+	stats, err := model.GetCacheSavingsStats(startTimestamp, endTimestamp, c.Query("username"), c.Query("model_name"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
+
+// GetLogsCostTagStat 按成本归属标签汇总消费，用于成本分摊
+func GetLogsCostTagStat(c *gin.Context) {
+	key := c.Query("key")
+	if key == "" {
+		common.ApiErrorMsg(c, "缺少标签名")
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	stats, err := model.GetCostTagStats(key, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, stats)
+}
+
 func GetLogsSelfStat(c *gin.Context) {
 	username := c.GetString("username")
 	logType, _ := strconv.Atoi(c.Query("type"))
@@ -166,3 +203,22 @@ func DeleteHistoryLogs(c *gin.Context) {
 	})
 	return
 }
+
+// GetLogBodyArchive 查询日志对应的请求体与响应体存档
+func GetLogBodyArchive(c *gin.Context) {
+	logId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiErrorMsg(c, "无效的日志 id")
+		return
+	}
+	archive, err := model.GetBodyArchiveByLogId(logId)
+	if err != nil {
+		common.ApiErrorMsg(c, "该日志没有请求存档")
+		return
+	}
+	if err = service.LoadArchivedBodies(archive); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, archive)
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	logExportBatchSize = 1000
+	// logExportMaxRows 单次请求最多导出的行数，超出后通过 X-Next-Cursor 继续
+	logExportMaxRows = 100000
+)
+
+var logExportCsvHeader = []string{
+	"id", "created_at", "type", "user_id", "username", "token_id", "token_name", "model_name",
+	"quota", "prompt_tokens", "completion_tokens", "use_time", "is_stream",
+	"channel", "channel_name", "group", "ip", "content", "other",
+}
+
+func logToCsvRecord(log *model.Log) []string {
+	return []string{
+		strconv.Itoa(log.Id),
+		strconv.FormatInt(log.CreatedAt, 10),
+		strconv.Itoa(log.Type),
+		strconv.Itoa(log.UserId),
+		log.Username,
+		strconv.Itoa(log.TokenId),
+		log.TokenName,
+		log.ModelName,
+		strconv.Itoa(log.Quota),
+		strconv.Itoa(log.PromptTokens),
+		strconv.Itoa(log.CompletionTokens),
+		strconv.Itoa(log.UseTime),
+		strconv.FormatBool(log.IsStream),
+		strconv.Itoa(log.ChannelId),
+		log.ChannelName,
+		log.Group,
+		log.Ip,
+		log.Content,
+		log.Other,
+	}
+}
+
+// ExportLogs 以 NDJSON 或 CSV 流式导出日志，按 id 升序游标分页
+// GET /api/log/export?format=ndjson|csv&cursor=0&limit=100000&type=&start_timestamp=&end_timestamp=&...
+// 响应结束时在 trailer X-Next-Cursor 中返回下一次请求使用的游标，没有更多数据时为空
+func ExportLogs(c *gin.Context) {
+	format := c.DefaultQuery("format", "ndjson")
+	if format != "ndjson" && format != "csv" {
+		common.ApiErrorMsg(c, "format 仅支持 ndjson 或 csv")
+		return
+	}
+	cursor, _ := strconv.Atoi(c.Query("cursor"))
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	if limit <= 0 || limit > logExportMaxRows {
+		limit = logExportMaxRows
+	}
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	channel, _ := strconv.Atoi(c.Query("channel"))
+	filter := &model.LogExportFilter{
+		LogType:        logType,
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+		ModelName:      c.Query("model_name"),
+		Username:       c.Query("username"),
+		TokenName:      c.Query("token_name"),
+		Channel:        channel,
+		Group:          c.Query("group"),
+	}
+
+	// 先取第一批，查询出错时还能返回正常的错误响应
+	batchSize := min(logExportBatchSize, limit)
+	logs, err := model.GetLogsAfterId(filter, cursor, batchSize)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("logs-%s.%s", time.Now().Format("20060102150405"), format)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	c.Header("Trailer", "X-Next-Cursor")
+	c.Status(http.StatusOK)
+
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		csvWriter = csv.NewWriter(c.Writer)
+		_ = csvWriter.Write(logExportCsvHeader)
+	}
+	exported := 0
+	nextCursor := ""
+	for len(logs) > 0 {
+		for _, log := range logs {
+			if csvWriter != nil {
+				err = csvWriter.Write(logToCsvRecord(log))
+			} else {
+				var data []byte
+				data, err = common.Marshal(log)
+				if err == nil {
+					data = append(data, '\n')
+					_, err = c.Writer.Write(data)
+				}
+			}
+			if err != nil {
+				common.SysError("failed to write log export: " + err.Error())
+				return
+			}
+		}
+		if csvWriter != nil {
+			csvWriter.Flush()
+		}
+		c.Writer.Flush()
+
+		exported += len(logs)
+		cursor = logs[len(logs)-1].Id
+		if len(logs) < batchSize {
+			break
+		}
+		if exported >= limit {
+			nextCursor = strconv.Itoa(cursor)
+			break
+		}
+		if c.Request.Context().Err() != nil {
+			return
+		}
+		batchSize = min(logExportBatchSize, limit-exported)
+		logs, err = model.GetLogsAfterId(filter, cursor, batchSize)
+		if err != nil {
+			// 已导出的部分保持有效，客户端可以从当前游标继续
+			common.SysError("failed to query logs for export: " + err.Error())
+			nextCursor = strconv.Itoa(cursor)
+			break
+		}
+	}
+	c.Writer.Header().Set("X-Next-Cursor", nextCursor)
+}
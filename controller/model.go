@@ -16,6 +16,7 @@ import (
 	"one-api/relay/channel/moonshot"
 	relaycommon "one-api/relay/common"
 	"one-api/setting"
+	"one-api/setting/ratio_setting"
 	"time"
 )
 
@@ -106,6 +107,35 @@ func init() {
 func ListModels(c *gin.Context, modelType int) {
 	userOpenAiModels := make([]dto.OpenAIModels, 0)
 
+	userId := c.GetInt("id")
+	userGroup, err := model.GetUserGroup(userId, false)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "get user group failed",
+		})
+		return
+	}
+	group := userGroup
+	tokenGroup := common.GetContextKeyString(c, constant.ContextKeyTokenGroup)
+	if tokenGroup != "" {
+		group = tokenGroup
+	}
+	var models []string
+	if tokenGroup == "auto" {
+		for _, autoGroup := range setting.AutoGroups {
+			groupModels := model.GetGroupEnabledModels(autoGroup)
+			for _, g := range groupModels {
+				if !common.StringsContains(models, g) {
+					models = append(models, g)
+				}
+			}
+		}
+	} else {
+		models = model.GetGroupEnabledModels(group)
+	}
+
+	// 令牌限制了可用模型时，只返回分组内实际可用且令牌允许的模型，避免客户端选到必然 403 的模型
 	modelLimitEnable := common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled)
 	if modelLimitEnable {
 		s, ok := common.GetContextKey(c, constant.ContextKeyTokenModelLimit)
@@ -115,61 +145,30 @@ func ListModels(c *gin.Context, modelType int) {
 		} else {
 			tokenModelLimit = map[string]bool{}
 		}
-		for allowModel, _ := range tokenModelLimit {
-			if oaiModel, ok := openAIModelsMap[allowModel]; ok {
-				oaiModel.SupportedEndpointTypes = model.GetModelSupportEndpointTypes(allowModel)
-				userOpenAiModels = append(userOpenAiModels, oaiModel)
-			} else {
-				userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
-					Id:                     allowModel,
-					Object:                 "model",
-					Created:                1626777600,
-					OwnedBy:                "custom",
-					SupportedEndpointTypes: model.GetModelSupportEndpointTypes(allowModel),
-				})
-			}
-		}
-	} else {
-		userId := c.GetInt("id")
-		userGroup, err := model.GetUserGroup(userId, false)
-		if err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"success": false,
-				"message": "get user group failed",
-			})
-			return
-		}
-		group := userGroup
-		tokenGroup := common.GetContextKeyString(c, constant.ContextKeyTokenGroup)
-		if tokenGroup != "" {
-			group = tokenGroup
-		}
-		var models []string
-		if tokenGroup == "auto" {
-			for _, autoGroup := range setting.AutoGroups {
-				groupModels := model.GetGroupEnabledModels(autoGroup)
-				for _, g := range groupModels {
-					if !common.StringsContains(models, g) {
-						models = append(models, g)
-					}
-				}
-			}
+		models = lo.Filter(models, func(modelName string, _ int) bool {
+			return tokenModelLimit[modelName]
+		})
+	}
+
+	// <base>-thinking-* 展示为基础模型，按思考预算区分的变体通过基础模型加后缀调用
+	models = lo.Uniq(lo.Map(models, func(modelName string, _ int) string {
+		return ratio_setting.ListedModelName(modelName)
+	}))
+
+	for _, modelName := range models {
+		if oaiModel, ok := openAIModelsMap[modelName]; ok {
+			oaiModel.SupportedEndpointTypes = model.GetModelSupportEndpointTypes(modelName)
+			oaiModel.Capabilities = model.GetModelCapabilities(modelName)
+			userOpenAiModels = append(userOpenAiModels, oaiModel)
 		} else {
-			models = model.GetGroupEnabledModels(group)
-		}
-		for _, modelName := range models {
-			if oaiModel, ok := openAIModelsMap[modelName]; ok {
-				oaiModel.SupportedEndpointTypes = model.GetModelSupportEndpointTypes(modelName)
-				userOpenAiModels = append(userOpenAiModels, oaiModel)
-			} else {
-				userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
-					Id:                     modelName,
-					Object:                 "model",
-					Created:                1626777600,
-					OwnedBy:                "custom",
-					SupportedEndpointTypes: model.GetModelSupportEndpointTypes(modelName),
-				})
-			}
+			userOpenAiModels = append(userOpenAiModels, dto.OpenAIModels{
+				Id:                     modelName,
+				Object:                 "model",
+				Created:                1626777600,
+				OwnedBy:                "custom",
+				SupportedEndpointTypes: model.GetModelSupportEndpointTypes(modelName),
+				Capabilities:           model.GetModelCapabilities(modelName),
+			})
 		}
 	}
 	switch modelType {
@@ -183,11 +182,16 @@ func ListModels(c *gin.Context, modelType int) {
 				Type:        "model",
 			}
 		}
+		var firstId, lastId string
+		if len(useranthropicModels) > 0 {
+			firstId = useranthropicModels[0].ID
+			lastId = useranthropicModels[len(useranthropicModels)-1].ID
+		}
 		c.JSON(200, gin.H{
 			"data":     useranthropicModels,
-			"first_id": useranthropicModels[0].ID,
+			"first_id": firstId,
 			"has_more": false,
-			"last_id":  useranthropicModels[len(useranthropicModels)-1].ID,
+			"last_id":  lastId,
 		})
 	case constant.ChannelTypeGemini:
 		userGeminiModels := make([]dto.GeminiModel, len(userOpenAiModels))
@@ -256,3 +260,25 @@ func RetrieveModel(c *gin.Context, modelType int) {
 		})
 	}
 }
+
+// RetrieveGeminiModel 按 Gemini 原生接口格式返回模型信息，供 Google 官方 SDK 使用
+// GET /v1beta/models/:model
+func RetrieveGeminiModel(c *gin.Context) {
+	modelId := c.Param("model")
+	if _, ok := openAIModelsMap[modelId]; !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": gin.H{
+				"code":    http.StatusNotFound,
+				"message": fmt.Sprintf("models/%s is not found", modelId),
+				"status":  "NOT_FOUND",
+			},
+		})
+		return
+	}
+	c.JSON(http.StatusOK, dto.GeminiModel{
+		Name:                       "models/" + modelId,
+		BaseModelId:                modelId,
+		DisplayName:                modelId,
+		SupportedGenerationMethods: []interface{}{"generateContent", "streamGenerateContent"},
+	})
+}
@@ -0,0 +1,158 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
+)
+
+type ModelBenchmarkRequest struct {
+	ChannelIds []int    `json:"channel_ids"`
+	Models     []string `json:"models"`  // 为空时使用渠道的测试模型
+	Prompts    []string `json:"prompts"` // 为空时使用 benchmark_setting 中的提示词集
+}
+
+var (
+	modelBenchmarkLock    sync.Mutex
+	modelBenchmarkRunning = false
+)
+
+// RunModelBenchmark 在选定渠道上用标准提示词集运行基准测试，结果异步写入数据库
+// POST /api/model/benchmark
+func RunModelBenchmark(c *gin.Context) {
+	var req ModelBenchmarkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if len(req.ChannelIds) == 0 {
+		common.ApiErrorMsg(c, "请选择至少一个渠道")
+		return
+	}
+	setting := operation_setting.GetBenchmarkSetting()
+	prompts := req.Prompts
+	if len(prompts) == 0 {
+		prompts = setting.Prompts
+	}
+	if len(prompts) == 0 {
+		common.ApiErrorMsg(c, "未配置基准测试提示词")
+		return
+	}
+	channels := make([]*model.Channel, 0, len(req.ChannelIds))
+	for _, id := range req.ChannelIds {
+		channel, err := model.GetChannelById(id, true)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		channels = append(channels, channel)
+	}
+
+	modelBenchmarkLock.Lock()
+	if modelBenchmarkRunning {
+		modelBenchmarkLock.Unlock()
+		common.ApiError(c, errors.New("基准测试已在运行中"))
+		return
+	}
+	modelBenchmarkRunning = true
+	modelBenchmarkLock.Unlock()
+
+	runId := common.GetUUID()
+	gopool.Go(func() {
+		defer func() {
+			modelBenchmarkLock.Lock()
+			modelBenchmarkRunning = false
+			modelBenchmarkLock.Unlock()
+		}()
+		for _, channel := range channels {
+			for _, modelName := range getBenchmarkModels(channel, req.Models) {
+				for i, prompt := range prompts {
+					benchmark := runModelBenchmark(channel, modelName, prompt, setting.MaxTokens)
+					benchmark.RunId = runId
+					benchmark.PromptIndex = i
+					if err := benchmark.Insert(); err != nil {
+						common.SysError("failed to save model benchmark: " + err.Error())
+					}
+					time.Sleep(common.RequestInterval)
+				}
+			}
+		}
+		common.SysLog(fmt.Sprintf("model benchmark %s finished", runId))
+	})
+	common.ApiSuccess(c, gin.H{
+		"run_id": runId,
+	})
+}
+
+// getBenchmarkModels 返回渠道上需要测试的模型，渠道不支持的模型会被跳过
+func getBenchmarkModels(channel *model.Channel, models []string) []string {
+	if len(models) == 0 {
+		if channel.TestModel != nil && *channel.TestModel != "" {
+			return []string{*channel.TestModel}
+		}
+		if len(channel.GetModels()) > 0 {
+			return []string{channel.GetModels()[0]}
+		}
+		return nil
+	}
+	return lo.Intersect(channel.GetModels(), models)
+}
+
+func runModelBenchmark(channel *model.Channel, modelName string, prompt string, maxTokens uint) *model.ModelBenchmark {
+	benchmark := &model.ModelBenchmark{
+		ChannelId:   channel.Id,
+		ChannelName: channel.Name,
+		ModelName:   modelName,
+		CreatedTime: common.GetTimestamp(),
+	}
+	result := testChannelWithOptions(channel, modelName, "stream", &testOptions{
+		Prompt:    prompt,
+		MaxTokens: maxTokens,
+	})
+	if result.localErr != nil {
+		benchmark.ErrorMessage = result.localErr.Error()
+		return benchmark
+	}
+	if result.newAPIError != nil {
+		benchmark.ErrorMessage = result.newAPIError.Error()
+		return benchmark
+	}
+	benchmark.Success = true
+	benchmark.Quota = result.quota
+	if result.usage != nil {
+		benchmark.PromptTokens = result.usage.PromptTokens
+		benchmark.CompletionTokens = result.usage.CompletionTokens
+	}
+	if stats := result.streamStats; stats != nil {
+		benchmark.Latency = int64(stats.Duration * 1000)
+		benchmark.FirstTokenLatency = int64(stats.FirstTokenTime * 1000)
+		if generation := stats.Duration - stats.FirstTokenTime; generation > 0 {
+			benchmark.TokensPerSecond = float64(benchmark.CompletionTokens) / generation
+		}
+	}
+	return benchmark
+}
+
+// GetModelBenchmarks 查询基准测试结果
+// GET /api/model/benchmark?run_id=&channel_id=&model_name=
+func GetModelBenchmarks(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	benchmarks, total, err := model.GetModelBenchmarks(c.Query("run_id"), channelId, c.Query("model_name"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(benchmarks)
+	common.ApiSuccess(c, pageInfo)
+}
@@ -88,6 +88,10 @@ func CreateModelMeta(c *gin.Context) {
 		common.ApiErrorMsg(c, "模型名称不能为空")
 		return
 	}
+	if err := model.ValidateCostTagsJson(m.CostTags); err != nil {
+		common.ApiError(c, err)
+		return
+	}
 	// 名称冲突检查
 	if dup, err := model.IsModelNameDuplicated(0, m.ModelName); err != nil {
 		common.ApiError(c, err)
@@ -126,6 +130,10 @@ func UpdateModelMeta(c *gin.Context) {
 			return
 		}
 	} else {
+		if err := model.ValidateCostTagsJson(m.CostTags); err != nil {
+			common.ApiError(c, err)
+			return
+		}
 		// 名称冲突检查
 		if dup, err := model.IsModelNameDuplicated(m.Id, m.ModelName); err != nil {
 			common.ApiError(c, err)
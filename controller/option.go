@@ -7,6 +7,7 @@ import (
 	"one-api/model"
 	"one-api/setting"
 	"one-api/setting/console_setting"
+	"one-api/setting/model_setting"
 	"one-api/setting/ratio_setting"
 	"one-api/setting/system_setting"
 	"strings"
@@ -112,6 +113,69 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "gemini.cache_min_tokens":
+		err = model_setting.CheckGeminiCacheMinTokens(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "gemini.cached_input_ratio":
+		err = model_setting.CheckGeminiCachedInputRatio(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "gemini.cache_isolation":
+		err = model_setting.CheckGeminiCacheIsolation(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "gemini.cache_janitor_interval":
+		err = model_setting.CheckGeminiCacheJanitorInterval(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "gemini.cache_latency_slo_ms":
+		err = model_setting.CheckGeminiCacheLatencySLO(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "gemini.embedding_dimensions":
+		err = model_setting.CheckGeminiEmbeddingDimensions(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+	case "default_params.models":
+		err = model_setting.CheckModelDefaultParams(option.Value)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "ModelRequestRateLimitGroup":
 		err = setting.CheckModelRequestRateLimitGroup(option.Value)
 		if err != nil {
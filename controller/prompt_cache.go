@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/service/promptcache"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCacheStats 返回提示词缓存的命中统计，默认 provider 为 gemini
+// GET /api/cache/stats?provider=gemini
+func GetCacheStats(c *gin.Context) {
+	provider := c.DefaultQuery("provider", promptcache.ProviderGemini)
+	cache, ok := promptcache.Get(provider)
+	if !ok {
+		common.ApiErrorMsg(c, fmt.Sprintf("不支持的缓存供应商: %s", provider))
+		return
+	}
+	data := gin.H{
+		"provider": provider,
+		// 当前实例自启动以来的统计
+		"summary": cache.Stats(),
+	}
+	if detail, ok := cache.(interface {
+		DetailStats() (*promptcache.DetailStats, error)
+	}); ok {
+		stats, err := detail.DetailStats()
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		data["channels"] = stats.Channels
+		data["models"] = stats.Models
+	}
+	common.ApiSuccess(c, data)
+}
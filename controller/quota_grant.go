@@ -0,0 +1,106 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+
+	"one-api/common"
+	"one-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetQuotaGrantRules 获取定期额度发放规则列表
+func GetQuotaGrantRules(c *gin.Context) {
+	rules, err := model.GetAllQuotaGrantRules()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, rules)
+}
+
+// CreateQuotaGrantRule 创建定期额度发放规则
+func CreateQuotaGrantRule(c *gin.Context) {
+	var rule model.QuotaGrantRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := rule.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("创建定期额度发放规则「%s」：分组 %s，周期 %s，发放 %s，策略 %s", rule.Name, rule.UserGroup, rule.Period, common.LogQuota(rule.Amount), rule.Policy))
+	common.ApiSuccess(c, &rule)
+}
+
+// UpdateQuotaGrantRule 更新定期额度发放规则
+func UpdateQuotaGrantRule(c *gin.Context) {
+	var rule model.QuotaGrantRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if rule.Id == 0 {
+		common.ApiErrorMsg(c, "缺少规则 ID")
+		return
+	}
+	if err := rule.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := rule.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("修改定期额度发放规则「%s」：分组 %s，周期 %s，发放 %s，策略 %s，启用 %t", rule.Name, rule.UserGroup, rule.Period, common.LogQuota(rule.Amount), rule.Policy, rule.Enabled))
+	common.ApiSuccess(c, &rule)
+}
+
+// DeleteQuotaGrantRule 删除定期额度发放规则
+func DeleteQuotaGrantRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	rule, err := model.GetQuotaGrantRuleById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteQuotaGrantRuleById(id); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("删除定期额度发放规则「%s」", rule.Name))
+	common.ApiSuccess(c, nil)
+}
+
+// RunQuotaGrantRule 立即执行一次发放，不影响下次定时发放时间
+func RunQuotaGrantRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	rule, err := model.GetQuotaGrantRuleById(id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	granted, err := model.ExecuteQuotaGrantRule(rule)
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("手动执行定期额度发放规则「%s」，共 %d 个用户", rule.Name, granted))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"granted": granted,
+	})
+}
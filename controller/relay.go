@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"math/rand"
 	"net/http"
 	"one-api/common"
 	"one-api/constant"
@@ -17,8 +18,10 @@ import (
 	relayconstant "one-api/relay/constant"
 	"one-api/relay/helper"
 	"one-api/service"
+	"one-api/setting/operation_setting"
 	"one-api/types"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -68,6 +71,10 @@ func relayHandler(c *gin.Context, relayMode int) *types.NewAPIError {
 		other["channel_type"] = c.GetInt("channel_type")
 		adminInfo := make(map[string]interface{})
 		adminInfo["use_channel"] = c.GetStringSlice("use_channel")
+		adminInfo["attempt"] = len(c.GetStringSlice("use_channel"))
+		if attempts, ok := common.GetContextKeyType[[]types.RelayAttempt](c, constant.ContextKeyRelayAttempts); ok {
+			adminInfo["retry_attempts"] = attempts
+		}
 		isMultiKey := common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey)
 		if isMultiKey {
 			adminInfo["is_multi_key"] = true
@@ -95,11 +102,16 @@ func Relay(c *gin.Context) {
 			break
 		}
 
+		attemptStart := time.Now()
 		newAPIError = relayRequest(c, relayMode, channel)
 
+		recordChannelBreaker(channel.Id, originalModel, newAPIError)
+		service.RecordQuarantineProbeResult(channel, common.GetContextKeyString(c, constant.ContextKeyChannelKey), originalModel, newAPIError == nil)
+		service.RecordToolCallResult(c, channel.Id, originalModel, newAPIError)
 		if newAPIError == nil {
 			return // 成功处理请求，直接返回
 		}
+		addRelayAttempt(c, channel.Id, newAPIError, attemptStart)
 
 		go processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
@@ -118,6 +130,7 @@ func Relay(c *gin.Context) {
 		//	common.LogError(c, fmt.Sprintf("origin 429 error: %s", newAPIError.Error()))
 		//	newAPIError.SetMessage("当前分组上游负载已饱和，请稍后再试")
 		//}
+		notifyRequestFailed(c, newAPIError)
 		newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
 		c.JSON(newAPIError.StatusCode, gin.H{
 			"error": newAPIError.ToOpenAIError(),
@@ -158,11 +171,16 @@ func WssRelay(c *gin.Context) {
 			break
 		}
 
+		attemptStart := time.Now()
 		newAPIError = wssRequest(c, ws, relayMode, channel)
 
+		recordChannelBreaker(channel.Id, originalModel, newAPIError)
+		service.RecordQuarantineProbeResult(channel, common.GetContextKeyString(c, constant.ContextKeyChannelKey), originalModel, newAPIError == nil)
+		service.RecordToolCallResult(c, channel.Id, originalModel, newAPIError)
 		if newAPIError == nil {
 			return // 成功处理请求，直接返回
 		}
+		addRelayAttempt(c, channel.Id, newAPIError, attemptStart)
 
 		go processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
@@ -180,6 +198,7 @@ func WssRelay(c *gin.Context) {
 		//if newAPIError.StatusCode == http.StatusTooManyRequests {
 		//	newAPIError.SetMessage("当前分组上游负载已饱和，请稍后再试")
 		//}
+		notifyRequestFailed(c, newAPIError)
 		newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
 		helper.WssError(c, ws, newAPIError.ToOpenAIError())
 	}
@@ -200,11 +219,16 @@ func RelayClaude(c *gin.Context) {
 			break
 		}
 
+		attemptStart := time.Now()
 		newAPIError = claudeRequest(c, channel)
 
+		recordChannelBreaker(channel.Id, originalModel, newAPIError)
+		service.RecordQuarantineProbeResult(channel, common.GetContextKeyString(c, constant.ContextKeyChannelKey), originalModel, newAPIError == nil)
+		service.RecordToolCallResult(c, channel.Id, originalModel, newAPIError)
 		if newAPIError == nil {
 			return // 成功处理请求，直接返回
 		}
+		addRelayAttempt(c, channel.Id, newAPIError, attemptStart)
 
 		go processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
 
@@ -219,6 +243,7 @@ func RelayClaude(c *gin.Context) {
 	}
 
 	if newAPIError != nil {
+		notifyRequestFailed(c, newAPIError)
 		newAPIError.SetMessage(common.MessageWithRequestId(newAPIError.Error(), requestId))
 		c.JSON(newAPIError.StatusCode, gin.H{
 			"type":  "error",
@@ -227,8 +252,26 @@ func RelayClaude(c *gin.Context) {
 	}
 }
 
+// notifyRequestFailed 请求最终以 5xx 失败时推送用户订阅的 request_failed 事件
+func notifyRequestFailed(c *gin.Context, err *types.NewAPIError) {
+	if err.StatusCode < 500 || err.StatusCode > 599 {
+		return
+	}
+	service.DispatchUserWebhookEvent(c.GetInt("id"), dto.UserWebhookEventRequestFailed, "请求失败", map[string]interface{}{
+		"request_id":  c.GetString(common.RequestIdKey),
+		"model":       c.GetString("original_model"),
+		"token_name":  c.GetString("token_name"),
+		"status_code": err.StatusCode,
+		"error_code":  string(err.GetErrorCode()),
+		"message":     err.MaskSensitiveError(),
+	})
+}
+
 func relayRequest(c *gin.Context, relayMode int, channel *model.Channel) *types.NewAPIError {
 	addUsedChannel(c, channel.Id)
+	service.ChannelRequestStart(channel.Id)
+	defer service.ChannelRequestDone(channel.Id)
+	defer service.ReleasePendingQuota(c, c.GetInt("id"))
 	requestBody, _ := common.GetRequestBody(c)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 	return relayHandler(c, relayMode)
@@ -236,6 +279,9 @@ func relayRequest(c *gin.Context, relayMode int, channel *model.Channel) *types.
 
 func wssRequest(c *gin.Context, ws *websocket.Conn, relayMode int, channel *model.Channel) *types.NewAPIError {
 	addUsedChannel(c, channel.Id)
+	service.ChannelRequestStart(channel.Id)
+	defer service.ChannelRequestDone(channel.Id)
+	defer service.ReleasePendingQuota(c, c.GetInt("id"))
 	requestBody, _ := common.GetRequestBody(c)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 	return relay.WssHelper(c, ws)
@@ -243,11 +289,46 @@ func wssRequest(c *gin.Context, ws *websocket.Conn, relayMode int, channel *mode
 
 func claudeRequest(c *gin.Context, channel *model.Channel) *types.NewAPIError {
 	addUsedChannel(c, channel.Id)
+	service.ChannelRequestStart(channel.Id)
+	defer service.ChannelRequestDone(channel.Id)
+	defer service.ReleasePendingQuota(c, c.GetInt("id"))
 	requestBody, _ := common.GetRequestBody(c)
 	c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 	return relay.ClaudeHelper(c)
 }
 
+// recordChannelBreaker 按渠道与模型统计连续失败次数，仅上游故障（渠道错误、429、5xx）计入失败
+func recordChannelBreaker(channelId int, modelName string, err *types.NewAPIError) {
+	breakerSetting := operation_setting.GetChannelBreakerSetting()
+	if !breakerSetting.Enabled {
+		return
+	}
+	if err == nil {
+		model.RecordChannelModelSuccess(channelId, modelName)
+		return
+	}
+	if !types.IsChannelError(err) && err.StatusCode != http.StatusTooManyRequests && err.StatusCode/100 != 5 {
+		return
+	}
+	cooldown := time.Duration(breakerSetting.CooldownSeconds) * time.Second
+	if model.RecordChannelModelFailure(channelId, modelName, breakerSetting.FailureThreshold, cooldown) {
+		common.SysLog(fmt.Sprintf("channel #%d model %s circuit opened for %d seconds after %d consecutive failures",
+			channelId, modelName, breakerSetting.CooldownSeconds, breakerSetting.FailureThreshold))
+	}
+}
+
+// addRelayAttempt 记录失败的上游请求，重试成功或最终失败时写入日志
+func addRelayAttempt(c *gin.Context, channelId int, err *types.NewAPIError, startTime time.Time) {
+	attempts, _ := common.GetContextKeyType[[]types.RelayAttempt](c, constant.ContextKeyRelayAttempts)
+	attempts = append(attempts, types.RelayAttempt{
+		ChannelId:  channelId,
+		StatusCode: err.StatusCode,
+		ErrorCode:  err.GetErrorCode(),
+		Duration:   time.Since(startTime).Milliseconds(),
+	})
+	common.SetContextKey(c, constant.ContextKeyRelayAttempts, attempts)
+}
+
 func addUsedChannel(c *gin.Context, channelId int) {
 	useChannel := c.GetStringSlice("use_channel")
 	useChannel = append(useChannel, fmt.Sprintf("%d", channelId))
@@ -258,7 +339,8 @@ func getChannel(c *gin.Context, group, originalModel string, retryCount int) (*m
 	if common.IsGeminiModel(originalModel) {
 		if cachedChannelID := relay.GetGeminiCacheChannelID(c, originalModel); cachedChannelID != 0 {
 			channel, err := model.CacheGetChannel(cachedChannelID)
-			if err == nil {
+			// 渠道已排空、隔离、禁用，或该模型熔断时不再复用，回退到正常选择
+			if err == nil && model.IsPinnedChannelAvailable(c, channel, originalModel) {
 				newAPIError := middleware.SetupContextForSelectedChannel(c, channel, originalModel)
 				if newAPIError != nil {
 					return nil, newAPIError
@@ -283,6 +365,14 @@ func getChannel(c *gin.Context, group, originalModel string, retryCount int) (*m
 			AutoBan: &autoBanInt,
 		}, nil
 	}
+	// 少量重试请求分配给隔离中的渠道，用于观察其是否恢复
+	if disableSetting := operation_setting.GetChannelDisableSetting(); disableSetting.QuarantineEnabled && disableSetting.QuarantineRetryRatio > 0 && rand.Float64() < disableSetting.QuarantineRetryRatio {
+		if channel := model.CacheGetRandomQuarantinedChannel(c, group, originalModel); channel != nil {
+			if newAPIError := middleware.SetupContextForSelectedChannel(c, channel, originalModel); newAPIError == nil {
+				return channel, nil
+			}
+		}
+	}
 	channel, selectGroup, err := model.CacheGetRandomSatisfiedChannel(c, group, originalModel, retryCount)
 	if err != nil {
 		return nil, types.NewError(errors.New(fmt.Sprintf("获取分组 %s 下模型 %s 的可用渠道失败（retry）: %s", selectGroup, originalModel, err.Error())), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
@@ -347,8 +437,15 @@ func processChannelError(c *gin.Context, channelError types.ChannelError, err *t
 	// 不要使用context获取渠道信息，异步处理时可能会出现渠道信息不一致的情况
 	// do not use context to get channel info, there may be inconsistent channel info when processing asynchronously
 	common.LogError(c, fmt.Sprintf("relay error (channel #%d, status code: %d): %s", channelError.ChannelId, err.StatusCode, err.Error()))
-	if service.ShouldDisableChannel(channelError.ChannelId, err) && channelError.AutoBan {
-		service.DisableChannel(channelError, err.Error())
+	if service.ShouldDisableChannel(channelError.ChannelId, channelError.ChannelType, err) && channelError.AutoBan {
+		event := &service.ChannelStatusEvent{
+			ModelName: common.GetContextKeyString(c, constant.ContextKeyOriginalModel),
+			ErrorCode: string(err.GetErrorCode()),
+		}
+		if startTime := common.GetContextKeyTime(c, constant.ContextKeyRequestStartTime); !startTime.IsZero() {
+			event.Latency = time.Since(startTime).Milliseconds()
+		}
+		service.DisableChannel(channelError, err.Error(), event)
 	}
 }
 
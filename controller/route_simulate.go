@@ -0,0 +1,213 @@
+package controller
+
+import (
+	"sort"
+	"strings"
+
+	"one-api/common"
+	"one-api/model"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/setting"
+	"one-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type routeSimulateRequest struct {
+	Model string `json:"model"`
+	Group string `json:"group"`
+	Token string `json:"token"` // 可选，指定后按令牌的分组与模型限制模拟
+}
+
+type routeCandidate struct {
+	Id            int     `json:"id"`
+	Name          string  `json:"name"`
+	Type          int     `json:"type"`
+	Tag           string  `json:"tag,omitempty"`
+	Priority      int64   `json:"priority"`
+	Weight        int     `json:"weight"`
+	Tier          int     `json:"tier"`        // 第几次尝试会进入该优先级，0 表示首次请求
+	Probability   float64 `json:"probability"` // 在所属优先级内被选中的概率
+	UpstreamModel string  `json:"upstream_model"`
+	ModelMapped   bool    `json:"model_mapped"`
+	MappingError  string  `json:"mapping_error,omitempty"`
+	Degraded      string  `json:"degraded,omitempty"`     // 服务商故障描述，降级渠道的选择权重会降低
+	CircuitOpen   bool    `json:"circuit_open,omitempty"` // 该模型在当前节点熔断中，其他渠道可用时不会被选中
+}
+
+type routePricing struct {
+	UsePrice        bool    `json:"use_price"`
+	ModelPrice      float64 `json:"model_price"`
+	ModelRatio      float64 `json:"model_ratio"`
+	CompletionRatio float64 `json:"completion_ratio"`
+	GroupRatio      float64 `json:"group_ratio"`
+	Configured      bool    `json:"configured"` // 模型是否配置了价格或倍率
+}
+
+// SimulateRoute 模拟一次请求的渠道选择，返回候选渠道、模型重定向、计费倍率以及本次会选中的渠道，不会发起上游请求
+// POST /api/route/simulate
+func SimulateRoute(c *gin.Context) {
+	var req routeSimulateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Model == "" {
+		common.ApiErrorMsg(c, "模型不能为空")
+		return
+	}
+
+	userGroup := req.Group
+	usingGroup := req.Group
+	modelAllowed := true
+	if req.Token != "" {
+		key := strings.Split(strings.TrimPrefix(req.Token, "sk-"), "-")[0]
+		token, err := model.GetTokenByKey(key, false)
+		if err != nil {
+			common.ApiErrorMsg(c, "令牌不存在")
+			return
+		}
+		userGroup, err = model.GetUserGroup(token.UserId, false)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		usingGroup = userGroup
+		if token.Group != "" {
+			usingGroup = token.Group
+		}
+		if token.IsModelLimitsEnabled() {
+			modelAllowed = token.GetModelLimitsMap()[req.Model]
+		}
+	}
+	if usingGroup == "" {
+		common.ApiErrorMsg(c, "分组不能为空")
+		return
+	}
+
+	// auto 分组按顺序使用第一个有可用渠道的分组
+	candidateGroup := usingGroup
+	if usingGroup == "auto" {
+		candidateGroup = ""
+		for _, autoGroup := range setting.AutoGroups {
+			if _, channels, err := model.GetRoutingCandidates(autoGroup, req.Model); err == nil && len(channels) > 0 {
+				candidateGroup = autoGroup
+				break
+			}
+		}
+	}
+	matchedModel := req.Model
+	var channels []*model.Channel
+	if candidateGroup != "" {
+		var err error
+		matchedModel, channels, err = model.GetRoutingCandidates(candidateGroup, req.Model)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+
+	common.ApiSuccess(c, gin.H{
+		"model":         req.Model,
+		"matched_model": matchedModel,
+		"user_group":    userGroup,
+		"group":         candidateGroup,
+		"model_allowed": modelAllowed,
+		"pricing":       simulateRoutePricing(req.Model, userGroup, candidateGroup),
+		"candidates":    buildRouteCandidates(c, req.Model, channels),
+		"selected":      simulateRouteSelection(c, usingGroup, req.Model, modelAllowed),
+	})
+}
+
+// buildRouteCandidates 按优先级从高到低、权重从大到小排列候选渠道，并计算各渠道的模型重定向结果
+func buildRouteCandidates(c *gin.Context, modelName string, channels []*model.Channel) []routeCandidate {
+	sort.SliceStable(channels, func(i, j int) bool {
+		if channels[i].GetPriority() != channels[j].GetPriority() {
+			return channels[i].GetPriority() > channels[j].GetPriority()
+		}
+		return channels[i].GetWeight() > channels[j].GetWeight()
+	})
+	// 与渠道选择保持一致的平滑系数与降级权重
+	const smoothingFactor = 10
+	tierChannels := make(map[int64][]*model.Channel)
+	for _, channel := range channels {
+		tierChannels[channel.GetPriority()] = append(tierChannels[channel.GetPriority()], channel)
+	}
+	selectionWeights := make(map[int]int)
+	tierWeights := make(map[int64]int)
+	for priority, members := range tierChannels {
+		for i, weight := range model.ChannelSelectionWeights(members, smoothingFactor) {
+			selectionWeights[members[i].Id] = weight
+			tierWeights[priority] += weight
+		}
+	}
+
+	candidates := make([]routeCandidate, 0, len(channels))
+	tier := -1
+	for i, channel := range channels {
+		if i == 0 || channel.GetPriority() != channels[i-1].GetPriority() {
+			tier++
+		}
+		candidate := routeCandidate{
+			Id:            channel.Id,
+			Name:          channel.Name,
+			Type:          channel.Type,
+			Tag:           channel.GetTag(),
+			Priority:      channel.GetPriority(),
+			Weight:        channel.GetWeight(),
+			Tier:          tier,
+			Probability:   float64(selectionWeights[channel.Id]) / float64(tierWeights[channel.GetPriority()]),
+			UpstreamModel: modelName,
+			Degraded:      model.GetChannelDegradedReason(channel),
+			CircuitOpen:   model.IsChannelModelOpen(channel.Id, modelName),
+		}
+		c.Set("model_mapping", channel.GetModelMapping())
+		info := &relaycommon.RelayInfo{OriginModelName: modelName, UpstreamModelName: modelName}
+		if err := helper.ModelMappedHelper(c, info, nil); err != nil {
+			candidate.MappingError = err.Error()
+		} else {
+			candidate.UpstreamModel = info.UpstreamModelName
+			candidate.ModelMapped = info.IsModelMapped
+		}
+		candidates = append(candidates, candidate)
+	}
+	c.Set("model_mapping", "")
+	return candidates
+}
+
+func simulateRoutePricing(modelName string, userGroup string, usingGroup string) routePricing {
+	pricing := routePricing{GroupRatio: 1}
+	if groupRatio, ok := ratio_setting.GetGroupGroupRatio(userGroup, usingGroup); ok {
+		pricing.GroupRatio = groupRatio
+	} else if usingGroup != "" {
+		pricing.GroupRatio = ratio_setting.GetGroupRatio(usingGroup)
+	}
+	if modelPrice, ok := ratio_setting.GetModelPrice(modelName, false); ok {
+		pricing.UsePrice = true
+		pricing.ModelPrice = modelPrice
+		pricing.Configured = true
+		return pricing
+	}
+	modelRatio, ok, _ := ratio_setting.GetModelRatio(modelName)
+	pricing.ModelRatio = modelRatio
+	pricing.CompletionRatio = ratio_setting.GetCompletionRatio(modelName)
+	pricing.Configured = ok
+	return pricing
+}
+
+// simulateRouteSelection 使用与实际请求相同的选择逻辑抽取一次渠道，结果受权重随机影响
+func simulateRouteSelection(c *gin.Context, group string, modelName string, modelAllowed bool) gin.H {
+	if !modelAllowed {
+		return nil
+	}
+	channel, selectGroup, err := model.CacheGetRandomSatisfiedChannel(c, group, modelName, 0)
+	if err != nil || channel == nil {
+		return nil
+	}
+	return gin.H{
+		"id":    channel.Id,
+		"name":  channel.Name,
+		"group": selectGroup,
+	}
+}
@@ -3,6 +3,7 @@ package controller
 import (
 	"net/http"
 	"one-api/common"
+	"one-api/constant"
 	"one-api/model"
 	"strconv"
 
@@ -96,6 +97,16 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.MaxResponseTokens < 0 || token.MaxResponseBytes < 0 {
+		common.ApiErrorMsg(c, "响应上限不能为负数")
+		return
+	}
+	for _, endpoint := range token.GetAllowedEndpoints() {
+		if !common.StringsContains(constant.TokenEndpoints, endpoint) {
+			common.ApiErrorMsg(c, "未知的接口类别: "+endpoint)
+			return
+		}
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -119,6 +130,9 @@ func AddToken(c *gin.Context) {
 		AllowIps:           token.AllowIps,
 		Group:              token.Group,
 		EnableGeminiCache:  token.EnableGeminiCache,
+		MaxResponseTokens:  token.MaxResponseTokens,
+		MaxResponseBytes:   token.MaxResponseBytes,
+		AllowedEndpoints:   token.AllowedEndpoints,
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -163,6 +177,16 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.MaxResponseTokens < 0 || token.MaxResponseBytes < 0 {
+		common.ApiErrorMsg(c, "响应上限不能为负数")
+		return
+	}
+	for _, endpoint := range token.GetAllowedEndpoints() {
+		if !common.StringsContains(constant.TokenEndpoints, endpoint) {
+			common.ApiErrorMsg(c, "未知的接口类别: "+endpoint)
+			return
+		}
+	}
 	cleanToken, err := model.GetTokenByIds(token.Id, userId)
 	if err != nil {
 		common.ApiError(c, err)
@@ -197,6 +221,9 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.AllowIps = token.AllowIps
 		cleanToken.Group = token.Group
 		cleanToken.EnableGeminiCache = token.EnableGeminiCache
+		cleanToken.MaxResponseTokens = token.MaxResponseTokens
+		cleanToken.MaxResponseBytes = token.MaxResponseBytes
+		cleanToken.AllowedEndpoints = token.AllowedEndpoints
 	}
 	err = cleanToken.Update()
 	if err != nil {
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportUpstreamUsage 导入供应商控制台导出的用量文件
+// POST /api/usage_reconcile/import  form: provider, file
+func ImportUpstreamUsage(c *gin.Context) {
+	provider := c.PostForm("provider")
+	if !model.IsValidUpstreamUsageProvider(provider) {
+		common.ApiErrorMsg(c, fmt.Sprintf("不支持的供应商: %s", provider))
+		return
+	}
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	defer file.Close()
+
+	usages, err := service.ParseUpstreamUsageExport(provider, file)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err = model.ImportUpstreamUsage(provider, usages); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"imported": len(usages),
+	})
+}
+
+// GetUsageReconciliation 对比供应商账单与网关记录的 token 用量
+// GET /api/usage_reconcile/report?provider=openai&start_timestamp=&end_timestamp=&threshold=0.05
+func GetUsageReconciliation(c *gin.Context) {
+	provider := c.Query("provider")
+	if !model.IsValidUpstreamUsageProvider(provider) {
+		common.ApiErrorMsg(c, fmt.Sprintf("不支持的供应商: %s", provider))
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	threshold, err := strconv.ParseFloat(c.Query("threshold"), 64)
+	if err != nil || threshold <= 0 {
+		threshold = 0.05
+	}
+	items, err := model.GetUsageReconciliation(provider, startTimestamp, endTimestamp, threshold)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	discrepancies := 0
+	for _, item := range items {
+		if item.Discrepancy {
+			discrepancies++
+		}
+	}
+	common.ApiSuccess(c, gin.H{
+		"provider":      provider,
+		"threshold":     threshold,
+		"discrepancies": discrepancies,
+		"items":         items,
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"one-api/common"
 	"one-api/model"
+	"one-api/service"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -50,3 +51,10 @@ func GetUserQuotaDates(c *gin.Context) {
 	})
 	return
 }
+
+// GetChannelSizeStats 各渠道最近请求/响应体积的平均值与 p95
+// GET /api/data/channel_size?channel_id=
+func GetChannelSizeStats(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	common.ApiSuccess(c, service.GetChannelSizeStats(channelId))
+}
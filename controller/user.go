@@ -8,6 +8,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	"one-api/model"
+	"one-api/service"
 	"one-api/setting"
 	"strconv"
 	"strings"
@@ -432,6 +433,8 @@ func GetSelf(c *gin.Context) {
 	}
 	// Hide admin remarks: set to empty to trigger omitempty tag, ensuring the remark field is not included in JSON returned to regular users
 	user.Remark = ""
+	// 流式等长时间请求进行中时，可用额度会因预扣而暂时减少
+	user.PendingQuota = service.GetPendingQuota(id)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -980,3 +983,22 @@ func UpdateUserSetting(c *gin.Context) {
 		"message": "设置已更新",
 	})
 }
+
+// GetUserCacheSavings 统计当前用户命中提示缓存节省的额度，默认统计最近 30 天
+func GetUserCacheSavings(c *gin.Context) {
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	if startTimestamp == 0 {
+		startTimestamp = common.GetTimestamp() - 30*24*3600
+	}
+	if endTimestamp != 0 && endTimestamp < startTimestamp {
+		common.ApiErrorMsg(c, "结束时间不能早于开始时间")
+		return
+	}
+	report, err := model.GetUserCacheSavings(c.GetInt("id"), startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, report)
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"strconv"
+
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetUserWebhooks 获取当前用户注册的 webhook 列表
+func GetUserWebhooks(c *gin.Context) {
+	webhooks, err := model.GetUserWebhooks(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, gin.H{
+		"items":  webhooks,
+		"events": dto.UserWebhookEvents,
+	})
+}
+
+// CreateUserWebhook 为当前用户注册 webhook
+func CreateUserWebhook(c *gin.Context) {
+	var webhook model.UserWebhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	webhook.Id = 0
+	webhook.UserId = c.GetInt("id")
+	if err := webhook.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := webhook.Insert(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &webhook)
+}
+
+// UpdateUserWebhook 更新当前用户的 webhook，secret 留空表示不修改
+func UpdateUserWebhook(c *gin.Context) {
+	var webhook model.UserWebhook
+	if err := c.ShouldBindJSON(&webhook); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if webhook.Id == 0 {
+		common.ApiErrorMsg(c, "缺少 webhook ID")
+		return
+	}
+	webhook.UserId = c.GetInt("id")
+	old, err := model.GetUserWebhookById(webhook.Id, webhook.UserId)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = old.Secret
+	}
+	if err := webhook.Validate(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := webhook.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, &webhook)
+}
+
+// DeleteUserWebhook 删除当前用户的 webhook
+func DeleteUserWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := model.DeleteUserWebhookById(id, c.GetInt("id")); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// TestUserWebhook 向 webhook 发送一条测试消息
+func TestUserWebhook(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	webhook, err := model.GetUserWebhookById(id, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if err := service.SendUserWebhookTest(webhook); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "encoding/json"
+
 type ChannelSettings struct {
 	ForceFormat            bool   `json:"force_format,omitempty"`
 	ThinkingToContent      bool   `json:"thinking_to_content,omitempty"`
@@ -7,6 +9,64 @@ type ChannelSettings struct {
 	PassThroughBodyEnabled bool   `json:"pass_through_body_enabled,omitempty"`
 	SystemPrompt           string `json:"system_prompt,omitempty"`
 	SystemPromptOverride   bool   `json:"system_prompt_override,omitempty"`
+	JsonRepair             bool   `json:"json_repair,omitempty"`      // response_format 为 json 时只保留第一个 JSON 文档
+	TestFrequency          int    `json:"test_frequency,omitempty"`   // 自动测试间隔（分钟），为 0 时使用标签或全局设置
+	TestDisabled           bool   `json:"test_disabled,omitempty"`    // 不参与自动测试，适合按次计费的渠道
+	ImageURLOutput         bool   `json:"image_url_output,omitempty"` // 生成的图片上传到图片存储并返回 URL
+	// Languages 渠道擅长的提示词语言（ISO 639-1，如 zh、en），开启语言路由时同优先级内优先选择
+	Languages []string `json:"languages,omitempty"`
+	// CostTags 成本归属标签（如 team、environment、project），记录到消费日志中
+	CostTags map[string]string `json:"cost_tags,omitempty"`
+	// TestRequestTemplate 渠道测试请求的覆盖字段（OpenAI 请求格式的 JSON 对象），在适配器模板之后合并
+	TestRequestTemplate json.RawMessage `json:"test_request_template,omitempty"`
+	// ResponseFixups 兼容不规范 OpenAI 响应的修正项，按名称选择，见 ResponseFixup* 常量
+	ResponseFixups []string `json:"response_fixups,omitempty"`
+	// ModeEndpoints 按请求类型覆盖代理地址与 API 版本，适用于对话、嵌入、绘图由不同服务提供的自建部署，键见 ChannelMode* 常量
+	ModeEndpoints map[string]ChannelModeEndpoint `json:"mode_endpoints,omitempty"`
+	// ToolUnsupportedModels 该渠道工具调用不可靠的模型（如会改写工具参数结构），以 * 结尾按前缀匹配，开启工具路由时尽量避开
+	ToolUnsupportedModels []string `json:"tool_unsupported_models,omitempty"`
+}
+
+// ChannelModeEndpoint 某类请求使用的代理地址与 API 版本，为空的字段沿用渠道配置
+type ChannelModeEndpoint struct {
+	BaseURL    string `json:"base_url,omitempty"`
+	ApiVersion string `json:"api_version,omitempty"`
+}
+
+const (
+	ChannelModeChat        = "chat" // chat/completions、completions 与 Gemini 原生对话
+	ChannelModeResponses   = "responses"
+	ChannelModeEmbeddings  = "embeddings"
+	ChannelModeImages      = "images"
+	ChannelModeAudio       = "audio"
+	ChannelModeModerations = "moderations"
+	ChannelModeRerank      = "rerank"
+)
+
+var ChannelModes = []string{
+	ChannelModeChat,
+	ChannelModeResponses,
+	ChannelModeEmbeddings,
+	ChannelModeImages,
+	ChannelModeAudio,
+	ChannelModeModerations,
+	ChannelModeRerank,
+}
+
+const (
+	ResponseFixupFillFields   = "fill_fields"   // 补全缺失的 id、object、created、model 字段
+	ResponseFixupChoiceIndex  = "choice_index"  // 补全缺失的 choices[].index
+	ResponseFixupFinishReason = "finish_reason" // 将非字符串或非标准的 finish_reason 转换为标准值
+	ResponseFixupMessageRole  = "message_role"  // 补全缺失的 message.role
+	ResponseFixupUsageNumbers = "usage_numbers" // 将字符串形式的 usage 数值转换为数字
+)
+
+var ResponseFixups = []string{
+	ResponseFixupFillFields,
+	ResponseFixupChoiceIndex,
+	ResponseFixupFinishReason,
+	ResponseFixupMessageRole,
+	ResponseFixupUsageNumbers,
 }
 
 type ChannelOtherSettings struct {
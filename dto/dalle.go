@@ -27,3 +27,10 @@ type ImageData struct {
 	B64Json       string `json:"b64_json"`
 	RevisedPrompt string `json:"revised_prompt"`
 }
+
+// ImageModerationResult 生成图片的审核结论，记录在日志中
+type ImageModerationResult struct {
+	Action  string `json:"action"`
+	Flagged []int  `json:"flagged,omitempty"` // 违规图片在上游响应中的下标
+	Error   string `json:"error,omitempty"`   // 分类器调用失败且放行时的错误信息
+}
@@ -11,6 +11,7 @@ type GeminiChatRequest struct {
 	SafetySettings     []GeminiChatSafetySettings `json:"safetySettings,omitempty"`
 	GenerationConfig   GeminiChatGenerationConfig `json:"generationConfig,omitempty"`
 	Tools              json.RawMessage            `json:"tools,omitempty"`
+	ToolConfig         *GeminiToolConfig          `json:"toolConfig,omitempty"`
 	SystemInstructions *GeminiChatContent         `json:"systemInstruction,omitempty"`
 	CachedContent      string 					  `json:"cachedContent,omitempty"`
 }
@@ -27,9 +28,26 @@ type GeminiCachedContentResponse struct {
 	Name string `json:"name"` // e.g. "cachedContents/abc123"
 }
 
+type GeminiCachedContent struct {
+	Name          string `json:"name"`
+	Model         string `json:"model"`
+	DisplayName   string `json:"displayName,omitempty"`
+	CreateTime    string `json:"createTime,omitempty"`
+	UpdateTime    string `json:"updateTime,omitempty"`
+	ExpireTime    string `json:"expireTime,omitempty"`
+	UsageMetadata struct {
+		TotalTokenCount int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type GeminiCachedContentListResponse struct {
+	CachedContents []GeminiCachedContent `json:"cachedContents"`
+	NextPageToken  string                `json:"nextPageToken,omitempty"`
+}
+
 func (r *GeminiChatRequest) GetTools() []GeminiChatTool {
 	var tools []GeminiChatTool
-	if strings.HasSuffix(string(r.Tools), "[") {
+	if strings.HasPrefix(string(r.Tools), "[") {
 		// is array
 		if err := common.Unmarshal(r.Tools, &tools); err != nil {
 			common.LogError(nil, "error_unmarshalling_tools: "+err.Error())
@@ -62,6 +80,15 @@ func (r *GeminiChatRequest) SetTools(tools []GeminiChatTool) {
 	r.Tools = data
 }
 
+type GeminiToolConfig struct {
+	FunctionCallingConfig *GeminiFunctionCallingConfig `json:"functionCallingConfig,omitempty"`
+}
+
+type GeminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode,omitempty"` // AUTO, ANY, NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
 type GeminiThinkingConfig struct {
 	IncludeThoughts bool `json:"includeThoughts,omitempty"`
 	ThinkingBudget  *int `json:"thinkingBudget,omitempty"`
@@ -194,13 +221,32 @@ type GeminiChatGenerationConfig struct {
 	ThinkingConfig     *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
 	SpeechConfig       json.RawMessage       `json:"speechConfig,omitempty"` // RawMessage to allow flexible speech config
 	Model string `json:"model,omitempty"`
+	// ExtraFields 通过 extra_body 透传的字段，序列化时合并进 generationConfig 并覆盖同名字段
+	ExtraFields map[string]json.RawMessage `json:"-"`
+}
+
+func (c GeminiChatGenerationConfig) MarshalJSON() ([]byte, error) {
+	type Alias GeminiChatGenerationConfig
+	data, err := common.Marshal(Alias(c))
+	if err != nil || len(c.ExtraFields) == 0 {
+		return data, err
+	}
+	merged := make(map[string]json.RawMessage)
+	if err = common.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for key, value := range c.ExtraFields {
+		merged[key] = value
+	}
+	return common.Marshal(merged)
 }
 
 type GeminiChatCandidate struct {
-	Content       GeminiChatContent        `json:"content"`
-	FinishReason  *string                  `json:"finishReason"`
-	Index         int64                    `json:"index"`
-	SafetyRatings []GeminiChatSafetyRating `json:"safetyRatings"`
+	Content           GeminiChatContent        `json:"content"`
+	FinishReason      *string                  `json:"finishReason"`
+	Index             int64                    `json:"index"`
+	SafetyRatings     []GeminiChatSafetyRating `json:"safetyRatings"`
+	GroundingMetadata *GeminiGroundingMetadata `json:"groundingMetadata,omitempty"`
 }
 
 type GeminiChatSafetyRating struct {
@@ -219,11 +265,12 @@ type GeminiChatResponse struct {
 }
 
 type GeminiUsageMetadata struct {
-	PromptTokenCount     int                         `json:"promptTokenCount"`
-	CandidatesTokenCount int                         `json:"candidatesTokenCount"`
-	TotalTokenCount      int                         `json:"totalTokenCount"`
-	ThoughtsTokenCount   int                         `json:"thoughtsTokenCount"`
-	PromptTokensDetails  []GeminiPromptTokensDetails `json:"promptTokensDetails"`
+	PromptTokenCount        int                         `json:"promptTokenCount"`
+	CandidatesTokenCount    int                         `json:"candidatesTokenCount"`
+	TotalTokenCount         int                         `json:"totalTokenCount"`
+	ThoughtsTokenCount      int                         `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int                         `json:"cachedContentTokenCount"` // 命中 cachedContent 的 token 数，已包含在 PromptTokenCount 中
+	PromptTokensDetails     []GeminiPromptTokensDetails `json:"promptTokensDetails"`
 }
 
 type GeminiPromptTokensDetails struct {
@@ -245,6 +292,8 @@ type GeminiImageParameters struct {
 	SampleCount      int    `json:"sampleCount,omitempty"`
 	AspectRatio      string `json:"aspectRatio,omitempty"`
 	PersonGeneration string `json:"personGeneration,omitempty"`
+	NegativePrompt   string `json:"negativePrompt,omitempty"`
+	Seed             *int64 `json:"seed,omitempty"`
 }
 
 type GeminiImageResponse struct {
@@ -276,7 +325,8 @@ type GeminiEmbeddingResponse struct {
 }
 
 type GeminiBatchEmbeddingResponse struct {
-	Embeddings []*ContentEmbedding `json:"embeddings"`
+	Embeddings    []*ContentEmbedding  `json:"embeddings"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
 }
 
 type ContentEmbedding struct {
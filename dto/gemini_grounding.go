@@ -0,0 +1,30 @@
+package dto
+
+// GeminiGroundingMetadata 开启 googleSearch 后，候选结果中的搜索来源与引用位置
+type GeminiGroundingMetadata struct {
+	WebSearchQueries  []string                 `json:"webSearchQueries,omitempty"`
+	GroundingChunks   []GeminiGroundingChunk   `json:"groundingChunks,omitempty"`
+	GroundingSupports []GeminiGroundingSupport `json:"groundingSupports,omitempty"`
+}
+
+type GeminiGroundingChunk struct {
+	Web *GeminiGroundingWeb `json:"web,omitempty"`
+}
+
+type GeminiGroundingWeb struct {
+	Uri   string `json:"uri"`
+	Title string `json:"title"`
+}
+
+type GeminiGroundingSupport struct {
+	Segment               GeminiGroundingSegment `json:"segment"`
+	GroundingChunkIndices []int                  `json:"groundingChunkIndices"`
+}
+
+// GeminiGroundingSegment StartIndex、EndIndex 为回复文本中的字节偏移
+type GeminiGroundingSegment struct {
+	PartIndex  int    `json:"partIndex,omitempty"`
+	StartIndex int    `json:"startIndex,omitempty"`
+	EndIndex   int    `json:"endIndex,omitempty"`
+	Text       string `json:"text,omitempty"`
+}
@@ -1,10 +1,11 @@
 package dto
 
 type Notify struct {
-	Type    string        `json:"type"`
-	Title   string        `json:"title"`
-	Content string        `json:"content"`
-	Values  []interface{} `json:"values"`
+	Type     string                 `json:"type"`
+	Title    string                 `json:"title"`
+	Content  string                 `json:"content"`
+	Values   []interface{}          `json:"values"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"` // 触发通知的请求所携带的 metadata
 }
 
 const ContentValueParam = "{{value}}"
@@ -13,6 +14,7 @@ const (
 	NotifyTypeQuotaExceed   = "quota_exceed"
 	NotifyTypeChannelUpdate = "channel_update"
 	NotifyTypeChannelTest   = "channel_test"
+	NotifyTypeChannelSize   = "channel_size"
 )
 
 func NewNotify(t string, title string, content string, values []interface{}) Notify {
@@ -23,3 +25,25 @@ func NewNotify(t string, title string, content string, values []interface{}) Not
 		Values:  values,
 	}
 }
+
+// 用户 webhook 可订阅的事件
+const (
+	UserWebhookEventQuotaBelow    = "quota_below"    // 剩余额度低于设定阈值
+	UserWebhookEventTokenExpired  = "token_expired"  // 令牌已过期
+	UserWebhookEventRequestFailed = "request_failed" // 请求最终以 5xx 失败
+)
+
+var UserWebhookEvents = []string{
+	UserWebhookEventQuotaBelow,
+	UserWebhookEventTokenExpired,
+	UserWebhookEventRequestFailed,
+}
+
+func IsUserWebhookEvent(event string) bool {
+	for _, e := range UserWebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -58,6 +58,7 @@ type GeneralOpenAIRequest struct {
 	ExtraBody           json.RawMessage   `json:"extra_body,omitempty"`
 	SearchParameters    any               `json:"search_parameters,omitempty"` //xai
 	WebSearchOptions    *WebSearchOptions `json:"web_search_options,omitempty"`
+	Cache               *CacheOptions     `json:"cache,omitempty"`
 	// OpenRouter Params
 	Usage     json.RawMessage `json:"usage,omitempty"`
 	Reasoning json.RawMessage `json:"reasoning,omitempty"`
@@ -102,6 +103,23 @@ type StreamOptions struct {
 	IncludeUsage bool `json:"include_usage,omitempty"`
 }
 
+// IsAudioOutput 请求是否要求输出音频（modalities 包含 audio 或设置了 audio 参数）
+func (r *GeneralOpenAIRequest) IsAudioOutput() bool {
+	if len(r.Audio) > 0 && string(r.Audio) != "null" {
+		return true
+	}
+	var modalities []string
+	if err := common.Unmarshal(r.Modalities, &modalities); err != nil {
+		return false
+	}
+	for _, modality := range modalities {
+		if modality == "audio" {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *GeneralOpenAIRequest) GetMaxTokens() uint {
 	if r.MaxCompletionTokens != 0 {
 		return r.MaxCompletionTokens
@@ -137,6 +155,8 @@ type Message struct {
 	Reasoning        string          `json:"reasoning,omitempty"`
 	ToolCalls        json.RawMessage `json:"tool_calls,omitempty"`
 	ToolCallId       string          `json:"tool_call_id,omitempty"`
+	Audio            json.RawMessage `json:"audio,omitempty"` // 音频输出，多轮对话中以 {"id": ...} 引用
+	Annotations      []Annotation    `json:"annotations,omitempty"`
 	parsedContent    []MediaContent
 	//parsedStringContent *string
 }
@@ -620,6 +640,18 @@ type WebSearchOptions struct {
 	UserLocation      json.RawMessage `json:"user_location,omitempty"`
 }
 
+// CacheOptions 客户端对上下文缓存的控制，不会透传给上游
+type CacheOptions struct {
+	Enabled *bool  `json:"enabled,omitempty"` // 为 false 时本次请求不使用缓存
+	Ttl     int    `json:"ttl,omitempty"`     // 新建缓存的有效期（秒），不超过管理员设置的上限
+	Key     string `json:"key,omitempty"`     // 自定义缓存 key，替代按内容计算的 hash，仅在同一用户、同一模型内生效
+}
+
+// IsCacheDisabled 客户端是否显式关闭了缓存
+func (c *CacheOptions) IsCacheDisabled() bool {
+	return c != nil && c.Enabled != nil && !*c.Enabled
+}
+
 // https://platform.openai.com/docs/api-reference/responses/create
 type OpenAIResponsesRequest struct {
 	Model              string           `json:"model"`
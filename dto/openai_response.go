@@ -85,6 +85,22 @@ type ChatCompletionsStreamResponseChoiceDelta struct {
 	Reasoning        *string            `json:"reasoning,omitempty"`
 	Role             string             `json:"role,omitempty"`
 	ToolCalls        []ToolCallResponse `json:"tool_calls,omitempty"`
+	Audio            json.RawMessage    `json:"audio,omitempty"` // 音频输出的分片
+	Annotations      []Annotation       `json:"annotations,omitempty"`
+}
+
+// Annotation 回复中的引用，目前只有联网搜索产生的 url_citation
+type Annotation struct {
+	Type        string       `json:"type"`
+	UrlCitation *UrlCitation `json:"url_citation,omitempty"`
+}
+
+// UrlCitation StartIndex、EndIndex 为引用内容在回复中的字符位置
+type UrlCitation struct {
+	Url        string `json:"url"`
+	Title      string `json:"title"`
+	StartIndex int    `json:"start_index"`
+	EndIndex   int    `json:"end_index"`
 }
 
 func (c *ChatCompletionsStreamResponseChoiceDelta) SetContentString(s string) {
@@ -281,8 +297,12 @@ type ResponsesOutput struct {
 	Type    string                   `json:"type"`
 	ID      string                   `json:"id"`
 	Status  string                   `json:"status"`
-	Role    string                   `json:"role"`
+	Role    string                   `json:"role,omitempty"`
 	Content []ResponsesOutputContent `json:"content"`
+	// function_call
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 type ResponsesOutputContent struct {
@@ -307,10 +327,16 @@ const (
 
 // ResponsesStreamResponse 用于处理 /v1/responses 流式响应
 type ResponsesStreamResponse struct {
-	Type     string                   `json:"type"`
-	Response *OpenAIResponsesResponse `json:"response,omitempty"`
-	Delta    string                   `json:"delta,omitempty"`
-	Item     *ResponsesOutput         `json:"item,omitempty"`
+	Type         string                   `json:"type"`
+	Response     *OpenAIResponsesResponse `json:"response,omitempty"`
+	Delta        string                   `json:"delta,omitempty"`
+	Item         *ResponsesOutput         `json:"item,omitempty"`
+	ItemId       string                   `json:"item_id,omitempty"`
+	OutputIndex  *int                     `json:"output_index,omitempty"`
+	ContentIndex *int                     `json:"content_index,omitempty"`
+	Part         *ResponsesOutputContent  `json:"part,omitempty"`
+	Text         string                   `json:"text,omitempty"`
+	Arguments    string                   `json:"arguments,omitempty"`
 }
 
 // GetOpenAIError 从动态错误类型中提取OpenAIError结构
@@ -9,6 +9,7 @@ type OpenAIModels struct {
 	Created                int                     `json:"created"`
 	OwnedBy                string                  `json:"owned_by"`
 	SupportedEndpointTypes []constant.EndpointType `json:"supported_endpoint_types"`
+	Capabilities           []string                `json:"capabilities,omitempty"` // 能力提示，如 chat、vision、tools、rerank
 }
 
 type AnthropicModel struct {
@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"one-api/common"
+	"one-api/common/eventbus"
 	"one-api/constant"
 	"one-api/controller"
 	"one-api/middleware"
@@ -15,6 +16,7 @@ import (
 	"one-api/setting/ratio_setting"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"github.com/gin-contrib/sessions"
@@ -100,6 +102,26 @@ func main() {
 		}
 		go controller.AutomaticallyTestChannels(frequency)
 	}
+	// 密钥检查会自动禁用渠道，只在主节点执行，避免多节点重复请求上游
+	if common.IsMasterNode && os.Getenv("CHANNEL_KEY_CHECK_FREQUENCY") != "" {
+		frequency, err := strconv.Atoi(os.Getenv("CHANNEL_KEY_CHECK_FREQUENCY"))
+		if err != nil {
+			common.FatalLog("failed to parse CHANNEL_KEY_CHECK_FREQUENCY: " + err.Error())
+		}
+		go controller.AutomaticallyCheckChannelKeys(frequency)
+	}
+	// 根据服务商状态页降低故障服务商渠道的权重
+	go service.AutomaticallyCheckProviderStatus()
+	// 清理超过保留天数的生成图片
+	go service.AutomaticallyCleanupStoredImages()
+	if common.IsMasterNode {
+		// 定期额度发放
+		go model.AutomaticallyRunQuotaGrants()
+		// 核对 Gemini 缓存记录与上游缓存
+		go controller.AutomaticallyReconcileGeminiCaches()
+		// 继续删除重启前未完成排空的渠道
+		controller.ResumeDrainingChannels()
+	}
 	if common.IsMasterNode && constant.UpdateTask {
 		gopool.Go(func() {
 			controller.UpdateMidjourneyTaskBulk()
@@ -149,6 +171,9 @@ func main() {
 	server.Use(sessions.Sessions("session", store))
 
 	router.SetRouter(server, buildFS, indexPage)
+	if os.Getenv("ADMIN_TLS_PORT") != "" {
+		startAdminTLSServer(server)
+	}
 	var port = os.Getenv("PORT")
 	if port == "" {
 		port = strconv.Itoa(*common.Port)
@@ -159,6 +184,39 @@ func main() {
 	}
 }
 
+// startAdminTLSServer 在单独端口上以双向 TLS 提供管理 API，客户端证书指纹映射到用户与角色
+func startAdminTLSServer(handler http.Handler) {
+	certs, err := common.ParseAdminTLSClientCerts(os.Getenv("ADMIN_TLS_CLIENT_CERTS"))
+	if err != nil {
+		common.FatalLog("failed to parse ADMIN_TLS_CLIENT_CERTS: " + err.Error())
+	}
+	common.AdminTLSClientCerts = certs
+	common.AdminTLSEnabled = true
+	tlsConfig, err := common.NewAdminTLSConfig(os.Getenv("ADMIN_TLS_CLIENT_CA_FILE"))
+	if err != nil {
+		common.FatalLog("failed to load ADMIN_TLS_CLIENT_CA_FILE: " + err.Error())
+	}
+	adminServer := &http.Server{
+		Addr:      ":" + os.Getenv("ADMIN_TLS_PORT"),
+		TLSConfig: tlsConfig,
+		// 管理端口只提供 /api 下的接口
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, "/api/") {
+				http.NotFound(w, r)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}),
+	}
+	gopool.Go(func() {
+		common.SysLog("admin TLS server listening on port " + os.Getenv("ADMIN_TLS_PORT"))
+		err := adminServer.ListenAndServeTLS(os.Getenv("ADMIN_TLS_CERT_FILE"), os.Getenv("ADMIN_TLS_KEY_FILE"))
+		if err != nil {
+			common.FatalLog("failed to start admin TLS server: " + err.Error())
+		}
+	})
+}
+
 func InitResources() error {
 	// Initialize resources here if needed
 	// This is a placeholder function for future resource initialization
@@ -206,5 +264,11 @@ func InitResources() error {
 	if err != nil {
 		return err
 	}
+
+	// 初始化用量事件发布
+	err = eventbus.Init()
+	if err != nil {
+		return err
+	}
 	return nil
 }
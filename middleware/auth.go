@@ -6,6 +6,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/model"
+	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/ratio_setting"
 	"strconv"
@@ -32,7 +33,35 @@ func authHelper(c *gin.Context, minRole int) {
 	role := session.Get("role")
 	id := session.Get("id")
 	status := session.Get("status")
+	group := session.Get("group")
 	useAccessToken := false
+	useClientCert := false
+	if c.Request.TLS != nil && common.AdminTLSEnabled {
+		// 管理端口只认客户端证书，忽略会话与 access token，角色不超过证书映射的角色
+		user, certRole := getClientCertUser(c)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"message": "无权进行此操作，客户端证书未授权",
+			})
+			c.Abort()
+			return
+		}
+		username = user.Username
+		role = min(user.Role, certRole)
+		id = user.Id
+		status = user.Status
+		group = user.Group
+		useClientCert = true
+	} else if common.AdminTLSEnabled && minRole >= common.RoleAdminUser {
+		// 启用管理端口后，管理接口只能通过 mTLS 端口访问
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"message": "无权进行此操作，管理接口仅可通过管理端口访问",
+		})
+		c.Abort()
+		return
+	}
 	if username == nil {
 		// Check access token
 		accessToken := c.Request.Header.Get("Authorization")
@@ -71,6 +100,10 @@ func authHelper(c *gin.Context, minRole int) {
 	}
 	// get header New-Api-User
 	apiUserIdStr := c.Request.Header.Get("New-Api-User")
+	if apiUserIdStr == "" && useClientCert {
+		// 客户端证书已确定用户身份，可以不提供 New-Api-User
+		apiUserIdStr = strconv.Itoa(id.(int))
+	}
 	if apiUserIdStr == "" {
 		c.JSON(http.StatusUnauthorized, gin.H{
 			"success": false,
@@ -121,11 +154,15 @@ func authHelper(c *gin.Context, minRole int) {
 		c.Abort()
 		return
 	}
+	if common.AdminTLSEnabled && !useClientCert && role.(int) > common.RoleCommonUser {
+		// 主端口上管理员只具有普通用户权限
+		role = common.RoleCommonUser
+	}
 	c.Set("username", username)
 	c.Set("role", role)
 	c.Set("id", id)
-	c.Set("group", session.Get("group"))
-	c.Set("user_group", session.Get("group"))
+	c.Set("group", group)
+	c.Set("user_group", group)
 	c.Set("use_access_token", useAccessToken)
 
 	//userCache, err := model.GetUserCache(id.(int))
@@ -142,6 +179,22 @@ func authHelper(c *gin.Context, minRole int) {
 	c.Next()
 }
 
+// getClientCertUser 根据已校验的客户端证书指纹查找对应用户及证书允许的角色，仅在 mTLS 管理端口上生效
+func getClientCertUser(c *gin.Context) (*model.User, int) {
+	if c.Request.TLS == nil || len(c.Request.TLS.VerifiedChains) == 0 || len(c.Request.TLS.VerifiedChains[0]) == 0 {
+		return nil, 0
+	}
+	mapping, ok := common.AdminTLSClientCerts[common.CertFingerprint(c.Request.TLS.VerifiedChains[0][0])]
+	if !ok {
+		return nil, 0
+	}
+	user, err := model.GetUserById(mapping.UserId, false)
+	if err != nil || !validUserInfo(user.Username, user.Role) {
+		return nil, 0
+	}
+	return user, mapping.Role
+}
+
 func TryUserAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
 		session := sessions.Default(c)
@@ -236,6 +289,9 @@ func TokenAuth() func(c *gin.Context) {
 			}
 		}
 		if err != nil {
+			if token != nil && token.ExpiredTime != -1 && token.ExpiredTime < common.GetTimestamp() {
+				service.DispatchTokenExpiredEvent(token)
+			}
 			abortWithOpenAiMessage(c, http.StatusUnauthorized, err.Error())
 			return
 		}
@@ -308,6 +364,9 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 		c.Set("token_model_limit_enabled", false)
 	}
 	c.Set("token_enable_gemini_cache", token.EnableGeminiCache)
+	c.Set("token_max_response_tokens", token.MaxResponseTokens)
+	c.Set("token_max_response_bytes", token.MaxResponseBytes)
+	c.Set("token_allowed_endpoints", token.GetAllowedEndpoints())
 	c.Set("token_group", token.Group)
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strings"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// bodyArchiveWriter 保留响应内容的前 limit 个字节
+type bodyArchiveWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *bodyArchiveWriter) capture(data []byte) {
+	if remaining := w.limit - w.body.Len(); remaining < len(data) {
+		w.truncated = true
+		if remaining > 0 {
+			w.body.Write(data[:remaining])
+		}
+		return
+	}
+	w.body.Write(data)
+}
+
+func (w *bodyArchiveWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyArchiveWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// BodyArchive 保存中继请求与响应的内容（脱敏并截断），按日志 id 查询，需放在其他中继中间件之前
+func BodyArchive() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		archiveSetting := operation_setting.GetBodyArchiveSetting()
+		if !archiveSetting.Enabled {
+			c.Next()
+			return
+		}
+		limit := archiveSetting.MaxBodyKB << 10
+		var requestBody []byte
+		contentType := c.Request.Header.Get("Content-Type")
+		if strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/") {
+			body, err := common.GetRequestBody(c)
+			if err == nil {
+				c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
+				// 保留完整请求体，脱敏后再截断，避免跨越截断点的敏感信息漏脱敏
+				requestBody = append([]byte(nil), body...)
+			}
+		} else if contentType != "" {
+			// 文件上传等二进制内容不保存
+			requestBody = []byte("[" + contentType + " body omitted]")
+		}
+
+		// 响应多保留一段，脱敏后再截断到 limit，避免跨越截断点的敏感信息漏脱敏
+		writer := &bodyArchiveWriter{ResponseWriter: c.Writer, limit: limit + service.BodyArchiveRedactMargin}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		status := writer.ResponseWriter.Status()
+		if archiveSetting.OnlyFailed && status == http.StatusOK {
+			return
+		}
+		archive := &model.BodyArchive{
+			LogId:      common.GetContextKeyInt(c, constant.ContextKeyLogId),
+			RequestId:  c.GetString(common.RequestIdKey),
+			CreatedAt:  common.GetTimestamp(),
+			Path:       c.Request.URL.Path,
+			StatusCode: status,
+			Truncated:  writer.truncated,
+		}
+		responseBody := writer.body.Bytes()
+		gopool.Go(func() {
+			if err := service.ArchiveBodies(archive, requestBody, responseBody); err != nil {
+				common.SysError("failed to archive relay bodies: " + err.Error())
+			}
+		})
+	}
+}
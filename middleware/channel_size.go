@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"one-api/common"
+	"one-api/constant"
+	"one-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelSizeStats 请求结束后按最终使用的渠道记录请求/响应体积
+func ChannelSizeStats() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		c.Next()
+		channelId := common.GetContextKeyInt(c, constant.ContextKeyChannelId)
+		if channelId == 0 {
+			return
+		}
+		requestBytes := c.Request.ContentLength
+		if requestBytes < 0 {
+			// 分块传输时以缓存的请求体为准
+			requestBody, _ := c.Get(common.KeyRequestBody)
+			if body, ok := requestBody.([]byte); ok {
+				requestBytes = int64(len(body))
+			} else {
+				requestBytes = 0
+			}
+		}
+		responseBytes := int64(c.Writer.Size())
+		if responseBytes < 0 {
+			responseBytes = 0
+		}
+		service.RecordChannelSize(channelId, common.GetContextKeyString(c, constant.ContextKeyChannelName), requestBytes, responseBytes)
+	}
+}
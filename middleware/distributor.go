@@ -11,6 +11,7 @@ import (
 	relayconstant "one-api/relay/constant"
 	"one-api/service"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"one-api/setting/ratio_setting"
 	"one-api/types"
 	"strconv"
@@ -25,8 +26,51 @@ type ModelRequest struct {
 	Group string `json:"group,omitempty"`
 }
 
+// tokenEndpointOfPath 返回请求路径所属的接口类别，无法识别时返回空字符串
+func tokenEndpointOfPath(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/v1/chat/completions"), strings.HasPrefix(path, "/pg/chat/completions"),
+		strings.HasPrefix(path, "/v1/completions"), strings.HasPrefix(path, "/v1/edits"),
+		strings.HasPrefix(path, "/v1beta/openai"):
+		return constant.TokenEndpointChat
+	case strings.HasPrefix(path, "/v1/responses"):
+		return constant.TokenEndpointResponses
+	case strings.HasPrefix(path, "/v1/messages"):
+		return constant.TokenEndpointMessages
+	case strings.HasSuffix(path, "/embeddings"):
+		return constant.TokenEndpointEmbeddings
+	case strings.HasPrefix(path, "/v1/images"):
+		return constant.TokenEndpointImages
+	case strings.HasPrefix(path, "/v1/audio"):
+		return constant.TokenEndpointAudio
+	case strings.HasPrefix(path, "/v1/moderations"):
+		return constant.TokenEndpointModerations
+	case strings.HasPrefix(path, "/v1/rerank"):
+		return constant.TokenEndpointRerank
+	case strings.HasPrefix(path, "/v1/realtime"):
+		return constant.TokenEndpointRealtime
+	case strings.HasPrefix(path, "/v1beta/models"), strings.HasPrefix(path, "/v1/models/"):
+		return constant.TokenEndpointGemini
+	case strings.Contains(path, "/mj/"):
+		return constant.TokenEndpointMidjourney
+	case strings.HasPrefix(path, "/suno"):
+		return constant.TokenEndpointSuno
+	case strings.HasPrefix(path, "/v1/video"), strings.HasPrefix(path, "/kling"):
+		return constant.TokenEndpointVideo
+	}
+	return ""
+}
+
 func Distribute() func(c *gin.Context) {
 	return func(c *gin.Context) {
+		// 令牌限制了可访问的接口类别时，无法识别的接口一律拒绝
+		if endpoints := common.GetContextKeyStringSlice(c, constant.ContextKeyTokenAllowedEndpoints); len(endpoints) > 0 {
+			endpoint := tokenEndpointOfPath(c.Request.URL.Path)
+			if endpoint == "" || !common.StringsContains(endpoints, endpoint) {
+				abortWithOpenAiError(c, http.StatusForbidden, "invalid_request_error", "endpoint_not_allowed", "该令牌无权访问此接口")
+				return
+			}
+		}
 		var channel *model.Channel
 		channelId, ok := common.GetContextKey(c, constant.ContextKeyTokenSpecificChannelId)
 		modelRequest, shouldSelectChannel, err := getModelRequest(c)
@@ -65,8 +109,12 @@ func Distribute() func(c *gin.Context) {
 				if !ok {
 					tokenModelLimit = map[string]bool{}
 				}
-				matchName := ratio_setting.FormatMatchingModelName(modelRequest.Model) // match gpts & thinking-*
-				if _, ok := tokenModelLimit[matchName]; !ok {
+				// match gpts & thinking-*
+				_, ok = ratio_setting.MatchModelVariant(modelRequest.Model, func(name string) bool {
+					_, exists := tokenModelLimit[name]
+					return exists
+				})
+				if !ok {
 					abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问模型 "+modelRequest.Model)
 					return
 				}
@@ -95,6 +143,14 @@ func Distribute() func(c *gin.Context) {
 						userGroup = playgroundRequest.Group
 					}
 				}
+				if operation_setting.GetLanguageRoutingSetting().Enabled {
+					if language := service.DetectRequestLanguage(c); language != "" {
+						common.SetContextKey(c, constant.ContextKeyPromptLanguage, language)
+					}
+				}
+				if operation_setting.GetToolRoutingSetting().Enabled && service.RequestHasTools(c) {
+					common.SetContextKey(c, constant.ContextKeyRequestHasTools, true)
+				}
 				channel, selectGroup, err = model.CacheGetRandomSatisfiedChannel(c, userGroup, modelRequest.Model, 0)
 				if err != nil {
 					showGroup := userGroup
@@ -18,7 +18,7 @@ func DecompressRequestMiddleware() gin.HandlerFunc {
 		case "gzip":
 			gzipReader, err := gzip.NewReader(c.Request.Body)
 			if err != nil {
-				c.AbortWithStatus(http.StatusBadRequest)
+				abortWithOpenAiError(c, http.StatusBadRequest, "invalid_request_error", "invalid_content_encoding", "Failed to decompress gzip request body: "+err.Error())
 				return
 			}
 			defer gzipReader.Close()
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	IdempotencyKeyHeader     = "Idempotency-Key"
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+)
+
+// Idempotency 非流式 POST 请求携带 Idempotency-Key 时，在窗口期内保存成功的响应，
+// 客户端使用同一个 key 重试时直接返回保存的响应，不会再次请求上游和计费。
+// 同一个 key 对应的请求内容不同时返回 422，前一个请求仍在处理中时返回 409
+func Idempotency() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetIdempotencySetting()
+		idempotencyKey := strings.TrimSpace(c.Request.Header.Get(IdempotencyKeyHeader))
+		if !setting.Enabled || idempotencyKey == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			abortWithOpenAiError(c, http.StatusBadRequest, "invalid_request_error", "invalid_idempotency_key",
+				fmt.Sprintf("Idempotency-Key must not exceed %d characters", maxIdempotencyKeyLength))
+			return
+		}
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		var request struct {
+			Stream bool `json:"stream"`
+		}
+		if err := common.Unmarshal(requestBody, &request); err != nil || request.Stream {
+			// 流式响应无法完整重放
+			c.Next()
+			return
+		}
+
+		tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+		key := fmt.Sprintf("%d:%s", tokenId, hex.EncodeToString(common.Sha256Raw([]byte(idempotencyKey))))
+		requestHash := hex.EncodeToString(common.Sha256Raw(append([]byte(c.Request.URL.Path+"\n"), requestBody...)))
+
+		lockTTL := time.Duration(setting.LockSeconds) * time.Second
+		existing, claimed, err := service.ClaimIdempotencyKey(key, &service.IdempotencyRecord{
+			RequestHash: requestHash,
+			Pending:     true,
+		}, lockTTL)
+		if err != nil {
+			// 存储不可用时不阻断请求
+			common.LogError(c.Request.Context(), err.Error())
+			c.Next()
+			return
+		}
+		if !claimed {
+			switch {
+			case existing.RequestHash != requestHash:
+				abortWithOpenAiError(c, http.StatusUnprocessableEntity, "invalid_request_error", "idempotency_key_reused",
+					"Idempotency-Key has already been used with a different request")
+			case existing.Pending:
+				abortWithOpenAiError(c, http.StatusConflict, "invalid_request_error", "idempotency_request_in_progress",
+					"A request with the same Idempotency-Key is still being processed")
+			default:
+				common.LogInfo(c, "idempotent request replayed")
+				c.Header("Content-Type", existing.ContentType)
+				c.Header(IdempotentReplayedHeader, "true")
+				c.Writer.WriteHeader(existing.Status)
+				_, _ = c.Writer.Write(existing.Body)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &relayDedupWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		// 处理过程中 panic 也要释放占用
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			status := writer.Status()
+			if !completed || status < http.StatusOK || status >= http.StatusMultipleChoices ||
+				writer.body.Len() > setting.MaxResponseKB<<10 {
+				// 失败的请求没有计费，允许客户端重试
+				if err := service.ReleaseIdempotencyKey(key); err != nil {
+					common.SysError("release idempotency key failed: " + err.Error())
+				}
+				return
+			}
+			err := service.SaveIdempotencyRecord(key, &service.IdempotencyRecord{
+				RequestHash: requestHash,
+				Status:      status,
+				ContentType: writer.Header().Get("Content-Type"),
+				Body:        writer.body.Bytes(),
+			}, time.Duration(setting.WindowSeconds)*time.Second)
+			if err != nil {
+				common.SysError("save idempotency record failed: " + err.Error())
+			}
+		}()
+		c.Next()
+		completed = true
+	}
+}
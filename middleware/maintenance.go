@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maintenance 维护模式下拒绝中继请求，白名单令牌可继续使用，需放在 TokenAuth 之后
+func Maintenance() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetMaintenanceSetting()
+		if !setting.Enabled || setting.IsTokenAllowed(common.GetContextKeyInt(c, constant.ContextKeyTokenId)) {
+			c.Next()
+			return
+		}
+		if setting.RetryAfterSeconds > 0 {
+			c.Header("Retry-After", strconv.Itoa(setting.RetryAfterSeconds))
+		}
+		message := setting.Message
+		if message == "" {
+			message = "service is under maintenance"
+		}
+		abortWithOpenAiError(c, http.StatusServiceUnavailable, "new_api_error", "maintenance", message)
+	}
+}
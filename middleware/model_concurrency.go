@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// modelConcurrencyPollInterval 排队模式下重新尝试占位的间隔
+const modelConcurrencyPollInterval = 100 * time.Millisecond
+
+// ModelConcurrencyLimit 限制每个模型同时处理中的请求数，需放在 Distribute 之后。
+// 超出上限时按配置直接返回 429，或排队等待空闲并发位，等待超时后返回 429
+func ModelConcurrencyLimit() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetModelConcurrencySetting()
+		if !setting.Enabled {
+			c.Next()
+			return
+		}
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		key, limit := setting.GetLimit(modelName)
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+		lease := time.Duration(setting.LeaseSeconds) * time.Second
+		if lease <= 0 {
+			lease = time.Minute
+		}
+
+		release, ok, err := service.TryAcquireModelSlot(key, limit, lease)
+		if err == nil && !ok && setting.Mode == operation_setting.ModelConcurrencyModeQueue {
+			deadline := time.Now().Add(time.Duration(setting.QueueTimeoutSeconds) * time.Second)
+			for !ok && err == nil && time.Now().Before(deadline) {
+				select {
+				case <-c.Request.Context().Done():
+					c.Abort()
+					return
+				case <-time.After(modelConcurrencyPollInterval):
+				}
+				release, ok, err = service.TryAcquireModelSlot(key, limit, lease)
+			}
+		}
+		if err != nil {
+			// 计数不可用时不阻断请求
+			common.LogError(c.Request.Context(), err.Error())
+			c.Next()
+			return
+		}
+		if !ok {
+			abortWithOpenAiError(c, http.StatusTooManyRequests, "new_api_error", "model_concurrency_exceeded", fmt.Sprintf("模型 %s 当前并发请求数已达上限 %d，请稍后再试", modelName, limit))
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
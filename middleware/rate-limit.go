@@ -111,3 +111,7 @@ func DownloadRateLimit() func(c *gin.Context) {
 func UploadRateLimit() func(c *gin.Context) {
 	return rateLimitFactory(common.UploadRateLimitNum, common.UploadRateLimitDuration, "UP")
 }
+
+func ExportRateLimit() func(c *gin.Context) {
+	return rateLimitFactory(common.ExportRateLimitNum, common.ExportRateLimitDuration, "EX")
+}
@@ -0,0 +1,246 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// relayDedupCall 一次正在进行或刚完成的上游调用，相同请求等待其完成后复用响应
+type relayDedupCall struct {
+	done   chan struct{}
+	ok     bool // 请求成功，响应可以复用
+	status int
+	header http.Header
+	body   []byte
+}
+
+var (
+	relayDedupCalls     = make(map[string]*relayDedupCall)
+	relayDedupCallsLock sync.Mutex
+)
+
+const (
+	// relayDedupPendingTTL 开启 Redis 时处理中记录的过期时间，避免发起请求的节点异常退出后其余节点一直等待
+	relayDedupPendingTTL = 5 * time.Minute
+	// relayDedupPollInterval 开启 Redis 时等待中的请求轮询结果的间隔
+	relayDedupPollInterval = 200 * time.Millisecond
+)
+
+// relayDedupRecord 开启 Redis 时在节点间共享的调用记录，Pending 为 true 表示请求仍在处理中
+type relayDedupRecord struct {
+	Pending bool        `json:"pending"`
+	Status  int         `json:"status,omitempty"`
+	Header  http.Header `json:"header,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+}
+
+func relayDedupRedisKey(key string) string {
+	return "relay_dedup:" + key
+}
+
+// relayDedupWriter 在正常写出响应的同时保留一份副本
+type relayDedupWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *relayDedupWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *relayDedupWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// RelayDedup 同一令牌在窗口期内发送字节完全相同的请求时，只向上游发起一次调用，其余请求复用该响应且不重复计费。
+// 上游调用失败时不复用，等待中的请求各自重新发起
+func RelayDedup() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetRelayDedupSetting()
+		if !setting.Enabled || !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+			c.Next()
+			return
+		}
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+
+		tokenId := common.GetContextKeyInt(c, constant.ContextKeyTokenId)
+		digest := common.Sha256Raw(append([]byte(c.Request.URL.Path+"\n"), requestBody...))
+		key := fmt.Sprintf("%d:%s", tokenId, hex.EncodeToString(digest))
+		if common.RedisEnabled {
+			relayDedupWithRedis(c, key, setting.WindowSeconds)
+			return
+		}
+
+		relayDedupCallsLock.Lock()
+		call, exists := relayDedupCalls[key]
+		if !exists {
+			call = &relayDedupCall{done: make(chan struct{})}
+			relayDedupCalls[key] = call
+		}
+		relayDedupCallsLock.Unlock()
+
+		if exists {
+			select {
+			case <-call.done:
+			case <-c.Request.Context().Done():
+				c.Abort()
+				return
+			}
+			if call.ok {
+				writeRelayDedupResponse(c, call.status, call.header, call.body)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		writer := &relayDedupWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		completed := false
+		// 处理过程中 panic 也要唤醒等待中的请求
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			call.ok = completed && writer.Status() == http.StatusOK
+			call.status = writer.Status()
+			call.header = writer.Header().Clone()
+			call.body = writer.body.Bytes()
+			close(call.done)
+
+			removeCall := func() {
+				relayDedupCallsLock.Lock()
+				defer relayDedupCallsLock.Unlock()
+				if relayDedupCalls[key] == call {
+					delete(relayDedupCalls, key)
+				}
+			}
+			if !call.ok {
+				removeCall()
+				return
+			}
+			time.AfterFunc(time.Duration(setting.WindowSeconds)*time.Second, removeCall)
+		}()
+		c.Next()
+		completed = true
+	}
+}
+
+// writeRelayDedupResponse 将相同请求的响应写给当前请求，不再经过后续处理
+func writeRelayDedupResponse(c *gin.Context, status int, header http.Header, body []byte) {
+	common.LogInfo(c, "duplicate relay request, reusing response of the identical request")
+	for k, v := range header {
+		if k == common.RequestIdKey {
+			continue
+		}
+		c.Writer.Header()[k] = v
+	}
+	c.Writer.WriteHeader(status)
+	_, _ = c.Writer.Write(body)
+	c.Abort()
+}
+
+// relayDedupWithRedis 多节点部署时通过 Redis 合并相同请求：先占用 key 的请求发起调用，
+// 其余节点上的相同请求轮询等待其结果。Redis 出错时不合并，直接处理请求
+func relayDedupWithRedis(c *gin.Context, key string, windowSeconds int) {
+	ctx := context.Background()
+	redisKey := relayDedupRedisKey(key)
+	pending, err := common.Marshal(&relayDedupRecord{Pending: true})
+	if err != nil {
+		c.Next()
+		return
+	}
+	claimed, err := common.RDB.SetNX(ctx, redisKey, pending, relayDedupPendingTTL).Result()
+	if err != nil {
+		common.LogWarn(c, fmt.Sprintf("relay dedup claim failed: %s", err.Error()))
+		c.Next()
+		return
+	}
+	if !claimed {
+		record := waitRelayDedupRecord(c, redisKey)
+		if c.IsAborted() {
+			return
+		}
+		if record != nil {
+			writeRelayDedupResponse(c, record.Status, record.Header, record.Body)
+			return
+		}
+		c.Next()
+		return
+	}
+
+	writer := &relayDedupWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	completed := false
+	// 处理过程中 panic 也要更新记录，避免其余节点等到记录过期
+	defer func() {
+		c.Writer = writer.ResponseWriter
+		if !completed || writer.Status() != http.StatusOK || windowSeconds <= 0 {
+			_ = common.RDB.Del(ctx, redisKey).Err()
+			return
+		}
+		value, err := common.Marshal(&relayDedupRecord{
+			Status: writer.Status(),
+			Header: writer.Header().Clone(),
+			Body:   writer.body.Bytes(),
+		})
+		if err == nil {
+			err = common.RDB.Set(ctx, redisKey, value, time.Duration(windowSeconds)*time.Second).Err()
+		}
+		if err != nil {
+			common.SysError(fmt.Sprintf("relay dedup save response failed: %s", err.Error()))
+			_ = common.RDB.Del(ctx, redisKey).Err()
+		}
+	}()
+	c.Next()
+	completed = true
+}
+
+// waitRelayDedupRecord 等待其他请求完成，返回可复用的记录；
+// 对方失败（记录被删除）或读取出错时返回 nil，客户端断开时中止当前请求
+func waitRelayDedupRecord(c *gin.Context, redisKey string) *relayDedupRecord {
+	ticker := time.NewTicker(relayDedupPollInterval)
+	defer ticker.Stop()
+	for {
+		data, err := common.RDB.Get(context.Background(), redisKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		if err != nil {
+			common.LogWarn(c, fmt.Sprintf("relay dedup get record failed: %s", err.Error()))
+			return nil
+		}
+		record := &relayDedupRecord{}
+		if err = common.Unmarshal(data, record); err != nil {
+			return nil
+		}
+		if !record.Pending {
+			return record
+		}
+		select {
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			c.Abort()
+			return nil
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"embed"
+	"net/http"
+	"one-api/common"
+	"one-api/common/jsonschema"
+	"one-api/setting/operation_setting"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed schemas/*.json
+var requestSchemaFiles embed.FS
+
+// requestSchemaRoutes 请求路径与 Schema 名称的对应关系
+var requestSchemaRoutes = map[string]string{
+	"/v1/chat/completions":          operation_setting.RequestSchemaChatCompletions,
+	"/v1/embeddings":                operation_setting.RequestSchemaEmbeddings,
+	"/v1/images/generations":        operation_setting.RequestSchemaImageGenerations,
+	"/v1/engines/:model/embeddings": operation_setting.RequestSchemaEmbeddings,
+}
+
+var (
+	builtinRequestSchemas = make(map[string]*jsonschema.Schema)
+	// customRequestSchemas 按配置内容缓存解析结果，配置变更后自动使用新的 Schema
+	customRequestSchemas     = make(map[string]*jsonschema.Schema)
+	customRequestSchemasLock sync.Mutex
+)
+
+func init() {
+	for _, name := range []string{
+		operation_setting.RequestSchemaChatCompletions,
+		operation_setting.RequestSchemaEmbeddings,
+		operation_setting.RequestSchemaImageGenerations,
+	} {
+		data, err := requestSchemaFiles.ReadFile("schemas/" + name + ".json")
+		if err != nil {
+			panic(err)
+		}
+		schema, err := jsonschema.Parse(data)
+		if err != nil {
+			panic(err)
+		}
+		builtinRequestSchemas[name] = schema
+	}
+}
+
+// getRequestSchema 优先使用配置中的 Schema，解析失败时退回内置 Schema
+func getRequestSchema(name string) *jsonschema.Schema {
+	custom := operation_setting.GetRequestSchemaSetting().Schemas[name]
+	if strings.TrimSpace(custom) == "" {
+		return builtinRequestSchemas[name]
+	}
+	customRequestSchemasLock.Lock()
+	defer customRequestSchemasLock.Unlock()
+	if schema, ok := customRequestSchemas[custom]; ok {
+		return schema
+	}
+	schema, err := jsonschema.Parse([]byte(custom))
+	if err != nil {
+		common.SysError("invalid request schema " + name + ": " + err.Error())
+		schema = builtinRequestSchemas[name]
+	}
+	customRequestSchemas[custom] = schema
+	return schema
+}
+
+// RequestSchemaValidation 在选择渠道之前校验请求体，避免不合法的请求占用渠道重试次数，需放在 Distribute 之前
+func RequestSchemaValidation() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetRequestSchemaSetting()
+		if !setting.Enabled || !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+			c.Next()
+			return
+		}
+		name, ok := requestSchemaRoutes[c.FullPath()]
+		if !ok || !setting.Routes[name] {
+			c.Next()
+			return
+		}
+		schema := getRequestSchema(name)
+		if schema == nil {
+			c.Next()
+			return
+		}
+		var request any
+		if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+			// 交给 ValidateJSONMiddleware 与后续处理返回错误
+			c.Next()
+			return
+		}
+		if err := schema.Validate(request); err != nil {
+			abortWithOpenAiError(c, http.StatusBadRequest, "invalid_request_error", "invalid_request_schema", "Invalid request: "+err.Error())
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,268 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/service"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ResponseCacheHeader 请求中为 use 时使用响应缓存
+	ResponseCacheHeader = "X-Response-Cache"
+	// ResponseCacheStatusHeader 响应是否来自缓存：HIT、MISS，或未使用缓存时为 BYPASS
+	ResponseCacheStatusHeader = "X-Cache"
+	// ResponseCacheSimilarityHeader 语义缓存命中时返回的相似度
+	ResponseCacheSimilarityHeader = "X-Response-Cache-Similarity"
+	responseCacheDirective        = "use"
+)
+
+// responseCacheControl 客户端通过 Cache-Control 指定的缓存行为
+type responseCacheControl struct {
+	noStore bool  // 不读取也不保存缓存
+	noCache bool  // 不读取缓存，但保存本次响应
+	maxAge  int64 // 只使用不超过该秒数的缓存，-1 表示不限制
+}
+
+func parseResponseCacheControl(header string) responseCacheControl {
+	control := responseCacheControl{maxAge: -1}
+	for _, directive := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store":
+			control.noStore = true
+		case "no-cache":
+			control.noCache = true
+		case "max-age":
+			if maxAge, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64); err == nil && maxAge >= 0 {
+				control.maxAge = maxAge
+			}
+		}
+	}
+	if control.maxAge == 0 {
+		control.noCache = true
+	}
+	return control
+}
+
+// fresh 缓存的响应是否满足 max-age 要求
+func (control responseCacheControl) fresh(cached *service.CachedResponse) bool {
+	return control.maxAge < 0 || common.GetTimestamp()-cached.CreatedAt <= control.maxAge
+}
+
+// responseCacheRequest 满足缓存条件的请求
+type responseCacheRequest struct {
+	key      string // 去除无关字段后整个请求体的 key
+	indexKey string // 除提示词外其余参数的 key，语义缓存只在同一用户相同参数的请求之间匹配
+	prompt   string // 用于计算语义向量的提示词
+}
+
+// parseResponseCacheRequest 解析请求体并计算缓存 key，不满足缓存条件时返回 nil
+func parseResponseCacheRequest(c *gin.Context, requestBody []byte, shareAcrossUsers bool) *responseCacheRequest {
+	var request map[string]any
+	if err := common.Unmarshal(requestBody, &request); err != nil {
+		return nil
+	}
+	if stream, _ := request["stream"].(bool); stream {
+		return nil
+	}
+	// 只有确定性的请求才能复用响应
+	if temperature, ok := request["temperature"].(float64); !ok || temperature != 0 {
+		return nil
+	}
+	delete(request, "user")
+	delete(request, "stream")
+	userScope := fmt.Sprintf("user:%d", c.GetInt("id"))
+	scope := userScope
+	if shareAcrossUsers {
+		scope = "shared"
+	}
+	hash := func(scope string, value map[string]any) string {
+		// 重新编码时按字段名排序，字段顺序与空白不影响 key
+		normalized, err := common.Marshal(value)
+		if err != nil {
+			return ""
+		}
+		digest := common.Sha256Raw(append([]byte(c.Request.URL.Path+"\n"), normalized...))
+		return scope + ":" + hex.EncodeToString(digest)
+	}
+	parsed := &responseCacheRequest{key: hash(scope, request)}
+	if parsed.key == "" {
+		return nil
+	}
+	parsed.prompt = responseCachePrompt(request)
+	for _, field := range []string{"messages", "prompt", "input"} {
+		delete(request, field)
+	}
+	// 语义匹配只在同一用户内进行，即使开启共享也不会把相似但不同的提示词命中到其他用户的响应
+	parsed.indexKey = hash(userScope, request)
+	return parsed
+}
+
+// responseCachePrompt 拼接请求中的文本内容，包含非文本内容时返回空字符串，不参与语义匹配
+func responseCachePrompt(request map[string]any) string {
+	var builder strings.Builder
+	if messages, ok := request["messages"].([]any); ok {
+		for _, item := range messages {
+			message, _ := item.(map[string]any)
+			role, _ := message["role"].(string)
+			builder.WriteString(role + ": ")
+			switch content := message["content"].(type) {
+			case string:
+				builder.WriteString(content)
+			case []any:
+				for _, part := range content {
+					partMap, _ := part.(map[string]any)
+					if partType, _ := partMap["type"].(string); partType != "text" {
+						return ""
+					}
+					text, _ := partMap["text"].(string)
+					builder.WriteString(text)
+				}
+			case nil:
+			default:
+				return ""
+			}
+			builder.WriteString("\n")
+		}
+		return builder.String()
+	}
+	for _, field := range []string{"prompt", "input"} {
+		if text, ok := request[field].(string); ok {
+			return text
+		}
+	}
+	return ""
+}
+
+// ResponseCache 客户端通过 X-Response-Cache: use 显式要求缓存时，temperature 为 0 的非流式请求直接返回缓存的响应，
+// 不请求上游，只记录一条额度为 0 的消费日志。需放在 Distribute 之后，以便先完成令牌的模型权限校验。
+// 语义模式下精确匹配未命中时，再在参数相同的请求中按提示词的向量相似度查找。
+// 支持 Cache-Control 的 no-store、no-cache 与 max-age，响应通过 X-Cache 与 Age 表明是否命中缓存
+func ResponseCache() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetResponseCacheSetting()
+		if !setting.Enabled || !strings.EqualFold(c.Request.Header.Get(ResponseCacheHeader), responseCacheDirective) ||
+			!strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+			c.Next()
+			return
+		}
+		control := parseResponseCacheControl(c.Request.Header.Get("Cache-Control"))
+		modelName := common.GetContextKeyString(c, constant.ContextKeyOriginalModel)
+		if control.noStore || !setting.IsModelAllowed(modelName) {
+			c.Header(ResponseCacheStatusHeader, "BYPASS")
+			c.Next()
+			return
+		}
+		requestBody, err := common.GetRequestBody(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+		request := parseResponseCacheRequest(c, requestBody, setting.ShareAcrossUsers)
+		if request == nil {
+			c.Header(ResponseCacheStatusHeader, "BYPASS")
+			c.Next()
+			return
+		}
+
+		var cached *service.CachedResponse
+		var vector []float32
+		if !control.noCache {
+			cached, err = service.GetCachedResponse(request.key)
+			if err != nil {
+				common.LogError(c, err.Error())
+			}
+			if cached != nil && !control.fresh(cached) {
+				cached = nil
+			}
+		}
+		// 即使不读取缓存，语义模式下仍需要向量以便保存本次响应
+		if cached == nil && setting.IsSemantic() && request.prompt != "" {
+			vector, err = service.EmbedText(setting.EmbeddingUrl, setting.EmbeddingApiKey, setting.EmbeddingModel, request.prompt)
+			if err != nil {
+				common.LogError(c, "embed prompt for semantic cache failed: "+err.Error())
+			} else if !control.noCache {
+				if responseKey, similarity := service.FindSimilarCachedResponse(request.indexKey, vector, setting.SimilarityThreshold); responseKey != "" {
+					cached, err = service.GetCachedResponse(responseKey)
+					if err != nil {
+						common.LogError(c, err.Error())
+					}
+					if cached != nil && control.fresh(cached) {
+						c.Header(ResponseCacheSimilarityHeader, strconv.FormatFloat(similarity, 'f', 4, 64))
+					} else {
+						cached = nil
+					}
+				}
+			}
+		}
+		if cached != nil {
+			recordResponseCacheHit(c, modelName, cached.Body)
+			c.Header("Content-Type", cached.ContentType)
+			c.Header(ResponseCacheStatusHeader, "HIT")
+			c.Header("Age", strconv.FormatInt(max(common.GetTimestamp()-cached.CreatedAt, 0), 10))
+			c.Writer.WriteHeader(cached.Status)
+			_, _ = c.Writer.Write(cached.Body)
+			c.Abort()
+			return
+		}
+
+		c.Header(ResponseCacheStatusHeader, "MISS")
+		writer := &relayDedupWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+		if writer.Status() != http.StatusOK || writer.body.Len() > setting.MaxResponseKB<<10 {
+			return
+		}
+		ttl := time.Duration(setting.TTLSeconds) * time.Second
+		err = service.SetCachedResponse(request.key, &service.CachedResponse{
+			CreatedAt:   common.GetTimestamp(),
+			Status:      writer.Status(),
+			ContentType: writer.Header().Get("Content-Type"),
+			Body:        writer.body.Bytes(),
+		}, ttl)
+		if err != nil {
+			common.LogError(c, "save cached response failed: "+err.Error())
+			return
+		}
+		if vector != nil {
+			service.AddSemanticCacheEntry(request.indexKey, request.key, vector, ttl, setting.SemanticMaxEntries)
+		}
+	}
+}
+
+// recordResponseCacheHit 命中缓存不扣除额度，记录用量以便统计
+func recordResponseCacheHit(c *gin.Context, modelName string, body []byte) {
+	var response struct {
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	_ = common.Unmarshal(body, &response)
+	model.RecordConsumeLog(c, c.GetInt("id"), model.RecordConsumeLogParams{
+		ChannelId:        0,
+		PromptTokens:     response.Usage.PromptTokens,
+		CompletionTokens: response.Usage.CompletionTokens,
+		ModelName:        modelName,
+		TokenName:        c.GetString("token_name"),
+		Quota:            0,
+		Content:          "响应缓存命中，不计费",
+		TokenId:          common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+		Group:            common.GetContextKeyString(c, constant.ContextKeyUsingGroup),
+		Other:            map[string]interface{}{"response_cache_hit": true},
+	})
+}
@@ -0,0 +1,354 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// resumableStream 一次流式响应已发送的事件，事件 i 的 id 为 "<流 id>:<i+1>"
+type resumableStream struct {
+	lock      sync.Mutex
+	tokenId   int
+	events    [][]byte
+	size      int
+	truncated bool // 超出缓存上限，不再可续传
+	done      bool
+	notify    chan struct{} // 有新事件或流结束时关闭并替换
+}
+
+var (
+	resumableStreams     = make(map[string]*resumableStream)
+	resumableStreamsLock sync.Mutex
+)
+
+const (
+	// streamResumeStreamingTTL 开启 Redis 时流仍在进行中的缓存过期时间，流结束后改为配置的保留时间
+	streamResumeStreamingTTL = 30 * time.Minute
+	// streamResumePollInterval 开启 Redis 时续传请求轮询新事件的间隔
+	streamResumePollInterval = 200 * time.Millisecond
+)
+
+// streamResumeRedisKeys 开启 Redis 时流的元信息（令牌、是否结束、是否超出上限）与事件列表的 key，多个节点共享
+func streamResumeRedisKeys(streamId string) (metaKey string, eventsKey string) {
+	return "stream_resume:" + streamId + ":meta", "stream_resume:" + streamId + ":events"
+}
+
+func (s *resumableStream) append(event []byte, maxBytes int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.truncated {
+		return
+	}
+	if s.size+len(event) > maxBytes {
+		s.truncated = true
+		s.events = nil
+	} else {
+		s.events = append(s.events, event)
+		s.size += len(event)
+	}
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+func (s *resumableStream) finish() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.done = true
+	close(s.notify)
+	s.notify = make(chan struct{})
+}
+
+// streamResumeWriter 为每个 SSE 事件加上 id 并缓存，客户端断开后继续接收写入
+type streamResumeWriter struct {
+	gin.ResponseWriter
+	streamId    string
+	tokenId     int
+	maxBytes    int
+	stream      *resumableStream
+	passthrough bool
+	pending     []byte
+	seq         int
+	size        int  // 开启 Redis 时已缓存的字节数
+	truncated   bool // 开启 Redis 时超出缓存上限
+}
+
+func (w *streamResumeWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.stream == nil {
+		if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			w.passthrough = true
+			return w.ResponseWriter.Write(data)
+		}
+		w.stream = &resumableStream{tokenId: w.tokenId, notify: make(chan struct{})}
+		if common.RedisEnabled {
+			w.startRedis()
+		} else {
+			resumableStreamsLock.Lock()
+			resumableStreams[w.streamId] = w.stream
+			resumableStreamsLock.Unlock()
+		}
+	}
+
+	// 事件可能分多次写入，凑齐以空行结尾的完整事件后再加 id 发出
+	w.pending = append(w.pending, data...)
+	for {
+		idx := bytes.Index(w.pending, []byte("\n\n"))
+		if idx < 0 {
+			break
+		}
+		event := bytes.TrimLeft(w.pending[:idx+2], "\n")
+		w.pending = w.pending[idx+2:]
+		if len(event) == 0 {
+			continue
+		}
+		// 注释（如 PING）不计入事件序号
+		if event[0] == ':' {
+			_, _ = w.ResponseWriter.Write(event)
+			continue
+		}
+		w.seq++
+		out := append([]byte(fmt.Sprintf("id: %s:%d\n", w.streamId, w.seq)), event...)
+		if common.RedisEnabled {
+			w.appendRedis(out)
+		} else {
+			w.stream.append(out, w.maxBytes)
+		}
+		// 客户端断开时写入失败，继续缓存供重连补发
+		_, _ = w.ResponseWriter.Write(out)
+	}
+	return len(data), nil
+}
+
+func (w *streamResumeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *streamResumeWriter) finish(ttl time.Duration) {
+	if len(w.pending) > 0 {
+		_, _ = w.ResponseWriter.Write(w.pending)
+		w.pending = nil
+	}
+	if w.stream == nil {
+		return
+	}
+	if common.RedisEnabled {
+		w.finishRedis(ttl)
+		return
+	}
+	w.stream.finish()
+	stream := w.stream
+	time.AfterFunc(ttl, func() {
+		resumableStreamsLock.Lock()
+		defer resumableStreamsLock.Unlock()
+		if resumableStreams[w.streamId] == stream {
+			delete(resumableStreams, w.streamId)
+		}
+	})
+}
+
+func (w *streamResumeWriter) startRedis() {
+	ctx := context.Background()
+	metaKey, _ := streamResumeRedisKeys(w.streamId)
+	pipe := common.RDB.TxPipeline()
+	pipe.HSet(ctx, metaKey, "token_id", w.tokenId)
+	pipe.Expire(ctx, metaKey, streamResumeStreamingTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		common.SysError(fmt.Sprintf("stream resume save stream failed: %s", err.Error()))
+	}
+}
+
+func (w *streamResumeWriter) appendRedis(event []byte) {
+	if w.truncated {
+		return
+	}
+	ctx := context.Background()
+	metaKey, eventsKey := streamResumeRedisKeys(w.streamId)
+	pipe := common.RDB.TxPipeline()
+	w.size += len(event)
+	if w.size > w.maxBytes {
+		w.truncated = true
+		pipe.HSet(ctx, metaKey, "truncated", 1)
+		pipe.Del(ctx, eventsKey)
+	} else {
+		pipe.RPush(ctx, eventsKey, event)
+		pipe.Expire(ctx, eventsKey, streamResumeStreamingTTL)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		common.SysError(fmt.Sprintf("stream resume save event failed: %s", err.Error()))
+	}
+}
+
+func (w *streamResumeWriter) finishRedis(ttl time.Duration) {
+	ctx := context.Background()
+	metaKey, eventsKey := streamResumeRedisKeys(w.streamId)
+	pipe := common.RDB.TxPipeline()
+	pipe.HSet(ctx, metaKey, "done", 1)
+	pipe.Expire(ctx, metaKey, ttl)
+	pipe.Expire(ctx, eventsKey, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		common.SysError(fmt.Sprintf("stream resume finish stream failed: %s", err.Error()))
+	}
+}
+
+// StreamResume 开启后缓存流式响应的事件，客户端携带 Last-Event-ID 重新发起请求时从断点补发，不再请求上游、不重复计费
+func StreamResume() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		setting := operation_setting.GetStreamResumeSetting()
+		if !setting.Enabled {
+			c.Next()
+			return
+		}
+		if lastEventId := c.GetHeader("Last-Event-ID"); lastEventId != "" {
+			resumeStream(c, lastEventId)
+			c.Abort()
+			return
+		}
+
+		writer := &streamResumeWriter{
+			ResponseWriter: c.Writer,
+			streamId:       c.GetString(common.RequestIdKey),
+			tokenId:        common.GetContextKeyInt(c, constant.ContextKeyTokenId),
+			maxBytes:       setting.MaxBytes,
+		}
+		c.Writer = writer
+		common.SetContextKey(c, constant.ContextKeyStreamResumable, true)
+		defer func() {
+			c.Writer = writer.ResponseWriter
+			writer.finish(time.Duration(setting.TTLSeconds) * time.Second)
+		}()
+		c.Next()
+	}
+}
+
+func resumeStream(c *gin.Context, lastEventId string) {
+	streamId, seqStr, _ := strings.Cut(lastEventId, ":")
+	seq, err := strconv.Atoi(seqStr)
+	if err != nil || seq < 0 {
+		abortWithOpenAiMessage(c, http.StatusBadRequest, "invalid Last-Event-ID")
+		return
+	}
+	if common.RedisEnabled {
+		resumeStreamFromRedis(c, streamId, seq)
+		return
+	}
+	resumableStreamsLock.Lock()
+	stream, ok := resumableStreams[streamId]
+	resumableStreamsLock.Unlock()
+	if !ok || stream.tokenId != common.GetContextKeyInt(c, constant.ContextKeyTokenId) {
+		abortWithOpenAiMessage(c, http.StatusNotFound, "stream not found or expired")
+		return
+	}
+
+	headerWritten := false
+	for {
+		stream.lock.Lock()
+		if stream.truncated {
+			stream.lock.Unlock()
+			if !headerWritten {
+				abortWithOpenAiMessage(c, http.StatusGone, "stream is too large to resume")
+			}
+			return
+		}
+		var events [][]byte
+		if seq < len(stream.events) {
+			events = stream.events[seq:]
+		}
+		done := stream.done
+		notify := stream.notify
+		stream.lock.Unlock()
+
+		if !headerWritten {
+			writeStreamResumeHeader(c)
+			headerWritten = true
+		}
+		for _, event := range events {
+			if _, err := c.Writer.Write(event); err != nil {
+				return
+			}
+		}
+		seq += len(events)
+		c.Writer.Flush()
+		if done {
+			return
+		}
+		select {
+		case <-notify:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func writeStreamResumeHeader(c *gin.Context) {
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+	c.Writer.Header().Set("X-Accel-Buffering", "no")
+	c.Writer.WriteHeader(http.StatusOK)
+}
+
+// resumeStreamFromRedis 从 Redis 补发事件，流可能由其他节点写入，未结束时轮询新事件
+func resumeStreamFromRedis(c *gin.Context, streamId string, seq int) {
+	ctx := context.Background()
+	metaKey, eventsKey := streamResumeRedisKeys(streamId)
+	tokenId := strconv.Itoa(common.GetContextKeyInt(c, constant.ContextKeyTokenId))
+	ticker := time.NewTicker(streamResumePollInterval)
+	defer ticker.Stop()
+	headerWritten := false
+	for {
+		// 先读元信息再读事件：读到结束标记时所有事件都已写入
+		meta, err := common.RDB.HGetAll(ctx, metaKey).Result()
+		if err != nil || meta["token_id"] != tokenId {
+			if !headerWritten {
+				abortWithOpenAiMessage(c, http.StatusNotFound, "stream not found or expired")
+			}
+			return
+		}
+		if meta["truncated"] == "1" {
+			if !headerWritten {
+				abortWithOpenAiMessage(c, http.StatusGone, "stream is too large to resume")
+			}
+			return
+		}
+		events, err := common.RDB.LRange(ctx, eventsKey, int64(seq), -1).Result()
+		if err != nil {
+			if !headerWritten {
+				abortWithOpenAiMessage(c, http.StatusInternalServerError, "read stream failed")
+			}
+			return
+		}
+
+		if !headerWritten {
+			writeStreamResumeHeader(c)
+			headerWritten = true
+		}
+		for _, event := range events {
+			if _, err := c.Writer.WriteString(event); err != nil {
+				return
+			}
+		}
+		seq += len(events)
+		c.Writer.Flush()
+		if meta["done"] == "1" {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
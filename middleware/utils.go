@@ -7,12 +7,21 @@ import (
 )
 
 func abortWithOpenAiMessage(c *gin.Context, statusCode int, message string) {
+	abortWithOpenAiError(c, statusCode, "new_api_error", "", message)
+}
+
+// abortWithOpenAiError 返回 OpenAI 兼容的错误对象，message 附带 request id，code 为空时不返回
+func abortWithOpenAiError(c *gin.Context, statusCode int, errorType string, code string, message string) {
 	userId := c.GetInt("id")
+	errorBody := gin.H{
+		"message": common.MessageWithRequestId(message, c.GetString(common.RequestIdKey)),
+		"type":    errorType,
+	}
+	if code != "" {
+		errorBody["code"] = code
+	}
 	c.JSON(statusCode, gin.H{
-		"error": gin.H{
-			"message": common.MessageWithRequestId(message, c.GetString(common.RequestIdKey)),
-			"type":    "new_api_error",
-		},
+		"error": errorBody,
 	})
 	c.Abort()
 	common.LogError(c.Request.Context(), fmt.Sprintf("user %d | %s", userId, message))
@@ -1,47 +1,137 @@
 package middleware
 
 import (
-    "bytes"
-    "encoding/json"
-    "io"
-    "net/http"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"strings"
 
-    "github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin"
 )
 
+var errRequestBodyTooLarge = errors.New("request body too large")
+
+// skipJSONValidation 文件上传与音频等二进制请求体不做 JSON 校验
+func skipJSONValidation(contentType string) bool {
+	return strings.HasPrefix(contentType, "multipart/form-data") ||
+		strings.HasPrefix(contentType, "application/octet-stream") ||
+		strings.HasPrefix(contentType, "audio/")
+}
+
+// readAndValidateJSON 边读取边校验 JSON，请求体只缓存一份。
+// 超过 maxBytes 时返回 errRequestBodyTooLarge；JSON 不合法时 syntaxErr 描述错误及其字节偏移
+func readAndValidateJSON(body io.Reader, maxBytes int64) (data []byte, syntaxErr error, err error) {
+	if maxBytes > 0 {
+		// 多读一个字节用于判断是否超出上限
+		body = io.LimitReader(body, maxBytes+1)
+	}
+	var buf bytes.Buffer
+	reader := io.TeeReader(body, &buf)
+	decoder := json.NewDecoder(reader)
+	depth := 0
+	for {
+		var token json.Token
+		token, syntaxErr = decoder.Token()
+		if syntaxErr != nil {
+			break
+		}
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			// 顶层值结束后只允许空白字符
+			offset := decoder.InputOffset()
+			if _, err = decoder.Token(); err != io.EOF {
+				syntaxErr = fmt.Errorf("unexpected data after top-level value at offset %d", offset)
+			} else {
+				syntaxErr = nil
+			}
+			break
+		}
+	}
+	// 校验提前结束时读完剩余内容，保证缓存的请求体完整
+	if _, err = io.Copy(io.Discard, reader); err != nil {
+		return nil, nil, err
+	}
+	if maxBytes > 0 && int64(buf.Len()) > maxBytes {
+		return nil, nil, errRequestBodyTooLarge
+	}
+	if syntaxErr != nil {
+		syntaxErr = describeJSONSyntaxError(syntaxErr, buf.Len())
+	}
+	return buf.Bytes(), syntaxErr, nil
+}
+
+// describeJSONSyntaxError 为 JSON 错误补充字节偏移，便于定位
+func describeJSONSyntaxError(err error, length int) error {
+	var syntaxError *json.SyntaxError
+	switch {
+	case errors.As(err, &syntaxError):
+		return fmt.Errorf("%s at offset %d", syntaxError.Error(), syntaxError.Offset)
+	case errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return fmt.Errorf("unexpected end of JSON input at offset %d", length)
+	}
+	return err
+}
+
 func ValidateJSONMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        // Checking POST, PUT, PATCH
-        if c.Request.Method != http.MethodPost &&
-            c.Request.Method != http.MethodPut &&
-            c.Request.Method != http.MethodPatch {
-            c.Next()
-            return
-        }
-
-        // Reading body
-        body, err := io.ReadAll(c.Request.Body)
-        if err != nil {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
-            c.Abort()
-            return
-        }
-
-        c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
-
-        // Empty body — skipping
-        if len(body) == 0 {
-            c.Next()
-            return
-        }
-
-        // Check Valid JSON
-        if !json.Valid(body) {
-            c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON"})
-            c.Abort()
-            return
-        }
-
-        c.Next()
-    }
+	return func(c *gin.Context) {
+		// Checking POST, PUT, PATCH
+		if c.Request.Method != http.MethodPost &&
+			c.Request.Method != http.MethodPut &&
+			c.Request.Method != http.MethodPatch {
+			c.Next()
+			return
+		}
+		if skipJSONValidation(c.Request.Header.Get("Content-Type")) {
+			c.Next()
+			return
+		}
+		maxBytes := int64(constant.MaxRequestBodyMB) << 20
+		if maxBytes > 0 && c.Request.ContentLength > maxBytes {
+			abortWithRequestBodyTooLarge(c)
+			return
+		}
+
+		body, syntaxErr, err := readAndValidateJSON(c.Request.Body, maxBytes)
+		if errors.Is(err, errRequestBodyTooLarge) {
+			abortWithRequestBodyTooLarge(c)
+			return
+		}
+		if err != nil {
+			abortWithOpenAiError(c, http.StatusBadRequest, "invalid_request_error", "read_request_body_failed", "Failed to read request body: "+err.Error())
+			return
+		}
+		_ = c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Empty body — skipping
+		if len(body) == 0 {
+			c.Next()
+			return
+		}
+
+		// Check Valid JSON
+		if syntaxErr != nil {
+			abortWithOpenAiError(c, http.StatusBadRequest, "invalid_request_error", "invalid_json", "Invalid JSON: "+syntaxErr.Error())
+			return
+		}
+		// 后续通过 GetRequestBody 读取时直接复用，避免再次缓存
+		c.Set(common.KeyRequestBody, body)
+
+		c.Next()
+	}
+}
+
+func abortWithRequestBodyTooLarge(c *gin.Context) {
+	abortWithOpenAiError(c, http.StatusRequestEntityTooLarge, "invalid_request_error", "request_body_too_large",
+		fmt.Sprintf("Request body too large, the limit is %d MB", constant.MaxRequestBodyMB))
 }
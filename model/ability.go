@@ -103,6 +103,12 @@ func getChannelQuery(group string, model string, retry int) (*gorm.DB, error) {
 }
 
 func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel, error) {
+	return getRandomSatisfiedChannelOfTypes(group, model, retry, nil)
+}
+
+// getRandomSatisfiedChannelOfTypes 从数据库选择渠道，supportsType 不为空时只选择其允许的渠道类型，并绕过该模型处于熔断中的渠道。
+// 选择权重与内存缓存路径一致，服务商故障时降低相关渠道的权重
+func getRandomSatisfiedChannelOfTypes(group string, model string, retry int, supportsType func(channelType int) bool) (*Channel, error) {
 	var abilities []Ability
 
 	var err error = nil
@@ -118,28 +124,56 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	if err != nil {
 		return nil, err
 	}
-	channel := Channel{}
-	if len(abilities) > 0 {
-		// Randomly choose one
-		weightSum := uint(0)
-		for _, ability_ := range abilities {
-			weightSum += ability_.Weight + 10
-		}
-		// Randomly choose one
-		weight := common.GetRandomInt(int(weightSum))
-		for _, ability_ := range abilities {
-			weight -= int(ability_.Weight) + 10
-			//log.Printf("weight: %d, ability weight: %d", weight, *ability_.Weight)
-			if weight <= 0 {
-				channel.Id = ability_.ChannelId
-				break
-			}
-		}
-	} else {
+	if len(abilities) == 0 {
+		return nil, nil
+	}
+	channelIds := make([]int, 0, len(abilities))
+	for _, ability := range abilities {
+		channelIds = append(channelIds, ability.ChannelId)
+	}
+	var channels []*Channel
+	if err = DB.Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	if supportsType != nil {
+		channels = lo.Filter(channels, func(channel *Channel, _ int) bool {
+			return supportsType(channel.Type)
+		})
+	}
+	channels = filterOpenChannelList(channels, model)
+	if len(channels) == 0 {
 		return nil, nil
 	}
-	err = DB.First(&channel, "id = ?", channel.Id).Error
-	return &channel, err
+
+	// 平滑系数与内存缓存路径相同
+	weights := ChannelSelectionWeights(channels, 10)
+	totalWeight := 0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	randomWeight := common.GetRandomInt(totalWeight)
+	for i, channel := range channels {
+		randomWeight -= weights[i]
+		if randomWeight < 0 {
+			return channel, nil
+		}
+	}
+	return nil, errors.New("channel not found")
+}
+
+// filterOpenChannelList 与 filterOpenChannels 相同，作用于渠道列表
+func filterOpenChannelList(channels []*Channel, model string) []*Channel {
+	channelIds := make([]int, 0, len(channels))
+	for _, channel := range channels {
+		channelIds = append(channelIds, channel.Id)
+	}
+	available := make(map[int]bool, len(channels))
+	for _, channelId := range filterOpenChannels(channelIds, model) {
+		available[channelId] = true
+	}
+	return lo.Filter(channels, func(channel *Channel, _ int) bool {
+		return available[channel.Id]
+	})
 }
 
 func (channel *Channel) AddAbilities(tx *gorm.DB) error {
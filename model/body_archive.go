@@ -0,0 +1,46 @@
+package model
+
+import "context"
+
+// BodyArchive 一次中继请求的请求体与响应体，保存在对象存储时只记录对象键
+type BodyArchive struct {
+	Id           int    `json:"id"`
+	LogId        int    `json:"log_id" gorm:"index"`
+	RequestId    string `json:"request_id" gorm:"index;default:''"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+	Path         string `json:"path"`
+	StatusCode   int    `json:"status_code"`
+	RequestBody  string `json:"request_body,omitempty" gorm:"type:text"`
+	ResponseBody string `json:"response_body,omitempty" gorm:"type:text"`
+	Truncated    bool   `json:"truncated"`
+	StorageKey   string `json:"storage_key,omitempty" gorm:"default:''"`
+}
+
+func (archive *BodyArchive) Insert() error {
+	return LOG_DB.Create(archive).Error
+}
+
+func GetBodyArchiveByLogId(logId int) (*BodyArchive, error) {
+	archive := &BodyArchive{}
+	err := LOG_DB.Where("log_id = ?", logId).First(archive).Error
+	return archive, err
+}
+
+// DeleteOldBodyArchives 删除早于 targetTimestamp 的存档记录，对象存储中的内容需通过存储桶的生命周期规则清理
+func DeleteOldBodyArchives(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		result := LOG_DB.Where("created_at < ?", targetTimestamp).Limit(limit).Delete(&BodyArchive{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(limit) {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,154 @@
+package model
+
+import (
+	"one-api/common"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+// CacheSavingsStat 单个模型（按用户汇总时为单个用户的单个模型）的缓存命中与节省额度
+type CacheSavingsStat struct {
+	UserId         int    `json:"user_id,omitempty"`
+	Username       string `json:"username,omitempty"`
+	ModelName      string `json:"model_name"`
+	Requests       int    `json:"requests"`
+	CachedRequests int    `json:"cached_requests"`
+	PromptTokens   int    `json:"prompt_tokens"`
+	CachedTokens   int    `json:"cached_tokens"`
+	SavedQuota     int    `json:"saved_quota"`
+}
+
+// CacheSavingsReport 用户在一段时间内命中提示缓存节省的额度，SavedAmount 为折算的美元金额
+type CacheSavingsReport struct {
+	StartTimestamp int64               `json:"start_timestamp"`
+	EndTimestamp   int64               `json:"end_timestamp"`
+	Requests       int                 `json:"requests"`
+	CachedRequests int                 `json:"cached_requests"`
+	PromptTokens   int                 `json:"prompt_tokens"`
+	CachedTokens   int                 `json:"cached_tokens"`
+	SavedQuota     int                 `json:"saved_quota"`
+	SavedAmount    float64             `json:"saved_amount"`
+	Models         []*CacheSavingsStat `json:"models"`
+}
+
+// cacheSavingsOther 消费日志 other 字段中与缓存计费相关的部分
+type cacheSavingsOther struct {
+	CacheTokens int      `json:"cache_tokens"`
+	CacheRatio  *float64 `json:"cache_ratio"`
+	ModelRatio  float64  `json:"model_ratio"`
+	GroupRatio  float64  `json:"group_ratio"`
+	ModelPrice  float64  `json:"model_price"`
+}
+
+// savedQuota 缓存 token 按 cache_ratio 计费，与全价相比节省的额度；按次计费的模型不受缓存影响
+func (o *cacheSavingsOther) savedQuota() int {
+	if o.CacheTokens <= 0 || o.CacheRatio == nil || *o.CacheRatio >= 1 || o.ModelPrice > 0 {
+		return 0
+	}
+	return int(float64(o.CacheTokens) * (1 - *o.CacheRatio) * o.ModelRatio * o.GroupRatio)
+}
+
+// cacheSavingsQuery 时间范围内的消费日志
+func cacheSavingsQuery(startTimestamp int64, endTimestamp int64) *gorm.DB {
+	tx := LOG_DB.Model(&Log{}).Where("type = ?", LogTypeConsume)
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	return tx
+}
+
+// scanCacheSavings 分批读取日志，累加到 statFor 返回的统计项中
+func scanCacheSavings(tx *gorm.DB, statFor func(log *Log) *CacheSavingsStat) error {
+	var logs []*Log
+	return tx.FindInBatches(&logs, 1000, func(_ *gorm.DB, _ int) error {
+		for _, log := range logs {
+			stat := statFor(log)
+			stat.Requests++
+			stat.PromptTokens += log.PromptTokens
+			var other cacheSavingsOther
+			if log.Other == "" || common.UnmarshalJsonStr(log.Other, &other) != nil || other.CacheTokens <= 0 {
+				continue
+			}
+			stat.CachedRequests++
+			stat.CachedTokens += other.CacheTokens
+			stat.SavedQuota += other.savedQuota()
+		}
+		return nil
+	}).Error
+}
+
+func GetUserCacheSavings(userId int, startTimestamp int64, endTimestamp int64) (*CacheSavingsReport, error) {
+	tx := cacheSavingsQuery(startTimestamp, endTimestamp).Select("id", "model_name", "prompt_tokens", "other").
+		Where("user_id = ?", userId)
+	report := &CacheSavingsReport{
+		StartTimestamp: startTimestamp,
+		EndTimestamp:   endTimestamp,
+	}
+	statMap := make(map[string]*CacheSavingsStat)
+	err := scanCacheSavings(tx, func(log *Log) *CacheSavingsStat {
+		stat, ok := statMap[log.ModelName]
+		if !ok {
+			stat = &CacheSavingsStat{ModelName: log.ModelName}
+			statMap[log.ModelName] = stat
+		}
+		return stat
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Models = make([]*CacheSavingsStat, 0, len(statMap))
+	for _, stat := range statMap {
+		report.Requests += stat.Requests
+		report.CachedRequests += stat.CachedRequests
+		report.PromptTokens += stat.PromptTokens
+		report.CachedTokens += stat.CachedTokens
+		report.SavedQuota += stat.SavedQuota
+		report.Models = append(report.Models, stat)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		return report.Models[i].SavedQuota > report.Models[j].SavedQuota
+	})
+	report.SavedAmount = float64(report.SavedQuota) / common.QuotaPerUnit
+	return report, nil
+}
+
+// GetCacheSavingsStats 按用户、模型汇总时间范围内的缓存命中与节省额度，username、modelName 为空时不过滤
+func GetCacheSavingsStats(startTimestamp int64, endTimestamp int64, username string, modelName string) ([]*CacheSavingsStat, error) {
+	tx := cacheSavingsQuery(startTimestamp, endTimestamp).Select("id", "user_id", "username", "model_name", "prompt_tokens", "other")
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	type statKey struct {
+		userId    int
+		modelName string
+	}
+	statMap := make(map[statKey]*CacheSavingsStat)
+	err := scanCacheSavings(tx, func(log *Log) *CacheSavingsStat {
+		key := statKey{userId: log.UserId, modelName: log.ModelName}
+		stat, ok := statMap[key]
+		if !ok {
+			stat = &CacheSavingsStat{UserId: log.UserId, Username: log.Username, ModelName: log.ModelName}
+			statMap[key] = stat
+		}
+		return stat
+	})
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]*CacheSavingsStat, 0, len(statMap))
+	for _, stat := range statMap {
+		stats = append(stats, stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].SavedQuota > stats[j].SavedQuota
+	})
+	return stats, nil
+}
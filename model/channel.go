@@ -61,6 +61,8 @@ type ChannelInfo struct {
 	MultiKeyDisabledTime   map[int]int64         `json:"multi_key_disabled_time,omitempty"`   // key禁用时间列表，key index -> time
 	MultiKeyPollingIndex   int                   `json:"multi_key_polling_index"`             // 多Key模式下轮询的key索引
 	MultiKeyMode           constant.MultiKeyMode `json:"multi_key_mode"`
+	MultiKeyTestKeyIndex   *int                  `json:"multi_key_test_key_index,omitempty"` // 专用于渠道测试的key索引，正常请求不会使用
+	PriorityAdjustment     int64                 `json:"priority_adjustment,omitempty"`      // 根据测试延迟自动调整的优先级累计量，非正数
 }
 
 // Value implements driver.Valuer interface
@@ -123,10 +125,31 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		return common.ChannelStatusEnabled
 	}
 
+	// 测试专用key不参与正常请求，除非没有其他可用key
+	testIdx := -1
+	if channel.ChannelInfo.MultiKeyTestKeyIndex != nil {
+		testIdx = *channel.ChannelInfo.MultiKeyTestKeyIndex
+	}
+	selectable := func(idx int) bool {
+		return getStatus(idx) == common.ChannelStatusEnabled && idx != testIdx
+	}
+	if testIdx >= 0 && testIdx < len(keys) {
+		hasOther := false
+		for i := range keys {
+			if selectable(i) {
+				hasOther = true
+				break
+			}
+		}
+		if !hasOther {
+			testIdx = -1
+		}
+	}
+
 	// Collect indexes of enabled keys
 	enabledIdx := make([]int, 0, len(keys))
 	for i := range keys {
-		if getStatus(i) == common.ChannelStatusEnabled {
+		if selectable(i) {
 			enabledIdx = append(enabledIdx, i)
 		}
 	}
@@ -168,7 +191,7 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		}
 		for i := 0; i < len(keys); i++ {
 			idx := (start + i) % len(keys)
-			if getStatus(idx) == common.ChannelStatusEnabled {
+			if selectable(idx) {
 				// update polling index for next call (point to the next position)
 				channel.ChannelInfo.MultiKeyPollingIndex = (idx + 1) % len(keys)
 				return keys[idx], idx, nil
@@ -182,6 +205,22 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	}
 }
 
+// GetTestKey 返回渠道测试专用的key，未设置或该key已禁用时 ok 为 false，调用方应回退到正常选择的key
+func (channel *Channel) GetTestKey() (key string, index int, ok bool) {
+	if !channel.ChannelInfo.IsMultiKey || channel.ChannelInfo.MultiKeyTestKeyIndex == nil {
+		return "", 0, false
+	}
+	index = *channel.ChannelInfo.MultiKeyTestKeyIndex
+	keys := channel.GetKeys()
+	if index < 0 || index >= len(keys) {
+		return "", 0, false
+	}
+	if status, exists := channel.ChannelInfo.MultiKeyStatusList[index]; exists && status != common.ChannelStatusEnabled {
+		return "", 0, false
+	}
+	return keys[index], index, true
+}
+
 func (channel *Channel) SaveChannelInfo() error {
 	return DB.Model(channel).Update("channel_info", channel.ChannelInfo).Error
 }
@@ -595,6 +634,10 @@ func UpdateChannelStatus(channelId int, usingKey string, status int, reason stri
 		if channelCache == nil {
 			return false
 		}
+		// 排空中的渠道等待删除，不允许被自动禁用或启用
+		if channelCache.Status == common.ChannelStatusDraining {
+			return false
+		}
 		if channelCache.ChannelInfo.IsMultiKey {
 			// 如果是多Key模式，更新缓存中的状态
 			handlerMultiKeyUpdate(channelCache, usingKey, status, reason)
@@ -625,6 +668,9 @@ func UpdateChannelStatus(channelId int, usingKey string, status int, reason stri
 		if channel.Status == status {
 			return false
 		}
+		if channel.Status == common.ChannelStatusDraining {
+			return false
+		}
 
 		if channel.ChannelInfo.IsMultiKey {
 			beforeStatus := channel.Status
@@ -813,7 +859,20 @@ func (channel *Channel) ValidateSettings() error {
 			return err
 		}
 	}
-	return nil
+	for _, fixup := range channelParams.ResponseFixups {
+		if !lo.Contains(dto.ResponseFixups, fixup) {
+			return fmt.Errorf("不支持的响应修正项：%s", fixup)
+		}
+	}
+	for mode, endpoint := range channelParams.ModeEndpoints {
+		if !lo.Contains(dto.ChannelModes, mode) {
+			return fmt.Errorf("不支持的请求类型：%s", mode)
+		}
+		if endpoint.BaseURL != "" && !strings.HasPrefix(endpoint.BaseURL, "http://") && !strings.HasPrefix(endpoint.BaseURL, "https://") {
+			return fmt.Errorf("请求类型 %s 的代理地址必须以 http:// 或 https:// 开头", mode)
+		}
+	}
+	return ValidateCostTags(channelParams.CostTags)
 }
 
 func (channel *Channel) GetSetting() dto.ChannelSettings {
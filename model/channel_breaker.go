@@ -0,0 +1,159 @@
+package model
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ChannelBreakerClosed   = "closed"
+	ChannelBreakerOpen     = "open"
+	ChannelBreakerHalfOpen = "half_open"
+)
+
+type channelBreakerKey struct {
+	channelId int
+	model     string
+}
+
+type channelBreaker struct {
+	failures    int
+	trips       int
+	openUntil   time.Time
+	lastFailure time.Time
+}
+
+// ChannelBreakerState 渠道与模型的熔断状态，仅统计当前节点
+type ChannelBreakerState struct {
+	ChannelId           int    `json:"channel_id"`
+	Model               string `json:"model"`
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutive_failures"`
+	Trips               int    `json:"trips"`
+	OpenUntil           int64  `json:"open_until"`
+	LastFailureTime     int64  `json:"last_failure_time"`
+}
+
+var (
+	channelBreakers     = make(map[channelBreakerKey]*channelBreaker)
+	channelBreakersLock sync.RWMutex
+)
+
+// RecordChannelModelSuccess 请求成功后关闭熔断并清空连续失败次数
+func RecordChannelModelSuccess(channelId int, model string) {
+	key := channelBreakerKey{channelId: channelId, model: model}
+	channelBreakersLock.RLock()
+	_, ok := channelBreakers[key]
+	channelBreakersLock.RUnlock()
+	if !ok {
+		return
+	}
+	channelBreakersLock.Lock()
+	delete(channelBreakers, key)
+	channelBreakersLock.Unlock()
+}
+
+// RecordChannelModelFailure 记录一次失败，连续失败达到阈值后熔断 cooldown 时长，返回本次是否触发熔断。
+// 熔断到期后的试探请求再次失败会立即重新熔断
+func RecordChannelModelFailure(channelId int, model string, threshold int, cooldown time.Duration) bool {
+	key := channelBreakerKey{channelId: channelId, model: model}
+	channelBreakersLock.Lock()
+	defer channelBreakersLock.Unlock()
+	breaker, ok := channelBreakers[key]
+	if !ok {
+		breaker = &channelBreaker{}
+		channelBreakers[key] = breaker
+	}
+	now := time.Now()
+	breaker.failures++
+	breaker.lastFailure = now
+	if threshold <= 0 || breaker.failures < threshold || now.Before(breaker.openUntil) {
+		return false
+	}
+	breaker.openUntil = now.Add(cooldown)
+	breaker.trips++
+	return true
+}
+
+// IsChannelModelOpen 判断渠道的该模型是否处于熔断中
+func IsChannelModelOpen(channelId int, model string) bool {
+	channelBreakersLock.RLock()
+	defer channelBreakersLock.RUnlock()
+	breaker, ok := channelBreakers[channelBreakerKey{channelId: channelId, model: model}]
+	return ok && time.Now().Before(breaker.openUntil)
+}
+
+// filterOpenChannels 过滤掉该模型处于熔断中的渠道，全部熔断时原样返回，避免模型完全不可用
+func filterOpenChannels(channelIds []int, model string) []int {
+	channelBreakersLock.RLock()
+	defer channelBreakersLock.RUnlock()
+	if len(channelBreakers) == 0 {
+		return channelIds
+	}
+	now := time.Now()
+	available := make([]int, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		breaker, ok := channelBreakers[channelBreakerKey{channelId: channelId, model: model}]
+		if ok && now.Before(breaker.openUntil) {
+			continue
+		}
+		available = append(available, channelId)
+	}
+	if len(available) == 0 {
+		return channelIds
+	}
+	return available
+}
+
+// GetChannelBreakerStates 返回所有有失败记录的渠道与模型的熔断状态
+func GetChannelBreakerStates() []ChannelBreakerState {
+	channelBreakersLock.RLock()
+	defer channelBreakersLock.RUnlock()
+	now := time.Now()
+	states := make([]ChannelBreakerState, 0, len(channelBreakers))
+	for key, breaker := range channelBreakers {
+		state := ChannelBreakerState{
+			ChannelId:           key.channelId,
+			Model:               key.model,
+			State:               ChannelBreakerClosed,
+			ConsecutiveFailures: breaker.failures,
+			Trips:               breaker.trips,
+			LastFailureTime:     breaker.lastFailure.Unix(),
+		}
+		if !breaker.openUntil.IsZero() {
+			state.OpenUntil = breaker.openUntil.Unix()
+			if now.Before(breaker.openUntil) {
+				state.State = ChannelBreakerOpen
+			} else {
+				state.State = ChannelBreakerHalfOpen
+			}
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].ChannelId != states[j].ChannelId {
+			return states[i].ChannelId < states[j].ChannelId
+		}
+		return states[i].Model < states[j].Model
+	})
+	return states
+}
+
+// ResetChannelBreakers 清除熔断状态，model 为空时清除该渠道的所有模型，channelId 为 0 时清除全部
+func ResetChannelBreakers(channelId int, model string) int {
+	channelBreakersLock.Lock()
+	defer channelBreakersLock.Unlock()
+	count := 0
+	for key := range channelBreakers {
+		if channelId != 0 && key.channelId != channelId {
+			continue
+		}
+		if model != "" && key.model != model {
+			continue
+		}
+		delete(channelBreakers, key)
+		count++
+	}
+	return count
+}
@@ -0,0 +1,86 @@
+package model
+
+import (
+	"one-api/common"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+const breakerTestModel = "gpt-4o-mini"
+
+// setupBreakerTestDB 使用内存 SQLite 作为主数据库并关闭内存缓存，测试结束后恢复
+func setupBreakerTestDB(t *testing.T) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	if err = db.AutoMigrate(&Channel{}, &Ability{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	oldDB, oldMemoryCacheEnabled, oldUsingSQLite := DB, common.MemoryCacheEnabled, common.UsingSQLite
+	DB = db
+	common.MemoryCacheEnabled = false
+	common.UsingSQLite = true
+	initCol()
+	t.Cleanup(func() {
+		DB, common.MemoryCacheEnabled, common.UsingSQLite = oldDB, oldMemoryCacheEnabled, oldUsingSQLite
+		initCol()
+		channelBreakersLock.Lock()
+		channelBreakers = make(map[channelBreakerKey]*channelBreaker)
+		channelBreakersLock.Unlock()
+	})
+
+	for _, id := range []int{1, 2} {
+		channel := &Channel{
+			Id:     id,
+			Type:   1,
+			Key:    "sk-test",
+			Status: common.ChannelStatusEnabled,
+			Name:   "breaker-test",
+			Models: breakerTestModel,
+			Group:  "default",
+		}
+		if err = DB.Create(channel).Error; err != nil {
+			t.Fatalf("create channel: %v", err)
+		}
+		if err = channel.AddAbilities(nil); err != nil {
+			t.Fatalf("add abilities: %v", err)
+		}
+	}
+}
+
+func TestGetRandomSatisfiedChannelSkipsOpenBreakerWithoutMemoryCache(t *testing.T) {
+	setupBreakerTestDB(t)
+	RecordChannelModelFailure(1, breakerTestModel, 1, time.Minute)
+	if !IsChannelModelOpen(1, breakerTestModel) {
+		t.Fatal("breaker of channel 1 should be open")
+	}
+
+	for i := 0; i < 50; i++ {
+		channel, err := getRandomSatisfiedChannel("default", breakerTestModel, 0, channelSelectOptions{})
+		if err != nil {
+			t.Fatalf("select channel: %v", err)
+		}
+		if channel == nil || channel.Id != 2 {
+			t.Fatalf("expected channel 2, got %+v", channel)
+		}
+	}
+}
+
+func TestGetRandomSatisfiedChannelKeepsOpenBreakerWhenAllOpen(t *testing.T) {
+	setupBreakerTestDB(t)
+	RecordChannelModelFailure(1, breakerTestModel, 1, time.Minute)
+	RecordChannelModelFailure(2, breakerTestModel, 1, time.Minute)
+
+	channel, err := getRandomSatisfiedChannel("default", breakerTestModel, 0, channelSelectOptions{})
+	if err != nil {
+		t.Fatalf("select channel: %v", err)
+	}
+	if channel == nil {
+		t.Fatal("all channels open should still return a channel")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/setting"
+	"one-api/setting/operation_setting"
 	"one-api/setting/ratio_setting"
 	"sort"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 var group2model2channels map[string]map[string][]int // enabled channel
@@ -99,6 +101,7 @@ func CacheGetRandomSatisfiedChannel(c *gin.Context, group string, model string,
 	var channel *Channel
 	var err error
 	selectGroup := group
+	options := newChannelSelectOptions(c)
 	if group == "auto" {
 		if len(setting.AutoGroups) == 0 {
 			return nil, selectGroup, errors.New("auto groups is not enabled")
@@ -107,7 +110,7 @@ func CacheGetRandomSatisfiedChannel(c *gin.Context, group string, model string,
 			if common.DebugEnabled {
 				println("autoGroup:", autoGroup)
 			}
-			channel, _ = getRandomSatisfiedChannel(autoGroup, model, retry)
+			channel, _ = getRandomSatisfiedChannel(autoGroup, model, retry, options)
 			if channel == nil {
 				continue
 			} else {
@@ -120,7 +123,7 @@ func CacheGetRandomSatisfiedChannel(c *gin.Context, group string, model string,
 			}
 		}
 	} else {
-		channel, err = getRandomSatisfiedChannel(group, model, retry)
+		channel, err = getRandomSatisfiedChannel(group, model, retry, options)
 		if err != nil {
 			return nil, group, err
 		}
@@ -128,9 +131,54 @@ func CacheGetRandomSatisfiedChannel(c *gin.Context, group string, model string,
 	return channel, selectGroup, nil
 }
 
-func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel, error) {
+// IsPinnedChannelAvailable 复用缓存亲和等固定的渠道前检查其是否仍可被正常选择：渠道已启用、该模型未熔断，请求包含工具时工具调用可靠
+func IsPinnedChannelAvailable(c *gin.Context, channel *Channel, model string) bool {
+	if channel.Status != common.ChannelStatusEnabled {
+		return false
+	}
+	if IsChannelModelOpen(channel.Id, model) {
+		return false
+	}
+	if common.GetContextKeyBool(c, constant.ContextKeyRequestHasTools) && !isToolReliableChannel(channel, model, operation_setting.GetToolRoutingSetting()) {
+		return false
+	}
+	return true
+}
+
+// channelSelectOptions 选择渠道时的附加条件，语言与工具偏好仅在开启内存缓存时生效
+type channelSelectOptions struct {
+	language string // 不为空时，目标优先级内优先选择擅长该语言的渠道
+	rerank   bool   // rerank 请求只选择支持 rerank 的渠道类型
+	claude   bool   // Claude 格式请求只选择支持 Claude 格式的渠道类型
+	tools    bool   // 请求包含工具定义时，目标优先级内优先选择工具调用可靠的渠道
+}
+
+func newChannelSelectOptions(c *gin.Context) channelSelectOptions {
+	return channelSelectOptions{
+		language: common.GetContextKeyString(c, constant.ContextKeyPromptLanguage),
+		tools:    common.GetContextKeyBool(c, constant.ContextKeyRequestHasTools),
+		rerank:   c.Request != nil && strings.HasPrefix(c.Request.URL.Path, "/v1/rerank"),
+		claude:   c.Request != nil && strings.HasPrefix(c.Request.URL.Path, "/v1/messages"),
+	}
+}
+
+// supportsChannelType 返回该渠道类型是否满足 rerank、Claude 格式等请求对渠道类型的要求
+func (options channelSelectOptions) supportsChannelType(channelType int) bool {
+	if options.rerank && !common.ChannelTypeSupportsRerank(channelType) {
+		return false
+	}
+	if options.claude && !common.ChannelTypeSupportsClaudeMessages(channelType) {
+		return false
+	}
+	return true
+}
+
+func getRandomSatisfiedChannel(group string, model string, retry int, options channelSelectOptions) (*Channel, error) {
 	// if memory cache is disabled, get channel directly from database
 	if !common.MemoryCacheEnabled {
+		if options.rerank || options.claude {
+			return getRandomSatisfiedChannelOfTypes(group, model, retry, options.supportsChannelType)
+		}
 		return GetRandomSatisfiedChannel(group, model, retry)
 	}
 
@@ -142,10 +190,22 @@ func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 
 	// If no channels found, try to find channels with the normalized model name.
 	if len(channels) == 0 {
-		normalizedModel := ratio_setting.FormatMatchingModelName(model)
+		normalizedModel, _ := ratio_setting.MatchModelVariant(model, func(name string) bool {
+			return len(group2model2channels[group][name]) > 0
+		})
 		channels = group2model2channels[group][normalizedModel]
 	}
 
+	if options.rerank || options.claude {
+		channels = lo.Filter(channels, func(channelId int, _ int) bool {
+			channel, ok := channelsIDM[channelId]
+			return !ok || options.supportsChannelType(channel.Type)
+		})
+	}
+
+	// 绕过该模型处于熔断中的渠道
+	channels = filterOpenChannels(channels, model)
+
 	if len(channels) == 0 {
 		return nil, nil
 	}
@@ -187,20 +247,36 @@ func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channelId)
 		}
 	}
+	if options.language != "" {
+		languageChannels := make([]*Channel, 0, len(targetChannels))
+		for _, channel := range targetChannels {
+			if common.StringsContains(channel.GetSetting().Languages, options.language) {
+				languageChannels = append(languageChannels, channel)
+			}
+		}
+		if len(languageChannels) > 0 {
+			targetChannels = languageChannels
+		}
+	}
+	if options.tools {
+		targetChannels = filterToolReliableChannels(targetChannels, model)
+	}
 
 	// 平滑系数
 	smoothingFactor := 10
+	// 服务商故障时降低相关渠道的权重
+	weights := ChannelSelectionWeights(targetChannels, smoothingFactor)
 	// Calculate the total weight of all channels up to endIdx
 	totalWeight := 0
-	for _, channel := range targetChannels {
-		totalWeight += channel.GetWeight() + smoothingFactor
+	for _, weight := range weights {
+		totalWeight += weight
 	}
 	// Generate a random value in the range [0, totalWeight)
 	randomWeight := rand.Intn(totalWeight)
 
 	// Find a channel based on its weight
-	for _, channel := range targetChannels {
-		randomWeight -= channel.GetWeight() + smoothingFactor
+	for i, channel := range targetChannels {
+		randomWeight -= weights[i]
 		if randomWeight < 0 {
 			return channel, nil
 		}
@@ -209,6 +285,46 @@ func getRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	return nil, errors.New("channel not found")
 }
 
+// GetRoutingCandidates 返回分组下可服务该模型的启用渠道，以及实际匹配到的模型名（可能为模型变体的基础名），用于路由模拟
+func GetRoutingCandidates(group string, model string) (string, []*Channel, error) {
+	if !common.MemoryCacheEnabled {
+		return getRoutingCandidatesFromDB(group, model)
+	}
+	channelSyncLock.RLock()
+	defer channelSyncLock.RUnlock()
+
+	matchedModel := model
+	channelIds := group2model2channels[group][model]
+	if len(channelIds) == 0 {
+		matchedModel, _ = ratio_setting.MatchModelVariant(model, func(name string) bool {
+			return len(group2model2channels[group][name]) > 0
+		})
+		channelIds = group2model2channels[group][matchedModel]
+	}
+	channels := make([]*Channel, 0, len(channelIds))
+	for _, channelId := range channelIds {
+		if channel, ok := channelsIDM[channelId]; ok {
+			channels = append(channels, channel)
+		}
+	}
+	return matchedModel, channels, nil
+}
+
+func getRoutingCandidatesFromDB(group string, model string) (string, []*Channel, error) {
+	var channelIds []int
+	err := DB.Model(&Ability{}).Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
+		Pluck("channel_id", &channelIds).Error
+	if err != nil {
+		return model, nil, err
+	}
+	channels := make([]*Channel, 0, len(channelIds))
+	if len(channelIds) == 0 {
+		return model, channels, nil
+	}
+	err = DB.Where("id in (?)", channelIds).Find(&channels).Error
+	return model, channels, err
+}
+
 func CacheGetChannel(id int) (*Channel, error) {
 	if !common.MemoryCacheEnabled {
 		return GetChannelById(id, true)
@@ -282,3 +398,57 @@ func CacheUpdateChannel(channel *Channel) {
 	channelsIDM[channel.Id] = channel
 	println("after :", channelsIDM[channel.Id].ChannelInfo.MultiKeyPollingIndex)
 }
+
+// CacheGetRandomQuarantinedChannel 从隔离中的渠道里随机选择一个满足分组与模型的渠道，没有时返回 nil。
+// 与正常选择一样要求渠道类型支持该请求、该模型未熔断，请求包含工具时要求工具调用可靠
+func CacheGetRandomQuarantinedChannel(c *gin.Context, group string, model string) *Channel {
+	groups := []string{group}
+	if group == "auto" {
+		groups = setting.AutoGroups
+	}
+	options := newChannelSelectOptions(c)
+	routingSetting := operation_setting.GetToolRoutingSetting()
+	satisfied := func(channel *Channel) bool {
+		if channel.Status != common.ChannelStatusQuarantined || !common.StringsContains(channel.GetModels(), model) {
+			return false
+		}
+		if !options.supportsChannelType(channel.Type) || IsChannelModelOpen(channel.Id, model) {
+			return false
+		}
+		if options.tools && !isToolReliableChannel(channel, model, routingSetting) {
+			return false
+		}
+		channelGroups := channel.GetGroups()
+		for _, g := range groups {
+			if common.StringsContains(channelGroups, g) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var candidates []*Channel
+	if common.MemoryCacheEnabled {
+		channelSyncLock.RLock()
+		for _, channel := range channelsIDM {
+			if satisfied(channel) {
+				candidates = append(candidates, channel)
+			}
+		}
+		channelSyncLock.RUnlock()
+	} else {
+		var channels []*Channel
+		if err := DB.Where("status = ?", common.ChannelStatusQuarantined).Find(&channels).Error; err != nil {
+			return nil
+		}
+		for _, channel := range channels {
+			if satisfied(channel) {
+				candidates = append(candidates, channel)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
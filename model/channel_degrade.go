@@ -0,0 +1,126 @@
+package model
+
+import (
+	"net/url"
+	"one-api/constant"
+	"strings"
+	"sync"
+
+	"github.com/samber/lo"
+)
+
+// DegradedProvider 正在发生故障的服务商，按渠道类型与 base URL 主机名匹配受影响的渠道
+type DegradedProvider struct {
+	ChannelTypes []int    // 为空表示不限渠道类型
+	Hosts        []string // 为空表示不限主机名，渠道未设置 base URL 时使用该类型的默认地址，仍无法确定时只按类型匹配
+	Reason       string
+}
+
+var (
+	// degradedProviders 当前发生故障的服务商
+	degradedProviders     []DegradedProvider
+	degradedWeightFactor  float64
+	degradedProvidersLock sync.RWMutex
+)
+
+// SetDegradedProviders 替换当前降级的服务商，weightFactor 为降级渠道的权重系数
+func SetDegradedProviders(providers []DegradedProvider, weightFactor float64) {
+	degradedProvidersLock.Lock()
+	defer degradedProvidersLock.Unlock()
+	degradedProviders = providers
+	degradedWeightFactor = weightFactor
+}
+
+// channelBaseURLHost 返回渠道实际请求的主机名
+func channelBaseURLHost(channel *Channel) string {
+	baseURL := channel.GetBaseURL()
+	if baseURL == "" && channel.Type >= 0 && channel.Type < len(constant.ChannelBaseURLs) {
+		baseURL = constant.ChannelBaseURLs[channel.Type]
+	}
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+func (provider DegradedProvider) matches(channel *Channel) bool {
+	if len(provider.ChannelTypes) == 0 && len(provider.Hosts) == 0 {
+		return false
+	}
+	if len(provider.ChannelTypes) > 0 && !lo.Contains(provider.ChannelTypes, channel.Type) {
+		return false
+	}
+	if len(provider.Hosts) == 0 {
+		return true
+	}
+	host := channelBaseURLHost(channel)
+	if host == "" {
+		// 无法确定请求地址（如 Vertex 按区域拼接地址）时只按渠道类型匹配
+		return len(provider.ChannelTypes) > 0
+	}
+	for _, h := range provider.Hosts {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// getChannelDegradedReason 调用方需持有 degradedProvidersLock
+func getChannelDegradedReason(channel *Channel) string {
+	reasons := make([]string, 0)
+	for _, provider := range degradedProviders {
+		if provider.matches(channel) {
+			reasons = append(reasons, provider.Reason)
+		}
+	}
+	return strings.Join(reasons, " | ")
+}
+
+// GetChannelDegradedReason 返回渠道所属服务商的故障描述，未降级时返回空字符串
+func GetChannelDegradedReason(channel *Channel) string {
+	degradedProvidersLock.RLock()
+	defer degradedProvidersLock.RUnlock()
+	return getChannelDegradedReason(channel)
+}
+
+// ChannelSelectionWeights 计算同一优先级内候选渠道的选择权重（已包含平滑系数）。
+// 降级渠道的权重按系数缩小，系数为 0 时只要有未降级的渠道就不选择降级渠道
+func ChannelSelectionWeights(channels []*Channel, smoothingFactor int) []int {
+	degradedProvidersLock.RLock()
+	defer degradedProvidersLock.RUnlock()
+
+	weights := make([]int, len(channels))
+	degraded := make([]bool, len(channels))
+	healthy := 0
+	for i, channel := range channels {
+		weights[i] = channel.GetWeight() + smoothingFactor
+		if len(degradedProviders) > 0 {
+			degraded[i] = getChannelDegradedReason(channel) != ""
+		}
+		if !degraded[i] {
+			healthy++
+		}
+	}
+	if healthy == len(channels) {
+		return weights
+	}
+	for i := range channels {
+		if !degraded[i] {
+			continue
+		}
+		if degradedWeightFactor <= 0 {
+			if healthy > 0 {
+				weights[i] = 0
+			}
+			continue
+		}
+		weights[i] = int(float64(weights[i]) * degradedWeightFactor)
+		if weights[i] < 1 {
+			weights[i] = 1
+		}
+	}
+	return weights
+}
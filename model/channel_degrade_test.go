@@ -0,0 +1,55 @@
+package model
+
+import (
+	"one-api/constant"
+	"testing"
+)
+
+func TestChannelSelectionWeightsMatchesDegradedHost(t *testing.T) {
+	SetDegradedProviders([]DegradedProvider{{
+		ChannelTypes: []int{constant.ChannelTypeOpenAI},
+		Hosts:        []string{"api.openai.com"},
+		Reason:       "OpenAI: elevated errors",
+	}}, 0)
+	t.Cleanup(func() { SetDegradedProviders(nil, 1) })
+
+	thirdPartyURL := "https://api.example.com"
+	official := &Channel{Id: 1, Type: constant.ChannelTypeOpenAI}
+	thirdParty := &Channel{Id: 2, Type: constant.ChannelTypeOpenAI, BaseURL: &thirdPartyURL}
+
+	if GetChannelDegradedReason(official) == "" {
+		t.Fatal("official OpenAI channel should be degraded")
+	}
+	if reason := GetChannelDegradedReason(thirdParty); reason != "" {
+		t.Fatalf("third-party OpenAI-compatible channel should not be degraded, got %q", reason)
+	}
+
+	weights := ChannelSelectionWeights([]*Channel{official, thirdParty}, 10)
+	if weights[0] != 0 || weights[1] == 0 {
+		t.Fatalf("unexpected weights %v", weights)
+	}
+}
+
+func TestGetRandomSatisfiedChannelAppliesDegradedWeightsWithoutMemoryCache(t *testing.T) {
+	setupBreakerTestDB(t)
+	SetDegradedProviders([]DegradedProvider{{
+		ChannelTypes: []int{constant.ChannelTypeOpenAI},
+		Hosts:        []string{"api.openai.com"},
+		Reason:       "OpenAI: elevated errors",
+	}}, 0)
+	t.Cleanup(func() { SetDegradedProviders(nil, 1) })
+	thirdPartyURL := "https://api.example.com"
+	if err := DB.Model(&Channel{}).Where("id = ?", 2).Update("base_url", thirdPartyURL).Error; err != nil {
+		t.Fatalf("update channel: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		channel, err := getRandomSatisfiedChannel("default", breakerTestModel, 0, channelSelectOptions{})
+		if err != nil {
+			t.Fatalf("select channel: %v", err)
+		}
+		if channel == nil || channel.Id != 2 {
+			t.Fatalf("expected healthy channel 2, got %+v", channel)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"one-api/common"
+	"sort"
+)
+
+// ChannelDeleteImpact 删除渠道后失去最后一个可用渠道的模型及其所在分组
+type ChannelDeleteImpact struct {
+	Model  string   `json:"model"`
+	Groups []string `json:"groups"`
+}
+
+// SetChannelDraining 将渠道标记为排空中并禁用其能力，渠道不再被选中处理新请求
+func SetChannelDraining(channelId int) error {
+	err := DB.Model(&Channel{}).Where("id = ?", channelId).Update("status", common.ChannelStatusDraining).Error
+	if err != nil {
+		return err
+	}
+	return UpdateAbilityStatus(channelId, false)
+}
+
+// GetDrainingChannelIds 返回所有排空中的渠道，用于重启后继续完成删除
+func GetDrainingChannelIds() ([]int, error) {
+	var ids []int
+	err := DB.Model(&Channel{}).Where("status = ?", common.ChannelStatusDraining).Pluck("id", &ids).Error
+	return ids, err
+}
+
+// GetReplacementChannelId 返回除 excludeIds 外提供该模型的任意一个启用渠道，没有时返回 0
+func GetReplacementChannelId(modelName string, excludeIds []int) int {
+	var ids []int
+	DB.Model(&Ability{}).Where("model = ? AND enabled = ? AND channel_id NOT IN ?", modelName, true, excludeIds).
+		Order("priority DESC").Limit(1).Pluck("channel_id", &ids)
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[0]
+}
+
+// GetChannelDeleteImpact 计算删除这些渠道后，哪些模型在哪些分组中不再有启用的渠道
+func GetChannelDeleteImpact(channelIds []int) ([]ChannelDeleteImpact, error) {
+	var channels []*Channel
+	if err := DB.Select("id", "models", commonGroupCol).Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	// 分组 -> 模型，被删除渠道覆盖的范围
+	affected := make(map[string]map[string]bool)
+	var models []string
+	for _, channel := range channels {
+		for _, group := range channel.GetGroups() {
+			if affected[group] == nil {
+				affected[group] = make(map[string]bool)
+			}
+			for _, modelName := range channel.GetModels() {
+				if !affected[group][modelName] {
+					affected[group][modelName] = true
+					models = append(models, modelName)
+				}
+			}
+		}
+	}
+	if len(models) == 0 {
+		return []ChannelDeleteImpact{}, nil
+	}
+
+	var remaining []Ability
+	err := DB.Model(&Ability{}).Distinct(commonGroupCol, "model").
+		Where("enabled = ? AND channel_id NOT IN ? AND model IN ?", true, channelIds, models).
+		Find(&remaining).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, ability := range remaining {
+		if affected[ability.Group] != nil {
+			delete(affected[ability.Group], ability.Model)
+		}
+	}
+
+	lostGroups := make(map[string][]string)
+	for group, groupModels := range affected {
+		for modelName := range groupModels {
+			lostGroups[modelName] = append(lostGroups[modelName], group)
+		}
+	}
+	impacts := make([]ChannelDeleteImpact, 0, len(lostGroups))
+	for modelName, groups := range lostGroups {
+		sort.Strings(groups)
+		impacts = append(impacts, ChannelDeleteImpact{Model: modelName, Groups: groups})
+	}
+	sort.Slice(impacts, func(i, j int) bool { return impacts[i].Model < impacts[j].Model })
+	return impacts, nil
+}
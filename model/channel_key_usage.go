@@ -0,0 +1,57 @@
+package model
+
+import (
+	"encoding/hex"
+	"one-api/common"
+
+	"gorm.io/gorm"
+)
+
+// ChannelKeyUsage 多 key 渠道中单个 key 的用量，以 key 的哈希标识，key 顺序调整后仍能对应
+type ChannelKeyUsage struct {
+	Id           int    `json:"id"`
+	ChannelId    int    `json:"channel_id" gorm:"uniqueIndex:idx_channel_key_usage,priority:1"`
+	KeyHash      string `json:"key_hash" gorm:"size:64;uniqueIndex:idx_channel_key_usage,priority:2"`
+	UsedQuota    int    `json:"used_quota" gorm:"default:0"`
+	RequestCount int    `json:"request_count" gorm:"default:0"`
+	UpdatedTime  int64  `json:"updated_time" gorm:"bigint"`
+}
+
+func HashChannelKey(key string) string {
+	return hex.EncodeToString(common.Sha256Raw([]byte(key)))
+}
+
+func UpdateChannelKeyUsedQuota(channelId int, key string, quota int) {
+	keyHash := HashChannelKey(key)
+	now := common.GetTimestamp()
+	result := DB.Model(&ChannelKeyUsage{}).Where("channel_id = ? and key_hash = ?", channelId, keyHash).Updates(map[string]interface{}{
+		"used_quota":    gorm.Expr("used_quota + ?", quota),
+		"request_count": gorm.Expr("request_count + ?", 1),
+		"updated_time":  now,
+	})
+	if result.Error == nil && result.RowsAffected == 0 {
+		result = DB.Create(&ChannelKeyUsage{
+			ChannelId:    channelId,
+			KeyHash:      keyHash,
+			UsedQuota:    quota,
+			RequestCount: 1,
+			UpdatedTime:  now,
+		})
+	}
+	if result.Error != nil {
+		common.SysError("failed to update channel key used quota: " + result.Error.Error())
+	}
+}
+
+// GetChannelKeyUsages 返回渠道各 key 的用量，以 key 哈希为键
+func GetChannelKeyUsages(channelId int) (map[string]*ChannelKeyUsage, error) {
+	var usages []*ChannelKeyUsage
+	if err := DB.Where("channel_id = ?", channelId).Find(&usages).Error; err != nil {
+		return nil, err
+	}
+	usageMap := make(map[string]*ChannelKeyUsage, len(usages))
+	for _, usage := range usages {
+		usageMap[usage.KeyHash] = usage
+	}
+	return usageMap, nil
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"gorm.io/gorm"
+)
+
+// AdjustChannelPriority 调整渠道及其 abilities 的优先级，并在 ChannelInfo 中记录累计调整量，便于逐步恢复
+func AdjustChannelPriority(channel *Channel, delta int64) error {
+	priority := channel.GetPriority() + delta
+	channel.ChannelInfo.PriorityAdjustment += delta
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Channel{}).Where("id = ?", channel.Id).Updates(map[string]interface{}{
+			"priority":     priority,
+			"channel_info": channel.ChannelInfo,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&Ability{}).Where("channel_id = ?", channel.Id).Update("priority", priority).Error
+	})
+	if err != nil {
+		channel.ChannelInfo.PriorityAdjustment -= delta
+		return err
+	}
+	channel.Priority = &priority
+	return nil
+}
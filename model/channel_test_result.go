@@ -0,0 +1,63 @@
+package model
+
+import "context"
+
+// ChannelTestResult 渠道测试记录，手动测试与定时测试都会写入
+type ChannelTestResult struct {
+	Id           int    `json:"id"`
+	ChannelId    int    `json:"channel_id" gorm:"index"`
+	ModelName    string `json:"model_name" gorm:"size:128"`
+	TestType     string `json:"test_type" gorm:"size:32"`
+	Success      bool   `json:"success"`
+	Latency      int64  `json:"latency"` // 毫秒
+	ErrorCode    string `json:"error_code" gorm:"size:64"`
+	ErrorMessage string `json:"error_message"`
+	CreatedTime  int64  `json:"created_time" gorm:"bigint;index"`
+}
+
+func (result *ChannelTestResult) Insert() error {
+	return DB.Create(result).Error
+}
+
+// GetChannelTestResults 按条件分页查询渠道测试记录，最新的在前
+func GetChannelTestResults(channelId int, modelName string, startIdx int, num int) (results []*ChannelTestResult, total int64, err error) {
+	tx := DB.Model(&ChannelTestResult{})
+	if channelId != 0 {
+		tx = tx.Where("channel_id = ?", channelId)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&results).Error
+	return results, total, err
+}
+
+// GetRecentChannelTestLatencies 返回渠道最近 num 次成功测试的延迟（毫秒），最新的在前
+func GetRecentChannelTestLatencies(channelId int, num int) ([]int64, error) {
+	var latencies []int64
+	err := DB.Model(&ChannelTestResult{}).Where("channel_id = ? AND success = ?", channelId, true).
+		Order("id desc").Limit(num).Pluck("latency", &latencies).Error
+	return latencies, err
+}
+
+// DeleteOldChannelTestResults 删除早于 targetTimestamp 的渠道测试记录，随清理历史日志一并执行
+func DeleteOldChannelTestResults(ctx context.Context, targetTimestamp int64, limit int) (int64, error) {
+	var total int64 = 0
+	for {
+		if nil != ctx.Err() {
+			return total, ctx.Err()
+		}
+		result := DB.Where("created_time < ?", targetTimestamp).Limit(limit).Delete(&ChannelTestResult{})
+		if nil != result.Error {
+			return total, result.Error
+		}
+		total += result.RowsAffected
+		if result.RowsAffected < int64(limit) {
+			break
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,33 @@
+package model
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeleteOldChannelTestResults(t *testing.T) {
+	setupBreakerTestDB(t)
+	if err := DB.AutoMigrate(&ChannelTestResult{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for i := int64(1); i <= 5; i++ {
+		if err := (&ChannelTestResult{ChannelId: 1, ModelName: breakerTestModel, CreatedTime: i * 100}).Insert(); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	deleted, err := DeleteOldChannelTestResults(context.Background(), 300, 2)
+	if err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if deleted != 2 {
+		t.Fatalf("expected 2 deleted results, got %d", deleted)
+	}
+	_, total, err := GetChannelTestResults(1, "", 0, 10)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if total != 3 {
+		t.Fatalf("expected 3 remaining results, got %d", total)
+	}
+}
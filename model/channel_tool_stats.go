@@ -0,0 +1,99 @@
+package model
+
+import (
+	"one-api/setting/operation_setting"
+	"strings"
+	"sync"
+)
+
+// channelToolStats 渠道某个模型最近若干次带工具请求的结果
+type channelToolStats struct {
+	results []bool
+	next    int
+	filled  bool
+}
+
+var (
+	channelToolStatsMap  = make(map[channelBreakerKey]*channelToolStats)
+	channelToolStatsLock sync.RWMutex
+)
+
+// RecordChannelToolCallResult 记录一次带工具请求的结果，仅保留最近 windowSize 次
+func RecordChannelToolCallResult(channelId int, model string, success bool, windowSize int) {
+	if windowSize <= 0 {
+		return
+	}
+	key := channelBreakerKey{channelId: channelId, model: model}
+	channelToolStatsLock.Lock()
+	defer channelToolStatsLock.Unlock()
+	stats, ok := channelToolStatsMap[key]
+	if !ok || len(stats.results) != windowSize {
+		stats = &channelToolStats{results: make([]bool, windowSize)}
+		channelToolStatsMap[key] = stats
+	}
+	stats.results[stats.next] = success
+	stats.next = (stats.next + 1) % windowSize
+	if stats.next == 0 {
+		stats.filled = true
+	}
+}
+
+// GetChannelToolCallSuccessRate 返回渠道该模型带工具请求的成功率与样本数
+func GetChannelToolCallSuccessRate(channelId int, model string) (float64, int) {
+	channelToolStatsLock.RLock()
+	defer channelToolStatsLock.RUnlock()
+	stats, ok := channelToolStatsMap[channelBreakerKey{channelId: channelId, model: model}]
+	if !ok {
+		return 0, 0
+	}
+	samples := stats.next
+	if stats.filled {
+		samples = len(stats.results)
+	}
+	if samples == 0 {
+		return 0, 0
+	}
+	succeeded := 0
+	for _, success := range stats.results[:samples] {
+		if success {
+			succeeded++
+		}
+	}
+	return float64(succeeded) / float64(samples), samples
+}
+
+// isToolUnsupportedModel 判断渠道设置中是否标注该模型的工具调用不可用，以 * 结尾的规则按前缀匹配
+func isToolUnsupportedModel(channel *Channel, model string) bool {
+	for _, rule := range channel.GetSetting().ToolUnsupportedModels {
+		if rule == model || (strings.HasSuffix(rule, "*") && strings.HasPrefix(model, strings.TrimSuffix(rule, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// isToolReliableChannel 渠道未标注该模型不支持工具调用，且近期工具调用成功率不低于阈值
+func isToolReliableChannel(channel *Channel, model string, routingSetting *operation_setting.ToolRoutingSetting) bool {
+	if isToolUnsupportedModel(channel, model) {
+		return false
+	}
+	if rate, samples := GetChannelToolCallSuccessRate(channel.Id, model); samples >= routingSetting.MinSamples && samples > 0 && rate < routingSetting.MinSuccessRate {
+		return false
+	}
+	return true
+}
+
+// filterToolReliableChannels 过滤掉标注不支持工具调用或近期工具调用成功率过低的渠道，全部被过滤时原样返回
+func filterToolReliableChannels(channels []*Channel, model string) []*Channel {
+	routingSetting := operation_setting.GetToolRoutingSetting()
+	reliable := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if isToolReliableChannel(channel, model, routingSetting) {
+			reliable = append(reliable, channel)
+		}
+	}
+	if len(reliable) == 0 {
+		return channels
+	}
+	return reliable
+}
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetCostTags 解析模型的成本归属标签
+func (mi *Model) GetCostTags() map[string]string {
+	if strings.TrimSpace(mi.CostTags) == "" {
+		return nil
+	}
+	costTags := make(map[string]string)
+	if err := common.UnmarshalJsonStr(mi.CostTags, &costTags); err != nil {
+		common.SysError(fmt.Sprintf("failed to unmarshal cost tags of model %s: %s", mi.ModelName, err.Error()))
+		return nil
+	}
+	return costTags
+}
+
+// ValidateCostTags 成本归属标签必须是键值均为字符串的 JSON 对象
+func ValidateCostTags(costTags map[string]string) error {
+	for key := range costTags {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("成本归属标签的键不能为空")
+		}
+	}
+	return nil
+}
+
+// ValidateCostTagsJson 校验 JSON 格式的成本归属标签
+func ValidateCostTagsJson(jsonStr string) error {
+	if strings.TrimSpace(jsonStr) == "" {
+		return nil
+	}
+	costTags := make(map[string]string)
+	if err := common.UnmarshalJsonStr(jsonStr, &costTags); err != nil {
+		return errors.New("成本归属标签必须是键值均为字符串的 JSON 对象")
+	}
+	return ValidateCostTags(costTags)
+}
+
+// GetModelCostTags 获取模型元数据上的成本归属标签，模型名匹配规则与定价一致
+func GetModelCostTags(modelName string) map[string]string {
+	if time.Since(lastGetPricingTime) > time.Minute*1 || len(pricingMap) == 0 {
+		GetPricing()
+	}
+	modelEnableGroupsLock.RLock()
+	defer modelEnableGroupsLock.RUnlock()
+	return modelCostTagsMap[modelName]
+}
+
+// GetConsumeCostTags 合并模型与渠道的成本归属标签，同名标签以渠道为准
+func GetConsumeCostTags(channelId int, modelName string) map[string]string {
+	costTags := make(map[string]string)
+	for key, value := range GetModelCostTags(modelName) {
+		costTags[key] = value
+	}
+	if channelId != 0 {
+		if channel, err := CacheGetChannel(channelId); err == nil {
+			for key, value := range channel.GetSetting().CostTags {
+				costTags[key] = value
+			}
+		}
+	}
+	return costTags
+}
+
+type CostTagStat struct {
+	Value            string `json:"value"` // 标签值，未设置该标签的消费记为空字符串
+	Quota            int    `json:"quota"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	Count            int    `json:"count"`
+}
+
+// GetCostTagStats 按指定标签汇总时间范围内的消费，用于成本分摊
+func GetCostTagStats(key string, startTimestamp int64, endTimestamp int64) ([]*CostTagStat, error) {
+	tx := LOG_DB.Model(&Log{}).Select("id", "quota", "prompt_tokens", "completion_tokens", "cost_tags").
+		Where("type = ?", LogTypeConsume)
+	if startTimestamp != 0 {
+		tx = tx.Where("created_at >= ?", startTimestamp)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("created_at <= ?", endTimestamp)
+	}
+	statMap := make(map[string]*CostTagStat)
+	var logs []*Log
+	err := tx.FindInBatches(&logs, 1000, func(_ *gorm.DB, _ int) error {
+		for _, log := range logs {
+			value := ""
+			if log.CostTags != "" {
+				costTags := make(map[string]string)
+				if err := common.UnmarshalJsonStr(log.CostTags, &costTags); err == nil {
+					value = costTags[key]
+				}
+			}
+			stat, ok := statMap[value]
+			if !ok {
+				stat = &CostTagStat{Value: value}
+				statMap[value] = stat
+			}
+			stat.Quota += log.Quota
+			stat.PromptTokens += log.PromptTokens
+			stat.CompletionTokens += log.CompletionTokens
+			stat.Count++
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	stats := make([]*CostTagStat, 0, len(statMap))
+	for _, stat := range statMap {
+		stats = append(stats, stat)
+	}
+	return stats, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"one-api/common"
+	"one-api/common/eventbus"
+	"one-api/constant"
 	"os"
 	"strings"
 	"time"
@@ -34,6 +36,7 @@ type Log struct {
 	Group            string `json:"group" gorm:"index"`
 	Ip               string `json:"ip" gorm:"index;default:''"`
 	Other            string `json:"other"`
+	CostTags         string `json:"cost_tags,omitempty" gorm:"type:text"` // 成本归属标签，JSON 对象
 }
 
 const (
@@ -95,6 +98,21 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 	isStream bool, group string, other map[string]interface{}) {
 	common.LogInfo(c, fmt.Sprintf("record error log: userId=%d, channelId=%d, modelName=%s, tokenName=%s, content=%s", userId, channelId, modelName, tokenName, content))
 	username := c.GetString("username")
+	eventbus.Publish(eventbus.Event{
+		Type:           eventbus.EventTypeError,
+		RequestId:      c.GetString(common.RequestIdKey),
+		CreatedAt:      common.GetTimestamp(),
+		UserId:         userId,
+		Username:       username,
+		TokenId:        tokenId,
+		TokenName:      tokenName,
+		ChannelId:      channelId,
+		ModelName:      modelName,
+		Group:          group,
+		UseTimeSeconds: useTimeSeconds,
+		IsStream:       isStream,
+		Content:        content,
+	})
 	otherStr := common.MapToJsonStr(other)
 	// 判断是否需要记录 IP
 	needRecordIp := false
@@ -130,6 +148,8 @@ func RecordErrorLog(c *gin.Context, userId int, channelId int, modelName string,
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		common.LogError(c, "failed to record log: "+err.Error())
+	} else {
+		common.SetContextKey(c, constant.ContextKeyLogId, log.Id)
 	}
 }
 
@@ -151,11 +171,34 @@ type RecordConsumeLogParams struct {
 
 func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams) {
 	common.LogInfo(c, fmt.Sprintf("record consume log: userId=%d, params=%s", userId, common.GetJsonString(params)))
+	username := c.GetString("username")
+	// 事件发布不受是否记录消费日志影响
+	eventbus.Publish(eventbus.Event{
+		Type:             eventbus.EventTypeConsume,
+		RequestId:        c.GetString(common.RequestIdKey),
+		CreatedAt:        common.GetTimestamp(),
+		UserId:           userId,
+		Username:         username,
+		TokenId:          params.TokenId,
+		TokenName:        params.TokenName,
+		ChannelId:        params.ChannelId,
+		ModelName:        params.ModelName,
+		Group:            params.Group,
+		PromptTokens:     params.PromptTokens,
+		CompletionTokens: params.CompletionTokens,
+		Quota:            params.Quota,
+		UseTimeSeconds:   params.UseTimeSeconds,
+		IsStream:         params.IsStream,
+		Content:          params.Content,
+	})
 	if !common.LogConsumeEnabled {
 		return
 	}
-	username := c.GetString("username")
 	otherStr := common.MapToJsonStr(params.Other)
+	costTagsStr := ""
+	if costTags := GetConsumeCostTags(params.ChannelId, params.ModelName); len(costTags) > 0 {
+		costTagsStr = common.GetJsonString(costTags)
+	}
 	// 判断是否需要记录 IP
 	needRecordIp := false
 	if settingMap, err := GetUserSetting(userId, false); err == nil {
@@ -185,11 +228,14 @@ func RecordConsumeLog(c *gin.Context, userId int, params RecordConsumeLogParams)
 			}
 			return ""
 		}(),
-		Other: otherStr,
+		Other:    otherStr,
+		CostTags: costTagsStr,
 	}
 	err := LOG_DB.Create(log).Error
 	if err != nil {
 		common.LogError(c, "failed to record log: "+err.Error())
+	} else {
+		common.SetContextKey(c, constant.ContextKeyLogId, log.Id)
 	}
 	if common.DataExportEnabled {
 		gopool.Go(func() {
@@ -236,35 +282,87 @@ func GetAllLogs(logType int, startTimestamp int64, endTimestamp int64, modelName
 		return nil, 0, err
 	}
 
+	err = fillLogChannelNames(logs)
+	return logs, total, err
+}
+
+// fillLogChannelNames 批量补全日志的渠道名称
+func fillLogChannelNames(logs []*Log) error {
 	channelIdsMap := make(map[int]struct{})
-	channelMap := make(map[int]string)
 	for _, log := range logs {
 		if log.ChannelId != 0 {
 			channelIdsMap[log.ChannelId] = struct{}{}
 		}
 	}
-
+	if len(channelIdsMap) == 0 {
+		return nil
+	}
 	channelIds := make([]int, 0, len(channelIdsMap))
 	for channelId := range channelIdsMap {
 		channelIds = append(channelIds, channelId)
 	}
-	if len(channelIds) > 0 {
-		var channels []struct {
-			Id   int    `gorm:"column:id"`
-			Name string `gorm:"column:name"`
-		}
-		if err = DB.Table("channels").Select("id, name").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
-			return logs, total, err
-		}
-		for _, channel := range channels {
-			channelMap[channel.Id] = channel.Name
-		}
-		for i := range logs {
-			logs[i].ChannelName = channelMap[logs[i].ChannelId]
-		}
+	var channels []struct {
+		Id   int    `gorm:"column:id"`
+		Name string `gorm:"column:name"`
+	}
+	if err := DB.Table("channels").Select("id, name").Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+		return err
+	}
+	channelMap := make(map[int]string, len(channels))
+	for _, channel := range channels {
+		channelMap[channel.Id] = channel.Name
+	}
+	for i := range logs {
+		logs[i].ChannelName = channelMap[logs[i].ChannelId]
 	}
+	return nil
+}
 
-	return logs, total, err
+// LogExportFilter 日志导出的筛选条件
+type LogExportFilter struct {
+	LogType        int
+	StartTimestamp int64
+	EndTimestamp   int64
+	ModelName      string
+	Username       string
+	TokenName      string
+	Channel        int
+	Group          string
+}
+
+// GetLogsAfterId 按 id 升序读取 cursor 之后的一批日志，用于导出时游标分页
+func GetLogsAfterId(filter *LogExportFilter, cursor int, num int) (logs []*Log, err error) {
+	tx := LOG_DB.Where("logs.id > ?", cursor)
+	if filter.LogType != LogTypeUnknown {
+		tx = tx.Where("logs.type = ?", filter.LogType)
+	}
+	if filter.ModelName != "" {
+		tx = tx.Where("logs.model_name like ?", filter.ModelName)
+	}
+	if filter.Username != "" {
+		tx = tx.Where("logs.username = ?", filter.Username)
+	}
+	if filter.TokenName != "" {
+		tx = tx.Where("logs.token_name = ?", filter.TokenName)
+	}
+	if filter.StartTimestamp != 0 {
+		tx = tx.Where("logs.created_at >= ?", filter.StartTimestamp)
+	}
+	if filter.EndTimestamp != 0 {
+		tx = tx.Where("logs.created_at <= ?", filter.EndTimestamp)
+	}
+	if filter.Channel != 0 {
+		tx = tx.Where("logs.channel_id = ?", filter.Channel)
+	}
+	if filter.Group != "" {
+		tx = tx.Where("logs."+logGroupCol+" = ?", filter.Group)
+	}
+	err = tx.Order("logs.id asc").Limit(num).Find(&logs).Error
+	if err != nil {
+		return nil, err
+	}
+	err = fillLogChannelNames(logs)
+	return logs, err
 }
 
 func GetUserLogs(userId int, logType int, startTimestamp int64, endTimestamp int64, modelName string, tokenName string, startIdx int, num int, group string) (logs []*Log, total int64, err error) {
@@ -407,5 +505,13 @@ func DeleteOldLog(ctx context.Context, targetTimestamp int64, limit int) (int64,
 		}
 	}
 
+	// 日志对应的请求存档一并删除
+	if _, err := DeleteOldBodyArchives(ctx, targetTimestamp, limit); err != nil {
+		return total, err
+	}
+	// 渠道测试记录没有单独的清理入口，按同一时间点清理
+	if _, err := DeleteOldChannelTestResults(ctx, targetTimestamp, limit); err != nil {
+		return total, err
+	}
 	return total, nil
 }
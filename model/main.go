@@ -279,6 +279,12 @@ func migrateDB() error {
 		&Setup{},
 		&TwoFA{},
 		&TwoFABackupCode{},
+		&UpstreamUsage{},
+		&ModelBenchmark{},
+		&ChannelTestResult{},
+		&ChannelKeyUsage{},
+		&QuotaGrantRule{},
+		&UserWebhook{},
 	)
 	if err != nil {
 		return err
@@ -318,6 +324,12 @@ func migrateDBFast() error {
 		{&Setup{}, "Setup"},
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
+		{&UpstreamUsage{}, "UpstreamUsage"},
+		{&ModelBenchmark{}, "ModelBenchmark"},
+		{&ChannelTestResult{}, "ChannelTestResult"},
+		{&ChannelKeyUsage{}, "ChannelKeyUsage"},
+		{&QuotaGrantRule{}, "QuotaGrantRule"},
+		{&UserWebhook{}, "UserWebhook"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -351,6 +363,9 @@ func migrateLOGDB() error {
 	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
 		return err
 	}
+	if err = LOG_DB.AutoMigrate(&BodyArchive{}); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -0,0 +1,43 @@
+package model
+
+// ModelBenchmark 一次基准测试中单个渠道、模型、提示词的运行结果
+type ModelBenchmark struct {
+	Id                int     `json:"id"`
+	RunId             string  `json:"run_id" gorm:"size:64;index"`
+	ChannelId         int     `json:"channel_id" gorm:"index"`
+	ChannelName       string  `json:"channel_name" gorm:"size:128"`
+	ModelName         string  `json:"model_name" gorm:"size:128;index"`
+	PromptIndex       int     `json:"prompt_index"`
+	Success           bool    `json:"success"`
+	ErrorMessage      string  `json:"error_message"`
+	Latency           int64   `json:"latency"`             // 总耗时，毫秒
+	FirstTokenLatency int64   `json:"first_token_latency"` // 首字耗时，毫秒
+	PromptTokens      int     `json:"prompt_tokens"`
+	CompletionTokens  int     `json:"completion_tokens"`
+	TokensPerSecond   float64 `json:"tokens_per_second"` // 首字之后的输出速度
+	Quota             int     `json:"quota"`
+	CreatedTime       int64   `json:"created_time" gorm:"bigint;index"`
+}
+
+func (benchmark *ModelBenchmark) Insert() error {
+	return DB.Create(benchmark).Error
+}
+
+// GetModelBenchmarks 按条件分页查询基准测试结果，最新的在前
+func GetModelBenchmarks(runId string, channelId int, modelName string, startIdx int, num int) (benchmarks []*ModelBenchmark, total int64, err error) {
+	tx := DB.Model(&ModelBenchmark{})
+	if runId != "" {
+		tx = tx.Where("run_id = ?", runId)
+	}
+	if channelId != 0 {
+		tx = tx.Where("channel_id = ?", channelId)
+	}
+	if modelName != "" {
+		tx = tx.Where("model_name = ?", modelName)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&benchmarks).Error
+	return benchmarks, total, err
+}
@@ -27,6 +27,7 @@ type Model struct {
 	Tags        string         `json:"tags,omitempty" gorm:"type:varchar(255)"`
 	VendorID    int            `json:"vendor_id,omitempty" gorm:"index"`
 	Endpoints   string         `json:"endpoints,omitempty" gorm:"type:text"`
+	CostTags    string         `json:"cost_tags,omitempty" gorm:"type:text"` // 成本归属标签，JSON 对象，记录到消费日志中
 	Status      int            `json:"status" gorm:"default:1"`
 	CreatedTime int64          `json:"created_time" gorm:"bigint"`
 	UpdatedTime int64          `json:"updated_time" gorm:"bigint"`
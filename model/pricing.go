@@ -11,6 +11,8 @@ import (
 	"one-api/types"
 	"sync"
 	"time"
+
+	"github.com/samber/lo"
 )
 
 type Pricing struct {
@@ -42,9 +44,11 @@ var (
 	lastGetPricingTime   time.Time
 	updatePricingLock    sync.Mutex
 
-	// 缓存映射：模型名 -> 启用分组 / 计费类型
+	// 缓存映射：模型名 -> 启用分组 / 计费类型 / 成本归属标签 / 元数据标签
 	modelEnableGroups     = make(map[string][]string)
 	modelQuotaTypeMap     = make(map[string]int)
+	modelCostTagsMap      = make(map[string]map[string]string)
+	modelMetaTagsMap      = make(map[string][]string)
 	modelEnableGroupsLock = sync.RWMutex{}
 )
 
@@ -88,6 +92,28 @@ func GetModelSupportEndpointTypes(model string) []constant.EndpointType {
 	return make([]constant.EndpointType, 0)
 }
 
+// GetModelCapabilities 返回模型的能力提示：由支持的端点推断，并合并模型元数据上的标签（如 vision、tools）
+func GetModelCapabilities(modelName string) []string {
+	if time.Since(lastGetPricingTime) > time.Minute*1 || len(pricingMap) == 0 {
+		GetPricing()
+	}
+	capabilities := make([]string, 0)
+	for _, endpointType := range GetModelSupportEndpointTypes(modelName) {
+		switch endpointType {
+		case constant.EndpointTypeOpenAI, constant.EndpointTypeOpenAIResponse, constant.EndpointTypeAnthropic, constant.EndpointTypeGemini:
+			capabilities = append(capabilities, "chat")
+		case constant.EndpointTypeJinaRerank:
+			capabilities = append(capabilities, "rerank")
+		case constant.EndpointTypeImageGeneration:
+			capabilities = append(capabilities, "image_generation")
+		}
+	}
+	modelEnableGroupsLock.RLock()
+	capabilities = append(capabilities, modelMetaTagsMap[modelName]...)
+	modelEnableGroupsLock.RUnlock()
+	return lo.Uniq(capabilities)
+}
+
 func updatePricing() {
 	//modelRatios := common.GetModelRatios()
 	enableAbilities, err := GetAllEnableAbilityWithChannels()
@@ -95,6 +121,10 @@ func updatePricing() {
 		common.SysError(fmt.Sprintf("GetAllEnableAbilityWithChannels error: %v", err))
 		return
 	}
+	// <base>-thinking-* 按基础模型展示，定价取基础模型调用时实际使用的倍率
+	for i := range enableAbilities {
+		enableAbilities[i].Model = ratio_setting.ListedModelName(enableAbilities[i].Model)
+	}
 	// 预加载模型元数据与供应商一次，避免循环查询
 	var allMeta []Model
 	_ = DB.Find(&allMeta).Error
@@ -298,6 +328,18 @@ func updatePricing() {
 		modelEnableGroups[p.ModelName] = p.EnableGroup
 		modelQuotaTypeMap[p.ModelName] = p.QuotaType
 	}
+	modelCostTagsMap = make(map[string]map[string]string)
+	modelMetaTagsMap = make(map[string][]string)
+	for modelName, meta := range metaMap {
+		if costTags := meta.GetCostTags(); len(costTags) > 0 {
+			modelCostTagsMap[modelName] = costTags
+		}
+		for _, tag := range strings.Split(meta.Tags, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				modelMetaTagsMap[modelName] = append(modelMetaTagsMap[modelName], tag)
+			}
+		}
+	}
 	modelEnableGroupsLock.Unlock()
 
 	lastGetPricingTime = time.Now()
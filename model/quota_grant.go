@@ -0,0 +1,245 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"one-api/common"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	QuotaGrantPeriodDaily   = "daily"
+	QuotaGrantPeriodWeekly  = "weekly"
+	QuotaGrantPeriodMonthly = "monthly"
+)
+
+const (
+	QuotaGrantPolicyReset     = "reset"      // 清空未用完的额度，重置为发放额度
+	QuotaGrantPolicyCarryOver = "carry_over" // 保留未用完的额度并累加，可设置结转上限
+)
+
+// QuotaGrantRule 定期额度发放规则：每个周期为指定分组的用户发放额度
+type QuotaGrantRule struct {
+	Id           int    `json:"id"`
+	Name         string `json:"name" gorm:"type:varchar(64);not null"`
+	UserGroup    string `json:"group" gorm:"type:varchar(64);index"`
+	Amount       int    `json:"amount"`
+	Period       string `json:"period" gorm:"type:varchar(16)"`
+	Policy       string `json:"policy" gorm:"type:varchar(16)"`
+	CarryOverCap int    `json:"carry_over_cap"` // 结转上限，0 表示不限制
+	Enabled      bool   `json:"enabled" gorm:"index"`
+	NextRunTime  int64  `json:"next_run_time" gorm:"bigint;index"`
+	LastRunTime  int64  `json:"last_run_time" gorm:"bigint"`
+	LastRunUsers int    `json:"last_run_users"`
+	CreatedTime  int64  `json:"created_time" gorm:"bigint"`
+	UpdatedTime  int64  `json:"updated_time" gorm:"bigint"`
+}
+
+func (r *QuotaGrantRule) Validate() error {
+	if r.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if r.UserGroup == "" {
+		return errors.New("分组不能为空")
+	}
+	if r.Amount <= 0 {
+		return errors.New("发放额度必须大于 0")
+	}
+	if r.CarryOverCap < 0 {
+		return errors.New("结转上限不能为负数")
+	}
+	switch r.Period {
+	case QuotaGrantPeriodDaily, QuotaGrantPeriodWeekly, QuotaGrantPeriodMonthly:
+	default:
+		return fmt.Errorf("不支持的周期：%s", r.Period)
+	}
+	switch r.Policy {
+	case QuotaGrantPolicyReset, QuotaGrantPolicyCarryOver:
+	default:
+		return fmt.Errorf("不支持的发放策略：%s", r.Policy)
+	}
+	return nil
+}
+
+// nextQuotaGrantTime 返回 from 之后下一个周期的开始时间（服务器本地时间 0 点）
+func nextQuotaGrantTime(period string, from time.Time) int64 {
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+	switch period {
+	case QuotaGrantPeriodWeekly:
+		// 每周一发放
+		days := (8 - int(day.Weekday())) % 7
+		if days == 0 {
+			days = 7
+		}
+		return day.AddDate(0, 0, days).Unix()
+	case QuotaGrantPeriodMonthly:
+		return time.Date(from.Year(), from.Month()+1, 1, 0, 0, 0, 0, from.Location()).Unix()
+	default:
+		return day.AddDate(0, 0, 1).Unix()
+	}
+}
+
+func (r *QuotaGrantRule) Insert() error {
+	now := common.GetTimestamp()
+	r.CreatedTime = now
+	r.UpdatedTime = now
+	r.NextRunTime = nextQuotaGrantTime(r.Period, time.Now())
+	return DB.Create(r).Error
+}
+
+// Update 更新规则，周期变化时重新计算下次发放时间
+func (r *QuotaGrantRule) Update() error {
+	var old QuotaGrantRule
+	if err := DB.First(&old, r.Id).Error; err != nil {
+		return err
+	}
+	r.CreatedTime = old.CreatedTime
+	r.LastRunTime = old.LastRunTime
+	r.LastRunUsers = old.LastRunUsers
+	r.NextRunTime = old.NextRunTime
+	if r.Period != old.Period || r.NextRunTime == 0 {
+		r.NextRunTime = nextQuotaGrantTime(r.Period, time.Now())
+	}
+	r.UpdatedTime = common.GetTimestamp()
+	return DB.Save(r).Error
+}
+
+func GetQuotaGrantRuleById(id int) (*QuotaGrantRule, error) {
+	var rule QuotaGrantRule
+	err := DB.First(&rule, id).Error
+	return &rule, err
+}
+
+func GetAllQuotaGrantRules() ([]*QuotaGrantRule, error) {
+	var rules []*QuotaGrantRule
+	err := DB.Order("id desc").Find(&rules).Error
+	return rules, err
+}
+
+func DeleteQuotaGrantRuleById(id int) error {
+	return DB.Delete(&QuotaGrantRule{}, id).Error
+}
+
+// quotaGrantExpr 返回发放后用户额度的 SQL 表达式，基于数据库中的当前额度原子计算，避免覆盖期间发生的消费或充值
+func quotaGrantExpr(rule *QuotaGrantRule) interface{} {
+	if rule.Policy != QuotaGrantPolicyCarryOver {
+		return rule.Amount
+	}
+	if rule.CarryOverCap > 0 {
+		return gorm.Expr("CASE WHEN quota > ? THEN ? ELSE quota END + ?", rule.CarryOverCap, rule.CarryOverCap, rule.Amount)
+	}
+	return gorm.Expr("quota + ?", rule.Amount)
+}
+
+// grantUserQuota 在事务中为单个用户发放额度并记录审计日志，日志库与主库相同时一并提交
+func grantUserQuota(rule *QuotaGrantRule, userId int) error {
+	username, _ := GetUsernameById(userId, false)
+	var log *Log
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var oldQuota, newQuota int
+		if err := tx.Model(&User{}).Select("quota").Where("id = ?", userId).Scan(&oldQuota).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Where("id = ?", userId).Update("quota", quotaGrantExpr(rule)).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&User{}).Select("quota").Where("id = ?", userId).Scan(&newQuota).Error; err != nil {
+			return err
+		}
+		log = &Log{
+			UserId:    userId,
+			Username:  username,
+			CreatedAt: common.GetTimestamp(),
+			Type:      LogTypeSystem,
+			Content:   fmt.Sprintf("定期额度发放「%s」：额度从 %s 变为 %s", rule.Name, common.LogQuota(oldQuota), common.LogQuota(newQuota)),
+		}
+		if LOG_DB == DB {
+			return tx.Create(log).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if LOG_DB != DB {
+		if err := LOG_DB.Create(log).Error; err != nil {
+			common.SysError("failed to record log: " + err.Error())
+		}
+	}
+	// 额度已在数据库中变更，删除缓存让下次读取时重新加载
+	if err := invalidateUserCache(userId); err != nil {
+		common.SysError("failed to invalidate user cache: " + err.Error())
+	}
+	return nil
+}
+
+// ExecuteQuotaGrantRule 为规则分组内的启用用户发放额度，每个用户的变更记录一条系统日志作为审计记录
+func ExecuteQuotaGrantRule(rule *QuotaGrantRule) (int, error) {
+	var userIds []struct {
+		Id int
+	}
+	granted := 0
+	err := DB.Model(&User{}).Select("id").
+		Where(commonGroupCol+" = ? AND status = ?", rule.UserGroup, common.UserStatusEnabled).
+		FindInBatches(&userIds, 500, func(tx *gorm.DB, batch int) error {
+			for _, user := range userIds {
+				if err := grantUserQuota(rule, user.Id); err != nil {
+					return err
+				}
+				granted++
+			}
+			return nil
+		}).Error
+	rule.LastRunTime = common.GetTimestamp()
+	rule.LastRunUsers = granted
+	if updateErr := DB.Model(rule).Select("last_run_time", "last_run_users").Updates(rule).Error; updateErr != nil {
+		common.SysError("failed to update quota grant rule: " + updateErr.Error())
+	}
+	return granted, err
+}
+
+// RunDueQuotaGrantRules 执行所有已到发放时间的规则
+func RunDueQuotaGrantRules() {
+	var rules []*QuotaGrantRule
+	err := DB.Where("enabled = ? AND next_run_time <= ?", true, common.GetTimestamp()).Find(&rules).Error
+	if err != nil {
+		common.SysError("failed to get due quota grant rules: " + err.Error())
+		return
+	}
+	for _, rule := range rules {
+		// 先推进下次发放时间再发放，中途失败也不会在下一轮重复发放
+		nextRunTime := nextQuotaGrantTime(rule.Period, time.Now())
+		result := DB.Model(&QuotaGrantRule{}).Where("id = ? AND next_run_time = ?", rule.Id, rule.NextRunTime).
+			Update("next_run_time", nextRunTime)
+		if result.Error != nil || result.RowsAffected == 0 {
+			continue
+		}
+		rule.NextRunTime = nextRunTime
+		granted, err := ExecuteQuotaGrantRule(rule)
+		if err != nil {
+			common.SysError(fmt.Sprintf("quota grant rule %d failed after %d users: %s", rule.Id, granted, err.Error()))
+			continue
+		}
+		common.SysLog(fmt.Sprintf("quota grant rule %d (%s) granted quota to %d users", rule.Id, rule.Name, granted))
+	}
+}
+
+// AutomaticallyRunQuotaGrants 每分钟检查一次到期的额度发放规则，仅在主节点运行
+func AutomaticallyRunQuotaGrants() {
+	for {
+		runDueQuotaGrantRulesSafely()
+		time.Sleep(time.Minute)
+	}
+}
+
+// runDueQuotaGrantRulesSafely 单轮执行出现 panic 时只跳过本轮，不影响后续发放
+func runDueQuotaGrantRulesSafely() {
+	defer func() {
+		if r := recover(); r != nil {
+			common.SysLog(fmt.Sprintf("AutomaticallyRunQuotaGrants panic: %v", r))
+		}
+	}()
+	RunDueQuotaGrantRules()
+}
@@ -27,6 +27,9 @@ type Token struct {
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
 	EnableGeminiCache  bool           `json:"enable_gemini_cache" gorm:"default:true"`
+	MaxResponseTokens  int            `json:"max_response_tokens" gorm:"default:0"`                  // 单次响应最多输出的 token 数，0 表示不限制
+	MaxResponseBytes   int            `json:"max_response_bytes" gorm:"default:0"`                   // 单次流式响应最多发送的字节数，0 表示不限制
+	AllowedEndpoints   string         `json:"allowed_endpoints" gorm:"type:varchar(255);default:''"` // 允许访问的接口类别，逗号分隔，为空表示不限制
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -185,7 +188,8 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "enable_gemini_cache").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "group", "enable_gemini_cache",
+		"max_response_tokens", "max_response_bytes", "allowed_endpoints").Updates(token).Error
 	return err
 }
 
@@ -239,6 +243,18 @@ func (token *Token) GetModelLimitsMap() map[string]bool {
 	return limitsMap
 }
 
+// GetAllowedEndpoints 返回允许访问的接口类别，为空表示不限制
+func (token *Token) GetAllowedEndpoints() []string {
+	if token.AllowedEndpoints == "" {
+		return []string{}
+	}
+	endpoints := strings.Split(token.AllowedEndpoints, ",")
+	for i := range endpoints {
+		endpoints[i] = strings.TrimSpace(endpoints[i])
+	}
+	return endpoints
+}
+
 func DisableModelLimits(tokenId int) error {
 	token, err := GetTokenById(tokenId)
 	if err != nil {
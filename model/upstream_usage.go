@@ -0,0 +1,186 @@
+package model
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/ratio_setting"
+
+	"gorm.io/gorm"
+)
+
+const (
+	UpstreamUsageProviderOpenAI    = "openai"
+	UpstreamUsageProviderAnthropic = "anthropic"
+	UpstreamUsageProviderGoogle    = "google"
+)
+
+// UpstreamUsageProviderChannelTypes 供应商与网关渠道类型的对应关系，用于对账时筛选日志
+var UpstreamUsageProviderChannelTypes = map[string][]int{
+	UpstreamUsageProviderOpenAI:    {constant.ChannelTypeOpenAI, constant.ChannelTypeAzure},
+	UpstreamUsageProviderAnthropic: {constant.ChannelTypeAnthropic, constant.ChannelTypeAws},
+	UpstreamUsageProviderGoogle:    {constant.ChannelTypeGemini, constant.ChannelTypeVertexAi},
+}
+
+// UpstreamUsage 从供应商控制台导出的用量数据，按天、模型聚合
+type UpstreamUsage struct {
+	Id               int    `json:"id"`
+	Provider         string `json:"provider" gorm:"size:32;index:idx_upstream_usage_day_model,priority:1"`
+	Day              int64  `json:"day" gorm:"bigint;index:idx_upstream_usage_day_model,priority:2"`
+	ModelName        string `json:"model_name" gorm:"size:128;index:idx_upstream_usage_day_model,priority:3"`
+	PromptTokens     int    `json:"prompt_tokens" gorm:"default:0"`
+	CompletionTokens int    `json:"completion_tokens" gorm:"default:0"`
+	Requests         int    `json:"requests" gorm:"default:0"`
+	CreatedTime      int64  `json:"created_time" gorm:"bigint"`
+}
+
+// UsageReconcileItem 单日单模型的对账结果
+type UsageReconcileItem struct {
+	Day                      int64   `json:"day"`
+	ModelName                string  `json:"model_name"`
+	ProviderPromptTokens     int     `json:"provider_prompt_tokens"`
+	ProviderCompletionTokens int     `json:"provider_completion_tokens"`
+	ProviderRequests         int     `json:"provider_requests"`
+	GatewayPromptTokens      int     `json:"gateway_prompt_tokens"`
+	GatewayCompletionTokens  int     `json:"gateway_completion_tokens"`
+	GatewayRequests          int     `json:"gateway_requests"`
+	TokenDiff                int     `json:"token_diff"`
+	DiffRatio                float64 `json:"diff_ratio"`
+	Discrepancy              bool    `json:"discrepancy"`
+}
+
+func IsValidUpstreamUsageProvider(provider string) bool {
+	_, ok := UpstreamUsageProviderChannelTypes[provider]
+	return ok
+}
+
+// ImportUpstreamUsage 导入供应商用量，同一供应商同一天同一模型的旧数据会被覆盖
+func ImportUpstreamUsage(provider string, usages []*UpstreamUsage) error {
+	now := common.GetTimestamp()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		for _, usage := range usages {
+			usage.Provider = provider
+			usage.CreatedTime = now
+			err := tx.Where("provider = ? and day = ? and model_name = ?", provider, usage.Day, usage.ModelName).
+				Delete(&UpstreamUsage{}).Error
+			if err != nil {
+				return err
+			}
+			if err = tx.Create(usage).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetUsageReconciliation 对比供应商账单 token 与网关记录 token，diff 超过 threshold 比例时标记为差异
+func GetUsageReconciliation(provider string, startTimestamp int64, endTimestamp int64, threshold float64) ([]*UsageReconcileItem, error) {
+	var upstream []*UpstreamUsage
+	tx := DB.Where("provider = ?", provider)
+	if startTimestamp != 0 {
+		tx = tx.Where("day >= ?", startTimestamp-startTimestamp%86400)
+	}
+	if endTimestamp != 0 {
+		tx = tx.Where("day <= ?", endTimestamp)
+	}
+	if err := tx.Order("day asc").Find(&upstream).Error; err != nil {
+		return nil, err
+	}
+
+	var channels []*Channel
+	err := DB.Select("id", "model_mapping").Where("type IN ?", UpstreamUsageProviderChannelTypes[provider]).Find(&channels).Error
+	if err != nil {
+		return nil, err
+	}
+	channelIds := make([]int, 0, len(channels))
+	channelModelMappings := make(map[int]map[string]string, len(channels))
+	for _, channel := range channels {
+		channelIds = append(channelIds, channel.Id)
+		modelMapping := make(map[string]string)
+		if mapping := channel.GetModelMapping(); mapping != "" {
+			_ = common.UnmarshalJsonStr(mapping, &modelMapping)
+		}
+		channelModelMappings[channel.Id] = modelMapping
+	}
+
+	var gateway []struct {
+		Day              int64  `gorm:"column:day"`
+		ModelName        string `gorm:"column:model_name"`
+		ChannelId        int    `gorm:"column:channel_id"`
+		PromptTokens     int    `gorm:"column:prompt_tokens"`
+		CompletionTokens int    `gorm:"column:completion_tokens"`
+		Requests         int    `gorm:"column:requests"`
+	}
+	if len(channelIds) > 0 {
+		// 日志记录的是客户端请求的模型名，按渠道分组后再换算为上游模型名
+		logTx := LOG_DB.Table("logs").
+			Select("(created_at - created_at % 86400) as day, model_name, channel_id, sum(prompt_tokens) as prompt_tokens, sum(completion_tokens) as completion_tokens, count(*) as requests").
+			Where("type = ? and channel_id IN ?", LogTypeConsume, channelIds)
+		if startTimestamp != 0 {
+			logTx = logTx.Where("created_at >= ?", startTimestamp-startTimestamp%86400)
+		}
+		if endTimestamp != 0 {
+			logTx = logTx.Where("created_at <= ?", endTimestamp)
+		}
+		if err = logTx.Group("day, model_name, channel_id").Scan(&gateway).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	items := make([]*UsageReconcileItem, 0, len(upstream))
+	itemMap := make(map[string]*UsageReconcileItem)
+	getItem := func(day int64, modelName string) *UsageReconcileItem {
+		key := fmt.Sprintf("%d|%s", day, modelName)
+		item, ok := itemMap[key]
+		if !ok {
+			item = &UsageReconcileItem{Day: day, ModelName: modelName}
+			itemMap[key] = item
+			items = append(items, item)
+		}
+		return item
+	}
+	for _, usage := range upstream {
+		item := getItem(usage.Day, usage.ModelName)
+		item.ProviderPromptTokens += usage.PromptTokens
+		item.ProviderCompletionTokens += usage.CompletionTokens
+		item.ProviderRequests += usage.Requests
+	}
+	for _, row := range gateway {
+		item := getItem(row.Day, reconcileUpstreamModelName(channelModelMappings[row.ChannelId], row.ModelName))
+		item.GatewayPromptTokens += row.PromptTokens
+		item.GatewayCompletionTokens += row.CompletionTokens
+		item.GatewayRequests += row.Requests
+	}
+
+	for _, item := range items {
+		providerTotal := item.ProviderPromptTokens + item.ProviderCompletionTokens
+		gatewayTotal := item.GatewayPromptTokens + item.GatewayCompletionTokens
+		item.TokenDiff = providerTotal - gatewayTotal
+		if providerTotal > 0 {
+			item.DiffRatio = float64(item.TokenDiff) / float64(providerTotal)
+		} else if gatewayTotal > 0 {
+			item.DiffRatio = -1
+		}
+		diffRatio := item.DiffRatio
+		if diffRatio < 0 {
+			diffRatio = -diffRatio
+		}
+		item.Discrepancy = diffRatio > threshold
+	}
+	return items, nil
+}
+
+// reconcileUpstreamModelName 按渠道的模型重定向（支持链式）与思考后缀换算出实际请求上游的模型名，与供应商账单中的模型名对应
+func reconcileUpstreamModelName(modelMapping map[string]string, modelName string) string {
+	visited := map[string]bool{modelName: true}
+	for {
+		mapped, ok := modelMapping[modelName]
+		if !ok || mapped == "" || visited[mapped] {
+			break
+		}
+		visited[mapped] = true
+		modelName = mapped
+	}
+	return ratio_setting.ParseModelVariant(modelName).BaseModel
+}
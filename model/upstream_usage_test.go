@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestGetUsageReconciliationGroupsByUpstreamModel(t *testing.T) {
+	setupBreakerTestDB(t)
+	oldLogDB := LOG_DB
+	LOG_DB = DB
+	t.Cleanup(func() { LOG_DB = oldLogDB })
+	if err := DB.AutoMigrate(&Log{}, &UpstreamUsage{}); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	mapping := `{"gpt-4o-mini":"gpt-4o-mini-2024-07-18"}`
+	if err := DB.Model(&Channel{}).Where("id = ?", 1).Update("model_mapping", mapping).Error; err != nil {
+		t.Fatalf("update channel: %v", err)
+	}
+
+	const day = int64(1700006400)
+	logs := []*Log{
+		{CreatedAt: day + 10, Type: LogTypeConsume, ModelName: breakerTestModel, ChannelId: 1, PromptTokens: 100, CompletionTokens: 10},
+		{CreatedAt: day + 20, Type: LogTypeConsume, ModelName: breakerTestModel, ChannelId: 2, PromptTokens: 50, CompletionTokens: 5},
+	}
+	for _, log := range logs {
+		if err := DB.Create(log).Error; err != nil {
+			t.Fatalf("create log: %v", err)
+		}
+	}
+	err := ImportUpstreamUsage(UpstreamUsageProviderOpenAI, []*UpstreamUsage{
+		{Day: day, ModelName: "gpt-4o-mini-2024-07-18", PromptTokens: 100, CompletionTokens: 10, Requests: 1},
+	})
+	if err != nil {
+		t.Fatalf("import usage: %v", err)
+	}
+
+	items, err := GetUsageReconciliation(UpstreamUsageProviderOpenAI, day, day+86399, 0.05)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	got := make(map[string]*UsageReconcileItem)
+	for _, item := range items {
+		got[item.ModelName] = item
+	}
+	mapped := got["gpt-4o-mini-2024-07-18"]
+	if mapped == nil || mapped.GatewayPromptTokens != 100 || mapped.Discrepancy {
+		t.Fatalf("mapped channel usage should match the upstream model, got %+v", mapped)
+	}
+	if unmapped := got[breakerTestModel]; unmapped == nil || unmapped.GatewayPromptTokens != 50 {
+		t.Fatalf("unmapped channel usage should keep the requested model, got %+v", unmapped)
+	}
+}
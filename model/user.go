@@ -44,6 +44,7 @@ type User struct {
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
 	Remark           string         `json:"remark,omitempty" gorm:"type:varchar(255)" validate:"max=255"`
 	StripeCustomer   string         `json:"stripe_customer" gorm:"type:varchar(64);column:stripe_customer;index"`
+	PendingQuota     int            `json:"pending_quota,omitempty" gorm:"-:all"` // 进行中的请求预扣且尚未结算的额度，仅查询自身信息时返回
 }
 
 func (user *User) ToBaseUser() *UserBase {
@@ -0,0 +1,190 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"one-api/common"
+	"one-api/dto"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 每个用户最多可注册的 webhook 数量
+const userWebhookMaxPerUser = 10
+
+// UserWebhook 用户为自己账户注册的 webhook，按订阅的事件推送
+type UserWebhook struct {
+	Id                int    `json:"id"`
+	UserId            int    `json:"user_id" gorm:"index"`
+	Name              string `json:"name" gorm:"type:varchar(64)"`
+	Url               string `json:"url" gorm:"type:varchar(512);not null"`
+	Secret            string `json:"secret" gorm:"type:varchar(128)"`
+	Events            string `json:"events" gorm:"type:varchar(255)"` // 订阅的事件，逗号分隔
+	QuotaThreshold    int    `json:"quota_threshold"`                 // quota_below 事件的额度阈值
+	Enabled           bool   `json:"enabled" gorm:"index"`
+	LastDeliveryTime  int64  `json:"last_delivery_time" gorm:"bigint"`
+	LastDeliveryError string `json:"last_delivery_error" gorm:"type:varchar(255)"`
+	CreatedTime       int64  `json:"created_time" gorm:"bigint"`
+	UpdatedTime       int64  `json:"updated_time" gorm:"bigint"`
+}
+
+func (w *UserWebhook) Validate() error {
+	if w.Url == "" {
+		return errors.New("Webhook地址不能为空")
+	}
+	parsed, err := url.ParseRequestURI(w.Url)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return errors.New("无效的Webhook地址")
+	}
+	events := w.GetEvents()
+	if len(events) == 0 {
+		return errors.New("至少需要订阅一个事件")
+	}
+	for _, event := range events {
+		if !dto.IsUserWebhookEvent(event) {
+			return fmt.Errorf("不支持的事件：%s", event)
+		}
+		if event == dto.UserWebhookEventQuotaBelow && w.QuotaThreshold <= 0 {
+			return errors.New("额度阈值必须大于0")
+		}
+	}
+	return nil
+}
+
+func (w *UserWebhook) GetEvents() []string {
+	events := make([]string, 0)
+	for _, event := range strings.Split(w.Events, ",") {
+		event = strings.TrimSpace(event)
+		if event != "" {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (w *UserWebhook) HasEvent(event string) bool {
+	for _, e := range w.GetEvents() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *UserWebhook) Insert() error {
+	var count int64
+	if err := DB.Model(&UserWebhook{}).Where("user_id = ?", w.UserId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count >= userWebhookMaxPerUser {
+		return fmt.Errorf("每个用户最多注册 %d 个 webhook", userWebhookMaxPerUser)
+	}
+	now := common.GetTimestamp()
+	w.CreatedTime = now
+	w.UpdatedTime = now
+	err := DB.Create(w).Error
+	invalidateUserWebhookCache(w.UserId)
+	return err
+}
+
+// Update 更新 webhook，只能更新属于该用户的记录
+func (w *UserWebhook) Update() error {
+	w.UpdatedTime = common.GetTimestamp()
+	result := DB.Model(&UserWebhook{}).Where("id = ? AND user_id = ?", w.Id, w.UserId).
+		Select("name", "url", "secret", "events", "quota_threshold", "enabled", "updated_time").Updates(w)
+	invalidateUserWebhookCache(w.UserId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("webhook 不存在")
+	}
+	return nil
+}
+
+func GetUserWebhookById(id int, userId int) (*UserWebhook, error) {
+	var webhook UserWebhook
+	err := DB.Where("id = ? AND user_id = ?", id, userId).First(&webhook).Error
+	return &webhook, err
+}
+
+func GetUserWebhooks(userId int) ([]*UserWebhook, error) {
+	var webhooks []*UserWebhook
+	err := DB.Where("user_id = ?", userId).Order("id desc").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func DeleteUserWebhookById(id int, userId int) error {
+	result := DB.Where("id = ? AND user_id = ?", id, userId).Delete(&UserWebhook{})
+	invalidateUserWebhookCache(userId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("webhook 不存在")
+	}
+	return nil
+}
+
+// UpdateUserWebhookDelivery 记录最近一次投递的时间与错误
+func UpdateUserWebhookDelivery(id int, deliveryErr error) {
+	errMsg := ""
+	if deliveryErr != nil {
+		errMsg = deliveryErr.Error()
+		if len(errMsg) > 255 {
+			errMsg = errMsg[:255]
+		}
+	}
+	err := DB.Model(&UserWebhook{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_delivery_time":  common.GetTimestamp(),
+		"last_delivery_error": errMsg,
+	}).Error
+	if err != nil {
+		common.SysError("failed to update user webhook delivery: " + err.Error())
+	}
+}
+
+// 用户已启用 webhook 的进程内缓存，避免每次请求都查库；多节点下最多延迟一个 TTL 生效
+const userWebhookCacheTTL = time.Minute
+
+type userWebhookCacheEntry struct {
+	webhooks  []*UserWebhook
+	expiresAt time.Time
+}
+
+var (
+	userWebhookCache     = make(map[int]userWebhookCacheEntry)
+	userWebhookCacheLock sync.RWMutex
+)
+
+func invalidateUserWebhookCache(userId int) {
+	userWebhookCacheLock.Lock()
+	delete(userWebhookCache, userId)
+	userWebhookCacheLock.Unlock()
+}
+
+// GetEnabledUserWebhooksByEvent 获取用户已启用且订阅了指定事件的 webhook
+func GetEnabledUserWebhooksByEvent(userId int, event string) ([]*UserWebhook, error) {
+	userWebhookCacheLock.RLock()
+	entry, ok := userWebhookCache[userId]
+	userWebhookCacheLock.RUnlock()
+	if !ok || time.Now().After(entry.expiresAt) {
+		var webhooks []*UserWebhook
+		if err := DB.Where("user_id = ? AND enabled = ?", userId, true).Find(&webhooks).Error; err != nil {
+			return nil, err
+		}
+		entry = userWebhookCacheEntry{webhooks: webhooks, expiresAt: time.Now().Add(userWebhookCacheTTL)}
+		userWebhookCacheLock.Lock()
+		userWebhookCache[userId] = entry
+		userWebhookCacheLock.Unlock()
+	}
+	matched := make([]*UserWebhook, 0)
+	for _, webhook := range entry.webhooks {
+		if webhook.HasEvent(event) {
+			matched = append(matched, webhook)
+		}
+	}
+	return matched, nil
+}
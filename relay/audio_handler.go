@@ -101,6 +101,11 @@ func AudioHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 
 	ioReader, err := adaptor.ConvertAudioRequest(c, relayInfo, *audioRequest)
 	if err != nil {
+		// 适配器已给出具体状态码（如不支持的 response_format 返回 400）时原样返回
+		var apiErr *types.NewAPIError
+		if errors.As(err, &apiErr) {
+			return apiErr
+		}
 		return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
 	}
 
@@ -29,6 +29,11 @@ type Adaptor interface {
 	ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error)
 }
 
+// TestRequestTemplater 可选实现，渠道测试时补充供应商要求的字段或替换默认的测试请求
+type TestRequestTemplater interface {
+	ApplyTestRequestTemplate(request *dto.GeneralOpenAIRequest)
+}
+
 type TaskAdaptor interface {
 	Init(info *relaycommon.TaskRelayInfo)
 
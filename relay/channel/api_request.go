@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	common2 "one-api/common"
+	constant2 "one-api/constant"
 	"one-api/relay/common"
 	"one-api/relay/constant"
 	"one-api/relay/helper"
@@ -249,9 +250,31 @@ func doRequest(c *gin.Context, req *http.Request, info *common.RelayInfo) (*http
 
 	_ = req.Body.Close()
 	_ = c.Request.Body.Close()
+	passThroughUpstreamHeaders(c, resp)
 	return resp, nil
 }
 
+// passThroughUpstreamHeaders 将白名单中的上游响应头（限流、处理耗时等）透传给客户端，
+// 先清除之前请求透传的响应头，重试后客户端只收到最终响应渠道的响应头。
+// 流式请求已发送过 ping 时响应头已写出，无法再透传
+func passThroughUpstreamHeaders(c *gin.Context, resp *http.Response) {
+	if c.Writer.Written() {
+		return
+	}
+	previous, _ := common2.GetContextKeyType[[]string](c, constant2.ContextKeyPassedThroughHeaders)
+	for _, k := range previous {
+		c.Writer.Header().Del(k)
+	}
+	passed := make([]string, 0)
+	for k, v := range resp.Header {
+		if operation_setting.ShouldPassThroughHeader(k) {
+			c.Writer.Header()[k] = v
+			passed = append(passed, k)
+		}
+	}
+	common2.SetContextKey(c, constant2.ContextKeyPassedThroughHeaders, passed)
+}
+
 func DoTaskApiRequest(a TaskAdaptor, c *gin.Context, info *common.TaskRelayInfo, requestBody io.Reader) (*http.Response, error) {
 	fullRequestURL, err := a.BuildRequestURL(info)
 	if err != nil {
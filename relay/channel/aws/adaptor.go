@@ -28,6 +28,7 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	claude.ApplyClaudePromptCache(c, info, request)
 	c.Set("request_model", request.Model)
 	c.Set("converted_request", request)
 	return request, nil
@@ -67,6 +68,7 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if err != nil {
 		return nil, err
 	}
+	claude.ApplyClaudePromptCache(c, info, claudeReq)
 	c.Set("request_model", claudeReq.Model)
 	c.Set("converted_request", claudeReq)
 	return claudeReq, err
@@ -24,9 +24,7 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertClaudeRequest(*gin.Context, *relaycommon.RelayInfo, *dto.ClaudeRequest) (any, error) {
-	//TODO implement me
-	panic("implement me")
-	return nil, nil
+	return nil, errors.New("not implemented")
 }
 
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
@@ -39,6 +37,13 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 	return nil, errors.New("not implemented")
 }
 
+// ApplyTestRequestTemplate 百度要求请求携带 user_id
+func (a *Adaptor) ApplyTestRequestTemplate(request *dto.GeneralOpenAIRequest) {
+	if request.User == "" {
+		request.User = "channel-test"
+	}
+}
+
 func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 
 }
@@ -30,6 +30,7 @@ func (a *Adaptor) ConvertGeminiRequest(*gin.Context, *relaycommon.RelayInfo, *dt
 }
 
 func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) (any, error) {
+	ApplyClaudePromptCache(c, info, request)
 	return request, nil
 }
 
@@ -78,7 +79,12 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 	if a.RequestMode == RequestModeCompletion {
 		return RequestOpenAI2ClaudeComplete(*request), nil
 	} else {
-		return RequestOpenAI2ClaudeMessage(*request)
+		claudeRequest, err := RequestOpenAI2ClaudeMessage(*request)
+		if err != nil {
+			return nil, err
+		}
+		ApplyClaudePromptCache(c, info, claudeRequest)
+		return claudeRequest, nil
 	}
 }
 
@@ -0,0 +1,130 @@
+package claude
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/service/promptcache"
+	"one-api/setting/model_setting"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Anthropic ephemeral 缓存默认存活 5 分钟，命中后会刷新
+const ClaudePromptCacheTTL = 5 * time.Minute
+
+var claudeEphemeralCacheControl = json.RawMessage(`{"type":"ephemeral"}`)
+
+// Anthropic 的缓存无需显式创建，这里只跟踪前缀在各渠道上的复用情况
+var claudePromptCache = promptcache.NewRedisPromptCache(promptcache.ProviderAnthropic, "claude_cache", ClaudePromptCacheTTL)
+
+func init() {
+	claudePromptCache.RefreshOnHit = true
+	promptcache.Register(promptcache.ProviderAnthropic, claudePromptCache)
+}
+
+// ApplyClaudePromptCache 为较长的工具定义与 system prompt 注入 cache_control 断点，
+// Anthropic、AWS Bedrock 与 Vertex AI 的 Claude 渠道共用；其他供应商不支持 cache_control，不做注入。
+// 客户端已自行设置 cache_control 时不做改动，避免超过上游 4 个断点的限制。
+func ApplyClaudePromptCache(c *gin.Context, info *relaycommon.RelayInfo, request *dto.ClaudeRequest) {
+	settings := model_setting.GetClaudeSettings()
+	if !settings.PromptCacheEnabled || request == nil {
+		return
+	}
+	// 直接检查客户端原始请求体，避免每次请求重新序列化
+	if body, err := common.GetRequestBody(c); err != nil || bytes.Contains(body, []byte(`"cache_control"`)) {
+		return
+	}
+
+	injected := false
+	// 缓存前缀顺序为 tools -> system -> messages，先处理 tools
+	if tools := toClaudeCacheBlocks(request.Tools); len(tools) > 0 {
+		if countClaudeCacheTokens(tools, info.UpstreamModelName) >= settings.PromptCacheMinTokens {
+			tools[len(tools)-1]["cache_control"] = claudeEphemeralCacheControl
+			request.Tools = tools
+			injected = true
+		}
+	}
+
+	switch system := request.System.(type) {
+	case string:
+		if system != "" && service.CountTextToken(system, info.UpstreamModelName) >= settings.PromptCacheMinTokens {
+			block := dto.ClaudeMediaMessage{
+				Type:         "text",
+				CacheControl: claudeEphemeralCacheControl,
+			}
+			block.SetText(system)
+			request.System = []dto.ClaudeMediaMessage{block}
+			injected = true
+		}
+	case nil:
+	default:
+		if blocks := toClaudeCacheBlocks(system); len(blocks) > 0 &&
+			countClaudeCacheTokens(blocks, info.UpstreamModelName) >= settings.PromptCacheMinTokens {
+			blocks[len(blocks)-1]["cache_control"] = claudeEphemeralCacheControl
+			request.System = blocks
+			injected = true
+		}
+	}
+
+	if injected {
+		trackClaudePromptCache(info, request)
+	}
+}
+
+// trackClaudePromptCache 在 Redis 中记录缓存前缀对应的渠道与复用次数，结果写入消费日志，汇总统计见 /api/cache/stats?provider=anthropic
+func trackClaudePromptCache(info *relaycommon.RelayInfo, request *dto.ClaudeRequest) {
+	result, err := claudePromptCache.GetOrCreate(&promptcache.Request{
+		ChannelID: info.ChannelId,
+		Model:     info.UpstreamModelName,
+		Hashes:    []string{HashClaudeCachePrefix(info.UpstreamModelName, request)},
+	})
+	if err != nil || result == nil {
+		return
+	}
+	info.ClaudePromptCacheTracked = true
+	info.ClaudePromptCacheHits = result.Hits
+	if !result.IsJustCreated {
+		// 本次请求也是一次复用
+		info.ClaudePromptCacheHits++
+	}
+	if common.DebugEnabled {
+		common.SysLog(fmt.Sprintf("Claude prompt cache tracked: %s, just created: %t, hits: %d", result.Hash, result.IsJustCreated, result.Hits))
+	}
+}
+
+// HashClaudeCachePrefix 以模型、tools 与 system 计算缓存前缀的 hash
+func HashClaudeCachePrefix(model string, request *dto.ClaudeRequest) string {
+	tools, _ := common.Marshal(request.Tools)
+	system, _ := common.Marshal(request.System)
+	return common.GetMD5Hash(model + "|" + string(tools) + "|" + string(system))
+}
+
+// toClaudeCacheBlocks 把 tools / system 数组统一转换为可修改的 map 列表
+func toClaudeCacheBlocks(v any) []map[string]any {
+	if v == nil {
+		return nil
+	}
+	data, err := common.Marshal(v)
+	if err != nil || len(data) == 0 || data[0] != '[' {
+		return nil
+	}
+	var blocks []map[string]any
+	if err = common.Unmarshal(data, &blocks); err != nil {
+		return nil
+	}
+	return blocks
+}
+
+func countClaudeCacheTokens(blocks []map[string]any, model string) int {
+	data, err := common.Marshal(blocks)
+	if err != nil {
+		return 0
+	}
+	return service.CountTextToken(string(data), model)
+}
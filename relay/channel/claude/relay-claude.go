@@ -605,6 +605,13 @@ func FormatClaudeResponseInfo(requestMode int, claudeResponse *dto.ClaudeRespons
 	return true
 }
 
+// includeClaudeCachePromptTokens Claude 的 input_tokens 不含缓存读写部分，
+// 转为 OpenAI 格式时需要加回，与 prompt_tokens 包含 cached_tokens 的语义保持一致
+func includeClaudeCachePromptTokens(usage *dto.Usage) {
+	usage.PromptTokens += usage.PromptTokensDetails.CachedTokens + usage.PromptTokensDetails.CachedCreationTokens
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+}
+
 func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claudeInfo *ClaudeResponseInfo, data string, requestMode int) *types.NewAPIError {
 	var claudeResponse dto.ClaudeResponse
 	err := common.UnmarshalJsonStr(data, &claudeResponse)
@@ -635,6 +642,7 @@ func HandleStreamResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 			return nil
 		}
 
+		helper.RepairJsonStreamResponse(info, response)
 		err = helper.ObjectData(c, response)
 		if err != nil {
 			common.LogError(c, "send_stream_response_failed: "+err.Error())
@@ -662,7 +670,7 @@ func HandleStreamFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, clau
 	if info.RelayFormat == relaycommon.RelayFormatClaude {
 		//
 	} else if info.RelayFormat == relaycommon.RelayFormatOpenAI {
-
+		includeClaudeCachePromptTokens(claudeInfo.Usage)
 		if info.ShouldIncludeUsage {
 			response := helper.GenerateFinalUsageResponse(claudeInfo.ResponseId, claudeInfo.Created, info.UpstreamModelName, *claudeInfo.Usage)
 			err := helper.ObjectData(c, response)
@@ -723,6 +731,7 @@ func HandleClaudeResponseData(c *gin.Context, info *relaycommon.RelayInfo, claud
 	switch info.RelayFormat {
 	case relaycommon.RelayFormatOpenAI:
 		openaiResponse := ResponseClaude2OpenAI(requestMode, &claudeResponse)
+		includeClaudeCachePromptTokens(claudeInfo.Usage)
 		openaiResponse.Usage = *claudeInfo.Usage
 		responseData, err = json.Marshal(openaiResponse)
 		if err != nil {
@@ -1,23 +1,28 @@
 package gemini
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"one-api/common"
 	"one-api/dto"
 	"one-api/relay/channel"
 	"one-api/relay/channel/openai"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/constant"
 	"one-api/setting/model_setting"
+	"one-api/setting/operation_setting"
 	"one-api/types"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/samber/lo"
 )
 
 type Adaptor struct {
+	ResponseFormat string
 }
 
 func (a *Adaptor) ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error) {
@@ -46,28 +51,55 @@ func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayIn
 	if err != nil {
 		return nil, err
 	}
-	return a.ConvertOpenAIRequest(c, info, oaiReq.(*dto.GeneralOpenAIRequest))
+	geminiRequest, err := ConvertGemini2OpenAI(c, *oaiReq.(*dto.GeneralOpenAIRequest), info)
+	if err != nil {
+		return nil, err
+	}
+	applyClaudeRequestOptions(geminiRequest, req, info)
+	return geminiRequest, nil
 }
 
 func (a *Adaptor) ConvertAudioRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (io.Reader, error) {
-	//TODO implement me
-	return nil, errors.New("not implemented")
+	a.ResponseFormat = request.ResponseFormat
+	var geminiRequest *dto.GeminiChatRequest
+	var err error
+	if info.RelayMode == constant.RelayModeAudioSpeech {
+		geminiRequest, err = convertTTSRequest(request)
+	} else {
+		geminiRequest, err = convertSTTRequest(c, info, request)
+	}
+	if err != nil {
+		return nil, err
+	}
+	jsonData, err := common.Marshal(geminiRequest)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling object: %w", err)
+	}
+	return bytes.NewReader(jsonData), nil
 }
 
 func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.ImageRequest) (any, error) {
 	if !strings.HasPrefix(info.UpstreamModelName, "imagen") {
 		return nil, errors.New("not supported model for image generation")
 	}
+	if request.ResponseFormat == "url" && !operation_setting.GetImageStorageSetting().IsEnabled() {
+		return nil, errors.New("response_format url is not supported, image storage is not configured")
+	}
+	a.ResponseFormat = request.ResponseFormat
 
-	// convert size to aspect ratio
-	aspectRatio := "1:1" // default aspect ratio
-	switch request.Size {
-	case "1024x1024":
-		aspectRatio = "1:1"
-	case "1024x1792":
-		aspectRatio = "9:16"
-	case "1792x1024":
-		aspectRatio = "16:9"
+	// OpenAI 请求之外的 Imagen 扩展字段
+	var extension imagenRequestExtension
+	if err := common.UnmarshalBodyReusable(c, &extension); err != nil {
+		return nil, err
+	}
+
+	aspectRatio, err := imagenAspectRatio(request.Size, extension.AspectRatio)
+	if err != nil {
+		return nil, err
+	}
+	personGeneration, err := imagenPersonGeneration(extension.PersonGeneration)
+	if err != nil {
+		return nil, err
 	}
 
 	// build gemini imagen request
@@ -80,7 +112,9 @@ func (a *Adaptor) ConvertImageRequest(c *gin.Context, info *relaycommon.RelayInf
 		Parameters: dto.GeminiImageParameters{
 			SampleCount:      request.N,
 			AspectRatio:      aspectRatio,
-			PersonGeneration: "allow_adult", // default allow adult
+			PersonGeneration: personGeneration,
+			NegativePrompt:   extension.NegativePrompt,
+			Seed:             extension.Seed,
 		},
 	}
 
@@ -93,17 +127,8 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 
-	if model_setting.GetGeminiSettings().ThinkingAdapterEnabled {
-		// 新增逻辑：处理 -thinking-<budget> 格式
-		if strings.Contains(info.UpstreamModelName, "-thinking-") {
-			parts := strings.Split(info.UpstreamModelName, "-thinking-")
-			info.UpstreamModelName = parts[0]
-		} else if strings.HasSuffix(info.UpstreamModelName, "-thinking") { // 旧的适配
-			info.UpstreamModelName = strings.TrimSuffix(info.UpstreamModelName, "-thinking")
-		} else if strings.HasSuffix(info.UpstreamModelName, "-nothinking") {
-			info.UpstreamModelName = strings.TrimSuffix(info.UpstreamModelName, "-nothinking")
-		}
-	}
+	// 去掉 -search 与思考后缀，搜索工具与思考参数已在转换请求时设置
+	info.UpstreamModelName = ThinkingVariantBaseModel(info.UpstreamModelName)
 
 	version := model_setting.GetGeminiVersionSetting(info.UpstreamModelName)
 
@@ -133,6 +158,10 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
 	channel.SetupApiRequestHeader(info, c, req)
+	if info.RelayMode == constant.RelayModeAudioTranscription || info.RelayMode == constant.RelayModeAudioTranslation {
+		// 音频文件已转为内联数据，以 JSON 发送
+		req.Set("Content-Type", "application/json")
+	}
 	req.Set("x-goog-api-key", info.ApiKey)
 	return nil
 }
@@ -151,7 +180,7 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 }
 
 func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
-	return nil, nil
+	return nil, errors.New("not implemented: rerank is not supported by gemini channels")
 }
 
 func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
@@ -163,9 +192,26 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 	if len(inputs) == 0 {
 		return nil, errors.New("input is empty")
 	}
+	// token 数组等非字符串输入 Gemini 无法处理，直接报错而不是丢弃
+	if items, ok := request.Input.([]any); ok && len(items) != len(inputs) {
+		return nil, errors.New("gemini embedding only supports string inputs")
+	}
+	// 只有支持 outputDimensionality 的模型才传递 dimensions，其余模型忽略该参数
+	outputDimensionality := 0
+	if request.Dimensions > 0 {
+		maxDimensions := model_setting.GetGeminiEmbeddingMaxDimensions(info.UpstreamModelName)
+		if maxDimensions > 0 {
+			if request.Dimensions > maxDimensions {
+				return nil, fmt.Errorf("dimensions %d exceeds the maximum %d of model %s", request.Dimensions, maxDimensions, info.UpstreamModelName)
+			}
+			outputDimensionality = request.Dimensions
+		}
+	}
 	// We always build a batch-style payload with `requests`, so ensure we call the
 	// batch endpoint upstream to avoid payload/endpoint mismatches.
 	info.IsGeminiBatchEmbedding = true
+	// 上游未返回 usageMetadata 时用于 countTokens 计费
+	c.Set(geminiEmbeddingInputsKey, inputs)
 	// process all inputs
 	geminiRequests := make([]map[string]interface{}, 0, len(inputs))
 	for _, input := range inputs {
@@ -179,27 +225,25 @@ func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.Rela
 				},
 			},
 		}
-
-		// set specific parameters for different models
 		// https://ai.google.dev/api/embeddings?hl=zh-cn#method:-models.embedcontent
-		switch info.UpstreamModelName {
-		case "text-embedding-004", "gemini-embedding-exp-03-07", "gemini-embedding-001":
-			// Only newer models introduced after 2024 support OutputDimensionality
-			if request.Dimensions > 0 {
-				geminiRequest["outputDimensionality"] = request.Dimensions
-			}
+		if outputDimensionality > 0 {
+			geminiRequest["outputDimensionality"] = outputDimensionality
 		}
 		geminiRequests = append(geminiRequests, geminiRequest)
 	}
 
+	// batchEmbedContents 单次请求数有上限，超出部分拆分为多个分片，由 GeminiEmbeddingHandler 依次请求并按顺序合并
+	chunks := lo.Chunk(geminiRequests, geminiBatchEmbeddingMaxInputs)
+	if len(chunks) > 1 {
+		c.Set(geminiEmbeddingChunksKey, chunks[1:])
+	}
 	return map[string]interface{}{
-		"requests": geminiRequests,
+		"requests": chunks[0],
 	}, nil
 }
 
 func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.OpenAIResponsesRequest) (any, error) {
-	// TODO implement me
-	return nil, errors.New("not implemented")
+	return ConvertResponsesRequest(c, request, info)
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
@@ -219,8 +263,22 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 		}
 	}
 
+	switch info.RelayMode {
+	case constant.RelayModeAudioSpeech:
+		return GeminiTTSHandler(c, info, resp, a.ResponseFormat)
+	case constant.RelayModeAudioTranscription, constant.RelayModeAudioTranslation:
+		return GeminiSTTHandler(c, info, resp, a.ResponseFormat)
+	}
+
+	if info.RelayMode == constant.RelayModeResponses {
+		if info.IsStream {
+			return GeminiResponsesStreamHandler(c, info, resp)
+		}
+		return GeminiResponsesHandler(c, info, resp)
+	}
+
 	if strings.HasPrefix(info.UpstreamModelName, "imagen") {
-		return GeminiImageHandler(c, info, resp)
+		return GeminiImageHandler(c, info, resp, a.ResponseFormat)
 	}
 
 	// check if the model is an embedding model
@@ -3,117 +3,373 @@ package gemini
 import (
 	"encoding/json"
 	"fmt"
-	"context"
-	"time"
 	"net/http"
-	"one-api/dto"
+	"net/url"
 	"one-api/common"
+	"one-api/dto"
 	"one-api/service"
+	"one-api/service/promptcache"
 	"one-api/setting/model_setting"
 	"strings"
+	"time"
 )
 
-const GeminiCacheMinTokenThreshold = 4096
-
 func ShouldEnableGeminiCache(model string, tokenCount int) bool {
 	settings := model_setting.GetGeminiSettings()
 	if !settings.EnableCache {
 		return false
 	}
 
-	if tokenCount < GeminiCacheMinTokenThreshold {
-		common.SysLog(fmt.Sprintf("Skipping cache creation: token count %d < %d", tokenCount, GeminiCacheMinTokenThreshold))
+	minTokens := model_setting.GetGeminiCacheMinTokens(model)
+	if tokenCount < minTokens {
+		common.SysLogSampled(common.LogCategoryGeminiCache, fmt.Sprintf("Skipping cache creation: token count %d < %d", tokenCount, minTokens))
 		return false
 	}
 	return true
 }
 
-func GetOrCreateGeminiCache(apiKey string, channelID int, model string, request *dto.GeminiChatRequest) (string, bool, int, error) {
-	tokenCount := CountTokensFromParts(request.SystemInstructions)
-	if !ShouldEnableGeminiCache(model, tokenCount) {
-		return "", false, 0, nil
-	}
+type GeminiCacheResult struct {
+	CacheName      string
+	IsJustCreated  bool
+	CreationTokens int
+	PrefixLength   int // 被缓存覆盖的 Contents 前缀条数
+	// 缓存查询（含确认上游缓存是否存在）与创建缓存分别花费的时间，均发生在请求上游之前
+	LookupLatency   time.Duration
+	CreationLatency time.Duration
+}
 
-	if request.SystemInstructions != nil {
-		hash := HashSystemInstructions(request.SystemInstructions)
-		redisKey := fmt.Sprintf("gemini_cache:%s", hash)
+const (
+	// geminiCacheTTL 上游缓存的有效期，本地记录与之保持一致
+	geminiCacheTTL = 10 * time.Minute
+	// geminiCacheKeepAliveThreshold 命中时剩余有效期低于该值则续期，避免热门前缀反复过期重建
+	geminiCacheKeepAliveThreshold = 2 * time.Minute
+)
 
-		var err error
+// geminiCacheBaseURL cachedContents 接口地址，测试时替换为本地假服务
+var geminiCacheBaseURL = "https://generativelanguage.googleapis.com/v1beta"
 
-		if common.RedisEnabled {
-			val, err := common.RDB.Get(context.Background(), redisKey).Result()
+var geminiPromptCache = promptcache.NewRedisPromptCache(promptcache.ProviderGemini, "gemini_cache", geminiCacheTTL)
 
-			if err == nil && val != "" {
-				var cached struct {
-					CacheName string `json:"cache_name"`
-					ChannelID int    `json:"channel_id"`
-				}
-				_ = json.Unmarshal([]byte(val), &cached)
+func init() {
+	geminiPromptCache.ShouldCacheFunc = ShouldEnableGeminiCache
+	geminiPromptCache.VerifyFunc = LookupGeminiCacheByID
+	promptcache.Register(promptcache.ProviderGemini, geminiPromptCache)
+}
 
-				common.SysLog("Found cachedID in Redis: " + cached.CacheName)
+// GeminiCacheOptions 单次请求的缓存参数，来自缓存隔离设置与客户端请求中的 cache 字段
+type GeminiCacheOptions struct {
+	Namespace string        // 缓存隔离的命名空间，为空时所有用户共享按内容计算的缓存
+	Key       string        // 自定义缓存 key，已按用户（或令牌）、模型隔离
+	TTL       time.Duration // 新建缓存的有效期
+}
 
-				if exists, err := LookupGeminiCacheByID(apiKey, cached.CacheName); err == nil && exists {
-					common.SysLog("Gemini cache confirmed via lookup: " + cached.CacheName)
-					return cached.CacheName, false, 0, nil
-				}
-				common.SysLog("Gemini lookup failed, creating new cache...")
-			}
-		} else {
-			common.SysLog("Redis not enabled...")
+// geminiCacheNamespace 按 CacheIsolation 设置返回命名空间
+func geminiCacheNamespace(userId int, tokenId int) string {
+	switch model_setting.GetGeminiSettings().CacheIsolation {
+	case model_setting.GeminiCacheIsolationUser:
+		return fmt.Sprintf("user:%d", userId)
+	case model_setting.GeminiCacheIsolationToken:
+		return fmt.Sprintf("token:%d", tokenId)
+	}
+	return ""
+}
+
+// NewGeminiCacheOptions 解析本次请求的缓存参数，ttl 不超过 CacheMaxTTL，无需特殊处理时返回 nil
+func NewGeminiCacheOptions(userId int, tokenId int, model string, cache *dto.CacheOptions) *GeminiCacheOptions {
+	options := &GeminiCacheOptions{
+		Namespace: geminiCacheNamespace(userId, tokenId),
+	}
+	if cache == nil || (cache.Key == "" && cache.Ttl <= 0) {
+		if options.Namespace == "" {
+			return nil
+		}
+		return options
+	}
+	if cache.Key != "" {
+		// 自定义 key 至少按用户隔离
+		scope := options.Namespace
+		if scope == "" {
+			scope = fmt.Sprintf("user:%d", userId)
+		}
+		options.Key = common.GetMD5Hash(fmt.Sprintf("custom|%s|%s|%s", scope, model, cache.Key))
+	}
+	if cache.Ttl > 0 {
+		ttl := cache.Ttl
+		if maxTTL := model_setting.GetGeminiSettings().CacheMaxTTL; maxTTL > 0 && ttl > maxTTL {
+			ttl = maxTTL
 		}
+		options.TTL = time.Duration(ttl) * time.Second
+	}
+	return options
+}
 
-		newID, err := CreateGeminiCache(apiKey, model, request, hash)
-		if err != nil {
-			return "", false, 0, err
+// GetOrCreateGeminiCache 以 SystemInstructions 加上 Contents 的稳定前缀作为缓存内容。
+// 开启 CacheHistoryEnabled 后，最后一轮之前的历史消息也会被缓存，查找时优先命中最长的已缓存前缀。
+// options 中指定了自定义 key 时，只按该 key 查找，由客户端保证相同 key 的缓存内容一致。
+func GetOrCreateGeminiCache(apiKey string, channelID int, model string, request *dto.GeminiChatRequest, options *GeminiCacheOptions) (*GeminiCacheResult, error) {
+	if !model_setting.GetGeminiSettings().EnableCache {
+		return nil, nil
+	}
+	prefixLength := getGeminiCachePrefixLength(request)
+	if request.SystemInstructions == nil && prefixLength == 0 {
+		return nil, nil
+	}
+
+	hashes := HashGeminiCachePrefixes(request.SystemInstructions, request.Contents[:prefixLength])
+	// 从最长前缀开始查找，没有 systemInstruction 时空前缀不参与
+	candidates := make([]string, 0, len(hashes))
+	for n := len(hashes) - 1; n >= 0; n-- {
+		if n == 0 && request.SystemInstructions == nil {
+			break
 		}
+		candidates = append(candidates, hashes[n])
+	}
+	ttl := geminiCacheTTL
+	if options != nil {
+		if options.Namespace != "" {
+			for i := range candidates {
+				candidates[i] = common.GetMD5Hash(options.Namespace + "|" + candidates[i])
+			}
+		}
+		if options.Key != "" {
+			candidates = []string{options.Key}
+		}
+		if options.TTL > 0 {
+			ttl = options.TTL
+		}
+	}
 
-		if common.RedisEnabled {
-			value := map[string]interface{}{
-				"cache_name": newID,
-				"channel_id": channelID,
+	tokenCount := CountTokensFromParts(request.SystemInstructions, model)
+	for i := 0; i < prefixLength; i++ {
+		tokenCount += CountTokensFromParts(&request.Contents[i], model)
+	}
+
+	startTime := time.Now()
+	var creationLatency time.Duration
+	result, err := geminiPromptCache.GetOrCreate(&promptcache.Request{
+		ApiKey:       apiKey,
+		ChannelID:    channelID,
+		Model:        model,
+		Hashes:       candidates,
+		TokenCount:   tokenCount,
+		PrefixLength: prefixLength,
+		TTL:          ttl,
+		Create: func() (string, error) {
+			creationStart := time.Now()
+			defer func() {
+				creationLatency = time.Since(creationStart)
+			}()
+			return CreateGeminiCache(apiKey, model, request.SystemInstructions, request.Contents[:prefixLength], hashes[prefixLength], ttl)
+		},
+	})
+	lookupLatency := time.Since(startTime) - creationLatency
+	common.SysLogSampled(common.LogCategoryGeminiCache, fmt.Sprintf("Gemini cache span: lookup %dms, creation %dms, created %t",
+		lookupLatency.Milliseconds(), creationLatency.Milliseconds(), result != nil && result.IsJustCreated))
+	if err != nil || result == nil {
+		return nil, err
+	}
+	if !result.IsJustCreated {
+		common.SysLogSampled(common.LogCategoryGeminiCache, "Gemini cache confirmed via lookup: "+result.Name)
+		cachedPrefixLength := prefixLength - result.Index
+		if options != nil && options.Key != "" {
+			// 自定义 key 无法从 hash 推出前缀长度，使用创建时记录的长度
+			cachedPrefixLength = result.PrefixLength
+			if cachedPrefixLength > len(request.Contents)-1 {
+				common.SysLog("Gemini custom cache key covers more contents than the request, skipping: " + result.Name)
+				return nil, nil
 			}
-			jsonValue, _ := json.Marshal(value)
-			_ = common.RDB.Set(context.Background(), redisKey, jsonValue, time.Hour).Err()
-			common.SysLog("Gemini cache saved to Redis: " + redisKey + " = " + string(jsonValue))
 		}
+		return &GeminiCacheResult{
+			CacheName:     result.Name,
+			PrefixLength:  cachedPrefixLength,
+			LookupLatency: lookupLatency,
+		}, nil
+	}
+	return &GeminiCacheResult{
+		CacheName:       result.Name,
+		IsJustCreated:   true,
+		CreationTokens:  tokenCount,
+		PrefixLength:    prefixLength,
+		LookupLatency:   lookupLatency,
+		CreationLatency: creationLatency,
+	}, nil
+}
+
+// ApplyGeminiCache 引用缓存后，请求中不能再携带已缓存的 systemInstruction 与历史消息
+func ApplyGeminiCache(request *dto.GeminiChatRequest, result *GeminiCacheResult) {
+	request.CachedContent = result.CacheName
+	request.SystemInstructions = nil
+	request.Contents = request.Contents[result.PrefixLength:]
+}
 
-		return newID, true, tokenCount, nil
+// getGeminiCachePrefixLength 返回可缓存的历史前缀长度，最后一轮用户消息不参与缓存
+func getGeminiCachePrefixLength(request *dto.GeminiChatRequest) int {
+	settings := model_setting.GetGeminiSettings()
+	if !settings.CacheHistoryEnabled {
+		return 0
+	}
+	prefixLength := len(request.Contents) - 1
+	if prefixLength < settings.CacheHistoryMinPrefix || prefixLength <= 0 {
+		return 0
 	}
+	return prefixLength
+}
 
-	return "", false, 0, nil
+// LookupGeminiCacheByID 确认上游缓存仍然存在，即将过期时顺带续期，extended 表示已续期
+func LookupGeminiCacheByID(apiKey string, cachedID string) (exists bool, extended bool, err error) {
+	cache, err := GetGeminiCache(apiKey, cachedID)
+	if err != nil || cache == nil {
+		return false, false, err
+	}
+	expireTime, err := time.Parse(time.RFC3339Nano, cache.ExpireTime)
+	if err != nil || time.Until(expireTime) > geminiCacheKeepAliveThreshold {
+		return true, false, nil
+	}
+	if err := ExtendGeminiCacheTTL(apiKey, cachedID); err != nil {
+		common.SysError("failed to extend gemini cache ttl: " + err.Error())
+		return true, false, nil
+	}
+	common.SysLog("Gemini cache ttl extended: " + cachedID)
+	return true, true, nil
 }
 
-func LookupGeminiCacheByID(apiKey string, cachedID string) (bool, error) {
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/%s?key=%s", cachedID, apiKey)
+// GetGeminiCache 读取上游缓存详情，缓存不存在或已过期时返回 nil
+func GetGeminiCache(apiKey string, name string) (*dto.GeminiCachedContent, error) {
+	url := fmt.Sprintf("%s/%s?key=%s", geminiCacheBaseURL, name, apiKey)
 
 	resp, err := service.GetHttpClient().Get(url)
 	if err != nil {
-		return false, fmt.Errorf("lookup by ID failed: %w", err)
+		return nil, fmt.Errorf("lookup by ID failed: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		return true, nil
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
 	}
 
-	if resp.StatusCode == http.StatusNotFound {
-		return false, nil
+	if resp.StatusCode != http.StatusOK {
+		var errResp map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return nil, fmt.Errorf("lookup by ID failed: %v", errResp)
+	}
+
+	var cache dto.GeminiCachedContent
+	if err := json.NewDecoder(resp.Body).Decode(&cache); err != nil {
+		// 无法解析详情时仍视为存在，过期时间留空
+		return &dto.GeminiCachedContent{Name: name}, nil
+	}
+	return &cache, nil
+}
+
+// ExtendGeminiCacheTTL 将上游缓存的有效期重置为 geminiCacheTTL
+func ExtendGeminiCacheTTL(apiKey string, name string) error {
+	body, err := json.Marshal(map[string]string{
+		"ttl": fmt.Sprintf("%ds", int(geminiCacheTTL.Seconds())),
+	})
+	if err != nil {
+		return err
+	}
+	url := fmt.Sprintf("%s/%s?updateMask=ttl&key=%s", geminiCacheBaseURL, name, apiKey)
+	req, err := http.NewRequest(http.MethodPatch, url, strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("update cache ttl failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errResp map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("update cache ttl failed: %v", errResp)
+	}
+	return nil
+}
+
+// ListGeminiCaches 列出该 key 在上游的全部 cachedContents
+func ListGeminiCaches(apiKey string) ([]dto.GeminiCachedContent, error) {
+	caches := make([]dto.GeminiCachedContent, 0)
+	pageToken := ""
+	for {
+		query := url.Values{}
+		query.Set("pageSize", "100")
+		query.Set("key", apiKey)
+		if pageToken != "" {
+			query.Set("pageToken", pageToken)
+		}
+		resp, err := service.GetHttpClient().Get(geminiCacheBaseURL + "/cachedContents?" + query.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("list caches failed: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			var errResp map[string]interface{}
+			_ = json.NewDecoder(resp.Body).Decode(&errResp)
+			resp.Body.Close()
+			return nil, fmt.Errorf("list caches failed: %v", errResp)
+		}
+		var listResp dto.GeminiCachedContentListResponse
+		err = json.NewDecoder(resp.Body).Decode(&listResp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error decoding list response: %w", err)
+		}
+		caches = append(caches, listResp.CachedContents...)
+		if listResp.NextPageToken == "" {
+			return caches, nil
+		}
+		pageToken = listResp.NextPageToken
 	}
+}
 
-	var errResp map[string]interface{}
-	_ = json.NewDecoder(resp.Body).Decode(&errResp)
-	return false, fmt.Errorf("lookup by ID failed: %v", errResp)
+// DeleteGeminiCache 删除上游的 cachedContent，name 形如 cachedContents/abc123
+func DeleteGeminiCache(apiKey string, name string) error {
+	url := fmt.Sprintf("%s/%s?key=%s", geminiCacheBaseURL, name, apiKey)
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+	resp, err := service.GetHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("delete cache failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var errResp map[string]interface{}
+		_ = json.NewDecoder(resp.Body).Decode(&errResp)
+		return fmt.Errorf("delete cache failed: %v", errResp)
+	}
+	return nil
 }
 
-func CreateGeminiCache(apiKey, model string, request *dto.GeminiChatRequest, displayName string) (string, error) {
+// PurgeChannelGeminiCaches 渠道 key 变更后删除绑定到该渠道的缓存记录，开启 CachePurgeUpstream 时同时用旧 key 删除上游缓存
+func PurgeChannelGeminiCaches(channelID int, oldKeys []string) (int, error) {
+	entries, err := geminiPromptCache.InvalidateChannel(channelID)
+	if err != nil || !model_setting.GetGeminiSettings().CachePurgeUpstream {
+		return len(entries), err
+	}
+	for _, entry := range entries {
+		// 多 key 渠道无法得知缓存由哪个 key 创建，依次尝试
+		for _, key := range oldKeys {
+			if err := DeleteGeminiCache(key, entry.CacheName); err == nil {
+				break
+			}
+		}
+	}
+	return len(entries), nil
+}
+
+func CreateGeminiCache(apiKey, model string, system *dto.GeminiChatContent, contents []dto.GeminiChatContent, displayName string, ttl time.Duration) (string, error) {
 	if !strings.HasPrefix(model, "models/") {
 		model = "models/" + model
 	}
 
 	cacheReq := &dto.GeminiCachedContentRequest{
 		Model:             model,
-		SystemInstruction: request.SystemInstructions,
-		Ttl:               "600s",
+		SystemInstruction: system,
+		Contents:          contents,
+		Ttl:               fmt.Sprintf("%ds", int(ttl.Seconds())),
 		DisplayName:       displayName,
 	}
 
@@ -122,7 +378,7 @@ func CreateGeminiCache(apiKey, model string, request *dto.GeminiChatRequest, dis
 		return "", fmt.Errorf("failed to marshal cache request: %w", err)
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/cachedContents?key=%s", apiKey)
+	url := fmt.Sprintf("%s/cachedContents?key=%s", geminiCacheBaseURL, apiKey)
 	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(body)))
 	if err != nil {
 		return "", fmt.Errorf("failed to create HTTP request: %w", err)
@@ -150,12 +406,25 @@ func CreateGeminiCache(apiKey, model string, request *dto.GeminiChatRequest, dis
 	return cacheResp.Name, nil
 }
 
+// geminiMediaPartTokens Gemini 对每张图片 / 文件分片按固定 258 token 计费
+const geminiMediaPartTokens = 258
 
-func CountTokensFromParts(content *dto.GeminiChatContent) int {
+// CountTokensFromParts 使用 relay 统一的 tokenizer 统计内容 token 数，用于判断是否达到缓存门槛
+func CountTokensFromParts(content *dto.GeminiChatContent, model string) int {
+	if content == nil {
+		return 0
+	}
 	count := 0
 	for _, part := range content.Parts {
 		if part.Text != "" {
-			count += len(strings.Split(part.Text, " "))
+			count += service.CountTextToken(part.Text, model)
+		}
+		if part.FunctionCall != nil || part.FunctionResponse != nil {
+			data, _ := json.Marshal(part)
+			count += service.CountTextToken(string(data), model)
+		}
+		if part.InlineData != nil || part.FileData != nil {
+			count += geminiMediaPartTokens
 		}
 	}
 	return count
@@ -167,4 +436,18 @@ func HashSystemInstructions(system *dto.GeminiChatContent) string {
 	}
 	bytes, _ := json.Marshal(system)
 	return common.GetMD5Hash(string(bytes))
-}
\ No newline at end of file
+}
+
+// HashGeminiCachePrefixes 返回链式哈希，hashes[n] 对应 systemInstruction 加前 n 条 contents，
+// hashes[0] 与 HashSystemInstructions 保持一致，兼容仅缓存系统提示的旧 key
+func HashGeminiCachePrefixes(system *dto.GeminiChatContent, contents []dto.GeminiChatContent) []string {
+	hashes := make([]string, 0, len(contents)+1)
+	hash := HashSystemInstructions(system)
+	hashes = append(hashes, hash)
+	for i := range contents {
+		bytes, _ := json.Marshal(contents[i])
+		hash = common.GetMD5Hash(hash + "|" + string(bytes))
+		hashes = append(hashes, hash)
+	}
+	return hashes
+}
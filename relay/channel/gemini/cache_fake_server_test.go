@@ -0,0 +1,236 @@
+package gemini
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"one-api/dto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const fakeGeminiAPIKey = "fake-gemini-key"
+
+// fakeGeminiCacheServer 模拟 Gemini cachedContents 接口，响应以 testdata 中录制的真实响应为模板
+type fakeGeminiCacheServer struct {
+	*httptest.Server
+	t *testing.T
+
+	fixtures map[string][]byte
+
+	mu       sync.Mutex
+	caches   map[string]*dto.GeminiCachedContent
+	requests []*dto.GeminiCachedContentRequest
+	calls    map[string]int
+	nextID   int
+	// rejectCreate 不为空时，创建缓存返回该 fixture 对应的 400 错误
+	rejectCreate string
+	// pageSize 列表接口每页返回的条数
+	pageSize int
+}
+
+func newFakeGeminiCacheServer(t *testing.T) *fakeGeminiCacheServer {
+	s := &fakeGeminiCacheServer{
+		t:        t,
+		caches:   make(map[string]*dto.GeminiCachedContent),
+		calls:    make(map[string]int),
+		pageSize: 100,
+		fixtures: make(map[string][]byte),
+	}
+	// fixture 在测试 goroutine 中预先读取，处理请求时不能调用 t.Fatal
+	for _, name := range []string{"cached_content.json", "error_not_found.json", "error_too_small.json", "error_invalid_key.json"} {
+		s.fixtures[name] = loadFixture(t, name)
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func loadFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("read fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func (s *fakeGeminiCacheServer) writeFixture(w http.ResponseWriter, status int, name string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_, _ = w.Write(s.fixtures[name])
+}
+
+func (s *fakeGeminiCacheServer) writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *fakeGeminiCacheServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("key") != fakeGeminiAPIKey {
+		s.writeFixture(w, http.StatusBadRequest, "error_invalid_key.json")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/v1beta/")
+	s.calls[r.Method]++
+	switch {
+	case r.Method == http.MethodPost && path == "cachedContents":
+		s.create(w, r)
+	case r.Method == http.MethodGet && path == "cachedContents":
+		s.list(w, r)
+	case r.Method == http.MethodGet:
+		s.get(w, path)
+	case r.Method == http.MethodPatch:
+		s.patch(w, r, path)
+	case r.Method == http.MethodDelete:
+		s.delete(w, path)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *fakeGeminiCacheServer) create(w http.ResponseWriter, r *http.Request) {
+	var req dto.GeminiCachedContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.t.Errorf("decode create request: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.requests = append(s.requests, &req)
+	if s.rejectCreate != "" {
+		s.writeFixture(w, http.StatusBadRequest, s.rejectCreate)
+		return
+	}
+	ttl, err := time.ParseDuration(req.Ttl)
+	if err != nil {
+		s.t.Errorf("invalid ttl %q: %v", req.Ttl, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var cache dto.GeminiCachedContent
+	if err = json.Unmarshal(s.fixtures["cached_content.json"], &cache); err != nil {
+		s.t.Errorf("decode fixture: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	s.nextID++
+	now := time.Now().UTC()
+	cache.Name = fmt.Sprintf("cachedContents/fake%d", s.nextID)
+	cache.Model = req.Model
+	cache.DisplayName = req.DisplayName
+	cache.CreateTime = now.Format(time.RFC3339Nano)
+	cache.UpdateTime = cache.CreateTime
+	cache.ExpireTime = now.Add(ttl).Format(time.RFC3339Nano)
+	s.caches[cache.Name] = &cache
+	s.writeJSON(w, cache)
+}
+
+// get 已过期的缓存与真实接口一样返回 404
+func (s *fakeGeminiCacheServer) get(w http.ResponseWriter, name string) {
+	cache, ok := s.caches[name]
+	if !ok || s.expired(cache) {
+		s.writeFixture(w, http.StatusNotFound, "error_not_found.json")
+		return
+	}
+	s.writeJSON(w, cache)
+}
+
+func (s *fakeGeminiCacheServer) patch(w http.ResponseWriter, r *http.Request, name string) {
+	cache, ok := s.caches[name]
+	if !ok || s.expired(cache) {
+		s.writeFixture(w, http.StatusNotFound, "error_not_found.json")
+		return
+	}
+	if r.URL.Query().Get("updateMask") != "ttl" {
+		s.t.Errorf("unexpected updateMask %q", r.URL.Query().Get("updateMask"))
+	}
+	var body struct {
+		Ttl string `json:"ttl"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	ttl, err := time.ParseDuration(body.Ttl)
+	if err != nil {
+		s.t.Errorf("invalid ttl %q: %v", body.Ttl, err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	now := time.Now().UTC()
+	cache.UpdateTime = now.Format(time.RFC3339Nano)
+	cache.ExpireTime = now.Add(ttl).Format(time.RFC3339Nano)
+	s.writeJSON(w, cache)
+}
+
+func (s *fakeGeminiCacheServer) delete(w http.ResponseWriter, name string) {
+	if _, ok := s.caches[name]; !ok {
+		s.writeFixture(w, http.StatusNotFound, "error_not_found.json")
+		return
+	}
+	delete(s.caches, name)
+	s.writeJSON(w, map[string]any{})
+}
+
+// list 按名称排序分页，pageToken 为下一页的起始下标，模仿上游带 +/= 的 base64 格式
+func (s *fakeGeminiCacheServer) list(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.caches))
+	for name, cache := range s.caches {
+		if !s.expired(cache) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	start := 0
+	if token := r.URL.Query().Get("pageToken"); token != "" {
+		if _, err := fmt.Sscanf(token, "p+%d/=", &start); err != nil {
+			s.t.Errorf("invalid page token %q: %v", token, err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	resp := dto.GeminiCachedContentListResponse{CachedContents: []dto.GeminiCachedContent{}}
+	for i := start; i < len(names) && i < start+s.pageSize; i++ {
+		resp.CachedContents = append(resp.CachedContents, *s.caches[names[i]])
+	}
+	if start+s.pageSize < len(names) {
+		resp.NextPageToken = fmt.Sprintf("p+%d/=", start+s.pageSize)
+	}
+	s.writeJSON(w, resp)
+}
+
+func (s *fakeGeminiCacheServer) expired(cache *dto.GeminiCachedContent) bool {
+	expireTime, err := time.Parse(time.RFC3339Nano, cache.ExpireTime)
+	return err == nil && !time.Now().Before(expireTime)
+}
+
+// expireIn 修改上游缓存的剩余有效期，d 不大于 0 时缓存立即过期
+func (s *fakeGeminiCacheServer) expireIn(name string, d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cache, ok := s.caches[name]
+	if !ok {
+		s.t.Fatalf("cache %s not found", name)
+	}
+	cache.ExpireTime = time.Now().UTC().Add(d).Format(time.RFC3339Nano)
+}
+
+// callCount 返回某个 HTTP 方法的请求次数
+func (s *fakeGeminiCacheServer) callCount(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func (s *fakeGeminiCacheServer) cacheCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.caches)
+}
@@ -0,0 +1,85 @@
+package gemini
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/service/promptcache"
+	"one-api/setting/model_setting"
+	"time"
+)
+
+// GeminiCacheReconcileResult 一轮核对的结果
+type GeminiCacheReconcileResult struct {
+	Checked  int
+	Stale    int // 上游已不存在、被删除的记录
+	Extended int // 已为上游缓存续期的记录
+	Errors   int // 上游请求失败、本轮跳过的记录
+}
+
+// ReconcileGeminiCaches 逐条核对本地缓存记录与上游缓存：上游已过期或渠道已不可用的记录直接删除，
+// 仍存在的记录按上游剩余有效期重新保存，避免请求时反复命中已失效的缓存。
+// 开启 CacheJanitorExtend 时，有命中记录且会在下一轮核对前过期的缓存会被续期。
+// getKeys 返回渠道当前的 API Key，渠道不存在或已不是 Gemini 渠道时返回空。
+func ReconcileGeminiCaches(getKeys func(channelID int) []string, interval time.Duration) (GeminiCacheReconcileResult, error) {
+	var result GeminiCacheReconcileResult
+	extend := model_setting.GetGeminiSettings().CacheJanitorExtend
+	err := geminiPromptCache.ForEach(func(hash string, entry *promptcache.Entry) {
+		result.Checked++
+		keys := getKeys(entry.ChannelID)
+		if len(keys) == 0 {
+			_ = geminiPromptCache.Invalidate(hash)
+			result.Stale++
+			return
+		}
+		var (
+			cache    *dto.GeminiCachedContent
+			cacheKey string
+			failed   bool
+		)
+		for _, key := range keys {
+			found, err := GetGeminiCache(key, entry.CacheName)
+			if err != nil {
+				failed = true
+				continue
+			}
+			if found != nil {
+				cache, cacheKey = found, key
+				break
+			}
+		}
+		if cache == nil {
+			if failed {
+				// 上游请求失败时无法确认缓存状态，留到下一轮
+				result.Errors++
+				return
+			}
+			_ = geminiPromptCache.Invalidate(hash)
+			result.Stale++
+			return
+		}
+		expireTime, err := time.Parse(time.RFC3339Nano, cache.ExpireTime)
+		if err != nil {
+			return
+		}
+		remaining := time.Until(expireTime)
+		if extend && entry.Hits > 0 && remaining < interval+geminiCacheKeepAliveThreshold {
+			if err = ExtendGeminiCacheTTL(cacheKey, entry.CacheName); err != nil {
+				common.SysError("failed to extend gemini cache ttl: " + err.Error())
+			} else {
+				remaining = geminiCacheTTL
+				result.Extended++
+			}
+		}
+		if remaining <= 0 {
+			_ = geminiPromptCache.Invalidate(hash)
+			result.Stale++
+			return
+		}
+		geminiPromptCache.Refresh(hash, entry, remaining)
+	})
+	if result.Stale > 0 || result.Extended > 0 {
+		common.SysLog(fmt.Sprintf("gemini cache janitor: checked %d, removed %d stale, extended %d", result.Checked, result.Stale, result.Extended))
+	}
+	return result, err
+}
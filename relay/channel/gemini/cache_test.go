@@ -0,0 +1,524 @@
+package gemini
+
+import (
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/service"
+	"one-api/service/promptcache"
+	"one-api/setting/model_setting"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+const cacheTestModel = "gemini-2.5-flash"
+
+func TestMain(m *testing.M) {
+	service.InitHttpClient()
+	service.InitTokenEncoders()
+	os.Exit(m.Run())
+}
+
+// setupGeminiCacheTest 将上游替换为假服务、Redis 替换为 miniredis，测试结束后恢复
+func setupGeminiCacheTest(t *testing.T) (*fakeGeminiCacheServer, *miniredis.Miniredis) {
+	t.Helper()
+	server := newFakeGeminiCacheServer(t)
+	mr := miniredis.RunT(t)
+
+	oldBaseURL, oldRDB, oldRedisEnabled := geminiCacheBaseURL, common.RDB, common.RedisEnabled
+	geminiCacheBaseURL = server.URL + "/v1beta"
+	common.RDB = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	common.RedisEnabled = true
+
+	settings := model_setting.GetGeminiSettings()
+	oldSettings := *settings
+	settings.EnableCache = true
+	settings.CacheHistoryEnabled = false
+	settings.CacheHistoryMinPrefix = 2
+	settings.CacheMinTokens = map[string]int{"default": 1}
+
+	t.Cleanup(func() {
+		_ = common.RDB.Close()
+		geminiCacheBaseURL, common.RDB, common.RedisEnabled = oldBaseURL, oldRDB, oldRedisEnabled
+		*settings = oldSettings
+	})
+	return server, mr
+}
+
+// newCacheTestRequest 构造带 systemInstruction 的请求，turns 依次作为 user / model 消息
+func newCacheTestRequest(system string, turns ...string) *dto.GeminiChatRequest {
+	request := &dto.GeminiChatRequest{
+		SystemInstructions: &dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{{Text: system}},
+		},
+	}
+	for i, turn := range turns {
+		role := "user"
+		if i%2 == 1 {
+			role = "model"
+		}
+		request.Contents = append(request.Contents, dto.GeminiChatContent{
+			Role:  role,
+			Parts: []dto.GeminiPart{{Text: turn}},
+		})
+	}
+	return request
+}
+
+func cacheTestKey(hash string) string {
+	return "gemini_cache:" + hash
+}
+
+func readCacheEntry(t *testing.T, mr *miniredis.Miniredis, hash string) *promptcache.Entry {
+	t.Helper()
+	val, err := mr.Get(cacheTestKey(hash))
+	if err != nil {
+		t.Fatalf("cache entry %s not found: %v", hash, err)
+	}
+	var entry promptcache.Entry
+	if err = common.Unmarshal([]byte(val), &entry); err != nil {
+		t.Fatalf("decode cache entry: %v", err)
+	}
+	return &entry
+}
+
+func mustCreateCache(t *testing.T, channelID int, request *dto.GeminiChatRequest) *GeminiCacheResult {
+	t.Helper()
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, channelID, cacheTestModel, request, nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateGeminiCache: %v", err)
+	}
+	if result == nil || !result.IsJustCreated {
+		t.Fatalf("expected a newly created cache, got %+v", result)
+	}
+	return result
+}
+
+func TestGeminiCacheCreateOnMiss(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("You are a meticulous code reviewer.", "Review this diff.")
+
+	result := mustCreateCache(t, 1, request)
+	if result.CacheName != "cachedContents/fake1" {
+		t.Errorf("cache name = %q, want cachedContents/fake1", result.CacheName)
+	}
+	if result.CreationTokens <= 0 {
+		t.Errorf("creation tokens = %d, want > 0", result.CreationTokens)
+	}
+	if result.PrefixLength != 0 {
+		t.Errorf("prefix length = %d, want 0 with history caching disabled", result.PrefixLength)
+	}
+
+	if got := server.callCount(http.MethodPost); got != 1 {
+		t.Fatalf("create calls = %d, want 1", got)
+	}
+	hash := HashSystemInstructions(request.SystemInstructions)
+	created := server.requests[0]
+	if created.Model != "models/"+cacheTestModel {
+		t.Errorf("model = %q, want models/%s", created.Model, cacheTestModel)
+	}
+	if created.Ttl != "600s" {
+		t.Errorf("ttl = %q, want 600s", created.Ttl)
+	}
+	if created.DisplayName != hash {
+		t.Errorf("display name = %q, want prefix hash %q", created.DisplayName, hash)
+	}
+	if len(created.Contents) != 0 {
+		t.Errorf("cached %d contents, want only the system instruction", len(created.Contents))
+	}
+
+	entry := readCacheEntry(t, mr, hash)
+	if entry.CacheName != result.CacheName || entry.ChannelID != 1 || entry.TokenCount != result.CreationTokens {
+		t.Errorf("unexpected cache entry %+v", entry)
+	}
+	if ttl := mr.TTL(cacheTestKey(hash)); ttl != geminiCacheTTL {
+		t.Errorf("local ttl = %s, want %s", ttl, geminiCacheTTL)
+	}
+}
+
+func TestGeminiCacheLookupHit(t *testing.T) {
+	server, _ := setupGeminiCacheTest(t)
+	created := mustCreateCache(t, 1, newCacheTestRequest("You are a translator.", "Bonjour"))
+
+	before := geminiPromptCache.Stats()
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, newCacheTestRequest("You are a translator.", "Hola"), nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateGeminiCache: %v", err)
+	}
+	if result == nil || result.IsJustCreated || result.CacheName != created.CacheName {
+		t.Fatalf("expected hit on %s, got %+v", created.CacheName, result)
+	}
+	if got := server.callCount(http.MethodPost); got != 1 {
+		t.Errorf("create calls = %d, want 1", got)
+	}
+	if got := server.callCount(http.MethodGet); got != 1 {
+		t.Errorf("lookup calls = %d, want 1", got)
+	}
+	if got := server.callCount(http.MethodPatch); got != 0 {
+		t.Errorf("ttl update calls = %d, want 0 for a fresh cache", got)
+	}
+	if hits := geminiPromptCache.Stats().Hits - before.Hits; hits != 1 {
+		t.Errorf("hits = %d, want 1", hits)
+	}
+}
+
+func TestGeminiCacheExtendsNearExpiry(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("You are a data analyst.", "Summarize the table.")
+	created := mustCreateCache(t, 1, request)
+	hash := HashSystemInstructions(request.SystemInstructions)
+
+	// 上游与本地都只剩 1 分钟，低于续期阈值
+	server.expireIn(created.CacheName, time.Minute)
+	mr.FastForward(geminiCacheTTL - time.Minute)
+
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, request, nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateGeminiCache: %v", err)
+	}
+	if result == nil || result.IsJustCreated {
+		t.Fatalf("expected hit, got %+v", result)
+	}
+	if got := server.callCount(http.MethodPatch); got != 1 {
+		t.Errorf("ttl update calls = %d, want 1", got)
+	}
+	if ttl := mr.TTL(cacheTestKey(hash)); ttl != geminiCacheTTL {
+		t.Errorf("local ttl = %s, want refreshed to %s", ttl, geminiCacheTTL)
+	}
+	if hits := readCacheEntry(t, mr, hash).Hits; hits != 1 {
+		t.Errorf("entry hits = %d, want 1", hits)
+	}
+}
+
+func TestGeminiCacheUpstreamExpired(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("You are a travel planner.", "Plan a weekend in Lisbon.")
+	created := mustCreateCache(t, 1, request)
+
+	// 上游缓存已过期而本地记录仍在，查询返回 404 后应作废本地记录并重建
+	server.expireIn(created.CacheName, 0)
+	before := geminiPromptCache.Stats()
+	result := mustCreateCache(t, 1, request)
+	if result.CacheName == created.CacheName {
+		t.Errorf("expected a new cache, got the expired %s", result.CacheName)
+	}
+	if got := server.callCount(http.MethodGet); got != 1 {
+		t.Errorf("lookup calls = %d, want 1", got)
+	}
+	if got := server.callCount(http.MethodPost); got != 2 {
+		t.Errorf("create calls = %d, want 2", got)
+	}
+	if entry := readCacheEntry(t, mr, HashSystemInstructions(request.SystemInstructions)); entry.CacheName != result.CacheName {
+		t.Errorf("entry points to %s, want %s", entry.CacheName, result.CacheName)
+	}
+	after := geminiPromptCache.Stats()
+	if after.Expirations-before.Expirations != 1 || after.Invalidations-before.Invalidations != 1 {
+		t.Errorf("expirations = %d, invalidations = %d, want 1 each",
+			after.Expirations-before.Expirations, after.Invalidations-before.Invalidations)
+	}
+}
+
+func TestGeminiCacheLocalExpiry(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("You are a chess coach.", "Analyze 1.e4 e5.")
+	mustCreateCache(t, 1, request)
+
+	mr.FastForward(geminiCacheTTL)
+	if mr.Exists(cacheTestKey(HashSystemInstructions(request.SystemInstructions))) {
+		t.Fatal("local record should expire together with the upstream cache")
+	}
+	mustCreateCache(t, 1, request)
+	if got := server.callCount(http.MethodGet); got != 0 {
+		t.Errorf("lookup calls = %d, want 0 after local expiry", got)
+	}
+}
+
+func TestGeminiCacheBelowMinTokens(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CacheMinTokens = map[string]int{"default": 1 << 20}
+	request := newCacheTestRequest("Be brief.", "Hi")
+
+	before := geminiPromptCache.Stats()
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, request, nil)
+	if err != nil || result != nil {
+		t.Fatalf("expected no cache, got %+v, %v", result, err)
+	}
+	if got := server.callCount(http.MethodPost); got != 0 {
+		t.Errorf("create calls = %d, want 0", got)
+	}
+	if mr.Exists(cacheTestKey(HashSystemInstructions(request.SystemInstructions))) {
+		t.Error("no local record should be saved below the token threshold")
+	}
+	if misses := geminiPromptCache.Stats().Misses - before.Misses; misses != 1 {
+		t.Errorf("misses = %d, want 1", misses)
+	}
+}
+
+func TestGeminiCacheCreateRejected(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	server.rejectCreate = "error_too_small.json"
+	request := newCacheTestRequest("You are a poet.", "Write a haiku.")
+
+	before := geminiPromptCache.Stats()
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, request, nil)
+	if err == nil || !strings.Contains(err.Error(), "too small") {
+		t.Fatalf("expected upstream rejection, got %+v, %v", result, err)
+	}
+	if mr.Exists(cacheTestKey(HashSystemInstructions(request.SystemInstructions))) {
+		t.Error("failed creation must not be recorded locally")
+	}
+	if errCount := geminiPromptCache.Stats().Errors - before.Errors; errCount != 1 {
+		t.Errorf("errors = %d, want 1", errCount)
+	}
+}
+
+func TestGeminiCacheOtherChannelMiss(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("You are a SQL expert.", "Optimize this query.")
+	mustCreateCache(t, 1, request)
+
+	// 缓存与渠道的 key 绑定，其他渠道不能复用
+	result := mustCreateCache(t, 2, request)
+	if got := server.callCount(http.MethodGet); got != 0 {
+		t.Errorf("lookup calls = %d, want 0 for another channel", got)
+	}
+	if entry := readCacheEntry(t, mr, HashSystemInstructions(request.SystemInstructions)); entry.ChannelID != 2 || entry.CacheName != result.CacheName {
+		t.Errorf("unexpected cache entry %+v", entry)
+	}
+}
+
+func TestGeminiCacheHistoryPrefix(t *testing.T) {
+	server, _ := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CacheHistoryEnabled = true
+
+	system := "You are a patient math tutor."
+	first := newCacheTestRequest(system, "What is a prime?", "A number with two divisors.", "Is 21 prime?")
+	created := mustCreateCache(t, 1, first)
+	if created.PrefixLength != 2 {
+		t.Fatalf("prefix length = %d, want 2", created.PrefixLength)
+	}
+	if got := len(server.requests[0].Contents); got != 2 {
+		t.Errorf("cached %d contents, want 2", got)
+	}
+
+	// 对话继续后应命中已缓存的最长前缀，只发送之后的消息
+	next := newCacheTestRequest(system, "What is a prime?", "A number with two divisors.", "Is 21 prime?", "No, 21 = 3 x 7.", "Is 23 prime?")
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, next, nil)
+	if err != nil {
+		t.Fatalf("GetOrCreateGeminiCache: %v", err)
+	}
+	if result == nil || result.IsJustCreated || result.CacheName != created.CacheName {
+		t.Fatalf("expected hit on %s, got %+v", created.CacheName, result)
+	}
+	if result.PrefixLength != 2 {
+		t.Errorf("prefix length = %d, want 2", result.PrefixLength)
+	}
+	ApplyGeminiCache(next, result)
+	if next.CachedContent != created.CacheName || next.SystemInstructions != nil || len(next.Contents) != 3 {
+		t.Errorf("unexpected request after applying cache: %+v", next)
+	}
+}
+
+func TestGeminiCacheWithoutCacheableContent(t *testing.T) {
+	server, _ := setupGeminiCacheTest(t)
+	request := newCacheTestRequest("", "Hello")
+	request.SystemInstructions = nil
+
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, request, nil)
+	if err != nil || result != nil {
+		t.Fatalf("expected no cache, got %+v, %v", result, err)
+	}
+	if got := server.callCount(http.MethodPost) + server.callCount(http.MethodGet); got != 0 {
+		t.Errorf("upstream calls = %d, want 0", got)
+	}
+}
+
+func TestLookupGeminiCacheByIDInvalidKey(t *testing.T) {
+	setupGeminiCacheTest(t)
+	created := mustCreateCache(t, 1, newCacheTestRequest("You are a historian.", "Who was Ashoka?"))
+
+	exists, extended, err := LookupGeminiCacheByID("wrong-key", created.CacheName)
+	if err == nil || exists || extended {
+		t.Errorf("expected lookup error, got exists=%t extended=%t err=%v", exists, extended, err)
+	}
+}
+
+func TestListAndDeleteGeminiCaches(t *testing.T) {
+	server, _ := setupGeminiCacheTest(t)
+	server.pageSize = 2
+	for _, system := range []string{"one", "two", "three"} {
+		if _, err := CreateGeminiCache(fakeGeminiAPIKey, cacheTestModel, &dto.GeminiChatContent{Parts: []dto.GeminiPart{{Text: system}}}, nil, system, geminiCacheTTL); err != nil {
+			t.Fatalf("CreateGeminiCache: %v", err)
+		}
+	}
+
+	caches, err := ListGeminiCaches(fakeGeminiAPIKey)
+	if err != nil {
+		t.Fatalf("ListGeminiCaches: %v", err)
+	}
+	if len(caches) != 3 {
+		t.Fatalf("listed %d caches, want 3", len(caches))
+	}
+	if got := server.callCount(http.MethodGet); got != 2 {
+		t.Errorf("list calls = %d, want 2 pages", got)
+	}
+	if caches[0].UsageMetadata.TotalTokenCount == 0 {
+		t.Error("usage metadata from the recorded fixture is missing")
+	}
+
+	if err = DeleteGeminiCache(fakeGeminiAPIKey, caches[0].Name); err != nil {
+		t.Fatalf("DeleteGeminiCache: %v", err)
+	}
+	if got := server.cacheCount(); got != 2 {
+		t.Errorf("%d caches left, want 2", got)
+	}
+	if err = DeleteGeminiCache(fakeGeminiAPIKey, caches[0].Name); err == nil {
+		t.Error("deleting a missing cache should fail")
+	}
+}
+
+func TestGeminiCacheCustomKey(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CacheMaxTTL = 1800
+	options := NewGeminiCacheOptions(7, 1, cacheTestModel, &dto.CacheOptions{Key: "my-agent-v2", Ttl: 3600})
+	if options.TTL != 30*time.Minute {
+		t.Fatalf("ttl = %s, want clamped to 30m", options.TTL)
+	}
+
+	result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, newCacheTestRequest("You are agent v2.", "Hi"), options)
+	if err != nil || result == nil || !result.IsJustCreated {
+		t.Fatalf("expected a newly created cache, got %+v, %v", result, err)
+	}
+	if created := server.requests[0]; created.Ttl != "1800s" {
+		t.Errorf("ttl = %q, want 1800s", created.Ttl)
+	}
+	if ttl := mr.TTL(cacheTestKey(options.Key)); ttl != 30*time.Minute {
+		t.Errorf("local ttl = %s, want 30m", ttl)
+	}
+
+	// 相同 key 即使系统提示不同也命中，由客户端保证内容一致
+	result, err = GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, newCacheTestRequest("You are agent v2!", "Hello"), options)
+	if err != nil || result == nil || result.IsJustCreated {
+		t.Fatalf("expected hit on custom key, got %+v, %v", result, err)
+	}
+
+	// 其他用户使用相同 key 不会命中
+	other := NewGeminiCacheOptions(8, 2, cacheTestModel, &dto.CacheOptions{Key: "my-agent-v2"})
+	if other.Key == options.Key {
+		t.Fatal("custom keys of different users should not collide")
+	}
+	if got := server.callCount(http.MethodPost); got != 1 {
+		t.Errorf("create calls = %d, want 1", got)
+	}
+}
+
+func TestGeminiCacheIsolation(t *testing.T) {
+	server, _ := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CacheIsolation = model_setting.GeminiCacheIsolationUser
+
+	first := NewGeminiCacheOptions(1, 10, cacheTestModel, nil)
+	mustCreateCacheWithOptions := func(options *GeminiCacheOptions) *GeminiCacheResult {
+		t.Helper()
+		result, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, newCacheTestRequest("Shared prompt.", "Hi"), options)
+		if err != nil || result == nil {
+			t.Fatalf("GetOrCreateGeminiCache: %+v, %v", result, err)
+		}
+		return result
+	}
+	if result := mustCreateCacheWithOptions(first); !result.IsJustCreated {
+		t.Fatal("expected a newly created cache for the first user")
+	}
+	if result := mustCreateCacheWithOptions(NewGeminiCacheOptions(1, 11, cacheTestModel, nil)); result.IsJustCreated {
+		t.Error("tokens of the same user should share the cache")
+	}
+	if result := mustCreateCacheWithOptions(NewGeminiCacheOptions(2, 20, cacheTestModel, nil)); !result.IsJustCreated {
+		t.Error("another user should not reuse the cache")
+	}
+	if got := server.callCount(http.MethodPost); got != 2 {
+		t.Errorf("create calls = %d, want 2", got)
+	}
+}
+
+func TestGeminiCachePurgeChannel(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CachePurgeUpstream = true
+	purged := newCacheTestRequest("Channel one prompt.", "Hi")
+	kept := newCacheTestRequest("Channel two prompt.", "Hi")
+	mustCreateCache(t, 1, purged)
+	mustCreateCache(t, 2, kept)
+
+	count, err := PurgeChannelGeminiCaches(1, []string{"stale-key", fakeGeminiAPIKey})
+	if err != nil {
+		t.Fatalf("PurgeChannelGeminiCaches: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("purged %d entries, want 1", count)
+	}
+	if mr.Exists(cacheTestKey(HashSystemInstructions(purged.SystemInstructions))) {
+		t.Error("entry of the rotated channel should be removed")
+	}
+	if !mr.Exists(cacheTestKey(HashSystemInstructions(kept.SystemInstructions))) {
+		t.Error("entry of another channel should be kept")
+	}
+	if got := server.cacheCount(); got != 1 {
+		t.Errorf("%d upstream caches left, want 1", got)
+	}
+}
+
+func TestGeminiCacheJanitor(t *testing.T) {
+	server, mr := setupGeminiCacheTest(t)
+	model_setting.GetGeminiSettings().CacheJanitorExtend = true
+	expired := newCacheTestRequest("You are a poet.", "Write a haiku.")
+	hot := newCacheTestRequest("You are a lawyer.", "Review the contract.")
+	idle := newCacheTestRequest("You are a chef.", "Plan a menu.")
+	orphan := newCacheTestRequest("You are a pilot.", "File a flight plan.")
+	expiredCache := mustCreateCache(t, 1, expired)
+	hotCache := mustCreateCache(t, 1, hot)
+	idleCache := mustCreateCache(t, 1, idle)
+	mustCreateCache(t, 2, orphan)
+
+	// 命中一次，命中次数保留在记录中且不改变过期时间
+	if _, err := GetOrCreateGeminiCache(fakeGeminiAPIKey, 1, cacheTestModel, hot, nil); err != nil {
+		t.Fatalf("GetOrCreateGeminiCache: %v", err)
+	}
+	hotHash := HashSystemInstructions(hot.SystemInstructions)
+	if hits := readCacheEntry(t, mr, hotHash).Hits; hits != 1 {
+		t.Fatalf("entry hits = %d, want 1", hits)
+	}
+	server.expireIn(expiredCache.CacheName, 0)
+	server.expireIn(hotCache.CacheName, 3*time.Minute)
+	server.expireIn(idleCache.CacheName, 3*time.Minute)
+
+	result, err := ReconcileGeminiCaches(func(channelID int) []string {
+		if channelID == 1 {
+			return []string{fakeGeminiAPIKey}
+		}
+		return nil
+	}, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("ReconcileGeminiCaches: %v", err)
+	}
+	if result.Checked != 4 || result.Stale != 2 || result.Extended != 1 {
+		t.Errorf("result = %+v, want 4 checked, 2 stale, 1 extended", result)
+	}
+	if mr.Exists(cacheTestKey(HashSystemInstructions(expired.SystemInstructions))) {
+		t.Error("entry of the expired upstream cache should be removed")
+	}
+	if mr.Exists(cacheTestKey(HashSystemInstructions(orphan.SystemInstructions))) {
+		t.Error("entry of an unavailable channel should be removed")
+	}
+	if ttl := mr.TTL(cacheTestKey(hotHash)); ttl != geminiCacheTTL {
+		t.Errorf("hot entry ttl = %s, want extended to %s", ttl, geminiCacheTTL)
+	}
+	if ttl := mr.TTL(cacheTestKey(HashSystemInstructions(idle.SystemInstructions))); ttl > 3*time.Minute {
+		t.Errorf("idle entry ttl = %s, want aligned with upstream", ttl)
+	}
+	if got := server.callCount(http.MethodPatch); got != 1 {
+		t.Errorf("ttl update calls = %d, want 1", got)
+	}
+}
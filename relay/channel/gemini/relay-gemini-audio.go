@@ -0,0 +1,321 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	relayconstant "one-api/relay/constant"
+	"one-api/setting/model_setting"
+	"one-api/types"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// geminiInlineAudioMaxBytes Gemini 内联数据的请求体上限为 20MB
+const geminiInlineAudioMaxBytes = 20 * 1024 * 1024
+
+const geminiDefaultTTSVoice = "Kore"
+
+// geminiAudioTokensPerSecond Gemini 音频每秒折合 32 token，上游未返回输出用量时按音频时长估算
+const geminiAudioTokensPerSecond = 32
+
+var geminiTTSVoices = []string{
+	"Zephyr", "Puck", "Charon", "Kore", "Fenrir", "Leda", "Orus", "Aoede", "Callirrhoe", "Autonoe",
+	"Enceladus", "Iapetus", "Umbriel", "Algieba", "Despina", "Erinome", "Algenib", "Rasalgethi", "Laomedeia", "Achernar",
+	"Alnilam", "Schedar", "Gacrux", "Pulcherrima", "Achird", "Zubenelgenubi", "Vindemiatrix", "Sadachbia", "Sadaltager", "Sulafat",
+}
+
+var geminiAudioMimeTypes = map[string]string{
+	".mp3":  "audio/mp3",
+	".mpeg": "audio/mp3",
+	".mpga": "audio/mp3",
+	".wav":  "audio/wav",
+	".aiff": "audio/aiff",
+	".aac":  "audio/aac",
+	".ogg":  "audio/ogg",
+	".oga":  "audio/ogg",
+	".opus": "audio/ogg",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp4":  "audio/mp4",
+	".webm": "audio/webm",
+}
+
+// geminiTTSVoice OpenAI 的音色名在 Gemini 中不存在，未匹配到 Gemini 音色时使用默认音色
+func geminiTTSVoice(voice string) string {
+	for _, v := range geminiTTSVoices {
+		if strings.EqualFold(v, voice) {
+			return v
+		}
+	}
+	return geminiDefaultTTSVoice
+}
+
+func geminiSafetySettings() []dto.GeminiChatSafetySettings {
+	safetySettings := make([]dto.GeminiChatSafetySettings, 0, len(SafetySettingList))
+	for _, category := range SafetySettingList {
+		safetySettings = append(safetySettings, dto.GeminiChatSafetySettings{
+			Category:  category,
+			Threshold: model_setting.GetGeminiSafetySetting(category),
+		})
+	}
+	return safetySettings
+}
+
+// convertTTSRequest 将 /v1/audio/speech 转为 Gemini TTS 模型的 generateContent 请求。
+// Gemini 只输出 PCM，不做 mp3、opus 等格式的转码，只支持 wav 与 pcm，未指定时返回 wav
+func convertTTSRequest(request dto.AudioRequest) (*dto.GeminiChatRequest, error) {
+	if request.Input == "" {
+		return nil, errors.New("input is required")
+	}
+	switch request.ResponseFormat {
+	case "", "wav", "pcm":
+	default:
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("response_format %s is not supported for gemini, supported formats are wav and pcm", request.ResponseFormat),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	speechConfig, err := common.Marshal(map[string]any{
+		"voiceConfig": map[string]any{
+			"prebuiltVoiceConfig": map[string]any{
+				"voiceName": geminiTTSVoice(request.Voice),
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &dto.GeminiChatRequest{
+		Contents: []dto.GeminiChatContent{
+			{
+				Role:  "user",
+				Parts: []dto.GeminiPart{{Text: request.Input}},
+			},
+		},
+		GenerationConfig: dto.GeminiChatGenerationConfig{
+			ResponseModalities: []string{"AUDIO"},
+			SpeechConfig:       speechConfig,
+		},
+	}, nil
+}
+
+// convertSTTRequest 将 /v1/audio/transcriptions、/v1/audio/translations 的音频文件以内联数据交给 Gemini 理解
+func convertSTTRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.AudioRequest) (*dto.GeminiChatRequest, error) {
+	switch request.ResponseFormat {
+	case "json", "text", "verbose_json":
+	default:
+		return nil, types.NewErrorWithStatusCode(
+			fmt.Errorf("response_format %s is not supported for gemini, supported formats are json, text and verbose_json", request.ResponseFormat),
+			types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		return nil, errors.New("file is required")
+	}
+	defer file.Close()
+	if header.Size > geminiInlineAudioMaxBytes {
+		return nil, fmt.Errorf("audio file is too large for gemini, max size is %d bytes", geminiInlineAudioMaxBytes)
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("read audio file failed: %w", err)
+	}
+	mimeType, ok := geminiAudioMimeTypes[strings.ToLower(filepath.Ext(header.Filename))]
+	if !ok {
+		mimeType = header.Header.Get("Content-Type")
+	}
+	if !strings.HasPrefix(mimeType, "audio/") {
+		return nil, fmt.Errorf("unsupported audio file: %s", header.Filename)
+	}
+
+	formData := c.Request.PostForm
+	var instruction string
+	if info.RelayMode == relayconstant.RelayModeAudioTranslation {
+		instruction = "Translate the speech in this audio into English. Output only the translated text, without any explanation."
+	} else {
+		instruction = "Generate a verbatim transcript of the speech in this audio. Output only the transcript text, without any explanation."
+		if language := formData.Get("language"); language != "" {
+			instruction += fmt.Sprintf(" The audio is in language %s.", language)
+		}
+	}
+	if prompt := formData.Get("prompt"); prompt != "" {
+		instruction += "\nContext and spelling hints: " + prompt
+	}
+
+	geminiRequest := &dto.GeminiChatRequest{
+		Contents: []dto.GeminiChatContent{
+			{
+				Role: "user",
+				Parts: []dto.GeminiPart{
+					{Text: instruction},
+					{
+						InlineData: &dto.GeminiInlineData{
+							MimeType: mimeType,
+							Data:     base64.StdEncoding.EncodeToString(data),
+						},
+					},
+				},
+			},
+		},
+		SafetySettings: geminiSafetySettings(),
+	}
+	if temperature, err := strconv.ParseFloat(formData.Get("temperature"), 64); err == nil {
+		geminiRequest.GenerationConfig.Temperature = common.GetPointer(temperature)
+	}
+	return geminiRequest, nil
+}
+
+func geminiAudioUsage(metadata dto.GeminiUsageMetadata) *dto.Usage {
+	usage := &dto.Usage{
+		PromptTokens:     metadata.PromptTokenCount,
+		CompletionTokens: metadata.CandidatesTokenCount,
+		TotalTokens:      metadata.TotalTokenCount,
+	}
+	for _, detail := range metadata.PromptTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.PromptTokensDetails.AudioTokens = detail.TokenCount
+		} else if detail.Modality == "TEXT" {
+			usage.PromptTokensDetails.TextTokens = detail.TokenCount
+		}
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
+
+func readGeminiAudioResponse(resp *http.Response) (*dto.GeminiChatResponse, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	common.CloseResponseBodyGracefully(resp)
+	var geminiResponse dto.GeminiChatResponse
+	if err = common.Unmarshal(responseBody, &geminiResponse); err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if len(geminiResponse.Candidates) == 0 {
+		return nil, types.NewOpenAIError(errors.New("no candidates returned"), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	return &geminiResponse, nil
+}
+
+// pcmToWav 为 16 位单声道 PCM 数据加上 WAV 头
+func pcmToWav(pcm []byte, sampleRate int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(44 + len(pcm))
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(36+len(pcm)))
+	buf.WriteString("WAVEfmt ")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(16))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // PCM
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(1)) // 单声道
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(sampleRate*2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(2))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(16))
+	buf.WriteString("data")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(pcm)))
+	buf.Write(pcm)
+	return buf.Bytes()
+}
+
+// pcmDuration 16 位单声道 PCM 数据的时长（秒）
+func pcmDuration(pcm []byte, sampleRate int) float64 {
+	return float64(len(pcm)) / float64(sampleRate*2)
+}
+
+// pcmSampleRate 从 "audio/L16;codec=pcm;rate=24000" 中解析采样率
+func pcmSampleRate(mimeType string) int {
+	for _, param := range strings.Split(mimeType, ";") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(param), "="); ok && key == "rate" {
+			if rate, err := strconv.Atoi(value); err == nil && rate > 0 {
+				return rate
+			}
+		}
+	}
+	return 24000
+}
+
+// GeminiTTSHandler Gemini 只输出 PCM，response_format 为 pcm 时原样返回，否则加上 WAV 头返回
+func GeminiTTSHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response, responseFormat string) (*dto.Usage, *types.NewAPIError) {
+	geminiResponse, apiErr := readGeminiAudioResponse(resp)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	var inlineData *dto.GeminiInlineData
+	for _, part := range geminiResponse.Candidates[0].Content.Parts {
+		if part.InlineData != nil && strings.HasPrefix(part.InlineData.MimeType, "audio/") {
+			inlineData = part.InlineData
+			break
+		}
+	}
+	if inlineData == nil {
+		return nil, types.NewOpenAIError(errors.New("no audio returned"), types.ErrorCodeEmptyResponse, http.StatusInternalServerError)
+	}
+	pcm, err := base64.StdEncoding.DecodeString(inlineData.Data)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	sampleRate := pcmSampleRate(inlineData.MimeType)
+	if responseFormat == "pcm" {
+		c.Data(http.StatusOK, "audio/pcm", pcm)
+	} else {
+		c.Data(http.StatusOK, "audio/wav", pcmToWav(pcm, sampleRate))
+	}
+
+	usage := geminiAudioUsage(geminiResponse.UsageMetadata)
+	if usage.CompletionTokens == 0 {
+		usage.CompletionTokens = int(math.Ceil(pcmDuration(pcm, sampleRate) * geminiAudioTokensPerSecond))
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	usage.CompletionTokenDetails.AudioTokens = usage.CompletionTokens
+	if usage.PromptTokens == 0 {
+		usage.PromptTokens = info.PromptTokens
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage, nil
+}
+
+func GeminiSTTHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response, responseFormat string) (*dto.Usage, *types.NewAPIError) {
+	geminiResponse, apiErr := readGeminiAudioResponse(resp)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	var texts []string
+	for _, part := range geminiResponse.Candidates[0].Content.Parts {
+		if !part.Thought && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(texts, ""))
+
+	switch responseFormat {
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(text))
+	case "verbose_json":
+		task := "transcribe"
+		if info.RelayMode == relayconstant.RelayModeAudioTranslation {
+			task = "translate"
+		}
+		c.JSON(http.StatusOK, dto.WhisperVerboseJSONResponse{
+			Task:     task,
+			Language: c.Request.PostForm.Get("language"),
+			Text:     text,
+		})
+	default:
+		c.JSON(http.StatusOK, dto.AudioResponse{Text: text})
+	}
+	return geminiAudioUsage(geminiResponse.UsageMetadata), nil
+}
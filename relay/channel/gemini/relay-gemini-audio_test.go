@@ -0,0 +1,24 @@
+package gemini
+
+import (
+	"errors"
+	"net/http"
+	"one-api/dto"
+	"one-api/types"
+	"testing"
+)
+
+func TestConvertTTSRequestRejectsUntranscodedFormats(t *testing.T) {
+	for _, format := range []string{"mp3", "opus", "aac", "flac"} {
+		_, err := convertTTSRequest(dto.AudioRequest{Input: "hello", ResponseFormat: format})
+		var apiErr *types.NewAPIError
+		if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+			t.Fatalf("response_format %s should be rejected with 400, got %v", format, err)
+		}
+	}
+	for _, format := range []string{"", "wav", "pcm"} {
+		if _, err := convertTTSRequest(dto.AudioRequest{Input: "hello", ResponseFormat: format}); err != nil {
+			t.Fatalf("response_format %q should be accepted, got %v", format, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package gemini
+
+import (
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"strings"
+)
+
+// applyClaudeRequestOptions 补充经 OpenAI 格式转换时丢失的 Claude 参数：停止序列、top_k、tool_choice 与思考预算。
+// 消息、system、工具及工具结果已由 Claude -> OpenAI -> Gemini 的转换处理
+func applyClaudeRequestOptions(geminiRequest *dto.GeminiChatRequest, req *dto.ClaudeRequest, info *relaycommon.RelayInfo) {
+	if len(req.StopSequences) > 0 {
+		geminiRequest.GenerationConfig.StopSequences = req.StopSequences
+	}
+	if req.TopK > 0 {
+		geminiRequest.GenerationConfig.TopK = float64(req.TopK)
+	}
+
+	if req.ToolChoice != nil {
+		if toolChoice, err := common.Any2Type[dto.ClaudeToolChoice](req.ToolChoice); err == nil {
+			switch toolChoice.Type {
+			case "auto":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "AUTO"},
+				}
+			case "any":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "ANY"},
+				}
+			case "tool":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{
+						Mode:                 "ANY",
+						AllowedFunctionNames: []string{toolChoice.Name},
+					},
+				}
+			case "none":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "NONE"},
+				}
+			}
+		}
+	}
+
+	// 客户端显式开启思考时以其预算为准，覆盖模型后缀推导出的配置
+	if req.Thinking != nil && req.Thinking.Type == "enabled" && supportsThinkingConfig(info.UpstreamModelName) {
+		thinkingConfig := &dto.GeminiThinkingConfig{
+			IncludeThoughts: true,
+		}
+		if budget := req.Thinking.GetBudgetTokens(); budget > 0 {
+			thinkingConfig.SetThinkingBudget(clampThinkingBudget(info.UpstreamModelName, budget))
+		}
+		geminiRequest.GenerationConfig.ThinkingConfig = thinkingConfig
+	}
+}
+
+// supportsThinkingConfig gemini 2.5 之前的模型不接受 thinkingConfig
+func supportsThinkingConfig(modelName string) bool {
+	return !strings.HasPrefix(modelName, "gemini-1") && !strings.HasPrefix(modelName, "gemini-2.0")
+}
@@ -0,0 +1,83 @@
+package gemini
+
+import (
+	"one-api/dto"
+	"strings"
+	"unicode/utf8"
+)
+
+// geminiSearchModelSuffix 模型名以 -search 结尾时开启 Google 搜索，如 gemini-2.5-flash-search
+const geminiSearchModelSuffix = "-search"
+
+func IsGeminiSearchModel(modelName string) bool {
+	return strings.HasSuffix(modelName, geminiSearchModelSuffix)
+}
+
+// EnableGoogleSearch 为请求加上 googleSearch 工具，已有时不重复添加
+func EnableGoogleSearch(request *dto.GeminiChatRequest) {
+	tools := request.GetTools()
+	for _, tool := range tools {
+		if tool.GoogleSearch != nil {
+			return
+		}
+	}
+	request.SetTools(append(tools, dto.GeminiChatTool{
+		GoogleSearch: make(map[string]string),
+	}))
+}
+
+// geminiGroundingAnnotations 将搜索来源转换为 OpenAI 的 url_citation，text 为已输出的回复文本，用于换算字符位置
+func geminiGroundingAnnotations(metadata *dto.GeminiGroundingMetadata, text string) []dto.Annotation {
+	if metadata == nil || len(metadata.GroundingChunks) == 0 {
+		return nil
+	}
+	annotations := make([]dto.Annotation, 0, len(metadata.GroundingSupports))
+	cited := make(map[int]bool)
+	for _, support := range metadata.GroundingSupports {
+		start, end := groundingSegmentRange(support.Segment, text)
+		for _, chunkIndex := range support.GroundingChunkIndices {
+			if chunkIndex < 0 || chunkIndex >= len(metadata.GroundingChunks) || metadata.GroundingChunks[chunkIndex].Web == nil {
+				continue
+			}
+			cited[chunkIndex] = true
+			web := metadata.GroundingChunks[chunkIndex].Web
+			annotations = append(annotations, dto.Annotation{
+				Type: "url_citation",
+				UrlCitation: &dto.UrlCitation{
+					Url:        web.Uri,
+					Title:      web.Title,
+					StartIndex: start,
+					EndIndex:   end,
+				},
+			})
+		}
+	}
+	// 没有被任何片段引用的来源也一并返回，位置为 0
+	for i, chunk := range metadata.GroundingChunks {
+		if cited[i] || chunk.Web == nil {
+			continue
+		}
+		annotations = append(annotations, dto.Annotation{
+			Type: "url_citation",
+			UrlCitation: &dto.UrlCitation{
+				Url:   chunk.Web.Uri,
+				Title: chunk.Web.Title,
+			},
+		})
+	}
+	return annotations
+}
+
+// groundingSegmentRange Gemini 返回的是字节偏移，OpenAI 使用字符位置，优先按片段文本定位
+func groundingSegmentRange(segment dto.GeminiGroundingSegment, text string) (int, int) {
+	if segment.Text != "" {
+		if idx := strings.Index(text, segment.Text); idx >= 0 {
+			start := utf8.RuneCountInString(text[:idx])
+			return start, start + utf8.RuneCountInString(segment.Text)
+		}
+	}
+	if segment.StartIndex >= 0 && segment.StartIndex <= segment.EndIndex && segment.EndIndex <= len(text) {
+		return utf8.RuneCountInString(text[:segment.StartIndex]), utf8.RuneCountInString(text[:segment.EndIndex])
+	}
+	return 0, 0
+}
@@ -0,0 +1,59 @@
+package gemini
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// imagenRequestExtension 图片生成请求中 OpenAI 格式之外的 Imagen 参数
+type imagenRequestExtension struct {
+	AspectRatio      string `json:"aspect_ratio,omitempty"`
+	NegativePrompt   string `json:"negative_prompt,omitempty"`
+	Seed             *int64 `json:"seed,omitempty"`
+	PersonGeneration string `json:"person_generation,omitempty"`
+}
+
+// https://ai.google.dev/gemini-api/docs/imagen#imagen-configuration
+var imagenAspectRatios = []string{"1:1", "3:4", "4:3", "9:16", "16:9"}
+
+var imagenPersonGenerations = []string{"dont_allow", "allow_adult", "allow_all"}
+
+// imagenSizeAspectRatios OpenAI 的 size 与 Imagen 各比例的实际输出尺寸
+var imagenSizeAspectRatios = map[string]string{
+	"1024x1024": "1:1",
+	"896x1280":  "3:4",
+	"1280x896":  "4:3",
+	"1024x1792": "9:16",
+	"768x1408":  "9:16",
+	"1792x1024": "16:9",
+	"1408x768":  "16:9",
+}
+
+// imagenAspectRatio 优先使用显式的 aspect_ratio，其次由 size 换算，size 也可以直接写比例
+func imagenAspectRatio(size string, aspectRatio string) (string, error) {
+	if aspectRatio == "" {
+		if ratio, ok := imagenSizeAspectRatios[size]; ok {
+			return ratio, nil
+		}
+		if !slices.Contains(imagenAspectRatios, size) {
+			return "1:1", nil
+		}
+		aspectRatio = size
+	}
+	if !slices.Contains(imagenAspectRatios, aspectRatio) {
+		return "", fmt.Errorf("invalid aspect_ratio %s, must be one of: %s", aspectRatio, strings.Join(imagenAspectRatios, ", "))
+	}
+	return aspectRatio, nil
+}
+
+// imagenPersonGeneration 未指定时默认允许生成成人
+func imagenPersonGeneration(personGeneration string) (string, error) {
+	if personGeneration == "" {
+		return "allow_adult", nil
+	}
+	if !slices.Contains(imagenPersonGenerations, personGeneration) {
+		return "", fmt.Errorf("invalid person_generation %s, must be one of: %s", personGeneration, strings.Join(imagenPersonGenerations, ", "))
+	}
+	return personGeneration, nil
+}
@@ -52,6 +52,7 @@ func GeminiTextGenerationHandler(c *gin.Context, info *relaycommon.RelayInfo, re
 			usage.PromptTokensDetails.TextTokens = detail.TokenCount
 		}
 	}
+	usage.PromptTokensDetails.CachedTokens = geminiCachedTokens(geminiResponse.UsageMetadata)
 
 	common.IOCopyBytesGracefully(c, resp, responseBody)
 
@@ -135,6 +136,7 @@ func GeminiTextGenerationStreamHandler(c *gin.Context, info *relaycommon.RelayIn
 					usage.PromptTokensDetails.TextTokens = detail.TokenCount
 				}
 			}
+			usage.PromptTokensDetails.CachedTokens = geminiCachedTokens(geminiResponse.UsageMetadata)
 		}
 
 		// 直接发送 GeminiChatResponse 响应
@@ -0,0 +1,579 @@
+package gemini
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"one-api/service"
+	"one-api/types"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responsesInputItem /v1/responses 的 input 项，可以是消息、函数调用或函数调用结果
+type responsesInputItem struct {
+	Type      string          `json:"type"`
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	CallId    string          `json:"call_id"`
+	Name      string          `json:"name"`
+	Arguments string          `json:"arguments"`
+	Output    json.RawMessage `json:"output"`
+}
+
+type responsesInputContent struct {
+	Type       string                 `json:"type"`
+	Text       string                 `json:"text"`
+	ImageUrl   string                 `json:"image_url"`
+	FileData   string                 `json:"file_data"`
+	FileId     string                 `json:"file_id"`
+	Filename   string                 `json:"filename"`
+	InputAudio *dto.MessageInputAudio `json:"input_audio"`
+}
+
+type responsesTextFormat struct {
+	Format struct {
+		Type   string `json:"type"`
+		Schema any    `json:"schema"`
+	} `json:"format"`
+}
+
+// ConvertResponsesRequest 将 /v1/responses 请求先转为 OpenAI Chat 格式，复用 Chat -> Gemini 的转换，
+// 再补充 tool_choice、结构化输出与推理强度
+func ConvertResponsesRequest(c *gin.Context, request dto.OpenAIResponsesRequest, info *relaycommon.RelayInfo) (*dto.GeminiChatRequest, error) {
+	if request.PreviousResponseID != "" {
+		return nil, errors.New("previous_response_id is not supported for gemini channels")
+	}
+	messages, err := responsesInput2Messages(request)
+	if err != nil {
+		return nil, err
+	}
+	oaiRequest := dto.GeneralOpenAIRequest{
+		Model:     request.Model,
+		Messages:  messages,
+		Stream:    request.Stream,
+		MaxTokens: request.MaxOutputTokens,
+		TopP:      request.TopP,
+	}
+	if request.Temperature != 0 {
+		oaiRequest.Temperature = common.GetPointer(request.Temperature)
+	}
+	if request.Reasoning != nil {
+		oaiRequest.ReasoningEffort = request.Reasoning.Effort
+	}
+	for _, tool := range request.Tools {
+		switch common.Interface2String(tool["type"]) {
+		case "function":
+			oaiRequest.Tools = append(oaiRequest.Tools, dto.ToolCallRequest{
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:        common.Interface2String(tool["name"]),
+					Description: common.Interface2String(tool["description"]),
+					Parameters:  tool["parameters"],
+				},
+			})
+		case dto.BuildInToolWebSearchPreview, "web_search":
+			oaiRequest.Tools = append(oaiRequest.Tools, dto.ToolCallRequest{
+				Type:     "function",
+				Function: dto.FunctionRequest{Name: "googleSearch"},
+			})
+		}
+	}
+
+	geminiRequest, err := ConvertGemini2OpenAI(c, oaiRequest, info)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(request.ToolChoice) > 0 {
+		var mode string
+		if common.Unmarshal(request.ToolChoice, &mode) == nil {
+			switch mode {
+			case "auto":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "AUTO"},
+				}
+			case "required":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "ANY"},
+				}
+			case "none":
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{Mode: "NONE"},
+				}
+			}
+		} else {
+			var toolChoice struct {
+				Type string `json:"type"`
+				Name string `json:"name"`
+			}
+			if common.Unmarshal(request.ToolChoice, &toolChoice) == nil && toolChoice.Type == "function" && toolChoice.Name != "" {
+				geminiRequest.ToolConfig = &dto.GeminiToolConfig{
+					FunctionCallingConfig: &dto.GeminiFunctionCallingConfig{
+						Mode:                 "ANY",
+						AllowedFunctionNames: []string{toolChoice.Name},
+					},
+				}
+			}
+		}
+	}
+
+	if len(request.Text) > 0 {
+		var textFormat responsesTextFormat
+		if common.Unmarshal(request.Text, &textFormat) == nil {
+			switch textFormat.Format.Type {
+			case "json_object":
+				geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
+			case "json_schema":
+				geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
+				if textFormat.Format.Schema != nil {
+					geminiRequest.GenerationConfig.ResponseSchema = removeAdditionalPropertiesWithDepth(textFormat.Format.Schema, 0)
+				}
+			}
+		}
+	}
+
+	// 客户端显式指定推理强度时以其为准，覆盖模型后缀推导出的配置
+	if request.Reasoning != nil && request.Reasoning.Effort != "" && supportsThinkingConfig(info.UpstreamModelName) {
+		budget := 0
+		if request.Reasoning.Effort != "minimal" && request.Reasoning.Effort != "none" {
+			budget = clampThinkingBudgetByEffort(info.UpstreamModelName, request.Reasoning.Effort)
+		}
+		thinkingConfig := &dto.GeminiThinkingConfig{}
+		thinkingConfig.SetThinkingBudget(clampThinkingBudget(info.UpstreamModelName, budget))
+		geminiRequest.GenerationConfig.ThinkingConfig = thinkingConfig
+	}
+	return geminiRequest, nil
+}
+
+// responsesInput2Messages 将 instructions 与 input 转为 OpenAI Chat 消息
+func responsesInput2Messages(request dto.OpenAIResponsesRequest) ([]dto.Message, error) {
+	messages := make([]dto.Message, 0)
+	if len(request.Instructions) > 0 {
+		var instructions string
+		if common.Unmarshal(request.Instructions, &instructions) == nil && instructions != "" {
+			messages = append(messages, dto.Message{Role: "system", Content: instructions})
+		}
+	}
+
+	var inputText string
+	if common.Unmarshal(request.Input, &inputText) == nil {
+		return append(messages, dto.Message{Role: "user", Content: inputText}), nil
+	}
+	var items []responsesInputItem
+	if err := common.Unmarshal(request.Input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+	for _, item := range items {
+		switch item.Type {
+		case "function_call":
+			toolCall := dto.ToolCallRequest{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			}
+			// 连续的函数调用合并到同一条 assistant 消息
+			if last := len(messages) - 1; last >= 0 && messages[last].Role == "assistant" {
+				messages[last].SetToolCalls(append(messages[last].ParseToolCalls(), toolCall))
+				continue
+			}
+			message := dto.Message{Role: "assistant"}
+			message.SetNullContent()
+			message.SetToolCalls([]dto.ToolCallRequest{toolCall})
+			messages = append(messages, message)
+		case "function_call_output":
+			output := string(item.Output)
+			var outputText string
+			if common.Unmarshal(item.Output, &outputText) == nil {
+				output = outputText
+			}
+			messages = append(messages, dto.Message{
+				Role:       "tool",
+				Content:    output,
+				ToolCallId: item.CallId,
+			})
+		case "message", "":
+			role := item.Role
+			if role == "developer" {
+				role = "system"
+			}
+			message := dto.Message{Role: role}
+			var contentText string
+			if common.Unmarshal(item.Content, &contentText) == nil {
+				message.SetStringContent(contentText)
+				messages = append(messages, message)
+				continue
+			}
+			var contents []responsesInputContent
+			if err := common.Unmarshal(item.Content, &contents); err != nil {
+				return nil, fmt.Errorf("invalid input message content: %w", err)
+			}
+			mediaContents := make([]dto.MediaContent, 0, len(contents))
+			for _, content := range contents {
+				switch content.Type {
+				case "input_text", "output_text":
+					mediaContents = append(mediaContents, dto.MediaContent{
+						Type: dto.ContentTypeText,
+						Text: content.Text,
+					})
+				case "input_image":
+					mediaContents = append(mediaContents, dto.MediaContent{
+						Type:     dto.ContentTypeImageURL,
+						ImageUrl: &dto.MessageImageUrl{Url: content.ImageUrl},
+					})
+				case "input_file":
+					mediaContents = append(mediaContents, dto.MediaContent{
+						Type: dto.ContentTypeFile,
+						File: &dto.MessageFile{
+							FileName: content.Filename,
+							FileData: content.FileData,
+							FileId:   content.FileId,
+						},
+					})
+				case "input_audio":
+					if content.InputAudio != nil {
+						mediaContents = append(mediaContents, dto.MediaContent{
+							Type:       dto.ContentTypeInputAudio,
+							InputAudio: content.InputAudio,
+						})
+					}
+				}
+			}
+			message.SetMediaContent(mediaContents)
+			messages = append(messages, message)
+		}
+		// reasoning 等其他类型的输入项 Gemini 无法使用，忽略
+	}
+	return messages, nil
+}
+
+// geminiResponsesUsage 计算计费用量，同时填充 /v1/responses 格式的 input/output 字段
+func geminiResponsesUsage(metadata dto.GeminiUsageMetadata) *dto.Usage {
+	usage := &dto.Usage{
+		PromptTokens: metadata.PromptTokenCount,
+		TotalTokens:  metadata.TotalTokenCount,
+	}
+	usage.CompletionTokens = usage.TotalTokens - usage.PromptTokens
+	usage.CompletionTokenDetails.ReasoningTokens = metadata.ThoughtsTokenCount
+	for _, detail := range metadata.PromptTokensDetails {
+		if detail.Modality == "AUDIO" {
+			usage.PromptTokensDetails.AudioTokens = detail.TokenCount
+		} else if detail.Modality == "TEXT" {
+			usage.PromptTokensDetails.TextTokens = detail.TokenCount
+		}
+	}
+	usage.PromptTokensDetails.CachedTokens = geminiCachedTokens(metadata)
+	return usage
+}
+
+func fillResponsesUsage(usage *dto.Usage) {
+	usage.InputTokens = usage.PromptTokens
+	usage.OutputTokens = usage.CompletionTokens
+	usage.InputTokensDetails = &dto.InputTokenDetails{
+		CachedTokens: usage.PromptTokensDetails.CachedTokens,
+	}
+}
+
+func newResponsesResponse(info *relaycommon.RelayInfo, id string, createdAt int64) *dto.OpenAIResponsesResponse {
+	return &dto.OpenAIResponsesResponse{
+		ID:        id,
+		Object:    "response",
+		CreatedAt: int(createdAt),
+		Status:    "in_progress",
+		Model:     info.UpstreamModelName,
+		Output:    make([]dto.ResponsesOutput, 0),
+	}
+}
+
+// setResponsesStatus 根据 Gemini 的结束原因设置响应状态，输出被截断时为 incomplete
+func setResponsesStatus(response *dto.OpenAIResponsesResponse, finishReason string) {
+	switch finishReason {
+	case "", "STOP":
+		response.Status = "completed"
+	case "MAX_TOKENS":
+		response.Status = "incomplete"
+		response.IncompleteDetails = &dto.IncompleteDetails{Reasoning: "max_output_tokens"}
+	default:
+		response.Status = "incomplete"
+		response.IncompleteDetails = &dto.IncompleteDetails{Reasoning: "content_filter"}
+	}
+}
+
+func newResponsesFunctionCall(part *dto.GeminiPart) *dto.ResponsesOutput {
+	call := getResponseToolCall(part)
+	if call == nil {
+		return nil
+	}
+	return &dto.ResponsesOutput{
+		Type:      "function_call",
+		ID:        fmt.Sprintf("fc_%s", common.GetUUID()),
+		Status:    "completed",
+		Content:   []dto.ResponsesOutputContent{},
+		CallId:    call.ID,
+		Name:      call.Function.Name,
+		Arguments: call.Function.Arguments,
+	}
+}
+
+func newResponsesMessage(text string) dto.ResponsesOutput {
+	return dto.ResponsesOutput{
+		Type:   "message",
+		ID:     fmt.Sprintf("msg_%s", common.GetUUID()),
+		Status: "completed",
+		Role:   "assistant",
+		Content: []dto.ResponsesOutputContent{
+			{
+				Type:        "output_text",
+				Text:        text,
+				Annotations: []interface{}{},
+			},
+		},
+	}
+}
+
+func GeminiResponsesHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	common.CloseResponseBodyGracefully(resp)
+	if common.DebugEnabled {
+		println(string(responseBody))
+	}
+	var geminiResponse dto.GeminiChatResponse
+	err = common.Unmarshal(responseBody, &geminiResponse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if len(geminiResponse.Candidates) == 0 {
+		return nil, types.NewOpenAIError(errors.New("no candidates returned"), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
+	response := newResponsesResponse(info, fmt.Sprintf("resp_%s", c.GetString(common.RequestIdKey)), common.GetTimestamp())
+	candidate := geminiResponse.Candidates[0]
+	var texts []string
+	for _, part := range candidate.Content.Parts {
+		if part.FunctionCall != nil {
+			if call := newResponsesFunctionCall(&part); call != nil {
+				response.Output = append(response.Output, *call)
+			}
+		} else if part.ExecutableCode != nil {
+			texts = append(texts, "```"+part.ExecutableCode.Language+"\n"+part.ExecutableCode.Code+"\n```")
+		} else if part.CodeExecutionResult != nil {
+			texts = append(texts, "```output\n"+part.CodeExecutionResult.Output+"\n```")
+		} else if !part.Thought && part.Text != "" && part.Text != "\n" {
+			texts = append(texts, part.Text)
+		}
+	}
+	if len(texts) > 0 {
+		// 文本消息放在函数调用之前
+		response.Output = append([]dto.ResponsesOutput{newResponsesMessage(strings.Join(texts, "\n"))}, response.Output...)
+	}
+	finishReason := ""
+	if candidate.FinishReason != nil {
+		finishReason = *candidate.FinishReason
+	}
+	setResponsesStatus(response, finishReason)
+
+	usage := geminiResponsesUsage(geminiResponse.UsageMetadata)
+	fillResponsesUsage(usage)
+	response.Usage = usage
+
+	responseBody, err = common.Marshal(response)
+	if err != nil {
+		return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+	}
+	common.IOCopyBytesGracefully(c, resp, responseBody)
+	return usage, nil
+}
+
+// geminiResponsesStream 将 Gemini 流式响应转换为 /v1/responses 的事件序列
+type geminiResponsesStream struct {
+	c        *gin.Context
+	response *dto.OpenAIResponsesResponse
+	// 正在输出的文本消息，-1 表示没有
+	messageIndex int
+	messageText  strings.Builder
+}
+
+func (s *geminiResponsesStream) send(event dto.ResponsesStreamResponse) {
+	data, err := common.Marshal(event)
+	if err != nil {
+		common.LogError(s.c, "marshal responses stream event failed: "+err.Error())
+		return
+	}
+	helper.ResponseChunkData(s.c, event, string(data))
+}
+
+func (s *geminiResponsesStream) appendText(text string) {
+	if s.messageIndex < 0 {
+		item := newResponsesMessage("")
+		item.Status = "in_progress"
+		item.Content = []dto.ResponsesOutputContent{}
+		s.response.Output = append(s.response.Output, item)
+		s.messageIndex = len(s.response.Output) - 1
+		s.messageText.Reset()
+		s.send(dto.ResponsesStreamResponse{
+			Type:        dto.ResponsesOutputTypeItemAdded,
+			OutputIndex: common.GetPointer(s.messageIndex),
+			Item:        &item,
+		})
+		s.send(dto.ResponsesStreamResponse{
+			Type:         "response.content_part.added",
+			ItemId:       item.ID,
+			OutputIndex:  common.GetPointer(s.messageIndex),
+			ContentIndex: common.GetPointer(0),
+			Part:         &dto.ResponsesOutputContent{Type: "output_text", Annotations: []interface{}{}},
+		})
+	}
+	s.messageText.WriteString(text)
+	s.send(dto.ResponsesStreamResponse{
+		Type:         "response.output_text.delta",
+		ItemId:       s.response.Output[s.messageIndex].ID,
+		OutputIndex:  common.GetPointer(s.messageIndex),
+		ContentIndex: common.GetPointer(0),
+		Delta:        text,
+	})
+}
+
+func (s *geminiResponsesStream) closeMessage() {
+	if s.messageIndex < 0 {
+		return
+	}
+	item := &s.response.Output[s.messageIndex]
+	part := dto.ResponsesOutputContent{Type: "output_text", Text: s.messageText.String(), Annotations: []interface{}{}}
+	item.Status = "completed"
+	item.Content = []dto.ResponsesOutputContent{part}
+	s.send(dto.ResponsesStreamResponse{
+		Type:         "response.output_text.done",
+		ItemId:       item.ID,
+		OutputIndex:  common.GetPointer(s.messageIndex),
+		ContentIndex: common.GetPointer(0),
+		Text:         part.Text,
+	})
+	s.send(dto.ResponsesStreamResponse{
+		Type:         "response.content_part.done",
+		ItemId:       item.ID,
+		OutputIndex:  common.GetPointer(s.messageIndex),
+		ContentIndex: common.GetPointer(0),
+		Part:         &part,
+	})
+	s.send(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemDone,
+		OutputIndex: common.GetPointer(s.messageIndex),
+		Item:        item,
+	})
+	s.messageIndex = -1
+}
+
+func (s *geminiResponsesStream) appendFunctionCall(call *dto.ResponsesOutput) {
+	s.closeMessage()
+	s.response.Output = append(s.response.Output, *call)
+	outputIndex := len(s.response.Output) - 1
+	added := *call
+	added.Status = "in_progress"
+	added.Arguments = ""
+	s.send(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemAdded,
+		OutputIndex: common.GetPointer(outputIndex),
+		Item:        &added,
+	})
+	s.send(dto.ResponsesStreamResponse{
+		Type:        "response.function_call_arguments.delta",
+		ItemId:      call.ID,
+		OutputIndex: common.GetPointer(outputIndex),
+		Delta:       call.Arguments,
+	})
+	s.send(dto.ResponsesStreamResponse{
+		Type:        "response.function_call_arguments.done",
+		ItemId:      call.ID,
+		OutputIndex: common.GetPointer(outputIndex),
+		Arguments:   call.Arguments,
+	})
+	s.send(dto.ResponsesStreamResponse{
+		Type:        dto.ResponsesOutputTypeItemDone,
+		OutputIndex: common.GetPointer(outputIndex),
+		Item:        call,
+	})
+}
+
+func GeminiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	stream := &geminiResponsesStream{
+		c:            c,
+		response:     newResponsesResponse(info, fmt.Sprintf("resp_%s", c.GetString(common.RequestIdKey)), common.GetTimestamp()),
+		messageIndex: -1,
+	}
+	responseText := strings.Builder{}
+	usage := &dto.Usage{}
+	finishReason := ""
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var geminiResponse dto.GeminiChatResponse
+		err := common.UnmarshalJsonStr(data, &geminiResponse)
+		if err != nil {
+			common.LogError(c, "error unmarshalling stream response: "+err.Error())
+			return false
+		}
+		if info.SendResponseCount == 0 {
+			created := *stream.response
+			stream.send(dto.ResponsesStreamResponse{Type: "response.created", Response: &created})
+			stream.send(dto.ResponsesStreamResponse{Type: "response.in_progress", Response: &created})
+		}
+		info.SendResponseCount++
+
+		if len(geminiResponse.Candidates) > 0 {
+			candidate := geminiResponse.Candidates[0]
+			for _, part := range candidate.Content.Parts {
+				if part.FunctionCall != nil {
+					if call := newResponsesFunctionCall(&part); call != nil {
+						stream.appendFunctionCall(call)
+					}
+				} else if part.ExecutableCode != nil {
+					stream.appendText("```" + part.ExecutableCode.Language + "\n" + part.ExecutableCode.Code + "\n```\n")
+				} else if part.CodeExecutionResult != nil {
+					stream.appendText("```output\n" + part.CodeExecutionResult.Output + "\n```\n")
+				} else if !part.Thought && part.Text != "" {
+					responseText.WriteString(part.Text)
+					stream.appendText(part.Text)
+				}
+			}
+			if candidate.FinishReason != nil {
+				finishReason = *candidate.FinishReason
+			}
+		}
+		if geminiResponse.UsageMetadata.TotalTokenCount != 0 {
+			usage = geminiResponsesUsage(geminiResponse.UsageMetadata)
+		}
+		return true
+	})
+
+	if info.SendResponseCount == 0 {
+		// 空补全，报错不计费
+		return nil, types.NewOpenAIError(errors.New("no response received from Gemini API"), types.ErrorCodeEmptyResponse, http.StatusInternalServerError)
+	}
+
+	if usage.CompletionTokens == 0 && responseText.Len() > 0 {
+		usage = service.ResponseText2Usage(responseText.String(), info.UpstreamModelName, info.PromptTokens)
+	}
+	fillResponsesUsage(usage)
+
+	stream.closeMessage()
+	setResponsesStatus(stream.response, finishReason)
+	stream.response.Usage = usage
+	eventType := "response.completed"
+	if stream.response.Status == "incomplete" {
+		eventType = "response.incomplete"
+	}
+	stream.send(dto.ResponsesStreamResponse{Type: eventType, Response: stream.response})
+	return usage, nil
+}
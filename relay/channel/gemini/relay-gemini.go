@@ -1,6 +1,8 @@
 package gemini
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,13 +11,15 @@ import (
 	"one-api/common"
 	"one-api/constant"
 	"one-api/dto"
+	"one-api/relay/channel"
 	"one-api/relay/channel/openai"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
 	"one-api/service"
 	"one-api/setting/model_setting"
+	"one-api/setting/operation_setting"
+	"one-api/setting/ratio_setting"
 	"one-api/types"
-	"strconv"
 	"strings"
 	"unicode/utf8"
 
@@ -99,8 +103,7 @@ func clampThinkingBudgetByEffort(modelName string, effort string) int {
 	maxBudget := 0
 	if is25FlashLite {
 		maxBudget = flash25LiteMaxBudget
-	}
-	if isNew25Pro {
+	} else if isNew25Pro {
 		maxBudget = pro25MaxBudget
 	} else {
 		maxBudget = flash25MaxBudget
@@ -116,25 +119,36 @@ func clampThinkingBudgetByEffort(modelName string, effort string) int {
 	return clampThinkingBudget(modelName, maxBudget)
 }
 
+// ThinkingVariantBaseModel 返回发送给上游的模型名：-search、-thinking-<budget> 后缀总是去掉，
+// -thinking、-nothinking 后缀仅在开启思考适配时去掉
+func ThinkingVariantBaseModel(modelName string) string {
+	modelName = strings.TrimSuffix(modelName, geminiSearchModelSuffix)
+	variant := ratio_setting.ParseModelVariant(modelName)
+	if variant.Variant == ratio_setting.ModelVariantBudget || model_setting.GetGeminiSettings().ThinkingAdapterEnabled {
+		return variant.BaseModel
+	}
+	return modelName
+}
+
+// ThinkingAdaptor 根据模型名后缀设置思考参数，-thinking-<budget> 的预算不依赖思考适配开关
 func ThinkingAdaptor(geminiRequest *dto.GeminiChatRequest, info *relaycommon.RelayInfo, oaiRequest ...dto.GeneralOpenAIRequest) {
+	modelName := strings.TrimSuffix(info.UpstreamModelName, geminiSearchModelSuffix)
+	variant := ratio_setting.ParseModelVariant(modelName)
+	if variant.Variant == ratio_setting.ModelVariantBudget {
+		// 预算按去掉后缀后的模型名限制范围
+		clampedBudget := clampThinkingBudget(variant.BaseModel, variant.Budget)
+		geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
+			ThinkingBudget:  common.GetPointer(clampedBudget),
+			IncludeThoughts: clampedBudget != 0,
+		}
+		return
+	}
 	if model_setting.GetGeminiSettings().ThinkingAdapterEnabled {
-		modelName := info.UpstreamModelName
 		isNew25Pro := strings.HasPrefix(modelName, "gemini-2.5-pro") &&
 			!strings.HasPrefix(modelName, "gemini-2.5-pro-preview-05-06") &&
 			!strings.HasPrefix(modelName, "gemini-2.5-pro-preview-03-25")
 
-		if strings.Contains(modelName, "-thinking-") {
-			parts := strings.SplitN(modelName, "-thinking-", 2)
-			if len(parts) == 2 && parts[1] != "" {
-				if budgetTokens, err := strconv.Atoi(parts[1]); err == nil {
-					clampedBudget := clampThinkingBudget(modelName, budgetTokens)
-					geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
-						ThinkingBudget:  common.GetPointer(clampedBudget),
-						IncludeThoughts: true,
-					}
-				}
-			}
-		} else if strings.HasSuffix(modelName, "-thinking") {
+		if variant.Variant == ratio_setting.ModelVariantThinking {
 			unsupportedModels := []string{
 				"gemini-2.5-pro-preview-05-06",
 				"gemini-2.5-pro-preview-03-25",
@@ -166,7 +180,7 @@ func ThinkingAdaptor(geminiRequest *dto.GeminiChatRequest, info *relaycommon.Rel
 					}
 				}
 			}
-		} else if strings.HasSuffix(modelName, "-nothinking") {
+		} else if variant.Variant == ratio_setting.ModelVariantNoThinking {
 			if !isNew25Pro {
 				geminiRequest.GenerationConfig.ThinkingConfig = &dto.GeminiThinkingConfig{
 					ThinkingBudget: common.GetPointer(0),
@@ -176,6 +190,31 @@ func ThinkingAdaptor(geminiRequest *dto.GeminiChatRequest, info *relaycommon.Rel
 	}
 }
 
+// mergeExtraGenerationConfig 将 extra_body 中的 generation_config 原样透传，字段名统一转为 Gemini 使用的 camelCase
+func mergeExtraGenerationConfig(config *dto.GeminiChatGenerationConfig, fields map[string]interface{}) error {
+	if config.ExtraFields == nil {
+		config.ExtraFields = make(map[string]json.RawMessage, len(fields))
+	}
+	for key, value := range fields {
+		data, err := common.Marshal(value)
+		if err != nil {
+			return err
+		}
+		config.ExtraFields[snakeToCamel(key)] = data
+	}
+	return nil
+}
+
+func snakeToCamel(s string) string {
+	parts := strings.Split(s, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
 // Setting safety to the lowest possible values since Gemini is already powerless enough
 func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest, info *relaycommon.RelayInfo) (*dto.GeminiChatRequest, error) {
 
@@ -199,13 +238,13 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 	adaptorWithExtraBody := false
 
 	if len(textRequest.ExtraBody) > 0 {
-		if !strings.HasSuffix(info.UpstreamModelName, "-nothinking") {
-			var extraBody map[string]interface{}
-			if err := common.Unmarshal(textRequest.ExtraBody, &extraBody); err != nil {
-				return nil, fmt.Errorf("invalid extra body: %w", err)
-			}
+		var extraBody map[string]interface{}
+		if err := common.Unmarshal(textRequest.ExtraBody, &extraBody); err != nil {
+			return nil, fmt.Errorf("invalid extra body: %w", err)
+		}
+		if googleBody, ok := extraBody["google"].(map[string]interface{}); ok {
 			// eg. {"google":{"thinking_config":{"thinking_budget":5324,"include_thoughts":true}}}
-			if googleBody, ok := extraBody["google"].(map[string]interface{}); ok {
+			if !strings.HasSuffix(info.UpstreamModelName, "-nothinking") {
 				adaptorWithExtraBody = true
 				if thinkingConfig, ok := googleBody["thinking_config"].(map[string]interface{}); ok {
 					if budget, ok := thinkingConfig["thinking_budget"].(float64); ok {
@@ -221,6 +260,12 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 					}
 				}
 			}
+			// eg. {"google":{"generation_config":{"candidate_count":2,"presence_penalty":0.5,"media_resolution":"MEDIA_RESOLUTION_LOW"}}}
+			if generationConfig, ok := googleBody["generation_config"].(map[string]interface{}); ok {
+				if err := mergeExtraGenerationConfig(&geminiRequest.GenerationConfig, generationConfig); err != nil {
+					return nil, fmt.Errorf("invalid extra body generation_config: %w", err)
+				}
+			}
 		}
 	}
 
@@ -237,10 +282,11 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 	}
 	geminiRequest.SafetySettings = safetySettings
 
+	// web_search_options 或 -search 后缀开启 Google 搜索
+	googleSearch := textRequest.WebSearchOptions != nil || IsGeminiSearchModel(info.UpstreamModelName)
 	// openaiContent.FuncToToolCalls()
 	if textRequest.Tools != nil {
 		functions := make([]dto.FunctionRequest, 0, len(textRequest.Tools))
-		googleSearch := false
 		codeExecution := false
 		for _, tool := range textRequest.Tools {
 			if tool.Function.Name == "googleSearch" {
@@ -273,11 +319,6 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 				CodeExecution: make(map[string]string),
 			})
 		}
-		if googleSearch {
-			geminiTools = append(geminiTools, dto.GeminiChatTool{
-				GoogleSearch: make(map[string]string),
-			})
-		}
 		if len(functions) > 0 {
 			geminiTools = append(geminiTools, dto.GeminiChatTool{
 				FunctionDeclarations: functions,
@@ -285,6 +326,9 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 		}
 		geminiRequest.SetTools(geminiTools)
 	}
+	if googleSearch {
+		EnableGoogleSearch(&geminiRequest)
+	}
 
 	if textRequest.ResponseFormat != nil && (textRequest.ResponseFormat.Type == "json_schema" || textRequest.ResponseFormat.Type == "json_object") {
 		geminiRequest.GenerationConfig.ResponseMimeType = "application/json"
@@ -468,21 +512,25 @@ func ConvertGemini2OpenAI(c *gin.Context, textRequest dto.GeneralOpenAIRequest,
 				},
 			},
 		}
+	}
 
-		if valRaw, ok := common.GetContextKey(c, constant.ContextKeyTokenEnableGeminiCache); ok {
-			if val, ok := valRaw.(bool); ok && val {
-				//Attaching cached SystemInstructions
-				cacheName, IsCacheJustCreated, createdTokens, err := GetOrCreateGeminiCache(info.ApiKey, info.ChannelId, info.UpstreamModelName, &geminiRequest)
-				if err == nil && cacheName != "" {
-					geminiRequest.CachedContent = cacheName
-					if IsCacheJustCreated {
-						info.IsGeminiCacheCreation = true
-						info.GeminiCacheCreationTokens = createdTokens
-					}
-					common.SysLog("Gemini cache attached: " + cacheName)
-				} else if err != nil {
-					common.SysLog("Failed to use Gemini cache: " + err.Error())
+	if valRaw, ok := common.GetContextKey(c, constant.ContextKeyTokenEnableGeminiCache); ok {
+		// 客户端可以通过 cache.enabled=false 关闭本次请求的缓存
+		if val, ok := valRaw.(bool); ok && val && !info.CacheOptions.IsCacheDisabled() {
+			// Attaching cached SystemInstructions and stable history prefix
+			cacheOptions := NewGeminiCacheOptions(info.UserId, info.TokenId, info.UpstreamModelName, info.CacheOptions)
+			cacheResult, err := GetOrCreateGeminiCache(info.ApiKey, info.ChannelId, info.UpstreamModelName, &geminiRequest, cacheOptions)
+			if err == nil && cacheResult != nil {
+				ApplyGeminiCache(&geminiRequest, cacheResult)
+				info.GeminiCacheLookupLatency = cacheResult.LookupLatency
+				info.GeminiCacheCreationLatency = cacheResult.CreationLatency
+				if cacheResult.IsJustCreated {
+					info.IsGeminiCacheCreation = true
+					info.GeminiCacheCreationTokens = cacheResult.CreationTokens
 				}
+				common.SysLogSampled(common.LogCategoryGeminiCache, fmt.Sprintf("Gemini cache attached: %s, prefix contents: %d", cacheResult.CacheName, cacheResult.PrefixLength))
+			} else if err != nil {
+				common.SysLog("Failed to use Gemini cache: " + err.Error())
 			}
 		}
 	}
@@ -744,6 +792,22 @@ func getResponseToolCall(item *dto.GeminiPart) *dto.ToolCallResponse {
 	}
 }
 
+// geminiCachedTokens 返回命中上下文缓存的 token 数，优先使用上游返回的 cachedContentTokenCount，
+// 旧版本响应没有该字段时，按 promptTokensDetails 未覆盖的部分估算
+func geminiCachedTokens(metadata dto.GeminiUsageMetadata) int {
+	if metadata.CachedContentTokenCount > 0 {
+		return metadata.CachedContentTokenCount
+	}
+	sumDetails := 0
+	for _, detail := range metadata.PromptTokensDetails {
+		sumDetails += detail.TokenCount
+	}
+	if len(metadata.PromptTokensDetails) > 0 && sumDetails < metadata.PromptTokenCount {
+		return metadata.PromptTokenCount - sumDetails
+	}
+	return 0
+}
+
 func responseGeminiChat2OpenAI(c *gin.Context, response *dto.GeminiChatResponse) *dto.OpenAITextResponse {
 	fullTextResponse := dto.OpenAITextResponse{
 		Id:      helper.GetResponseID(c),
@@ -789,8 +853,9 @@ func responseGeminiChat2OpenAI(c *gin.Context, response *dto.GeminiChatResponse)
 				choice.Message.SetToolCalls(toolCalls)
 				isToolCall = true
 			}
-			choice.Message.SetStringContent(strings.Join(texts, "\n"))
-
+			content := strings.Join(texts, "\n")
+			choice.Message.SetStringContent(content)
+			choice.Message.Annotations = geminiGroundingAnnotations(candidate.GroundingMetadata, content)
 		}
 		if candidate.FinishReason != nil {
 			switch *candidate.FinishReason {
@@ -934,6 +999,12 @@ func GeminiChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *
 		}
 
 		response, isStop := streamResponseGeminiChat2OpenAI(&geminiResponse)
+		// 搜索来源一般随最后一个分片返回，引用位置按已输出的全部文本换算
+		for i, candidate := range geminiResponse.Candidates {
+			if candidate.GroundingMetadata != nil && i < len(response.Choices) {
+				response.Choices[i].Delta.Annotations = geminiGroundingAnnotations(candidate.GroundingMetadata, responseText.String())
+			}
+		}
 
 		response.Id = id
 		response.Created = createAt
@@ -944,19 +1015,14 @@ func GeminiChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *
 			usage.CompletionTokenDetails.ReasoningTokens = geminiResponse.UsageMetadata.ThoughtsTokenCount
 			usage.TotalTokens = geminiResponse.UsageMetadata.TotalTokenCount
 
-			sumDetails := 0
 			for _, detail := range geminiResponse.UsageMetadata.PromptTokensDetails {
 				if detail.Modality == "AUDIO" {
 					usage.PromptTokensDetails.AudioTokens = detail.TokenCount
 				} else if detail.Modality == "TEXT" {
 					usage.PromptTokensDetails.TextTokens = detail.TokenCount
 				}
-				sumDetails += detail.TokenCount
-			}
-
-			if sumDetails < usage.PromptTokens {
-				usage.PromptTokensDetails.CachedTokens = usage.PromptTokens - sumDetails
 			}
+			usage.PromptTokensDetails.CachedTokens = geminiCachedTokens(geminiResponse.UsageMetadata)
 		}
 
 		if info.SendResponseCount == 0 {
@@ -1054,19 +1120,14 @@ func GeminiChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.R
 	usage.CompletionTokenDetails.ReasoningTokens = geminiResponse.UsageMetadata.ThoughtsTokenCount
 	usage.CompletionTokens = usage.TotalTokens - usage.PromptTokens
 
-	sumDetails := 0
 	for _, detail := range geminiResponse.UsageMetadata.PromptTokensDetails {
 		if detail.Modality == "AUDIO" {
 			usage.PromptTokensDetails.AudioTokens = detail.TokenCount
 		} else if detail.Modality == "TEXT" {
 			usage.PromptTokensDetails.TextTokens = detail.TokenCount
 		}
-		sumDetails += detail.TokenCount
-	}
-
-	if sumDetails < usage.PromptTokens {
-		usage.PromptTokensDetails.CachedTokens = usage.PromptTokens - sumDetails
 	}
+	usage.PromptTokensDetails.CachedTokens = geminiCachedTokens(geminiResponse.UsageMetadata)
 
 	fullTextResponse.Usage = usage
 
@@ -1105,6 +1166,15 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *h
 		return nil, types.NewOpenAIError(jsonErr, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
 	}
 
+	if newAPIError := fetchGeminiEmbeddingChunks(c, info, &geminiResponse); newAPIError != nil {
+		return nil, newAPIError
+	}
+
+	inputs, hasInputs := common.GetContextKeyType[[]string](c, geminiEmbeddingInputsKey)
+	if hasInputs && len(geminiResponse.Embeddings) != len(inputs) {
+		return nil, types.NewOpenAIError(fmt.Errorf("gemini returned %d embeddings for %d inputs", len(geminiResponse.Embeddings), len(inputs)), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+
 	// convert to openai format response
 	openAIResponse := dto.OpenAIEmbeddingResponse{
 		Object: "list",
@@ -1122,13 +1192,29 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *h
 
 	// calculate usage
 	// https://ai.google.dev/gemini-api/docs/pricing?hl=zh-cn#text-embedding-004
-	// Google has not yet clarified how embedding models will be billed
 	// refer to openai billing method to use input tokens billing
 	// https://platform.openai.com/docs/guides/embeddings#what-are-embeddings
+	// 优先使用上游返回的 usageMetadata，其次调用 countTokens，都失败时才使用本地估算
+	promptTokens := 0
+	if geminiResponse.UsageMetadata != nil {
+		promptTokens = geminiResponse.UsageMetadata.PromptTokenCount
+	}
+	if promptTokens == 0 {
+		if hasInputs && len(inputs) > 0 {
+			count, err := countGeminiEmbeddingTokens(info, inputs)
+			if err != nil {
+				common.LogWarn(c, "failed to count gemini embedding tokens: "+err.Error())
+			}
+			promptTokens = count
+		}
+	}
+	if promptTokens == 0 {
+		promptTokens = info.PromptTokens
+	}
 	usage := &dto.Usage{
-		PromptTokens:     info.PromptTokens,
+		PromptTokens:     promptTokens,
 		CompletionTokens: 0,
-		TotalTokens:      info.PromptTokens,
+		TotalTokens:      promptTokens,
 	}
 	openAIResponse.Usage = *usage
 
@@ -1141,7 +1227,107 @@ func GeminiEmbeddingHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *h
 	return usage, nil
 }
 
-func GeminiImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+// geminiEmbeddingInputsKey 保存 embedding 请求的原始输入
+const geminiEmbeddingInputsKey = "gemini_embedding_inputs"
+
+// geminiBatchEmbeddingMaxInputs batchEmbedContents 单次最多接受的请求数
+const geminiBatchEmbeddingMaxInputs = 100
+
+// geminiEmbeddingChunksKey 超过单次上限的 embedding 请求拆分后尚未发送的分片
+const geminiEmbeddingChunksKey = "gemini_embedding_chunks"
+
+// fetchGeminiEmbeddingChunks 依次请求剩余分片，按输入顺序追加向量并累计 usage
+func fetchGeminiEmbeddingChunks(c *gin.Context, info *relaycommon.RelayInfo, geminiResponse *dto.GeminiBatchEmbeddingResponse) *types.NewAPIError {
+	chunks, ok := common.GetContextKeyType[[][]map[string]interface{}](c, geminiEmbeddingChunksKey)
+	if !ok {
+		return nil
+	}
+	for _, chunk := range chunks {
+		body, err := common.Marshal(map[string]interface{}{
+			"requests": chunk,
+		})
+		if err != nil {
+			return types.NewError(err, types.ErrorCodeJsonMarshalFailed)
+		}
+		resp, err := channel.DoApiRequest(&Adaptor{}, c, info, bytes.NewReader(body))
+		if err != nil {
+			return types.NewOpenAIError(err, types.ErrorCodeDoRequestFailed, http.StatusInternalServerError)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return service.RelayErrorHandler(resp, false)
+		}
+		responseBody, err := io.ReadAll(resp.Body)
+		common.CloseResponseBodyGracefully(resp)
+		if err != nil {
+			return types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		var chunkResponse dto.GeminiBatchEmbeddingResponse
+		if err := common.Unmarshal(responseBody, &chunkResponse); err != nil {
+			return types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		if len(chunkResponse.Embeddings) != len(chunk) {
+			return types.NewOpenAIError(fmt.Errorf("gemini returned %d embeddings for %d inputs", len(chunkResponse.Embeddings), len(chunk)), types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		geminiResponse.Embeddings = append(geminiResponse.Embeddings, chunkResponse.Embeddings...)
+		// 任一分片缺少 usageMetadata 时改为对全部输入调用 countTokens
+		if geminiResponse.UsageMetadata != nil && chunkResponse.UsageMetadata != nil {
+			geminiResponse.UsageMetadata.PromptTokenCount += chunkResponse.UsageMetadata.PromptTokenCount
+		} else {
+			geminiResponse.UsageMetadata = nil
+		}
+	}
+	return nil
+}
+
+// countGeminiEmbeddingTokens 调用 countTokens 统计 embedding 输入的 token 数
+func countGeminiEmbeddingTokens(info *relaycommon.RelayInfo, inputs []string) (int, error) {
+	contents := make([]dto.GeminiChatContent, 0, len(inputs))
+	for _, input := range inputs {
+		contents = append(contents, dto.GeminiChatContent{
+			Parts: []dto.GeminiPart{{Text: input}},
+		})
+	}
+	body, err := common.Marshal(map[string]any{
+		"contents": contents,
+	})
+	if err != nil {
+		return 0, err
+	}
+	version := model_setting.GetGeminiVersionSetting(info.UpstreamModelName)
+	url := fmt.Sprintf("%s/%s/models/%s:countTokens", info.BaseUrl, version, info.UpstreamModelName)
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(body)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-goog-api-key", info.ApiKey)
+
+	client := service.GetHttpClient()
+	if info.ChannelSetting.Proxy != "" {
+		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
+		if err != nil {
+			return 0, fmt.Errorf("new proxy http client failed: %w", err)
+		}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("count tokens failed with status code: %d", resp.StatusCode)
+	}
+	var countResp struct {
+		TotalTokens int `json:"totalTokens"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&countResp); err != nil {
+		return 0, err
+	}
+	return countResp.TotalTokens, nil
+}
+
+// GeminiImageHandler 请求 response_format 为 url 或渠道开启图片 URL 输出时，将图片上传到图片存储并返回 URL
+func GeminiImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response, responseFormat string) (*dto.Usage, *types.NewAPIError) {
 	responseBody, readErr := io.ReadAll(resp.Body)
 	if readErr != nil {
 		return nil, types.NewOpenAIError(readErr, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
@@ -1163,12 +1349,28 @@ func GeminiImageHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.
 		Data:    make([]dto.ImageData, 0, len(geminiResponse.Predictions)),
 	}
 
+	urlOutput := (responseFormat == "url" || (responseFormat == "" && info.ChannelSetting.ImageURLOutput)) &&
+		operation_setting.GetImageStorageSetting().IsEnabled()
 	for _, prediction := range geminiResponse.Predictions {
 		if prediction.RaiFilteredReason != "" {
 			continue // skip filtered image
 		}
+		if !urlOutput {
+			openAIResponse.Data = append(openAIResponse.Data, dto.ImageData{
+				B64Json: prediction.BytesBase64Encoded,
+			})
+			continue
+		}
+		imageData, err := base64.StdEncoding.DecodeString(prediction.BytesBase64Encoded)
+		if err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+		}
+		imageURL, err := service.StoreImage(imageData, prediction.MimeType)
+		if err != nil {
+			return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponse, http.StatusInternalServerError)
+		}
 		openAIResponse.Data = append(openAIResponse.Data, dto.ImageData{
-			B64Json: prediction.BytesBase64Encoded,
+			Url: imageURL,
 		})
 	}
 
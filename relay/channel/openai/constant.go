@@ -23,6 +23,7 @@ var ModelList = []string{
 	"gpt-5-nano", "gpt-5-nano-2025-08-07",
 	"o1", "o1-2024-12-17",
 	"gpt-4o-audio-preview", "gpt-4o-audio-preview-2024-10-01",
+	"gpt-4o-mini-audio-preview", "gpt-audio", "gpt-audio-mini",
 	"gpt-4o-realtime-preview", "gpt-4o-realtime-preview-2024-10-01", "gpt-4o-realtime-preview-2024-12-17",
 	"gpt-4o-mini-realtime-preview", "gpt-4o-mini-realtime-preview-2024-12-17",
 	"text-embedding-ada-002", "text-embedding-3-small", "text-embedding-3-large",
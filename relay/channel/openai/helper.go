@@ -168,6 +168,26 @@ func processCompletions(streamResp string, streamItems []string, responseTextBui
 	return nil
 }
 
+// cutOffStreamData 响应被截断时先发出尚未发送的块，再以 finish_reason 为 length 的块作为最后的响应
+func cutOffStreamData(c *gin.Context, info *relaycommon.RelayInfo, lastStreamData string) string {
+	if info.RelayMode != relayconstant.RelayModeChatCompletions || lastStreamData == "" {
+		return lastStreamData
+	}
+	var lastStreamResponse dto.ChatCompletionsStreamResponse
+	if err := json.Unmarshal(common.StringToByteSlice(lastStreamData), &lastStreamResponse); err != nil {
+		return lastStreamData
+	}
+	if err := HandleStreamFormat(c, info, lastStreamData, info.ChannelSetting.ForceFormat, info.ChannelSetting.ThinkingToContent); err != nil {
+		common.SysError("error handling stream format: " + err.Error())
+	}
+	stopResponse := helper.GenerateStopResponse(lastStreamResponse.Id, lastStreamResponse.Created, lastStreamResponse.Model, "length")
+	stopData, err := json.Marshal(stopResponse)
+	if err != nil {
+		return lastStreamData
+	}
+	return string(stopData)
+}
+
 func handleLastResponse(lastStreamData string, responseId *string, createAt *int64,
 	systemFingerprint *string, model *string, usage **dto.Usage,
 	containStreamUsage *bool, info *relaycommon.RelayInfo,
@@ -30,6 +30,8 @@ func sendStreamData(c *gin.Context, info *relaycommon.RelayInfo, data string, fo
 		return nil
 	}
 
+	data = helper.RepairJsonStreamData(info, data)
+
 	if !forceFormat && !thinkToContent {
 		return helper.StringData(c, data)
 	}
@@ -126,6 +128,9 @@ func OaiStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Re
 	var lastStreamData string
 
 	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		if len(info.ChannelSetting.ResponseFixups) > 0 && len(data) > 0 {
+			data = string(applyResponseFixups(c, info, []byte(data), true))
+		}
 		if lastStreamData != "" {
 			err := HandleStreamFormat(c, info, lastStreamData, info.ChannelSetting.ForceFormat, info.ChannelSetting.ThinkingToContent)
 			if err != nil {
@@ -139,6 +144,11 @@ func OaiStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Re
 		return true
 	})
 
+	// 响应上限由 StreamScannerHandler 检查，截断时补发 finish_reason 为 length 的块
+	if info.ResponseCutOff {
+		lastStreamData = cutOffStreamData(c, info, lastStreamData)
+	}
+
 	// 处理最后的响应
 	shouldSendLastResp := true
 	if err := handleLastResponse(lastStreamData, &responseId, &createAt, &systemFingerprint, &model, &usage,
@@ -183,6 +193,7 @@ func OpenaiHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 	if common.DebugEnabled {
 		println("upstream response body:", string(responseBody))
 	}
+	responseBody = applyResponseFixups(c, info, responseBody, false)
 	err = common.Unmarshal(responseBody, &simpleResponse)
 	if err != nil {
 		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
@@ -209,6 +220,20 @@ func OpenaiHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Respo
 		}
 	}
 
+	if info.JsonRepairer != nil {
+		for i := range simpleResponse.Choices {
+			content, repaired := common.ExtractFirstJSON(simpleResponse.Choices[i].Message.StringContent())
+			if repaired {
+				simpleResponse.Choices[i].Message.SetStringContent(content)
+				info.JsonRepairer.Repaired = true
+			}
+		}
+		// 直接改写原始响应体，保留 dto 中未定义的字段
+		if repairedBody, ok := helper.RepairJsonResponseBody(responseBody); ok {
+			responseBody = repairedBody
+		}
+	}
+
 	switch info.RelayFormat {
 	case relaycommon.RelayFormatOpenAI:
 		if forceFormat {
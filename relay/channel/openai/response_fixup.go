@@ -0,0 +1,158 @@
+package openai
+
+import (
+	"one-api/common"
+	"one-api/constant"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/relay/helper"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// responseFixupContext 修正响应时需要的请求信息
+type responseFixupContext struct {
+	info       *relaycommon.RelayInfo
+	responseId string
+	stream     bool // 流式响应的单个数据块
+}
+
+// responseFixupFunc 修正一个响应对象
+type responseFixupFunc func(resp map[string]any, ctx *responseFixupContext)
+
+var responseFixupFuncs = map[string]responseFixupFunc{
+	dto.ResponseFixupFillFields:   fixupFillFields,
+	dto.ResponseFixupChoiceIndex:  fixupChoiceIndex,
+	dto.ResponseFixupFinishReason: fixupFinishReason,
+	dto.ResponseFixupMessageRole:  fixupMessageRole,
+	dto.ResponseFixupUsageNumbers: fixupUsageNumbers,
+}
+
+// applyResponseFixups 按渠道设置的修正项处理上游返回的 JSON，未配置修正项或无法解析时原样返回
+func applyResponseFixups(c *gin.Context, info *relaycommon.RelayInfo, data []byte, stream bool) []byte {
+	fixups := info.ChannelSetting.ResponseFixups
+	if len(fixups) == 0 {
+		return data
+	}
+	var resp map[string]any
+	if err := common.Unmarshal(data, &resp); err != nil || resp == nil {
+		return data
+	}
+	if _, isError := resp["error"]; isError {
+		return data
+	}
+	ctx := &responseFixupContext{
+		info:       info,
+		responseId: helper.GetResponseID(c),
+		stream:     stream,
+	}
+	for _, name := range fixups {
+		if fixup, ok := responseFixupFuncs[name]; ok {
+			fixup(resp, ctx)
+		}
+	}
+	fixed, err := common.Marshal(resp)
+	if err != nil {
+		return data
+	}
+	return fixed
+}
+
+func responseChoices(resp map[string]any) []map[string]any {
+	items, _ := resp["choices"].([]any)
+	choices := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		if choice, ok := item.(map[string]any); ok {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+func fixupFillFields(resp map[string]any, ctx *responseFixupContext) {
+	if id, _ := resp["id"].(string); id == "" {
+		resp["id"] = ctx.responseId
+	}
+	if object, _ := resp["object"].(string); object == "" {
+		if ctx.stream {
+			resp["object"] = "chat.completion.chunk"
+		} else {
+			resp["object"] = "chat.completion"
+		}
+	}
+	if _, ok := resp["created"].(float64); !ok {
+		resp["created"] = ctx.info.StartTime.Unix()
+	}
+	if model, _ := resp["model"].(string); model == "" {
+		resp["model"] = ctx.info.UpstreamModelName
+	}
+}
+
+func fixupChoiceIndex(resp map[string]any, ctx *responseFixupContext) {
+	for i, choice := range responseChoices(resp) {
+		if _, ok := choice["index"].(float64); !ok {
+			choice["index"] = i
+		}
+	}
+}
+
+func fixupFinishReason(resp map[string]any, ctx *responseFixupContext) {
+	for _, choice := range responseChoices(resp) {
+		value, exists := choice["finish_reason"]
+		if !exists || value == nil {
+			continue
+		}
+		reason, isString := value.(string)
+		if !isString {
+			// 部分服务返回布尔值或数字表示已结束
+			choice["finish_reason"] = constant.FinishReasonStop
+			continue
+		}
+		switch strings.ToLower(reason) {
+		case "", "null", "none":
+			choice["finish_reason"] = nil
+		case "stop", "eos", "eos_token", "end_turn", "stop_sequence", "end":
+			choice["finish_reason"] = constant.FinishReasonStop
+		case "length", "max_tokens", "max_length":
+			choice["finish_reason"] = constant.FinishReasonLength
+		case "tool_calls", "tool_use", "function_call":
+			choice["finish_reason"] = constant.FinishReasonToolCalls
+		case "content_filter":
+			choice["finish_reason"] = constant.FinishReasonContentFilter
+		default:
+			choice["finish_reason"] = strings.ToLower(reason)
+		}
+	}
+}
+
+func fixupMessageRole(resp map[string]any, ctx *responseFixupContext) {
+	if ctx.stream {
+		// 流式响应只有首个数据块携带 role
+		return
+	}
+	for _, choice := range responseChoices(resp) {
+		message, ok := choice["message"].(map[string]any)
+		if !ok {
+			continue
+		}
+		if role, _ := message["role"].(string); role == "" {
+			message["role"] = "assistant"
+		}
+	}
+}
+
+func fixupUsageNumbers(resp map[string]any, ctx *responseFixupContext) {
+	usage, ok := resp["usage"].(map[string]any)
+	if !ok {
+		return
+	}
+	for key, value := range usage {
+		if str, isString := value.(string); isString {
+			if number, err := strconv.Atoi(strings.TrimSpace(str)); err == nil {
+				usage[key] = number
+			}
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package vertex
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -13,7 +14,6 @@ import (
 	"one-api/relay/channel/openai"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/constant"
-	"one-api/setting/model_setting"
 	"one-api/types"
 	"strings"
 
@@ -43,6 +43,12 @@ const anthropicVersion = "vertex-2023-10-16"
 type Adaptor struct {
 	RequestMode        int
 	AccountCredentials Credentials
+	ExpressMode        bool // 使用 API key 鉴权的 express 模式，仅支持 Gemini 模型
+}
+
+// isExpressModeKey key 不是服务账号 JSON 时视为 express 模式的 API key
+func isExpressModeKey(apiKey string) bool {
+	return !strings.HasPrefix(strings.TrimSpace(apiKey), "{")
 }
 
 func (a *Adaptor) ConvertGeminiRequest(c *gin.Context, info *relaycommon.RelayInfo, request *dto.GeminiChatRequest) (any, error) {
@@ -56,6 +62,7 @@ func (a *Adaptor) ConvertClaudeRequest(c *gin.Context, info *relaycommon.RelayIn
 	} else {
 		c.Set("request_model", request.Model)
 	}
+	claude.ApplyClaudePromptCache(c, info, request)
 	vertexClaudeReq := copyRequest(request, anthropicVersion)
 	return vertexClaudeReq, nil
 }
@@ -82,25 +89,23 @@ func (a *Adaptor) Init(info *relaycommon.RelayInfo) {
 
 func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 	adc := &Credentials{}
-	if err := json.Unmarshal([]byte(info.ApiKey), adc); err != nil {
-		return "", fmt.Errorf("failed to decode credentials file: %w", err)
+	a.ExpressMode = isExpressModeKey(info.ApiKey)
+	if a.ExpressMode {
+		if a.RequestMode != RequestModeGemini {
+			return "", errors.New("vertex express mode only supports gemini models")
+		}
+	} else {
+		if err := json.Unmarshal([]byte(info.ApiKey), adc); err != nil {
+			return "", fmt.Errorf("failed to decode credentials file: %w", err)
+		}
+		a.AccountCredentials = *adc
 	}
 	region := GetModelRegion(info.ApiVersion, info.OriginModelName)
-	a.AccountCredentials = *adc
 	suffix := ""
 	if a.RequestMode == RequestModeGemini {
 
-		if model_setting.GetGeminiSettings().ThinkingAdapterEnabled {
-			// 新增逻辑：处理 -thinking-<budget> 格式
-			if strings.Contains(info.UpstreamModelName, "-thinking-") {
-				parts := strings.Split(info.UpstreamModelName, "-thinking-")
-				info.UpstreamModelName = parts[0]
-			} else if strings.HasSuffix(info.UpstreamModelName, "-thinking") { // 旧的适配
-				info.UpstreamModelName = strings.TrimSuffix(info.UpstreamModelName, "-thinking")
-			} else if strings.HasSuffix(info.UpstreamModelName, "-nothinking") {
-				info.UpstreamModelName = strings.TrimSuffix(info.UpstreamModelName, "-nothinking")
-			}
-		}
+		// 去掉 -search 与思考后缀，搜索工具与思考参数已在转换请求时设置
+		info.UpstreamModelName = gemini.ThinkingVariantBaseModel(info.UpstreamModelName)
 
 		if info.IsStream {
 			suffix = "streamGenerateContent?alt=sse"
@@ -112,6 +117,13 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 			suffix = "predict"
 		}
 
+		if a.ExpressMode {
+			return fmt.Sprintf(
+				"https://aiplatform.googleapis.com/v1/publishers/google/models/%s:%s",
+				info.UpstreamModelName,
+				suffix,
+			), nil
+		}
 		if region == "global" {
 			return fmt.Sprintf(
 				"https://aiplatform.googleapis.com/v1/projects/%s/locations/global/publishers/google/models/%s:%s",
@@ -169,6 +181,10 @@ func (a *Adaptor) GetRequestURL(info *relaycommon.RelayInfo) (string, error) {
 
 func (a *Adaptor) SetupRequestHeader(c *gin.Context, req *http.Header, info *relaycommon.RelayInfo) error {
 	channel.SetupApiRequestHeader(info, c, req)
+	if a.ExpressMode {
+		req.Set("x-goog-api-key", info.ApiKey)
+		return nil
+	}
 	accessToken, err := getAccessToken(a, info)
 	if err != nil {
 		return err
@@ -186,9 +202,10 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 		if err != nil {
 			return nil, err
 		}
-		vertexClaudeReq := copyRequest(claudeReq, anthropicVersion)
 		c.Set("request_model", claudeReq.Model)
 		info.UpstreamModelName = claudeReq.Model
+		claude.ApplyClaudePromptCache(c, info, claudeReq)
+		vertexClaudeReq := copyRequest(claudeReq, anthropicVersion)
 		return vertexClaudeReq, nil
 	} else if a.RequestMode == RequestModeGemini {
 		geminiRequest, err := gemini.ConvertGemini2OpenAI(c, *request, info)
@@ -204,7 +221,7 @@ func (a *Adaptor) ConvertOpenAIRequest(c *gin.Context, info *relaycommon.RelayIn
 }
 
 func (a *Adaptor) ConvertRerankRequest(c *gin.Context, relayMode int, request dto.RerankRequest) (any, error) {
-	return nil, nil
+	return nil, errors.New("not implemented: rerank is not supported by vertex channels")
 }
 
 func (a *Adaptor) ConvertEmbeddingRequest(c *gin.Context, info *relaycommon.RelayInfo, request dto.EmbeddingRequest) (any, error) {
@@ -218,7 +235,21 @@ func (a *Adaptor) ConvertOpenAIResponsesRequest(c *gin.Context, info *relaycommo
 }
 
 func (a *Adaptor) DoRequest(c *gin.Context, info *relaycommon.RelayInfo, requestBody io.Reader) (any, error) {
-	return channel.DoApiRequest(a, c, info, requestBody)
+	if a.ExpressMode {
+		return channel.DoApiRequest(a, c, info, requestBody)
+	}
+	body, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("read request body failed: %w", err)
+	}
+	resp, err := channel.DoApiRequest(a, c, info, bytes.NewReader(body))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	// 缓存的 access token 可能已被吊销，换新 token 重试一次；仍失败时交由自动禁用切换到其他服务账号
+	_ = resp.Body.Close()
+	invalidateAccessToken(a.AccountCredentials)
+	return channel.DoApiRequest(a, c, info, bytes.NewReader(body))
 }
 
 func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo) (usage any, err *types.NewAPIError) {
@@ -244,7 +275,7 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 				usage, err = gemini.GeminiTextGenerationHandler(c, info, resp)
 			} else {
 				if strings.HasPrefix(info.UpstreamModelName, "imagen") {
-					return gemini.GeminiImageHandler(c, info, resp)
+					return gemini.GeminiImageHandler(c, info, resp, "")
 				}
 				usage, err = gemini.GeminiChatHandler(c, info, resp)
 			}
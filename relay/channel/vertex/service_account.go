@@ -35,16 +35,26 @@ var Cache = asynccache.NewAsyncCache(asynccache.Options{
 	},
 })
 
+// accessTokenRefreshBefore access token 过期前提前刷新的时间
+const accessTokenRefreshBefore = 5 * time.Minute
+
+type accessToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// accessTokenCacheKey 按服务账号区分缓存，多 key 渠道调整 key 顺序后不会取到其他账号的 token
+func accessTokenCacheKey(credentials Credentials) string {
+	return fmt.Sprintf("access-token-%s-%s", credentials.ClientEmail, credentials.PrivateKeyID)
+}
+
 func getAccessToken(a *Adaptor, info *relaycommon.RelayInfo) (string, error) {
-	var cacheKey string
-	if info.ChannelIsMultiKey {
-		cacheKey = fmt.Sprintf("access-token-%d-%d", info.ChannelId, info.ChannelMultiKeyIndex)
-	} else {
-		cacheKey = fmt.Sprintf("access-token-%d", info.ChannelId)
-	}
+	cacheKey := accessTokenCacheKey(a.AccountCredentials)
 	val, err := Cache.Get(cacheKey)
 	if err == nil {
-		return val.(string), nil
+		if token, ok := val.(*accessToken); ok && time.Until(token.ExpiresAt) > accessTokenRefreshBefore {
+			return token.Token, nil
+		}
 	}
 
 	signedJWT, err := createSignedJWT(a.AccountCredentials.ClientEmail, a.AccountCredentials.PrivateKey)
@@ -55,10 +65,16 @@ func getAccessToken(a *Adaptor, info *relaycommon.RelayInfo) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to exchange JWT for access token: %w", err)
 	}
-	if err := Cache.SetDefault(cacheKey, newToken); err {
-		return newToken, nil
-	}
-	return newToken, nil
+	Cache.SetDefault(cacheKey, newToken)
+	return newToken.Token, nil
+}
+
+// invalidateAccessToken 上游鉴权失败时丢弃缓存的 token，下次请求重新获取
+func invalidateAccessToken(credentials Credentials) {
+	cacheKey := accessTokenCacheKey(credentials)
+	Cache.DeleteIf(func(key string) bool {
+		return key == cacheKey
+	})
 }
 
 func createSignedJWT(email, privateKeyPEM string) (string, error) {
@@ -102,7 +118,7 @@ func createSignedJWT(email, privateKeyPEM string) (string, error) {
 	return signedToken, nil
 }
 
-func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (string, error) {
+func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (*accessToken, error) {
 
 	authURL := "https://www.googleapis.com/oauth2/v4/token"
 	data := url.Values{}
@@ -114,7 +130,7 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 	if info.ChannelSetting.Proxy != "" {
 		client, err = service.NewProxyHttpClient(info.ChannelSetting.Proxy)
 		if err != nil {
-			return "", fmt.Errorf("new proxy http client failed: %w", err)
+			return nil, fmt.Errorf("new proxy http client failed: %w", err)
 		}
 	} else {
 		client = service.GetHttpClient()
@@ -122,18 +138,26 @@ func exchangeJwtForAccessToken(signedJWT string, info *relaycommon.RelayInfo) (s
 
 	resp, err := client.PostForm(authURL, data)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	var result map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
+		return nil, err
 	}
 
-	if accessToken, ok := result["access_token"].(string); ok {
-		return accessToken, nil
+	token, ok := result["access_token"].(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to get access token: %v", result)
 	}
-
-	return "", fmt.Errorf("failed to get access token: %v", result)
+	// access token 默认有效期为 1 小时
+	expiresIn := time.Hour
+	if seconds, ok := result["expires_in"].(float64); ok && seconds > 0 {
+		expiresIn = time.Duration(seconds) * time.Second
+	}
+	return &accessToken{
+		Token:     token,
+		ExpiresAt: time.Now().Add(expiresIn),
+	}, nil
 }
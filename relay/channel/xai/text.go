@@ -61,6 +61,7 @@ func xAIStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Re
 
 		openaiResponse := streamResponseXAI2OpenAI(xAIResp, usage)
 		_ = openai.ProcessStreamResponse(*openaiResponse, &responseTextBuilder, &toolCount)
+		helper.RepairJsonStreamResponse(info, openaiResponse)
 		err = helper.ObjectData(c, openaiResponse)
 		if err != nil {
 			common.SysError(err.Error())
@@ -105,3 +106,18 @@ func xAIHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response
 
 	return xaiResponse.Usage, nil
 }
+
+// claudeFormatHandler 以 Claude 格式返回响应，xAI 的 completion_tokens 不含推理 token，按 total_tokens 重新计算
+func claudeFormatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	var usage *dto.Usage
+	var err *types.NewAPIError
+	if info.IsStream {
+		usage, err = openai.OaiStreamHandler(c, info, resp)
+	} else {
+		usage, err = openai.OpenaiHandler(c, info, resp)
+	}
+	if usage != nil && usage.TotalTokens > usage.PromptTokens {
+		usage.CompletionTokens = usage.TotalTokens - usage.PromptTokens
+	}
+	return usage, err
+}
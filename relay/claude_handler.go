@@ -84,6 +84,9 @@ func ClaudeHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	if textRequest.MaxTokens == 0 {
 		textRequest.MaxTokens = uint(model_setting.GetClaudeSettings().GetDefaultMaxTokens(textRequest.Model))
 	}
+	if relayInfo.MaxResponseTokens > 0 && textRequest.MaxTokens > uint(relayInfo.MaxResponseTokens) {
+		textRequest.MaxTokens = uint(relayInfo.MaxResponseTokens)
+	}
 
 	if model_setting.GetClaudeSettings().ThinkingAdapterEnabled &&
 		strings.HasSuffix(textRequest.Model, "-thinking") {
@@ -0,0 +1,54 @@
+package common
+
+import (
+	"one-api/dto"
+	relayconstant "one-api/relay/constant"
+	"strings"
+)
+
+// GetChannelMode 返回请求对应的渠道请求类型，用于匹配渠道按请求类型设置的代理地址
+func GetChannelMode(relayMode int, path string) string {
+	switch relayMode {
+	case relayconstant.RelayModeChatCompletions, relayconstant.RelayModeCompletions, relayconstant.RelayModeEdits:
+		return dto.ChannelModeChat
+	case relayconstant.RelayModeResponses:
+		return dto.ChannelModeResponses
+	case relayconstant.RelayModeEmbeddings:
+		return dto.ChannelModeEmbeddings
+	case relayconstant.RelayModeImagesGenerations, relayconstant.RelayModeImagesEdits:
+		return dto.ChannelModeImages
+	case relayconstant.RelayModeAudioSpeech, relayconstant.RelayModeAudioTranscription, relayconstant.RelayModeAudioTranslation:
+		return dto.ChannelModeAudio
+	case relayconstant.RelayModeModerations:
+		return dto.ChannelModeModerations
+	case relayconstant.RelayModeRerank:
+		return dto.ChannelModeRerank
+	case relayconstant.RelayModeGemini:
+		if strings.Contains(path, "embed") {
+			return dto.ChannelModeEmbeddings
+		}
+		return dto.ChannelModeChat
+	}
+	// Claude messages 接口按对话处理
+	if strings.HasPrefix(path, "/v1/messages") {
+		return dto.ChannelModeChat
+	}
+	return ""
+}
+
+// applyModeEndpoint 渠道为当前请求类型设置了代理地址或 API 版本时，覆盖渠道的默认配置
+func (info *RelayInfo) applyModeEndpoint() {
+	if len(info.ChannelSetting.ModeEndpoints) == 0 {
+		return
+	}
+	endpoint, ok := info.ChannelSetting.ModeEndpoints[GetChannelMode(info.RelayMode, info.RequestURLPath)]
+	if !ok {
+		return
+	}
+	if endpoint.BaseURL != "" {
+		info.BaseUrl = strings.TrimSuffix(endpoint.BaseURL, "/")
+	}
+	if endpoint.ApiVersion != "" {
+		info.ApiVersion = endpoint.ApiVersion
+	}
+}
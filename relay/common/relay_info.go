@@ -93,6 +93,8 @@ type RelayInfo struct {
 	ShouldIncludeUsage   bool
 	DisablePing          bool // 是否禁止向下游发送自定义 Ping
 	IsModelMapped        bool
+	PriceOverridden      bool               // 管理员通过请求头覆盖了本次请求的倍率或价格
+	PriceOverrides       map[string]float64 // 实际生效的覆盖值，记录到消费日志
 	ClientWs             *websocket.Conn
 	TargetWs             *websocket.Conn
 	InputAudioFormat     string
@@ -102,6 +104,10 @@ type RelayInfo struct {
 	AudioUsage           bool
 	ReasoningEffort      string
 	ChannelSetting       dto.ChannelSettings
+	// JsonRepairer 非空表示需要修复 json_object 响应中 JSON 之外的内容
+	JsonRepairer *common.JsonStreamRepairer
+	// ImageModeration 生成图片的审核结论，非空时记录在日志中
+	ImageModeration *dto.ImageModerationResult
 	ChannelOtherSettings dto.ChannelOtherSettings
 	ParamOverride        map[string]interface{}
 	UserSetting          dto.UserSetting
@@ -112,6 +118,18 @@ type RelayInfo struct {
 	ChannelCreateTime    int64
 	IsGeminiCacheCreation bool
 	GeminiCacheCreationTokens int
+	// Gemini 缓存查询与创建在请求上游之前花费的时间
+	GeminiCacheLookupLatency   time.Duration
+	GeminiCacheCreationLatency time.Duration
+	// Claude 提示词缓存前缀的复用跟踪结果，跟踪后记录在日志中
+	ClaudePromptCacheTracked bool
+	ClaudePromptCacheHits    int
+	CacheOptions         *dto.CacheOptions // 客户端请求中的缓存控制字段，转换请求前已从请求体中移除
+	RequestMetadata      map[string]interface{} // 请求携带的 metadata，用于日志与回显
+	// 令牌设置的单次响应上限，0 表示不限制；超出时服务端截断流式响应
+	MaxResponseTokens int
+	MaxResponseBytes  int
+	ResponseCutOff    bool
 	ThinkingContentInfo
 	*ClaudeConvertInfo
 	*RerankerInfo
@@ -272,6 +290,8 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 
 		ChannelIsMultiKey:    common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey),
 		ChannelMultiKeyIndex: common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex),
+		MaxResponseTokens:    common.GetContextKeyInt(c, constant.ContextKeyTokenMaxResponseTokens),
+		MaxResponseBytes:     common.GetContextKeyInt(c, constant.ContextKeyTokenMaxResponseBytes),
 	}
 	if strings.HasPrefix(c.Request.URL.Path, "/pg") {
 		info.IsPlayground = true
@@ -295,6 +315,7 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 	if ok {
 		info.ChannelSetting = channelSetting
 	}
+	info.applyModeEndpoint()
 
 	channelOtherSettings, ok := common.GetContextKeyType[dto.ChannelOtherSettings](c, constant.ContextKeyChannelOtherSetting)
 	if ok {
@@ -306,6 +327,11 @@ func GenRelayInfo(c *gin.Context) *RelayInfo {
 		info.UserSetting = userSetting
 	}
 
+	requestMetadata, ok := common.GetContextKeyType[map[string]interface{}](c, constant.ContextKeyRequestMetadata)
+	if ok {
+		info.RequestMetadata = requestMetadata
+	}
+
 	return info
 }
 
@@ -91,26 +91,6 @@ func isNoThinkingRequest(req *dto.GeminiChatRequest) bool {
 	return false
 }
 
-func trimModelThinking(modelName string) string {
-	// 去除模型名称中的 -nothinking 后缀
-	if strings.HasSuffix(modelName, "-nothinking") {
-		return strings.TrimSuffix(modelName, "-nothinking")
-	}
-	// 去除模型名称中的 -thinking 后缀
-	if strings.HasSuffix(modelName, "-thinking") {
-		return strings.TrimSuffix(modelName, "-thinking")
-	}
-
-	// 去除模型名称中的 -thinking-number
-	if strings.Contains(modelName, "-thinking-") {
-		parts := strings.Split(modelName, "-thinking-")
-		if len(parts) > 1 {
-			return parts[0] + "-thinking"
-		}
-	}
-	return modelName
-}
-
 func GeminiHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	req, err := getAndValidateGeminiRequest(c)
 	if err != nil {
@@ -158,9 +138,12 @@ func GeminiHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 				}
 			}
 		}
-		if req.GenerationConfig.ThinkingConfig == nil {
-			gemini.ThinkingAdaptor(req, relayInfo)
-		}
+	}
+	if req.GenerationConfig.ThinkingConfig == nil {
+		gemini.ThinkingAdaptor(req, relayInfo)
+	}
+	if gemini.IsGeminiSearchModel(relayInfo.UpstreamModelName) {
+		gemini.EnableGoogleSearch(req)
 	}
 
 	priceData, err := helper.ModelPriceHelper(c, relayInfo, relayInfo.PromptTokens, int(req.GenerationConfig.MaxOutputTokens))
@@ -383,6 +366,10 @@ func GeminiEmbeddingHandler(c *gin.Context) (newAPIError *types.NewAPIError) {
 }
 
 func GetGeminiCacheChannelID(c *gin.Context, model string) int {
+	// 未启用 Redis 时缓存记录只在进程内，无需按渠道粘滞
+	if !common.RedisEnabled {
+		return 0
+	}
 	var request dto.GeminiMessagesRequest
 
 	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
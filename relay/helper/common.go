@@ -8,6 +8,7 @@ import (
 	"one-api/common"
 	"one-api/dto"
 	"one-api/types"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -64,6 +65,9 @@ func ResponseChunkData(c *gin.Context, resp dto.ResponsesStreamResponse, data st
 func StringData(c *gin.Context, str string) error {
 	//str = strings.TrimPrefix(str, "data: ")
 	//str = strings.TrimSuffix(str, "\r")
+	if strings.HasPrefix(str, "{") {
+		str = string(common.InjectRequestMetadata(c, []byte(str)))
+	}
 	c.Render(-1, common.CustomEvent{Data: "data: " + str})
 	if flusher, ok := c.Writer.(http.Flusher); ok {
 		flusher.Flush()
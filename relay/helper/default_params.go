@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"one-api/dto"
+	"one-api/setting/model_setting"
+)
+
+// applyDefaultParam 客户端未传（值为零）或强制模式下使用默认值
+func applyDefaultParam[T comparable](params *model_setting.ModelDefaultParams, value *T, defaultValue *T) {
+	var zero T
+	if defaultValue != nil && (params.Force || *value == zero) {
+		*value = *defaultValue
+	}
+}
+
+// ApplyModelDefaultParams 按客户端请求的模型填充默认的 temperature、top_p、max_tokens，
+// 强制模式下覆盖客户端传入的值。在 ModelMappedHelper 中调用，覆盖 OpenAI、Claude、Gemini 与 Responses 格式的请求，
+// 其余请求（如 embedding）没有这些参数，不做处理
+func ApplyModelDefaultParams(model string, request any) {
+	params := model_setting.GetModelDefaultParams(model)
+	if params == nil {
+		return
+	}
+	switch req := request.(type) {
+	case *dto.GeneralOpenAIRequest:
+		if params.Temperature != nil && (params.Force || req.Temperature == nil) {
+			temperature := *params.Temperature
+			req.Temperature = &temperature
+		}
+		applyDefaultParam(params, &req.TopP, params.TopP)
+		if params.MaxTokens != nil {
+			if req.MaxCompletionTokens != 0 {
+				if params.Force {
+					req.MaxCompletionTokens = *params.MaxTokens
+				}
+			} else if params.Force || req.MaxTokens == 0 {
+				req.MaxTokens = *params.MaxTokens
+			}
+		}
+	case *dto.ClaudeRequest:
+		if params.Temperature != nil && (params.Force || req.Temperature == nil) {
+			temperature := *params.Temperature
+			req.Temperature = &temperature
+		}
+		applyDefaultParam(params, &req.TopP, params.TopP)
+		applyDefaultParam(params, &req.MaxTokens, params.MaxTokens)
+	case *dto.GeminiChatRequest:
+		config := &req.GenerationConfig
+		if params.Temperature != nil && (params.Force || config.Temperature == nil) {
+			temperature := *params.Temperature
+			config.Temperature = &temperature
+		}
+		applyDefaultParam(params, &config.TopP, params.TopP)
+		applyDefaultParam(params, &config.MaxOutputTokens, params.MaxTokens)
+	case *dto.OpenAIResponsesRequest:
+		applyDefaultParam(params, &req.Temperature, params.Temperature)
+		applyDefaultParam(params, &req.TopP, params.TopP)
+		applyDefaultParam(params, &req.MaxOutputTokens, params.MaxTokens)
+	}
+}
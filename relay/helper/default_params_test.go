@@ -0,0 +1,38 @@
+package helper
+
+import (
+	"one-api/dto"
+	"one-api/setting/model_setting"
+	"testing"
+)
+
+func TestApplyModelDefaultParamsCoversRelayFormats(t *testing.T) {
+	temperature, topP, maxTokens := 0.3, 0.9, uint(2048)
+	settings := model_setting.GetDefaultParamsSettings()
+	settings.Models["default-params-model"] = model_setting.ModelDefaultParams{
+		Temperature: &temperature,
+		TopP:        &topP,
+		MaxTokens:   &maxTokens,
+	}
+	t.Cleanup(func() { delete(settings.Models, "default-params-model") })
+
+	clientTemperature := 1.0
+	claudeRequest := &dto.ClaudeRequest{Temperature: &clientTemperature}
+	ApplyModelDefaultParams("default-params-model", claudeRequest)
+	if *claudeRequest.Temperature != clientTemperature || claudeRequest.TopP != topP || claudeRequest.MaxTokens != maxTokens {
+		t.Fatalf("unexpected claude request %+v", claudeRequest)
+	}
+
+	geminiRequest := &dto.GeminiChatRequest{}
+	ApplyModelDefaultParams("default-params-model", geminiRequest)
+	config := geminiRequest.GenerationConfig
+	if config.Temperature == nil || *config.Temperature != temperature || config.TopP != topP || config.MaxOutputTokens != maxTokens {
+		t.Fatalf("unexpected gemini generation config %+v", config)
+	}
+
+	responsesRequest := &dto.OpenAIResponsesRequest{MaxOutputTokens: 100}
+	ApplyModelDefaultParams("default-params-model", responsesRequest)
+	if responsesRequest.Temperature != temperature || responsesRequest.TopP != topP || responsesRequest.MaxOutputTokens != 100 {
+		t.Fatalf("unexpected responses request %+v", responsesRequest)
+	}
+}
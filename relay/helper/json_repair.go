@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"encoding/json"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+)
+
+// RepairJsonStreamResponse 过滤已转换为 OpenAI 格式的流式响应中第一个 JSON 文档之外的内容
+func RepairJsonStreamResponse(info *relaycommon.RelayInfo, response *dto.ChatCompletionsStreamResponse) {
+	if info.JsonRepairer == nil || response == nil {
+		return
+	}
+	for i, choice := range response.Choices {
+		if choice.Delta.Content == nil {
+			continue
+		}
+		response.Choices[i].Delta.SetContentString(info.JsonRepairer.Filter(choice.Index, *choice.Delta.Content))
+	}
+}
+
+// RepairJsonStreamData 过滤上游原样返回的 OpenAI 流式数据，只改写 choices[].delta.content，其余字段原样保留
+func RepairJsonStreamData(info *relaycommon.RelayInfo, data string) string {
+	if info.JsonRepairer == nil {
+		return data
+	}
+	repaired, ok := rewriteChoiceContent(common.StringToByteSlice(data), "delta", info.JsonRepairer.Filter)
+	if !ok {
+		return data
+	}
+	return string(repaired)
+}
+
+// RepairJsonResponseBody 提取非流式响应 choices[].message.content 中第一个 JSON 文档，其余字段原样保留
+func RepairJsonResponseBody(body []byte) ([]byte, bool) {
+	return rewriteChoiceContent(body, "message", func(_ int, content string) string {
+		result, _ := common.ExtractFirstJSON(content)
+		return result
+	})
+}
+
+// rewriteChoiceContent 以 map 形式解析响应，改写每个 choice 中 field 对象的字符串 content，
+// 未识别的字段不经过 dto 结构体，避免被丢弃。没有内容被修改时返回 false
+func rewriteChoiceContent(data []byte, field string, rewrite func(index int, content string) string) ([]byte, bool) {
+	var response map[string]json.RawMessage
+	if err := common.Unmarshal(data, &response); err != nil {
+		return data, false
+	}
+	var choices []map[string]json.RawMessage
+	if err := common.Unmarshal(response["choices"], &choices); err != nil {
+		return data, false
+	}
+	changed := false
+	for _, choice := range choices {
+		var message map[string]json.RawMessage
+		if err := common.Unmarshal(choice[field], &message); err != nil {
+			continue
+		}
+		rawContent := message["content"]
+		if len(rawContent) == 0 || rawContent[0] != '"' {
+			continue
+		}
+		var content string
+		if err := common.Unmarshal(rawContent, &content); err != nil {
+			continue
+		}
+		var index int
+		_ = common.Unmarshal(choice["index"], &index)
+		rewritten := rewrite(index, content)
+		if rewritten == content {
+			continue
+		}
+		var err error
+		if message["content"], err = common.Marshal(rewritten); err != nil {
+			return data, false
+		}
+		if choice[field], err = common.Marshal(message); err != nil {
+			return data, false
+		}
+		changed = true
+	}
+	if !changed {
+		return data, false
+	}
+	var err error
+	if response["choices"], err = common.Marshal(choices); err != nil {
+		return data, false
+	}
+	repaired, err := common.Marshal(response)
+	if err != nil {
+		return data, false
+	}
+	return repaired, true
+}
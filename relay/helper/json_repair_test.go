@@ -0,0 +1,40 @@
+package helper
+
+import (
+	"one-api/common"
+	relaycommon "one-api/relay/common"
+	"strings"
+	"testing"
+)
+
+func TestRepairJsonStreamDataKeepsUnknownFields(t *testing.T) {
+	info := &relaycommon.RelayInfo{JsonRepairer: common.NewJsonStreamRepairer()}
+	data := `{"id":"1","object":"chat.completion.chunk","x_vendor":{"a":1},"choices":[{"index":0,"delta":{"content":"Sure: {\"a\":1}","x_extra":true},"logprobs":{"content":[]}}]}`
+
+	repaired := RepairJsonStreamData(info, data)
+	for _, want := range []string{`"x_vendor":{"a":1}`, `"x_extra":true`, `"logprobs":{"content":[]}`, `"content":"{\"a\":1}"`} {
+		if !strings.Contains(repaired, want) {
+			t.Fatalf("repaired chunk %s should contain %s", repaired, want)
+		}
+	}
+	if !info.JsonRepairer.Repaired {
+		t.Fatal("repairer should record the repair")
+	}
+}
+
+func TestRepairJsonResponseBodyKeepsUnknownFields(t *testing.T) {
+	body := []byte("{\"id\":\"1\",\"service_tier\":\"default\",\"choices\":[{\"index\":0,\"message\":{\"role\":\"assistant\",\"content\":\"```json\\n{\\\"a\\\":1}\\n```\",\"annotations\":[]}}]}")
+
+	repaired, ok := RepairJsonResponseBody(body)
+	if !ok {
+		t.Fatal("body should be repaired")
+	}
+	for _, want := range []string{`"service_tier":"default"`, `"annotations":[]`, `"content":"{\"a\":1}"`} {
+		if !strings.Contains(string(repaired), want) {
+			t.Fatalf("repaired body %s should contain %s", repaired, want)
+		}
+	}
+	if _, ok = RepairJsonResponseBody(repaired); ok {
+		t.Fatal("already valid body should not be repaired again")
+	}
+}
@@ -12,6 +12,9 @@ import (
 )
 
 func ModelMappedHelper(c *gin.Context, info *common.RelayInfo, request any) error {
+	// 默认参数按客户端请求的模型名配置，在映射前填充
+	ApplyModelDefaultParams(info.OriginModelName, request)
+
 	// map model name
 	modelMapping := c.GetString("model_mapping")
 	if modelMapping != "" && modelMapping != "{}" {
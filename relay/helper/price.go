@@ -1,9 +1,13 @@
 package helper
 
 import (
+	"errors"
 	"fmt"
 	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
 	relaycommon "one-api/relay/common"
+	"one-api/setting/model_setting"
 	"one-api/setting/ratio_setting"
 
 	"github.com/gin-gonic/gin"
@@ -62,10 +66,67 @@ func HandleGroupRatio(ctx *gin.Context, relayInfo *relaycommon.RelayInfo) GroupR
 	return groupRatioInfo
 }
 
+// PriceOverrideHeader 管理员可通过该请求头为单次请求覆盖倍率或价格，用于评估计费调整，不修改全局配置
+// eg. X-Price-Override: {"model_ratio":1.5,"completion_ratio":4}
+const PriceOverrideHeader = "X-Price-Override"
+
+type PriceOverride struct {
+	ModelPrice         *float64 `json:"model_price,omitempty"`
+	ModelRatio         *float64 `json:"model_ratio,omitempty"`
+	CompletionRatio    *float64 `json:"completion_ratio,omitempty"`
+	CacheRatio         *float64 `json:"cache_ratio,omitempty"`
+	CacheCreationRatio *float64 `json:"cache_creation_ratio,omitempty"`
+	ImageRatio         *float64 `json:"image_ratio,omitempty"`
+	GroupRatio         *float64 `json:"group_ratio,omitempty"`
+}
+
+// hasRatio 是否覆盖了按量计费使用的倍率（分组倍率对两种计费方式都生效，不计入）
+func (o *PriceOverride) hasRatio() bool {
+	return o.ModelRatio != nil || o.CompletionRatio != nil || o.CacheRatio != nil ||
+		o.CacheCreationRatio != nil || o.ImageRatio != nil
+}
+
+func getPriceOverride(c *gin.Context, info *relaycommon.RelayInfo) (*PriceOverride, error) {
+	header := c.Request.Header.Get(PriceOverrideHeader)
+	if header == "" {
+		return nil, nil
+	}
+	if !model.IsAdmin(info.UserId) {
+		return nil, errors.New("普通用户不支持覆盖价格")
+	}
+	var override PriceOverride
+	if err := common.UnmarshalJsonStr(header, &override); err != nil {
+		return nil, fmt.Errorf("invalid %s header: %w", PriceOverrideHeader, err)
+	}
+	return &override, nil
+}
+
 func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens int, maxTokens int) (PriceData, error) {
+	override, err := getPriceOverride(c, info)
+	if err != nil {
+		return PriceData{}, err
+	}
 	modelPrice, usePrice := ratio_setting.GetModelPrice(info.OriginModelName, false)
+	// 只有覆盖了价格才按次计费，只覆盖倍率时按量计费
+	if override != nil {
+		if override.ModelPrice != nil {
+			modelPrice, usePrice = *override.ModelPrice, true
+		} else if override.hasRatio() {
+			// 价格置为 -1，日志与前端据此按倍率展示
+			modelPrice, usePrice = -1, false
+		}
+	}
 
 	groupRatioInfo := HandleGroupRatio(c, info)
+	// 记录实际生效的覆盖值，写入消费日志便于核对计费
+	appliedOverrides := make(map[string]float64)
+	if override != nil {
+		overrideFloat(appliedOverrides, "group_ratio", &groupRatioInfo.GroupRatio, override.GroupRatio)
+		if usePrice {
+			overrideFloat(appliedOverrides, "model_price", &modelPrice, override.ModelPrice)
+		}
+		info.PriceOverridden = true
+	}
 
 	var preConsumedQuota int
 	var modelRatio float64
@@ -81,6 +142,10 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 		var success bool
 		var matchName string
 		modelRatio, success, matchName = ratio_setting.GetModelRatio(info.OriginModelName)
+		if override != nil && override.ModelRatio != nil {
+			overrideFloat(appliedOverrides, "model_ratio", &modelRatio, override.ModelRatio)
+			success = true
+		}
 		if !success {
 			acceptUnsetRatio := false
 			if info.UserSetting.AcceptUnsetRatioModel {
@@ -91,14 +156,28 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 			}
 		}
 		completionRatio = ratio_setting.GetCompletionRatio(info.OriginModelName)
-		cacheRatio, _ = ratio_setting.GetCacheRatio(info.OriginModelName)
+		var hasCacheRatio bool
+		cacheRatio, hasCacheRatio = ratio_setting.GetCacheRatio(info.OriginModelName)
+		if !hasCacheRatio && (info.ChannelType == constant.ChannelTypeGemini || info.ChannelType == constant.ChannelTypeVertexAi) {
+			// 命中 Gemini 上下文缓存的 token 按缓存输入倍率计费
+			cacheRatio = model_setting.GetGeminiSettings().CachedInputRatio
+		}
 		cacheCreationRatio, _ = ratio_setting.GetCreateCacheRatio(info.OriginModelName)
 		imageRatio, _ = ratio_setting.GetImageRatio(info.OriginModelName)
+		if override != nil {
+			overrideFloat(appliedOverrides, "completion_ratio", &completionRatio, override.CompletionRatio)
+			overrideFloat(appliedOverrides, "cache_ratio", &cacheRatio, override.CacheRatio)
+			overrideFloat(appliedOverrides, "cache_creation_ratio", &cacheCreationRatio, override.CacheCreationRatio)
+			overrideFloat(appliedOverrides, "image_ratio", &imageRatio, override.ImageRatio)
+		}
 		ratio := modelRatio * groupRatioInfo.GroupRatio
 		preConsumedQuota = int(float64(preConsumedTokens) * ratio)
 	} else {
 		preConsumedQuota = int(modelPrice * common.QuotaPerUnit * groupRatioInfo.GroupRatio)
 	}
+	if override != nil {
+		info.PriceOverrides = appliedOverrides
+	}
 
 	priceData := PriceData{
 		ModelPrice:             modelPrice,
@@ -119,6 +198,13 @@ func ModelPriceHelper(c *gin.Context, info *relaycommon.RelayInfo, promptTokens
 	return priceData, nil
 }
 
+func overrideFloat(applied map[string]float64, key string, target *float64, value *float64) {
+	if value != nil {
+		*target = *value
+		applied[key] = *value
+	}
+}
+
 type PerCallPriceData struct {
 	ModelPrice     float64
 	Quota          int
@@ -25,6 +25,30 @@ const (
 	DefaultPingInterval      = 10 * time.Second
 )
 
+// StreamChunkTokenCounter 估算单个流式块输出的 token 数，由 service 包注册，避免循环引用
+var StreamChunkTokenCounter func(data string, model string) int
+
+// responseLimiter 累计已转发的字节数与 token 数，用于令牌的单次响应上限
+type responseLimiter struct {
+	info       *relaycommon.RelayInfo
+	sentBytes  int
+	sentTokens int
+}
+
+// exceeded 计入当前块后是否超出上限，超出时当前块不再转发
+func (l *responseLimiter) exceeded(data string) bool {
+	info := l.info
+	if info.MaxResponseBytes <= 0 && info.MaxResponseTokens <= 0 {
+		return false
+	}
+	l.sentBytes += len(data)
+	if info.MaxResponseTokens > 0 && StreamChunkTokenCounter != nil {
+		l.sentTokens += StreamChunkTokenCounter(data, info.UpstreamModelName)
+	}
+	return (info.MaxResponseBytes > 0 && l.sentBytes > info.MaxResponseBytes) ||
+		(info.MaxResponseTokens > 0 && l.sentTokens > info.MaxResponseTokens)
+}
+
 func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon.RelayInfo, dataHandler func(data string) bool) {
 
 	if resp == nil || dataHandler == nil {
@@ -53,6 +77,12 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		wg         sync.WaitGroup // 用于等待所有 goroutine 退出
 	)
 
+	// 可续传的流在客户端断开后继续读取上游，供重连的客户端补齐
+	clientDone := c.Request.Context().Done()
+	if common.GetContextKeyBool(c, constant.ContextKeyStreamResumable) {
+		clientDone = nil
+	}
+
 	generalSettings := operation_setting.GetGeneralSetting()
 	pingEnabled := generalSettings.PingIntervalEnabled && !info.DisablePing
 	pingInterval := time.Duration(generalSettings.PingIntervalSeconds) * time.Second
@@ -97,6 +127,8 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		close(stopChan)
 	}()
 
+	limiter := &responseLimiter{info: info}
+
 	scanner.Buffer(make([]byte, InitialScannerBufferSize), MaxScannerBufferSize)
 	scanner.Split(bufio.ScanLines)
 	SetEventStreamHeaders(c)
@@ -190,7 +222,7 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 				return
 			case <-ctx.Done():
 				return
-			case <-c.Request.Context().Done():
+			case <-clientDone:
 				return
 			default:
 			}
@@ -213,6 +245,13 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 			if !strings.HasPrefix(data, "[DONE]") {
 				info.SetFirstResponseTime()
 
+				// 超出令牌的响应上限时丢弃当前块并停止读取上游
+				if limiter.exceeded(data) {
+					info.ResponseCutOff = true
+					common.LogWarn(c, fmt.Sprintf("response exceeds token limit (bytes: %d, tokens: %d), stream cut off", limiter.sentBytes, limiter.sentTokens))
+					return
+				}
+
 				// 使用超时机制防止写操作阻塞
 				done := make(chan bool, 1)
 				go func() {
@@ -258,7 +297,7 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 	case <-stopChan:
 		// 正常结束
 		common.LogInfo(c, "streaming finished")
-	case <-c.Request.Context().Done():
+	case <-clientDone:
 		// 客户端断开连接
 		common.LogInfo(c, "client disconnected")
 	}
@@ -0,0 +1,30 @@
+package helper
+
+import (
+	relaycommon "one-api/relay/common"
+	"testing"
+)
+
+func TestResponseLimiterStopsAfterByteLimit(t *testing.T) {
+	limiter := &responseLimiter{info: &relaycommon.RelayInfo{MaxResponseBytes: 10}}
+	if limiter.exceeded("12345") || limiter.exceeded("12345") {
+		t.Fatal("chunks within the limit should pass")
+	}
+	if !limiter.exceeded("1") {
+		t.Fatal("chunk crossing the limit should be cut off")
+	}
+}
+
+func TestResponseLimiterStopsAfterTokenLimit(t *testing.T) {
+	old := StreamChunkTokenCounter
+	StreamChunkTokenCounter = func(data string, model string) int { return len(data) }
+	t.Cleanup(func() { StreamChunkTokenCounter = old })
+
+	limiter := &responseLimiter{info: &relaycommon.RelayInfo{MaxResponseTokens: 3}}
+	if limiter.exceeded("ab") {
+		t.Fatal("chunk within the limit should pass")
+	}
+	if !limiter.exceeded("cd") {
+		t.Fatal("chunk crossing the limit should be cut off")
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"one-api/service"
 	"one-api/setting"
 	"one-api/setting/model_setting"
+	"one-api/setting/operation_setting"
 	"one-api/types"
 	"strings"
 
@@ -245,13 +246,21 @@ func ImageHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		}
 	}
 
+	// 开启图片审核时先暂存响应，审核后再返回给客户端
+	var recorder *imageResponseRecorder
+	if operation_setting.GetImageModerationSetting().IsEnabled() {
+		recorder = newImageResponseRecorder(c.Writer)
+		c.Writer = recorder
+	}
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, relayInfo)
+	if recorder != nil {
+		c.Writer = recorder.ResponseWriter
+	}
 	if newAPIError != nil {
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
-
 	if usage.(*dto.Usage).TotalTokens == 0 {
 		usage.(*dto.Usage).TotalTokens = imageRequest.N
 	}
@@ -264,6 +273,17 @@ func ImageHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	}
 
 	logContent := fmt.Sprintf("大小 %s, 品质 %s", imageRequest.Size, quality)
+	if recorder != nil {
+		newAPIError = moderateImageResponse(c, relayInfo, imageRequest.Prompt, recorder)
+		if newAPIError != nil {
+			if relayInfo.ImageModeration != nil && len(relayInfo.ImageModeration.Flagged) > 0 {
+				// 上游已生成图片，全部被审核拦截时仍按实际生成计费，审核结论记录在消费日志中，不再退还预扣额度
+				postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, logContent+", 生成的图片均被内容审核拦截")
+				preConsumedQuota = 0
+			}
+			return newAPIError
+		}
+	}
 	postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, logContent)
 	return nil
 }
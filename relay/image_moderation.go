@@ -0,0 +1,86 @@
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/service"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// imageResponseRecorder 暂存适配器写出的图片响应，审核通过后再发送给客户端
+type imageResponseRecorder struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func newImageResponseRecorder(writer gin.ResponseWriter) *imageResponseRecorder {
+	return &imageResponseRecorder{
+		ResponseWriter: writer,
+		status:         http.StatusOK,
+	}
+}
+
+func (r *imageResponseRecorder) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *imageResponseRecorder) WriteString(s string) (int, error) {
+	return r.body.WriteString(s)
+}
+
+func (r *imageResponseRecorder) WriteHeader(code int) {
+	r.status = code
+}
+
+func (r *imageResponseRecorder) WriteHeaderNow() {
+}
+
+func (r *imageResponseRecorder) Flush() {
+}
+
+// moderateImageResponse 审核暂存的图片响应并写回客户端，图片全部被移除时返回错误
+func moderateImageResponse(c *gin.Context, info *relaycommon.RelayInfo, prompt string, recorder *imageResponseRecorder) *types.NewAPIError {
+	body := recorder.body.Bytes()
+	var imageResponse dto.ImageResponse
+	if info.IsStream || common.Unmarshal(body, &imageResponse) != nil {
+		// 流式或无法解析的响应原样返回
+		writeImageResponse(c, recorder.status, body)
+		return nil
+	}
+
+	result, err := service.ModerateImageResponse(info, prompt, &imageResponse)
+	if err != nil {
+		// 分类器不可用且未开启 fail_open，图片本身未被判定违规
+		common.LogError(c, "image moderation failed: "+err.Error())
+		return types.NewErrorWithStatusCode(fmt.Errorf("image moderation is unavailable: %w", err), types.ErrorCodeModerationUnavailable, http.StatusServiceUnavailable, types.ErrOptionWithSkipRetry())
+	}
+	info.ImageModeration = result
+	if len(result.Flagged) == 0 {
+		writeImageResponse(c, recorder.status, body)
+		return nil
+	}
+	common.LogWarn(c, fmt.Sprintf("generated images flagged by moderation, action: %s, flagged: %v", result.Action, result.Flagged))
+	if len(imageResponse.Data) == 0 {
+		return types.NewErrorWithStatusCode(errors.New("generated images were blocked by content moderation"), types.ErrorCodeSensitiveWordsDetected, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	data, err := common.Marshal(imageResponse)
+	if err != nil {
+		return types.NewError(err, types.ErrorCodeBadResponseBody, types.ErrOptionWithSkipRetry())
+	}
+	writeImageResponse(c, recorder.status, data)
+	return nil
+}
+
+func writeImageResponse(c *gin.Context, status int, data []byte) {
+	c.Writer.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	c.Writer.WriteHeader(status)
+	_, _ = c.Writer.Write(data)
+}
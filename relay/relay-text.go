@@ -18,6 +18,7 @@ import (
 	"one-api/setting"
 	"one-api/setting/model_setting"
 	"one-api/setting/operation_setting"
+	"one-api/setting/ratio_setting"
 	"one-api/types"
 	"strings"
 	"time"
@@ -98,6 +99,13 @@ func TextHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		c.Set("chat_completion_web_search_context_size", textRequest.WebSearchOptions.SearchContextSize)
 	}
 
+	relayInfo.RequestMetadata = service.ExtractRequestMetadata(c)
+
+	if relayInfo.ChannelSetting.JsonRepair && textRequest.ResponseFormat != nil &&
+		(textRequest.ResponseFormat.Type == "json_object" || textRequest.ResponseFormat.Type == "json_schema") {
+		relayInfo.JsonRepairer = common.NewJsonStreamRepairer()
+	}
+
 	if setting.ShouldCheckPromptSensitive() {
 		words, err := checkRequestSensitive(textRequest, relayInfo)
 		if err != nil {
@@ -106,6 +114,16 @@ func TextHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		}
 	}
 
+	// 令牌设置了响应 token 上限时，同时限制上游的生成长度
+	if relayInfo.MaxResponseTokens > 0 {
+		limit := uint(relayInfo.MaxResponseTokens)
+		if textRequest.MaxCompletionTokens != 0 {
+			textRequest.MaxCompletionTokens = min(textRequest.MaxCompletionTokens, limit)
+		} else if textRequest.MaxTokens == 0 || textRequest.MaxTokens > limit {
+			textRequest.MaxTokens = limit
+		}
+	}
+
 	err = helper.ModelMappedHelper(c, relayInfo, textRequest)
 	if err != nil {
 		return types.NewError(err, types.ErrorCodeChannelModelMappedError, types.ErrOptionWithSkipRetry())
@@ -151,7 +169,8 @@ func TextHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		textRequest.StreamOptions = nil
 	} else {
 		// 如果支持StreamOptions，且请求中没有设置StreamOptions，根据配置文件设置StreamOptions
-		if constant.ForceStreamOption {
+		// 音频 token 只能从上游返回的用量中获得，输出音频时总是要求上游返回用量
+		if constant.ForceStreamOption || textRequest.IsAudioOutput() {
 			textRequest.StreamOptions = &dto.StreamOptions{
 				IncludeUsage: true,
 			}
@@ -159,6 +178,9 @@ func TextHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	}
 
 	relayInfo.ShouldIncludeUsage = includeUsage
+	// cache 为网关扩展字段，不转发给上游
+	relayInfo.CacheOptions = textRequest.Cache
+	textRequest.Cache = nil
 
 	adaptor := GetAdaptor(relayInfo.ApiType)
 	if adaptor == nil {
@@ -272,7 +294,7 @@ func TextHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		return newApiErr
 	}
 
-	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
+	if service.ShouldUseAudioQuota(relayInfo.OriginModelName, usage.(*dto.Usage)) {
 		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	} else {
 		postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
@@ -356,6 +378,7 @@ func preConsumeQuota(c *gin.Context, preConsumedQuota int, relayInfo *relaycommo
 		if err != nil {
 			return 0, 0, types.NewError(err, types.ErrorCodeUpdateDataError, types.ErrOptionWithSkipRetry())
 		}
+		service.HoldPendingQuota(c, relayInfo.UserId, preConsumedQuota)
 	}
 	return preConsumedQuota, userQuota, nil
 }
@@ -384,8 +407,13 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		extraContent += "（可能是请求出错）"
 	}
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
+	// 流式请求的首字时间与渠道测试耗时可比，计入渠道响应时间窗口
+	if disableSetting := operation_setting.GetChannelDisableSetting(); disableSetting.LatencyPercentileEnabled && relayInfo.IsStream && relayInfo.HasSendResponse() {
+		service.RecordChannelLatency(relayInfo.ChannelId, relayInfo.FirstResponseTime.Sub(relayInfo.StartTime).Milliseconds(), disableSetting.LatencyWindowSize)
+	}
 	promptTokens := usage.PromptTokens
 	cacheTokens := usage.PromptTokensDetails.CachedTokens
+	cacheCreationTokens := usage.PromptTokensDetails.CachedCreationTokens
 	imageTokens := usage.PromptTokensDetails.ImageTokens
 	audioTokens := usage.PromptTokensDetails.AudioTokens
 	completionTokens := usage.CompletionTokens
@@ -394,19 +422,33 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	tokenName := ctx.GetString("token_name")
 	completionRatio := priceData.CompletionRatio
 	cacheRatio := priceData.CacheRatio
+	cacheCreationRatio := priceData.CacheCreationRatio
 	imageRatio := priceData.ImageRatio
 	modelRatio := priceData.ModelRatio
 	groupRatio := priceData.GroupRatioInfo.GroupRatio
 	modelPrice := priceData.ModelPrice
 
+	if !priceData.UsePrice && model_setting.GetGlobalSettings().ReasoningReclassifyEnabled {
+		if variantName, ok := ratio_setting.GetReasoningVariant(modelName, usage.CompletionTokenDetails.ReasoningTokens); ok {
+			modelRatio, _, _ = ratio_setting.GetModelRatio(variantName)
+			completionRatio = ratio_setting.GetCompletionRatio(variantName)
+			if extraContent != "" {
+				extraContent += ", "
+			}
+			extraContent += fmt.Sprintf("按实际思考情况以 %s 计费", variantName)
+		}
+	}
+
 	// Convert values to decimal for precise calculation
 	dPromptTokens := decimal.NewFromInt(int64(promptTokens))
 	dCacheTokens := decimal.NewFromInt(int64(cacheTokens))
+	dCacheCreationTokens := decimal.NewFromInt(int64(cacheCreationTokens))
 	dImageTokens := decimal.NewFromInt(int64(imageTokens))
 	dAudioTokens := decimal.NewFromInt(int64(audioTokens))
 	dCompletionTokens := decimal.NewFromInt(int64(completionTokens))
 	dCompletionRatio := decimal.NewFromFloat(completionRatio)
 	dCacheRatio := decimal.NewFromFloat(cacheRatio)
+	dCacheCreationRatio := decimal.NewFromFloat(cacheCreationRatio)
 	dImageRatio := decimal.NewFromFloat(imageRatio)
 	dModelRatio := decimal.NewFromFloat(modelRatio)
 	dGroupRatio := decimal.NewFromFloat(groupRatio)
@@ -479,11 +521,18 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			cachedTokensWithRatio = dCacheTokens.Mul(dCacheRatio)
 		}
 
-		// gemini cache creation 
+		// 减去 claude cache creation tokens
+		var cachedCreationTokensWithRatio decimal.Decimal
+		if !dCacheCreationTokens.IsZero() {
+			baseTokens = baseTokens.Sub(dCacheCreationTokens)
+			cachedCreationTokensWithRatio = dCacheCreationTokens.Mul(dCacheCreationRatio)
+		}
+
+		// gemini cache creation，创建缓存的 token 按全价计费，请求本身命中缓存的部分按缓存倍率计费
+		var geminiCacheCreationQuota decimal.Decimal
 		if relayInfo.IsGeminiCacheCreation && relayInfo.GeminiCacheCreationTokens > 0 {
 			creationTokens := decimal.NewFromInt(int64(relayInfo.GeminiCacheCreationTokens))
-			creationQuota := creationTokens.Mul(ratio) // full price
-			quotaCalculateDecimal = quotaCalculateDecimal.Add(creationQuota)
+			geminiCacheCreationQuota = creationTokens.Mul(ratio) // full price
 			extraContent += fmt.Sprintf("Gemini cache creation: %d tokens (full price)", relayInfo.GeminiCacheCreationTokens)
 		}
 
@@ -504,11 +553,11 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 				extraContent += fmt.Sprintf("Audio Input 花费 %s", audioInputQuota.String())
 			}
 		}
-		promptQuota := baseTokens.Add(cachedTokensWithRatio).Add(imageTokensWithRatio)
+		promptQuota := baseTokens.Add(cachedTokensWithRatio).Add(cachedCreationTokensWithRatio).Add(imageTokensWithRatio)
 
 		completionQuota := dCompletionTokens.Mul(dCompletionRatio)
 
-		quotaCalculateDecimal = promptQuota.Add(completionQuota).Mul(ratio)
+		quotaCalculateDecimal = promptQuota.Add(completionQuota).Mul(ratio).Add(geminiCacheCreationQuota)
 
 		if !ratio.IsZero() && quotaCalculateDecimal.LessThanOrEqual(decimal.Zero) {
 			quotaCalculateDecimal = decimal.NewFromInt(1)
@@ -545,7 +594,7 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			quota = 1
 		}
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		service.UpdateChannelUsedQuota(relayInfo, quota)
 	}
 
 	quotaDelta := quota - preConsumedQuota
@@ -569,6 +618,13 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		logContent += ", " + extraContent
 	}
 	other := service.GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, cacheTokens, cacheRatio, modelPrice, priceData.GroupRatioInfo.GroupSpecialRatio)
+	if relayInfo.JsonRepairer != nil && relayInfo.JsonRepairer.Repaired {
+		other["json_repaired"] = true
+	}
+	if cacheCreationTokens != 0 {
+		other["cache_creation_tokens"] = cacheCreationTokens
+		other["cache_creation_ratio"] = cacheCreationRatio
+	}
 	if imageTokens != 0 {
 		other["image"] = true
 		other["image_ratio"] = imageRatio
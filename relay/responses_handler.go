@@ -55,6 +55,9 @@ func ResponsesHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 	}
 
 	relayInfo := relaycommon.GenRelayInfoResponses(c, req)
+	if relayInfo.MaxResponseTokens > 0 && (req.MaxOutputTokens == 0 || req.MaxOutputTokens > uint(relayInfo.MaxResponseTokens)) {
+		req.MaxOutputTokens = uint(relayInfo.MaxResponseTokens)
+	}
 
 	if setting.ShouldCheckPromptSensitive() {
 		sensitiveWords, err := checkInputSensitive(req, relayInfo)
@@ -160,7 +163,7 @@ func ResponsesHelper(c *gin.Context) (newAPIError *types.NewAPIError) {
 		return newAPIError
 	}
 
-	if strings.HasPrefix(relayInfo.OriginModelName, "gpt-4o-audio") {
+	if service.ShouldUseAudioQuota(relayInfo.OriginModelName, usage.(*dto.Usage)) {
 		service.PostAudioConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
 	} else {
 		postConsumeQuota(c, relayInfo, usage.(*dto.Usage), preConsumedQuota, userQuota, priceData, "")
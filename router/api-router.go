@@ -19,7 +19,9 @@ func SetApiRouter(router *gin.Engine) {
 		apiRouter.GET("/uptime/status", controller.GetUptimeKumaStatus)
 		apiRouter.GET("/models", middleware.UserAuth(), controller.DashboardListModels)
 		apiRouter.GET("/status/test", middleware.AdminAuth(), controller.TestStatus)
+		apiRouter.GET("/adaptors", middleware.AdminAuth(), controller.GetAdaptorFeatures)
 		apiRouter.GET("/notice", controller.GetNotice)
+		apiRouter.GET("/image_storage/:name", controller.GetStoredImage)
 		apiRouter.GET("/about", controller.GetAbout)
 		//apiRouter.GET("/midjourney", controller.GetMidjourney)
 		apiRouter.GET("/home_page_content", controller.GetHomePageContent)
@@ -56,6 +58,7 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.GET("/self/groups", controller.GetUserGroups)
 				selfRoute.GET("/self", controller.GetSelf)
 				selfRoute.GET("/models", controller.GetUserModels)
+				selfRoute.GET("/cache_savings", controller.GetUserCacheSavings)
 				selfRoute.PUT("/self", controller.UpdateSelf)
 				selfRoute.DELETE("/self", controller.DeleteSelf)
 				selfRoute.GET("/token", controller.GenerateAccessToken)
@@ -67,6 +70,11 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/stripe/amount", controller.RequestStripeAmount)
 				selfRoute.POST("/aff_transfer", controller.TransferAffQuota)
 				selfRoute.PUT("/setting", controller.UpdateUserSetting)
+				selfRoute.GET("/webhooks", controller.GetUserWebhooks)
+				selfRoute.POST("/webhooks", controller.CreateUserWebhook)
+				selfRoute.PUT("/webhooks", controller.UpdateUserWebhook)
+				selfRoute.DELETE("/webhooks/:id", controller.DeleteUserWebhook)
+				selfRoute.POST("/webhooks/:id/test", middleware.CriticalRateLimit(), controller.TestUserWebhook)
 
 				// 2FA routes
 				selfRoute.GET("/2fa/status", controller.Get2FAStatus)
@@ -92,6 +100,11 @@ func SetApiRouter(router *gin.Engine) {
 				adminRoute.DELETE("/:id/2fa", controller.AdminDisable2FA)
 			}
 		}
+		configRoute := apiRouter.Group("/config")
+		configRoute.Use(middleware.RootAuth())
+		{
+			configRoute.GET("/validate", controller.ValidateConfig)
+		}
 		optionRoute := apiRouter.Group("/option")
 		optionRoute.Use(middleware.RootAuth())
 		{
@@ -116,6 +129,11 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.GET("/test", controller.TestAllChannels)
 			channelRoute.GET("/test/:id", controller.TestChannel)
+			channelRoute.GET("/test_results", controller.GetChannelTestResults)
+			channelRoute.GET("/breakers", controller.GetChannelBreakers)
+			channelRoute.DELETE("/breakers", controller.ResetChannelBreakers)
+			channelRoute.POST("/:id/test_all_models", controller.TestChannelAllModels)
+			channelRoute.POST("/:id/test_keys", controller.TestChannelKeys)
 			channelRoute.GET("/update_balance", controller.UpdateAllChannelsBalance)
 			channelRoute.GET("/update_balance/:id", controller.UpdateChannelBalance)
 			channelRoute.POST("/", controller.AddChannel)
@@ -125,6 +143,7 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.POST("/tag/enabled", controller.EnableTagChannels)
 			channelRoute.PUT("/tag", controller.EditTagChannels)
 			channelRoute.DELETE("/:id", controller.DeleteChannel)
+			channelRoute.GET("/:id/delete_impact", controller.GetChannelDeleteImpact)
 			channelRoute.POST("/batch", controller.DeleteChannelBatch)
 			channelRoute.POST("/fix", controller.FixChannelsAbilities)
 			channelRoute.GET("/fetch_models/:id", controller.FetchUpstreamModels)
@@ -133,6 +152,9 @@ func SetApiRouter(router *gin.Engine) {
 			channelRoute.GET("/tag/models", controller.GetTagModels)
 			channelRoute.POST("/copy/:id", controller.CopyChannel)
 			channelRoute.GET("/:id/caches", controller.GetChannelCachedContent)
+			channelRoute.GET("/:id/gemini_caches", controller.GetChannelGeminiCaches)
+			channelRoute.DELETE("/:id/gemini_caches", controller.DeleteChannelGeminiCache)
+			channelRoute.GET("/:id/key_usage", controller.GetChannelKeyUsages)
 			channelRoute.POST("/multi_key/manage", controller.ManageMultiKeys)
 		}
 		tokenRoute := apiRouter.Group("/token")
@@ -161,14 +183,38 @@ func SetApiRouter(router *gin.Engine) {
 		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
 		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
 		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/cost_tag_stat", middleware.AdminAuth(), controller.GetLogsCostTagStat)
+		logRoute.GET("/cache_savings", middleware.AdminAuth(), controller.GetLogsCacheSavingsStat)
+		logRoute.GET("/:id/archive", middleware.AdminAuth(), controller.GetLogBodyArchive)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
 		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/export", middleware.AdminAuth(), middleware.ExportRateLimit(), controller.ExportLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
 
 		dataRoute := apiRouter.Group("/data")
 		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
+		dataRoute.GET("/channel_size", middleware.AdminAuth(), controller.GetChannelSizeStats)
+
+		usageReconcileRoute := apiRouter.Group("/usage_reconcile")
+		usageReconcileRoute.Use(middleware.AdminAuth())
+		{
+			usageReconcileRoute.POST("/import", controller.ImportUpstreamUsage)
+			usageReconcileRoute.GET("/report", controller.GetUsageReconciliation)
+		}
+
+		cacheRoute := apiRouter.Group("/cache")
+		cacheRoute.Use(middleware.AdminAuth())
+		{
+			cacheRoute.GET("/stats", controller.GetCacheStats)
+		}
+
+		routeRoute := apiRouter.Group("/route")
+		routeRoute.Use(middleware.AdminAuth())
+		{
+			routeRoute.POST("/simulate", controller.SimulateRoute)
+		}
 
 		logRoute.Use(middleware.CORS())
 		{
@@ -190,6 +236,16 @@ func SetApiRouter(router *gin.Engine) {
 			prefillGroupRoute.DELETE("/:id", controller.DeletePrefillGroup)
 		}
 
+		quotaGrantRoute := apiRouter.Group("/quota_grant")
+		quotaGrantRoute.Use(middleware.RootAuth())
+		{
+			quotaGrantRoute.GET("/", controller.GetQuotaGrantRules)
+			quotaGrantRoute.POST("/", controller.CreateQuotaGrantRule)
+			quotaGrantRoute.PUT("/", controller.UpdateQuotaGrantRule)
+			quotaGrantRoute.DELETE("/:id", controller.DeleteQuotaGrantRule)
+			quotaGrantRoute.POST("/:id/run", controller.RunQuotaGrantRule)
+		}
+
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
 		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
@@ -211,6 +267,13 @@ func SetApiRouter(router *gin.Engine) {
 			vendorRoute.DELETE("/:id", controller.DeleteVendorMeta)
 		}
 
+		modelRoute := apiRouter.Group("/model")
+		modelRoute.Use(middleware.AdminAuth())
+		{
+			modelRoute.GET("/benchmark", controller.GetModelBenchmarks)
+			modelRoute.POST("/benchmark", controller.RunModelBenchmark)
+		}
+
 		modelsRoute := apiRouter.Group("/models")
 		modelsRoute.Use(middleware.AdminAuth())
 		{
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"regexp"
+	"sync"
+	"time"
+)
+
+const bodyArchiveRedacted = "[REDACTED]"
+
+// archivedBodies 保存在对象存储中的内容
+type archivedBodies struct {
+	RequestBody  string `json:"request_body"`
+	ResponseBody string `json:"response_body"`
+}
+
+var (
+	bodyArchivePatterns     = make(map[string]*regexp.Regexp)
+	bodyArchivePatternsLock sync.Mutex
+)
+
+// getRedactPattern 编译并缓存脱敏规则，规则无效时返回 nil
+func getRedactPattern(pattern string) *regexp.Regexp {
+	bodyArchivePatternsLock.Lock()
+	defer bodyArchivePatternsLock.Unlock()
+	if re, ok := bodyArchivePatterns[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		common.SysError(fmt.Sprintf("invalid body archive redact pattern %q: %s", pattern, err.Error()))
+	}
+	bodyArchivePatterns[pattern] = re
+	return re
+}
+
+// RedactBody 将匹配脱敏规则的内容替换为 [REDACTED]
+func RedactBody(data []byte, patterns []string) []byte {
+	for _, pattern := range patterns {
+		if re := getRedactPattern(pattern); re != nil {
+			data = re.ReplaceAll(data, []byte(bodyArchiveRedacted))
+		}
+	}
+	return data
+}
+
+// BodyArchiveRedactMargin 响应超出保存上限后额外保留的字节数，保证跨越截断点的敏感信息能被完整匹配
+const BodyArchiveRedactMargin = 4 << 10
+
+// truncateArchivedBody 将脱敏后的内容截断到 limit 字节
+func truncateArchivedBody(data []byte, limit int) ([]byte, bool) {
+	if limit <= 0 || len(data) <= limit {
+		return data, false
+	}
+	return data[:limit], true
+}
+
+// ArchiveBodies 先脱敏再截断，保存请求体与响应体，使用对象存储时数据库中只保存对象键
+func ArchiveBodies(archive *model.BodyArchive, requestBody []byte, responseBody []byte) error {
+	archiveSetting := operation_setting.GetBodyArchiveSetting()
+	limit := archiveSetting.MaxBodyKB << 10
+	var requestTruncated, responseTruncated bool
+	requestBody, requestTruncated = truncateArchivedBody(RedactBody(requestBody, archiveSetting.RedactPatterns), limit)
+	responseBody, responseTruncated = truncateArchivedBody(RedactBody(responseBody, archiveSetting.RedactPatterns), limit)
+	archive.Truncated = archive.Truncated || requestTruncated || responseTruncated
+
+	if archiveSetting.Storage == operation_setting.BodyArchiveStorageS3 {
+		data, err := common.Marshal(archivedBodies{
+			RequestBody:  string(requestBody),
+			ResponseBody: string(responseBody),
+		})
+		if err != nil {
+			return err
+		}
+		key := fmt.Sprintf("%s%s/%s.json", archiveSetting.S3Prefix, time.Unix(archive.CreatedAt, 0).Format("2006-01-02"), common.GetUUID())
+		if _, err = putS3Object(operation_setting.GetImageStorageSetting(), key, data, "application/json"); err != nil {
+			return err
+		}
+		archive.StorageKey = key
+	} else {
+		archive.RequestBody = string(requestBody)
+		archive.ResponseBody = string(responseBody)
+	}
+	return archive.Insert()
+}
+
+// LoadArchivedBodies 读取保存在对象存储中的内容并填充到 archive
+func LoadArchivedBodies(archive *model.BodyArchive) error {
+	if archive.StorageKey == "" {
+		return nil
+	}
+	data, err := getS3Object(operation_setting.GetImageStorageSetting(), archive.StorageKey)
+	if err != nil {
+		return err
+	}
+	var bodies archivedBodies
+	if err = common.Unmarshal(data, &bodies); err != nil {
+		return err
+	}
+	archive.RequestBody = bodies.RequestBody
+	archive.ResponseBody = bodies.ResponseBody
+	return nil
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"one-api/common"
@@ -10,6 +11,8 @@ import (
 	"one-api/setting/operation_setting"
 	"one-api/types"
 	"strings"
+	"sync"
+	"time"
 )
 
 func formatNotifyType(channelId int, status int) string {
@@ -17,81 +20,141 @@ func formatNotifyType(channelId int, status int) string {
 }
 
 // disable & notify
-func DisableChannel(channelError types.ChannelError, reason string) {
-	success := model.UpdateChannelStatus(channelError.ChannelId, channelError.UsingKey, common.ChannelStatusAutoDisabled, reason)
+// 开启隔离后，启用中的单 key 渠道先进入隔离状态，隔离中再次触发才会被禁用
+func DisableChannel(channelError types.ChannelError, reason string, event *ChannelStatusEvent) {
+	if channel, err := model.CacheGetChannel(channelError.ChannelId); err == nil && channel.Status == common.ChannelStatusDraining {
+		return
+	}
+	status := common.ChannelStatusAutoDisabled
+	if operation_setting.GetChannelDisableSetting().QuarantineEnabled && !channelError.IsMultiKey {
+		if channel, err := model.CacheGetChannel(channelError.ChannelId); err == nil && channel.Status == common.ChannelStatusEnabled {
+			status = common.ChannelStatusQuarantined
+		}
+	}
+	success := model.UpdateChannelStatus(channelError.ChannelId, channelError.UsingKey, status, reason)
 	if success {
-		subject := fmt.Sprintf("通道「%s」（#%d）已被禁用", channelError.ChannelName, channelError.ChannelId)
-		content := fmt.Sprintf("通道「%s」（#%d）已被禁用，原因：%s", channelError.ChannelName, channelError.ChannelId, reason)
-		NotifyRootUser(formatNotifyType(channelError.ChannelId, common.ChannelStatusAutoDisabled), subject, content)
+		ResetChannelErrorWindow(channelError.ChannelId)
+		ResetChannelLatencyWindow(channelError.ChannelId)
+		action := "禁用"
+		if status == common.ChannelStatusQuarantined {
+			action = "隔离"
+		}
+		notifyChannelDisabled(channelError.ChannelId, channelError.ChannelName, status, action, reason, event)
 	}
 }
 
-func EnableChannel(channelId int, usingKey string, channelName string) {
+func EnableChannel(channelId int, usingKey string, channelName string, event *ChannelStatusEvent) {
 	success := model.UpdateChannelStatus(channelId, usingKey, common.ChannelStatusEnabled, "")
 	if success {
+		ResetChannelErrorWindow(channelId)
+		ResetChannelLatencyWindow(channelId)
 		subject := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		content := fmt.Sprintf("通道「%s」（#%d）已被启用", channelName, channelId)
 		NotifyRootUser(formatNotifyType(channelId, common.ChannelStatusEnabled), subject, content)
+		notifyChannelStatusChange(channelId, channelName, common.ChannelStatusEnabled, subject, "", event)
 	}
 }
 
-func ShouldDisableChannel(channelType int, err *types.NewAPIError) bool {
+func ShouldDisableChannel(channelId int, channelType int, err *types.NewAPIError) bool {
 	if !common.AutomaticDisableChannelEnabled {
 		return false
 	}
-	if err == nil {
+	category := ClassifyChannelError(channelType, err)
+	if category == "" {
 		return false
 	}
-	if types.IsChannelError(err) {
+	setting := operation_setting.GetChannelDisableSetting()
+	if !setting.WeightedEnabled {
+		// 未开启加权时保持单次错误即禁用，瞬时错误不参与
+		return !IsTransientChannelErrorCategory(category)
+	}
+	weight := setting.GetWeight(channelType, category)
+	if weight <= 0 {
+		return false
+	}
+	score := recordChannelErrorWeight(channelId, weight, setting.WindowSeconds)
+	return score >= setting.Threshold
+}
+
+// IsTransientChannelErrorCategory 限流、过载、上游 5xx 等瞬时错误不代表渠道本身不可用
+func IsTransientChannelErrorCategory(category string) bool {
+	switch category {
+	case operation_setting.ChannelErrorCategoryRateLimit, operation_setting.ChannelErrorCategoryOverloaded, operation_setting.ChannelErrorCategoryServerError:
 		return true
 	}
+	return false
+}
+
+// ClassifyChannelError 返回错误对应的自动禁用类型，无关错误返回空字符串
+func ClassifyChannelError(channelType int, err *types.NewAPIError) string {
+	if err == nil {
+		return ""
+	}
+	if types.IsChannelError(err) {
+		return operation_setting.ChannelErrorCategoryChannel
+	}
 	if types.IsSkipRetryError(err) {
-		return false
+		return ""
 	}
 	if err.StatusCode == http.StatusUnauthorized {
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	}
 	if err.StatusCode == http.StatusForbidden {
 		switch channelType {
 		case constant.ChannelTypeGemini:
-			return true
+			return operation_setting.ChannelErrorCategoryAuth
 		}
 	}
 	oaiErr := err.ToOpenAIError()
 	switch oaiErr.Code {
 	case "invalid_api_key":
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	case "account_deactivated":
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	case "billing_not_active":
-		return true
+		return operation_setting.ChannelErrorCategoryQuota
 	case "pre_consume_token_quota_failed":
-		return true
+		return operation_setting.ChannelErrorCategoryQuota
 	}
 	switch oaiErr.Type {
 	case "insufficient_quota":
-		return true
+		return operation_setting.ChannelErrorCategoryQuota
 	case "insufficient_user_quota":
-		return true
+		return operation_setting.ChannelErrorCategoryQuota
 	// https://docs.anthropic.com/claude/reference/errors
 	case "authentication_error":
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	case "permission_error":
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	case "forbidden":
-		return true
+		return operation_setting.ChannelErrorCategoryAuth
 	}
 
 	lowerMessage := strings.ToLower(err.Error())
-	search, _ := AcSearch(lowerMessage, operation_setting.AutomaticDisableKeywords, true)
-	return search
+	if search, _ := AcSearch(lowerMessage, operation_setting.AutomaticDisableKeywords, true); search {
+		return operation_setting.ChannelErrorCategoryKeyword
+	}
+
+	switch {
+	case err.StatusCode == http.StatusTooManyRequests:
+		return operation_setting.ChannelErrorCategoryRateLimit
+	case err.StatusCode == 529 || err.StatusCode == http.StatusServiceUnavailable || oaiErr.Type == "overloaded_error":
+		return operation_setting.ChannelErrorCategoryOverloaded
+	case err.StatusCode >= http.StatusInternalServerError:
+		return operation_setting.ChannelErrorCategoryServerError
+	}
+	return ""
 }
 
 func ShouldEnableChannel(newAPIError *types.NewAPIError, status int) bool {
-	if !common.AutomaticEnableChannelEnabled {
+	if newAPIError != nil {
 		return false
 	}
-	if newAPIError != nil {
+	// 隔离状态的渠道测试通过即恢复，隔离本身需要单独开启，不受自动启用开关影响
+	if status == common.ChannelStatusQuarantined {
+		return true
+	}
+	if !common.AutomaticEnableChannelEnabled {
 		return false
 	}
 	if status != common.ChannelStatusAutoDisabled {
@@ -99,3 +162,60 @@ func ShouldEnableChannel(newAPIError *types.NewAPIError, status int) bool {
 	}
 	return true
 }
+
+// 隔离渠道探测请求的连续成功次数，长时间没有探测请求时重新计数
+const quarantineProbeSuccessTTL = time.Hour
+
+var (
+	quarantineProbeSuccesses     = make(map[int]int)
+	quarantineProbeSuccessesLock sync.Mutex
+)
+
+func quarantineProbeSuccessKey(channelId int) string {
+	return fmt.Sprintf("quarantine_probe_success:%d", channelId)
+}
+
+func incrQuarantineProbeSuccesses(channelId int) int {
+	if !common.RedisEnabled {
+		quarantineProbeSuccessesLock.Lock()
+		defer quarantineProbeSuccessesLock.Unlock()
+		quarantineProbeSuccesses[channelId]++
+		return quarantineProbeSuccesses[channelId]
+	}
+	ctx := context.Background()
+	key := quarantineProbeSuccessKey(channelId)
+	count, err := common.RDB.Incr(ctx, key).Result()
+	if err != nil {
+		common.SysError("failed to update quarantine probe successes: " + err.Error())
+		return 0
+	}
+	common.RDB.Expire(ctx, key, quarantineProbeSuccessTTL)
+	return int(count)
+}
+
+func resetQuarantineProbeSuccesses(channelId int) {
+	if !common.RedisEnabled {
+		quarantineProbeSuccessesLock.Lock()
+		delete(quarantineProbeSuccesses, channelId)
+		quarantineProbeSuccessesLock.Unlock()
+		return
+	}
+	common.RDB.Del(context.Background(), quarantineProbeSuccessKey(channelId))
+}
+
+// RecordQuarantineProbeResult 记录分配给隔离渠道的请求结果，连续成功达到设定次数后恢复启用，失败时重新计数
+func RecordQuarantineProbeResult(channel *model.Channel, usingKey string, modelName string, success bool) {
+	if channel.Status != common.ChannelStatusQuarantined {
+		return
+	}
+	if !success {
+		resetQuarantineProbeSuccesses(channel.Id)
+		return
+	}
+	required := operation_setting.GetChannelDisableSetting().QuarantineRecoverSuccesses
+	if incrQuarantineProbeSuccesses(channel.Id) < max(required, 1) {
+		return
+	}
+	resetQuarantineProbeSuccesses(channel.Id)
+	EnableChannel(channel.Id, usingKey, channel.Name, &ChannelStatusEvent{ModelName: modelName})
+}
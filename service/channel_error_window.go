@@ -0,0 +1,48 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+type channelErrorRecord struct {
+	At     time.Time
+	Weight float64
+}
+
+var (
+	channelErrorWindows     = make(map[int][]channelErrorRecord)
+	channelErrorWindowsLock sync.Mutex
+)
+
+// recordChannelErrorWeight 记录一次渠道错误，返回滚动窗口内的权重之和
+func recordChannelErrorWeight(channelId int, weight float64, windowSeconds int) float64 {
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(windowSeconds) * time.Second)
+
+	channelErrorWindowsLock.Lock()
+	defer channelErrorWindowsLock.Unlock()
+
+	records := channelErrorWindows[channelId]
+	kept := records[:0]
+	for _, record := range records {
+		if record.At.After(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+	kept = append(kept, channelErrorRecord{At: now, Weight: weight})
+
+	score := 0.0
+	for _, record := range kept {
+		score += record.Weight
+	}
+	channelErrorWindows[channelId] = kept
+	return score
+}
+
+// ResetChannelErrorWindow 渠道被禁用或重新启用后清空窗口，避免旧错误影响后续判断
+func ResetChannelErrorWindow(channelId int) {
+	channelErrorWindowsLock.Lock()
+	defer channelErrorWindowsLock.Unlock()
+	delete(channelErrorWindows, channelId)
+}
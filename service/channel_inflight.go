@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// channelInFlightTTL Redis 中处理中请求计数的有效期，防止节点异常退出后计数无法归零
+const channelInFlightTTL = 30 * time.Minute
+
+var (
+	channelInFlight     = make(map[int]int)
+	channelInFlightLock sync.Mutex
+)
+
+func channelInFlightRedisKey(channelId int) string {
+	return fmt.Sprintf("channel_inflight:%d", channelId)
+}
+
+func addChannelInFlight(channelId int, delta int) {
+	if !common.RedisEnabled {
+		channelInFlightLock.Lock()
+		defer channelInFlightLock.Unlock()
+		channelInFlight[channelId] += delta
+		if channelInFlight[channelId] <= 0 {
+			delete(channelInFlight, channelId)
+		}
+		return
+	}
+	ctx := context.Background()
+	key := channelInFlightRedisKey(channelId)
+	if err := common.RDB.IncrBy(ctx, key, int64(delta)).Err(); err != nil {
+		common.SysError("failed to update channel in-flight count: " + err.Error())
+		return
+	}
+	common.RDB.Expire(ctx, key, channelInFlightTTL)
+}
+
+// ChannelRequestStart 记录渠道开始处理一个请求，渠道排空时据此等待处理中的请求结束
+func ChannelRequestStart(channelId int) {
+	if channelId == 0 {
+		return
+	}
+	addChannelInFlight(channelId, 1)
+}
+
+// ChannelRequestDone 记录渠道处理完一个请求
+func ChannelRequestDone(channelId int) {
+	if channelId == 0 {
+		return
+	}
+	addChannelInFlight(channelId, -1)
+}
+
+// GetChannelInFlight 返回渠道正在处理的请求数，启用 Redis 时为所有节点的合计
+func GetChannelInFlight(channelId int) int {
+	if !common.RedisEnabled {
+		channelInFlightLock.Lock()
+		defer channelInFlightLock.Unlock()
+		return channelInFlight[channelId]
+	}
+	value, err := common.RDB.Get(context.Background(), channelInFlightRedisKey(channelId)).Result()
+	if err != nil {
+		return 0
+	}
+	count, _ := strconv.Atoi(value)
+	if count < 0 {
+		return 0
+	}
+	return count
+}
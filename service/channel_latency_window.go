@@ -0,0 +1,46 @@
+package service
+
+import (
+	"sort"
+	"sync"
+)
+
+var (
+	channelLatencyWindows     = make(map[int][]int64)
+	channelLatencyWindowsLock sync.Mutex
+)
+
+// RecordChannelLatency 记录一次渠道响应时间（毫秒），只保留最近 windowSize 次
+func RecordChannelLatency(channelId int, latency int64, windowSize int) {
+	if channelId == 0 || windowSize <= 0 {
+		return
+	}
+	channelLatencyWindowsLock.Lock()
+	defer channelLatencyWindowsLock.Unlock()
+
+	latencies := append(channelLatencyWindows[channelId], latency)
+	if len(latencies) > windowSize {
+		latencies = latencies[len(latencies)-windowSize:]
+	}
+	channelLatencyWindows[channelId] = latencies
+}
+
+// GetChannelLatencyP95 返回窗口内响应时间的 p95 与样本数
+func GetChannelLatencyP95(channelId int) (int64, int) {
+	channelLatencyWindowsLock.Lock()
+	sorted := append([]int64(nil), channelLatencyWindows[channelId]...)
+	channelLatencyWindowsLock.Unlock()
+
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*95+99)/100-1], len(sorted)
+}
+
+// ResetChannelLatencyWindow 渠道被禁用或重新启用后清空窗口，避免旧样本影响后续判断
+func ResetChannelLatencyWindow(channelId int) {
+	channelLatencyWindowsLock.Lock()
+	defer channelLatencyWindowsLock.Unlock()
+	delete(channelLatencyWindows, channelId)
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"strings"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// ChannelStatusEvent 触发渠道状态变化的请求信息，用于外部通知，字段均可为空
+type ChannelStatusEvent struct {
+	ModelName string
+	ErrorCode string
+	Latency   int64 // 毫秒
+}
+
+// ChannelStatusWebhookPayload webhook 类型通知的负载数据
+type ChannelStatusWebhookPayload struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Content     string `json:"content"`
+	ChannelId   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	Status      int    `json:"status"`
+	Reason      string `json:"reason,omitempty"`
+	ModelName   string `json:"model_name,omitempty"`
+	ErrorCode   string `json:"error_code,omitempty"`
+	Latency     int64  `json:"latency,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+	// 聚合通知时为同一错误指纹下的全部渠道，ChannelId / ChannelName 为其中第一个
+	Fingerprint string                `json:"fingerprint,omitempty"`
+	Channels    []ChannelNotifyTarget `json:"channels,omitempty"`
+}
+
+// ChannelNotifyTarget 聚合通知中的单个渠道
+type ChannelNotifyTarget struct {
+	ChannelId   int    `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+}
+
+// notifyChannelStatusChange 异步发送渠道状态变化的外部通知
+func notifyChannelStatusChange(channelId int, channelName string, status int, title string, reason string, event *ChannelStatusEvent) {
+	setting := operation_setting.GetChannelNotifySetting()
+	if !setting.Enabled {
+		return
+	}
+	if event == nil {
+		event = &ChannelStatusEvent{}
+	}
+	payload := ChannelStatusWebhookPayload{
+		Type:        formatNotifyType(channelId, status),
+		Title:       title,
+		Content:     formatChannelStatusContent(title, reason, event),
+		ChannelId:   channelId,
+		ChannelName: channelName,
+		Status:      status,
+		Reason:      reason,
+		ModelName:   event.ModelName,
+		ErrorCode:   event.ErrorCode,
+		Latency:     event.Latency,
+		Timestamp:   time.Now().Unix(),
+	}
+	gopool.Go(func() {
+		if err := sendChannelStatusNotify(setting, payload); err != nil {
+			common.SysError(fmt.Sprintf("failed to send channel status notification: %s", err.Error()))
+		}
+	})
+}
+
+func formatChannelStatusContent(title string, reason string, event *ChannelStatusEvent) string {
+	lines := []string{title}
+	if reason != "" {
+		lines = append(lines, "原因："+reason)
+	}
+	if event.ModelName != "" {
+		lines = append(lines, "模型："+event.ModelName)
+	}
+	if event.ErrorCode != "" {
+		lines = append(lines, "错误码："+event.ErrorCode)
+	}
+	if event.Latency > 0 {
+		lines = append(lines, fmt.Sprintf("耗时：%dms", event.Latency))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func sendChannelStatusNotify(setting *operation_setting.ChannelNotifySetting, payload ChannelStatusWebhookPayload) error {
+	switch setting.Type {
+	case operation_setting.ChannelNotifyTypeSlack:
+		return postChannelNotify(setting.WebhookURL, "", map[string]string{"text": payload.Content})
+	case operation_setting.ChannelNotifyTypeDiscord:
+		return postChannelNotify(setting.WebhookURL, "", map[string]string{"content": payload.Content})
+	case operation_setting.ChannelNotifyTypeTelegram:
+		if setting.TelegramBotToken == "" || setting.TelegramChatId == "" {
+			return errors.New("telegram bot token or chat id is empty")
+		}
+		url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", setting.TelegramBotToken)
+		return postChannelNotify(url, "", map[string]string{
+			"chat_id": setting.TelegramChatId,
+			"text":    payload.Content,
+		})
+	default:
+		return postChannelNotify(setting.WebhookURL, setting.WebhookSecret, payload)
+	}
+}
+
+func postChannelNotify(url string, secret string, body any) error {
+	if url == "" {
+		return errors.New("notification url is empty")
+	}
+	payloadBytes, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %v", err)
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create notification request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set("X-Webhook-Signature", generateSignature(secret, payloadBytes))
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notification request failed with status code: %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+var (
+	fingerprintRequestIdPattern = regexp.MustCompile(`\(request id: [^)]*\)`)
+	fingerprintNumberPattern    = regexp.MustCompile(`\b[0-9a-f]*[0-9][0-9a-f]*\b`)
+)
+
+// ChannelErrorFingerprint 计算上游错误的指纹：去掉 request id、数字等易变部分后取哈希，
+// 同一次上游故障在不同渠道上产生的错误得到相同指纹
+func ChannelErrorFingerprint(reason string, event *ChannelStatusEvent) string {
+	normalized := strings.ToLower(reason)
+	normalized = fingerprintRequestIdPattern.ReplaceAllString(normalized, "")
+	normalized = fingerprintNumberPattern.ReplaceAllString(normalized, "#")
+	normalized = strings.Join(strings.Fields(normalized), " ")
+	errorCode := ""
+	if event != nil {
+		errorCode = event.ErrorCode
+	}
+	return common.GetMD5Hash(errorCode + "|" + normalized)[:12]
+}
+
+type channelDisableNotice struct {
+	channelId   int
+	channelName string
+	reason      string
+	event       *ChannelStatusEvent
+}
+
+type channelDisableGroup struct {
+	fingerprint string
+	status      int
+	action      string
+	notices     []channelDisableNotice
+}
+
+var (
+	channelDisableGroups     = make(map[string]*channelDisableGroup)
+	channelDisableGroupsLock sync.Mutex
+)
+
+// notifyChannelDisabled 发送渠道禁用/隔离通知，开启聚合时窗口内相同错误指纹的通知合并为一条
+func notifyChannelDisabled(channelId int, channelName string, status int, action string, reason string, event *ChannelStatusEvent) {
+	notice := channelDisableNotice{
+		channelId:   channelId,
+		channelName: channelName,
+		reason:      reason,
+		event:       event,
+	}
+	window := operation_setting.GetChannelNotifySetting().AggregateWindowSeconds
+	if window <= 0 {
+		sendChannelDisableNotice(status, action, notice)
+		return
+	}
+	fingerprint := ChannelErrorFingerprint(reason, event)
+	key := fmt.Sprintf("%d:%s", status, fingerprint)
+	channelDisableGroupsLock.Lock()
+	defer channelDisableGroupsLock.Unlock()
+	if group, ok := channelDisableGroups[key]; ok {
+		group.notices = append(group.notices, notice)
+		return
+	}
+	channelDisableGroups[key] = &channelDisableGroup{
+		fingerprint: fingerprint,
+		status:      status,
+		action:      action,
+		notices:     []channelDisableNotice{notice},
+	}
+	time.AfterFunc(time.Duration(window)*time.Second, func() {
+		flushChannelDisableGroup(key)
+	})
+}
+
+func flushChannelDisableGroup(key string) {
+	channelDisableGroupsLock.Lock()
+	group, ok := channelDisableGroups[key]
+	delete(channelDisableGroups, key)
+	channelDisableGroupsLock.Unlock()
+	if !ok || len(group.notices) == 0 {
+		return
+	}
+	if len(group.notices) == 1 {
+		sendChannelDisableNotice(group.status, group.action, group.notices[0])
+		return
+	}
+	sendAggregatedChannelDisableNotice(group)
+}
+
+func sendChannelDisableNotice(status int, action string, notice channelDisableNotice) {
+	subject := fmt.Sprintf("通道「%s」（#%d）已被%s", notice.channelName, notice.channelId, action)
+	content := fmt.Sprintf("通道「%s」（#%d）已被%s，原因：%s", notice.channelName, notice.channelId, action, notice.reason)
+	NotifyRootUser(formatNotifyType(notice.channelId, status), subject, content)
+	notifyChannelStatusChange(notice.channelId, notice.channelName, status, subject, notice.reason, notice.event)
+}
+
+// sendAggregatedChannelDisableNotice 相同错误导致多个渠道被禁用时只发送一条通知，附带渠道列表
+func sendAggregatedChannelDisableNotice(group *channelDisableGroup) {
+	fingerprint := group.fingerprint
+	first := group.notices[0]
+	// 沿用单条通知的类型，聚合通知与单条通知共享同一个发送频率限制
+	notifyType := formatNotifyType(first.channelId, group.status)
+	targets := make([]ChannelNotifyTarget, 0, len(group.notices))
+	names := make([]string, 0, len(group.notices))
+	for _, notice := range group.notices {
+		targets = append(targets, ChannelNotifyTarget{ChannelId: notice.channelId, ChannelName: notice.channelName})
+		names = append(names, fmt.Sprintf("「%s」（#%d）", notice.channelName, notice.channelId))
+	}
+	subject := fmt.Sprintf("%d 个通道因相同错误已被%s", len(group.notices), group.action)
+	content := fmt.Sprintf("%s：%s，原因：%s", subject, strings.Join(names, "、"), first.reason)
+	common.SysLog(fmt.Sprintf("channel error %s caused %d channels to be disabled", fingerprint, len(group.notices)))
+	NotifyRootUser(notifyType, subject, content)
+
+	setting := operation_setting.GetChannelNotifySetting()
+	if !setting.Enabled {
+		return
+	}
+	event := first.event
+	if event == nil {
+		event = &ChannelStatusEvent{}
+	}
+	payload := ChannelStatusWebhookPayload{
+		Type:        notifyType,
+		Title:       subject,
+		Content:     formatChannelStatusContent(subject+"："+strings.Join(names, "、"), first.reason, event),
+		ChannelId:   first.channelId,
+		ChannelName: first.channelName,
+		Status:      group.status,
+		Reason:      first.reason,
+		ModelName:   event.ModelName,
+		ErrorCode:   event.ErrorCode,
+		Timestamp:   time.Now().Unix(),
+		Fingerprint: fingerprint,
+		Channels:    targets,
+	}
+	gopool.Go(func() {
+		if err := sendChannelStatusNotify(setting, payload); err != nil {
+			common.SysError(fmt.Sprintf("failed to send channel status notification: %s", err.Error()))
+		}
+	})
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/setting/operation_setting"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// ChannelSizeStats 渠道最近请求/响应体积统计，单位字节
+type ChannelSizeStats struct {
+	ChannelId        int    `json:"channel_id"`
+	ChannelName      string `json:"channel_name"`
+	Samples          int    `json:"samples"`
+	AvgRequestBytes  int64  `json:"avg_request_bytes"`
+	P95RequestBytes  int64  `json:"p95_request_bytes"`
+	MaxRequestBytes  int64  `json:"max_request_bytes"`
+	AvgResponseBytes int64  `json:"avg_response_bytes"`
+	P95ResponseBytes int64  `json:"p95_response_bytes"`
+	MaxResponseBytes int64  `json:"max_response_bytes"`
+	LastAlertTime    int64  `json:"last_alert_time"`
+}
+
+// channelSizeWindow 单个渠道最近若干次请求的体积，环形缓冲
+type channelSizeWindow struct {
+	channelName   string
+	requests      []int64
+	responses     []int64
+	next          int
+	lastAlertTime time.Time
+}
+
+func (w *channelSizeWindow) add(size int, requestBytes int64, responseBytes int64) {
+	if len(w.requests) < size {
+		w.requests = append(w.requests, requestBytes)
+		w.responses = append(w.responses, responseBytes)
+		return
+	}
+	// 窗口大小被调小时丢弃多余的样本
+	if len(w.requests) > size {
+		w.requests = w.requests[len(w.requests)-size:]
+		w.responses = w.responses[len(w.responses)-size:]
+		w.next = 0
+	}
+	w.requests[w.next] = requestBytes
+	w.responses[w.next] = responseBytes
+	w.next = (w.next + 1) % size
+}
+
+var (
+	channelSizeWindows     = make(map[int]*channelSizeWindow)
+	channelSizeWindowsLock sync.Mutex
+)
+
+// sizeSummary 返回平均值、p95 与最大值
+func sizeSummary(values []int64) (avg int64, p95 int64, maxSize int64) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var sum int64
+	for _, v := range sorted {
+		sum += v
+	}
+	idx := (len(sorted)*95+99)/100 - 1
+	return sum / int64(len(sorted)), sorted[idx], sorted[len(sorted)-1]
+}
+
+// RecordChannelSize 记录一次转发的请求/响应体积，体积相对窗口 p95 突增时通知管理员
+func RecordChannelSize(channelId int, channelName string, requestBytes int64, responseBytes int64) {
+	if channelId == 0 {
+		return
+	}
+	setting := operation_setting.GetChannelSizeSetting()
+	if setting.WindowSize <= 0 {
+		return
+	}
+
+	channelSizeWindowsLock.Lock()
+	window, ok := channelSizeWindows[channelId]
+	if !ok {
+		window = &channelSizeWindow{}
+		channelSizeWindows[channelId] = window
+	}
+	window.channelName = channelName
+
+	var alertContent string
+	if setting.AlertEnabled && len(window.requests) >= setting.MinSamples &&
+		time.Since(window.lastAlertTime) >= time.Duration(setting.CooldownMinutes)*time.Minute {
+		_, requestP95, _ := sizeSummary(window.requests)
+		_, responseP95, _ := sizeSummary(window.responses)
+		if requestBytes >= setting.MinBytes && float64(requestBytes) > float64(requestP95)*setting.SpikeFactor {
+			alertContent = fmt.Sprintf("渠道「%s」（#%d）请求体积突增：%d 字节，近期 p95 为 %d 字节", channelName, channelId, requestBytes, requestP95)
+		} else if responseBytes >= setting.MinBytes && float64(responseBytes) > float64(responseP95)*setting.SpikeFactor {
+			alertContent = fmt.Sprintf("渠道「%s」（#%d）响应体积突增：%d 字节，近期 p95 为 %d 字节", channelName, channelId, responseBytes, responseP95)
+		}
+		if alertContent != "" {
+			window.lastAlertTime = time.Now()
+		}
+	}
+	window.add(setting.WindowSize, requestBytes, responseBytes)
+	channelSizeWindowsLock.Unlock()
+
+	if alertContent != "" {
+		common.SysLog(alertContent)
+		gopool.Go(func() {
+			NotifyRootUser(fmt.Sprintf("%s_%d", dto.NotifyTypeChannelSize, channelId), "渠道请求体积告警", alertContent)
+		})
+	}
+}
+
+// GetChannelSizeStats 返回各渠道最近窗口内的体积统计，channelId 为 0 时返回全部渠道
+func GetChannelSizeStats(channelId int) []ChannelSizeStats {
+	channelSizeWindowsLock.Lock()
+	defer channelSizeWindowsLock.Unlock()
+	stats := make([]ChannelSizeStats, 0, len(channelSizeWindows))
+	for id, window := range channelSizeWindows {
+		if channelId != 0 && id != channelId {
+			continue
+		}
+		item := ChannelSizeStats{
+			ChannelId:   id,
+			ChannelName: window.channelName,
+			Samples:     len(window.requests),
+		}
+		item.AvgRequestBytes, item.P95RequestBytes, item.MaxRequestBytes = sizeSummary(window.requests)
+		item.AvgResponseBytes, item.P95ResponseBytes, item.MaxResponseBytes = sizeSummary(window.responses)
+		if !window.lastAlertTime.IsZero() {
+			item.LastAlertTime = window.lastAlertTime.Unix()
+		}
+		stats = append(stats, item)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ChannelId < stats[j].ChannelId })
+	return stats
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// IdempotencyRecord 一个 Idempotency-Key 对应的请求，Pending 为 true 表示请求仍在处理中
+type IdempotencyRecord struct {
+	RequestHash string `json:"request_hash"`
+	Pending     bool   `json:"pending"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+type idempotencyMemoryEntry struct {
+	record    *IdempotencyRecord
+	expiresAt time.Time
+}
+
+var (
+	idempotencyRecords     = make(map[string]*idempotencyMemoryEntry)
+	idempotencyRecordsLock sync.Mutex
+)
+
+func idempotencyRedisKey(key string) string {
+	return "idempotency:" + key
+}
+
+// ClaimIdempotencyKey 尝试占用 key，占用成功时返回 claimed 为 true；
+// key 已被占用时返回已有记录，记录可能仍在处理中
+func ClaimIdempotencyKey(key string, record *IdempotencyRecord, ttl time.Duration) (existing *IdempotencyRecord, claimed bool, err error) {
+	if !common.RedisEnabled {
+		idempotencyRecordsLock.Lock()
+		defer idempotencyRecordsLock.Unlock()
+		now := time.Now()
+		for k, entry := range idempotencyRecords {
+			if now.After(entry.expiresAt) {
+				delete(idempotencyRecords, k)
+			}
+		}
+		if entry, ok := idempotencyRecords[key]; ok {
+			return entry.record, false, nil
+		}
+		idempotencyRecords[key] = &idempotencyMemoryEntry{record: record, expiresAt: now.Add(ttl)}
+		return nil, true, nil
+	}
+
+	ctx := context.Background()
+	redisKey := idempotencyRedisKey(key)
+	value, err := common.Marshal(record)
+	if err != nil {
+		return nil, false, err
+	}
+	// 记录恰好在两次操作之间过期时重试一次
+	for i := 0; i < 2; i++ {
+		claimed, err = common.RDB.SetNX(ctx, redisKey, value, ttl).Result()
+		if err != nil {
+			return nil, false, fmt.Errorf("claim idempotency key failed: %w", err)
+		}
+		if claimed {
+			return nil, true, nil
+		}
+		data, err := common.RDB.Get(ctx, redisKey).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("get idempotency record failed: %w", err)
+		}
+		existing = &IdempotencyRecord{}
+		if err = common.Unmarshal(data, existing); err != nil {
+			return nil, false, fmt.Errorf("decode idempotency record failed: %w", err)
+		}
+		return existing, false, nil
+	}
+	return nil, false, fmt.Errorf("claim idempotency key failed: record keeps expiring")
+}
+
+// SaveIdempotencyRecord 保存请求完成后的响应，覆盖处理中的占用记录
+func SaveIdempotencyRecord(key string, record *IdempotencyRecord, ttl time.Duration) error {
+	if !common.RedisEnabled {
+		idempotencyRecordsLock.Lock()
+		defer idempotencyRecordsLock.Unlock()
+		idempotencyRecords[key] = &idempotencyMemoryEntry{record: record, expiresAt: time.Now().Add(ttl)}
+		return nil
+	}
+	value, err := common.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return common.RDB.Set(context.Background(), idempotencyRedisKey(key), value, ttl).Err()
+}
+
+// ReleaseIdempotencyKey 请求失败时释放占用，客户端可以使用同一个 key 重试
+func ReleaseIdempotencyKey(key string) error {
+	if !common.RedisEnabled {
+		idempotencyRecordsLock.Lock()
+		defer idempotencyRecordsLock.Unlock()
+		delete(idempotencyRecords, key)
+		return nil
+	}
+	return common.RDB.Del(context.Background(), idempotencyRedisKey(key)).Err()
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/dto"
+	relaycommon "one-api/relay/common"
+	"one-api/setting/operation_setting"
+	"time"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// imageBlurFactor 模糊时先缩小到原图的 1/imageBlurFactor 再放大
+const imageBlurFactor = 32
+
+type imageModerationImage struct {
+	Index   int    `json:"index"`
+	Url     string `json:"url,omitempty"`
+	B64Json string `json:"b64_json,omitempty"`
+}
+
+type imageModerationRequest struct {
+	Model  string                 `json:"model"`
+	Prompt string                 `json:"prompt"`
+	UserId int                    `json:"user_id"`
+	Images []imageModerationImage `json:"images"`
+}
+
+type imageModerationResponse struct {
+	Results []struct {
+		Index      int      `json:"index"`
+		Flagged    bool     `json:"flagged"`
+		Score      float64  `json:"score"`
+		Categories []string `json:"categories,omitempty"`
+	} `json:"results"`
+}
+
+// ModerateImageResponse 调用 webhook 分类器审核生成的图片，按配置移除或模糊违规图片。
+// 分类器不可用时按 FailOpen 决定放行还是返回错误
+func ModerateImageResponse(info *relaycommon.RelayInfo, prompt string, response *dto.ImageResponse) (*dto.ImageModerationResult, error) {
+	setting := operation_setting.GetImageModerationSetting()
+	result := &dto.ImageModerationResult{
+		Action: setting.Action,
+	}
+	if len(response.Data) == 0 {
+		return result, nil
+	}
+
+	flagged, err := classifyImages(setting, info, prompt, response.Data)
+	if err != nil {
+		if !setting.FailOpen {
+			return nil, err
+		}
+		result.Error = err.Error()
+		return result, nil
+	}
+	if len(flagged) == 0 {
+		return result, nil
+	}
+
+	data := make([]dto.ImageData, 0, len(response.Data))
+	for i, item := range response.Data {
+		if !flagged[i] {
+			data = append(data, item)
+			continue
+		}
+		result.Flagged = append(result.Flagged, i)
+		if setting.Action != operation_setting.ImageModerationActionBlur || item.B64Json == "" {
+			// 已保存到图片存储的违规图片一并删除
+			if item.Url != "" {
+				if err := DeleteStoredImage(item.Url); err != nil {
+					common.SysError("failed to delete flagged stored image: " + err.Error())
+				}
+			}
+			continue
+		}
+		blurred, err := blurBase64Image(item.B64Json)
+		if err != nil {
+			common.SysError("failed to blur flagged image: " + err.Error())
+			continue
+		}
+		item.B64Json = blurred
+		data = append(data, item)
+	}
+	response.Data = data
+	return result, nil
+}
+
+func classifyImages(setting *operation_setting.ImageModerationSetting, info *relaycommon.RelayInfo, prompt string, images []dto.ImageData) (map[int]bool, error) {
+	payload := imageModerationRequest{
+		Model:  info.OriginModelName,
+		Prompt: prompt,
+		UserId: info.UserId,
+		Images: make([]imageModerationImage, 0, len(images)),
+	}
+	for i, item := range images {
+		payload.Images = append(payload.Images, imageModerationImage{
+			Index:   i,
+			Url:     item.Url,
+			B64Json: item.B64Json,
+		})
+	}
+	payloadBytes, err := common.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(setting.TimeoutSeconds)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.WebhookURL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create image moderation request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.WebhookSecret != "" {
+		req.Header.Set("X-Webhook-Signature", generateSignature(setting.WebhookSecret, payloadBytes))
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("image moderation request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("image moderation request failed with status code: %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var moderationResp imageModerationResponse
+	if err = common.Unmarshal(body, &moderationResp); err != nil {
+		return nil, fmt.Errorf("failed to parse image moderation response: %v", err)
+	}
+
+	flagged := make(map[int]bool)
+	for _, r := range moderationResp.Results {
+		if r.Flagged || r.Score >= setting.Threshold {
+			flagged[r.Index] = true
+		}
+	}
+	return flagged, nil
+}
+
+// blurBase64Image 缩小后再放大实现模糊，jpeg 保持原格式，其余格式输出 png
+func blurBase64Image(b64 string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		img, err = webp.Decode(bytes.NewReader(data))
+		if err != nil {
+			return "", errors.New("unsupported image format")
+		}
+		format = "webp"
+	}
+
+	bounds := img.Bounds()
+	small := image.NewRGBA(image.Rect(0, 0, max(1, bounds.Dx()/imageBlurFactor), max(1, bounds.Dy()/imageBlurFactor)))
+	draw.ApproxBiLinear.Scale(small, small.Bounds(), img, bounds, draw.Src, nil)
+	blurred := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.ApproxBiLinear.Scale(blurred, blurred.Bounds(), small, small.Bounds(), draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, blurred, nil)
+	} else {
+		err = png.Encode(&buf, blurred)
+	}
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"one-api/common"
+	"one-api/setting"
+	"one-api/setting/operation_setting"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/google/uuid"
+)
+
+// imageExtension 根据 MIME 类型选择文件扩展名，默认 png
+func imageExtension(mimeType string) string {
+	switch mimeType {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	}
+	return ".png"
+}
+
+// isStoredImageName 判断是否为 StoreImage 生成的文件名（uuid + 图片扩展名）
+func isStoredImageName(name string) bool {
+	ext := filepath.Ext(name)
+	switch ext {
+	case ".png", ".jpg", ".webp", ".gif":
+	default:
+		return false
+	}
+	_, err := uuid.Parse(strings.TrimSuffix(name, ext))
+	return err == nil
+}
+
+// StoreImage 将生成的图片保存到配置的存储中，返回可访问的 URL
+func StoreImage(data []byte, mimeType string) (string, error) {
+	storageSetting := operation_setting.GetImageStorageSetting()
+	name := uuid.New().String() + imageExtension(mimeType)
+	switch storageSetting.Type {
+	case operation_setting.ImageStorageTypeLocal:
+		return storeImageLocal(storageSetting, name, data)
+	case operation_setting.ImageStorageTypeS3:
+		return storeImageS3(storageSetting, name, data, mimeType)
+	}
+	return "", errors.New("image storage is not configured")
+}
+
+func storeImageLocal(storageSetting *operation_setting.ImageStorageSetting, name string, data []byte) (string, error) {
+	if err := os.MkdirAll(storageSetting.LocalDir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(storageSetting.LocalDir, name), data, 0644); err != nil {
+		return "", err
+	}
+	publicURL := storageSetting.PublicURL
+	if publicURL == "" {
+		publicURL = setting.ServerAddress + "/api/image_storage"
+	}
+	return strings.TrimSuffix(publicURL, "/") + "/" + name, nil
+}
+
+func storeImageS3(storageSetting *operation_setting.ImageStorageSetting, name string, data []byte, mimeType string) (string, error) {
+	key := storageSetting.S3Prefix + name
+	objectURL, err := putS3Object(storageSetting, key, data, mimeType)
+	if err != nil {
+		return "", err
+	}
+	if storageSetting.PublicURL != "" {
+		return strings.TrimSuffix(storageSetting.PublicURL, "/") + "/" + key, nil
+	}
+	return objectURL, nil
+}
+
+// s3ObjectRequest 创建签名后的 S3 请求，使用 path-style 地址，兼容 MinIO、R2 等 S3 兼容存储
+func s3ObjectRequest(storageSetting *operation_setting.ImageStorageSetting, method string, key string, data []byte) (*http.Request, error) {
+	endpoint := strings.TrimSuffix(storageSetting.S3Endpoint, "/")
+	if endpoint == "" || storageSetting.S3Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	objectURL := fmt.Sprintf("%s/%s/%s", endpoint, storageSetting.S3Bucket, key)
+	req, err := http.NewRequest(method, objectURL, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return signS3Request(storageSetting, req, data)
+}
+
+func signS3Request(storageSetting *operation_setting.ImageStorageSetting, req *http.Request, data []byte) (*http.Request, error) {
+	req.ContentLength = int64(len(data))
+	payloadHash := sha256.Sum256(data)
+	payloadHashHex := hex.EncodeToString(payloadHash[:])
+	req.Header.Set("X-Amz-Content-Sha256", payloadHashHex)
+	credentials := aws.Credentials{
+		AccessKeyID:     storageSetting.S3AccessKey,
+		SecretAccessKey: storageSetting.S3SecretKey,
+	}
+	if err := v4.NewSigner().SignHTTP(context.Background(), credentials, req, payloadHashHex, "s3", storageSetting.S3Region, time.Now()); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// putS3Object 上传对象，返回对象地址
+func putS3Object(storageSetting *operation_setting.ImageStorageSetting, key string, data []byte, contentType string) (string, error) {
+	req, err := s3ObjectRequest(storageSetting, http.MethodPut, key, data)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("upload object to s3 failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return req.URL.String(), nil
+}
+
+// getS3Object 下载对象内容
+func getS3Object(storageSetting *operation_setting.ImageStorageSetting, key string) ([]byte, error) {
+	req, err := s3ObjectRequest(storageSetting, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("download object from s3 failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// DeleteStoredImage 删除 StoreImage 保存的图片，不是图片存储生成的地址时忽略
+func DeleteStoredImage(imageURL string) error {
+	storageSetting := operation_setting.GetImageStorageSetting()
+	imageURL, _, _ = strings.Cut(imageURL, "?")
+	name := path.Base(imageURL)
+	if !isStoredImageName(name) {
+		return nil
+	}
+	switch storageSetting.Type {
+	case operation_setting.ImageStorageTypeLocal:
+		if err := os.Remove(filepath.Join(storageSetting.LocalDir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	case operation_setting.ImageStorageTypeS3:
+		return deleteS3Object(storageSetting, storageSetting.S3Prefix+name)
+	}
+	return nil
+}
+
+// GetStoredImagePath 返回本地存储图片的路径，name 只允许为文件名
+func GetStoredImagePath(name string) (string, error) {
+	storageSetting := operation_setting.GetImageStorageSetting()
+	if storageSetting.Type != operation_setting.ImageStorageTypeLocal {
+		return "", errors.New("local image storage is not enabled")
+	}
+	if name != filepath.Base(name) || !isStoredImageName(name) {
+		return "", errors.New("invalid image name")
+	}
+	return filepath.Join(storageSetting.LocalDir, name), nil
+}
+
+type s3ListObjectsResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+}
+
+// listS3Objects 列出前缀下的一页对象
+func listS3Objects(storageSetting *operation_setting.ImageStorageSetting, prefix string, continuationToken string) (*s3ListObjectsResult, error) {
+	endpoint := strings.TrimSuffix(storageSetting.S3Endpoint, "/")
+	if endpoint == "" || storageSetting.S3Bucket == "" {
+		return nil, errors.New("s3 endpoint and bucket are required")
+	}
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	if continuationToken != "" {
+		query.Set("continuation-token", continuationToken)
+	}
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/%s?%s", endpoint, storageSetting.S3Bucket, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	if req, err = signS3Request(storageSetting, req, nil); err != nil {
+		return nil, err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("list objects from s3 failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	var result s3ListObjectsResult
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// deleteS3Object 删除对象
+func deleteS3Object(storageSetting *operation_setting.ImageStorageSetting, key string) error {
+	req, err := s3ObjectRequest(storageSetting, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("delete object from s3 failed: status %d, %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+// cleanupLocalImages 删除本地存储目录中早于 before 的图片，只处理 StoreImage 生成的文件，目录中的其他文件不受影响
+func cleanupLocalImages(storageSetting *operation_setting.ImageStorageSetting, before time.Time) (int, error) {
+	entries, err := os.ReadDir(storageSetting.LocalDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	deleted := 0
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !isStoredImageName(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(before) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(storageSetting.LocalDir, entry.Name())); err != nil {
+			return deleted, err
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// cleanupS3Images 删除对象存储中早于 before 的图片。
+// 只删除前缀下 StoreImage 生成的对象，请求存档等其他对象不会被删除
+func cleanupS3Images(storageSetting *operation_setting.ImageStorageSetting, before time.Time) (int, error) {
+	deleted := 0
+	continuationToken := ""
+	for {
+		result, err := listS3Objects(storageSetting, storageSetting.S3Prefix, continuationToken)
+		if err != nil {
+			return deleted, err
+		}
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, storageSetting.S3Prefix)
+			if !isStoredImageName(name) || !object.LastModified.Before(before) {
+				continue
+			}
+			if err = deleteS3Object(storageSetting, object.Key); err != nil {
+				return deleted, err
+			}
+			deleted++
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return deleted, nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// CleanupStoredImages 删除超过保留天数的生成图片
+func CleanupStoredImages() (int, error) {
+	storageSetting := operation_setting.GetImageStorageSetting()
+	if storageSetting.RetentionDays <= 0 {
+		return 0, nil
+	}
+	before := time.Now().AddDate(0, 0, -storageSetting.RetentionDays)
+	switch storageSetting.Type {
+	case operation_setting.ImageStorageTypeLocal:
+		return cleanupLocalImages(storageSetting, before)
+	case operation_setting.ImageStorageTypeS3:
+		// 对象存储为各节点共享，只由主节点清理
+		if !common.IsMasterNode {
+			return 0, nil
+		}
+		return cleanupS3Images(storageSetting, before)
+	}
+	return 0, nil
+}
+
+// AutomaticallyCleanupStoredImages 每小时清理一次过期的生成图片。本地存储在各节点磁盘上，每个节点都需要运行
+func AutomaticallyCleanupStoredImages() {
+	for {
+		deleted, err := CleanupStoredImages()
+		if err != nil {
+			common.SysError("failed to cleanup stored images: " + err.Error())
+		} else if deleted > 0 {
+			common.SysLog(fmt.Sprintf("deleted %d expired stored images", deleted))
+		}
+		time.Sleep(time.Hour)
+	}
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/setting/operation_setting"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 检测语言时最多读取的字符数
+const languageDetectMaxRunes = 2000
+
+type languageDetectMessage struct {
+	Role    string `json:"role"`
+	Content any    `json:"content"`
+}
+
+type languageDetectRequest struct {
+	Messages []languageDetectMessage `json:"messages"`
+	Prompt   any                     `json:"prompt"`
+	Input    any                     `json:"input"`
+}
+
+// languageDetectText 提取内容中的文本，兼容字符串、字符串数组与 OpenAI/Claude 的多模态内容块
+func languageDetectText(content any) string {
+	switch v := content.(type) {
+	case string:
+		return v
+	case []any:
+		text := ""
+		for _, item := range v {
+			switch part := item.(type) {
+			case string:
+				text += part
+			case map[string]any:
+				if partText, ok := part["text"].(string); ok {
+					text += partText
+				}
+			}
+		}
+		return text
+	}
+	return ""
+}
+
+// DetectRequestLanguage 检测请求中最后一条用户消息（或 prompt/input）的语言，无法判断时返回空字符串
+func DetectRequestLanguage(c *gin.Context) string {
+	var request languageDetectRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return ""
+	}
+	text := ""
+	for i := len(request.Messages) - 1; i >= 0; i-- {
+		if request.Messages[i].Role == "user" {
+			text = languageDetectText(request.Messages[i].Content)
+			break
+		}
+	}
+	if text == "" {
+		text = languageDetectText(request.Prompt)
+	}
+	if text == "" {
+		text = languageDetectText(request.Input)
+	}
+	return DetectLanguage(text)
+}
+
+// DetectLanguage 按文字所属的书写系统判断语言，返回 ISO 639-1 代码
+// 含假名时判为日语，其余以字符数最多的书写系统为准，拉丁字母统一判为英语
+func DetectLanguage(text string) string {
+	counts := make(map[string]int)
+	total := 0
+	for _, r := range text {
+		if total >= languageDetectMaxRunes {
+			break
+		}
+		language := ""
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			language = "ja"
+		case unicode.Is(unicode.Han, r):
+			language = "zh"
+		case unicode.Is(unicode.Hangul, r):
+			language = "ko"
+		case unicode.Is(unicode.Cyrillic, r):
+			language = "ru"
+		case unicode.Is(unicode.Arabic, r):
+			language = "ar"
+		case unicode.Is(unicode.Thai, r):
+			language = "th"
+		case unicode.Is(unicode.Devanagari, r):
+			language = "hi"
+		case unicode.Is(unicode.Latin, r):
+			language = "en"
+		default:
+			continue
+		}
+		counts[language]++
+		total++
+	}
+	if total < operation_setting.GetLanguageRoutingSetting().MinTextRune {
+		return ""
+	}
+	if counts["ja"] > 0 {
+		return "ja"
+	}
+	detected := ""
+	for language, count := range counts {
+		if count > counts[detected] || (count == counts[detected] && language < detected) {
+			detected = language
+		}
+	}
+	return detected
+}
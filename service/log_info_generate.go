@@ -6,6 +6,9 @@ import (
 	"one-api/dto"
 	relaycommon "one-api/relay/common"
 	"one-api/relay/helper"
+	"one-api/setting/model_setting"
+	"one-api/setting/ratio_setting"
+	"one-api/types"
 
 	"github.com/gin-gonic/gin"
 )
@@ -28,6 +31,38 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
+	if variant := ratio_setting.ParseModelVariant(relayInfo.OriginModelName); variant.Variant != ratio_setting.ModelVariantNone {
+		other["model_variant"] = variant.Variant
+		other["base_model"] = variant.BaseModel
+		if variant.Variant == ratio_setting.ModelVariantBudget {
+			other["thinking_budget"] = variant.Budget
+		}
+	}
+
+	if relayInfo.GeminiCacheLookupLatency > 0 || relayInfo.GeminiCacheCreationLatency > 0 {
+		appendGeminiCacheLatencyInfo(other, relayInfo)
+	}
+
+	if relayInfo.ClaudePromptCacheTracked {
+		other["claude_prompt_cache_prefix_hits"] = relayInfo.ClaudePromptCacheHits
+	}
+
+	if relayInfo.PriceOverridden {
+		other["price_override"] = true
+		other["price_override_values"] = relayInfo.PriceOverrides
+	}
+
+	if relayInfo.ImageModeration != nil {
+		other["image_moderation"] = relayInfo.ImageModeration
+	}
+
+	if logMetadata := GetLogRequestMetadata(relayInfo.RequestMetadata); len(logMetadata) > 0 {
+		other["request_metadata"] = logMetadata
+	}
+
+	if language := common.GetContextKeyString(ctx, constant.ContextKeyPromptLanguage); language != "" {
+		other["prompt_language"] = language
+	}
 
 	isSystemPromptOverwritten := common.GetContextKeyBool(ctx, constant.ContextKeySystemPromptOverride)
 	if isSystemPromptOverwritten {
@@ -36,6 +71,9 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 
 	adminInfo := make(map[string]interface{})
 	adminInfo["use_channel"] = ctx.GetStringSlice("use_channel")
+	if attempts, ok := common.GetContextKeyType[[]types.RelayAttempt](ctx, constant.ContextKeyRelayAttempts); ok {
+		adminInfo["retry_attempts"] = attempts
+	}
 	isMultiKey := common.GetContextKeyBool(ctx, constant.ContextKeyChannelIsMultiKey)
 	if isMultiKey {
 		adminInfo["is_multi_key"] = true
@@ -45,8 +83,9 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 	return other
 }
 
+// 音频计费不区分缓存命中，上游返回的 cached_tokens 按倍率 1 记录，仅用于统计
 func GenerateWssOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.RealtimeUsage, modelRatio, groupRatio, completionRatio, audioRatio, audioCompletionRatio, modelPrice, userGroupRatio float64) map[string]interface{} {
-	info := GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, 0, 0.0, modelPrice, userGroupRatio)
+	info := GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, usage.InputTokenDetails.CachedTokens, 1, modelPrice, userGroupRatio)
 	info["ws"] = true
 	info["audio_input"] = usage.InputTokenDetails.AudioTokens
 	info["audio_output"] = usage.OutputTokenDetails.AudioTokens
@@ -57,8 +96,8 @@ func GenerateWssOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, us
 	return info
 }
 
-func GenerateAudioOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage, modelRatio, groupRatio, completionRatio, audioRatio, audioCompletionRatio, modelPrice, userGroupRatio float64) map[string]interface{} {
-	info := GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, 0, 0.0, modelPrice, userGroupRatio)
+func GenerateAudioOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage, modelRatio, groupRatio, completionRatio, audioRatio, audioCompletionRatio, cacheRatio, modelPrice, userGroupRatio float64) map[string]interface{} {
+	info := GenerateTextOtherInfo(ctx, relayInfo, modelRatio, groupRatio, completionRatio, usage.PromptTokensDetails.CachedTokens, cacheRatio, modelPrice, userGroupRatio)
 	info["audio"] = true
 	info["audio_input"] = usage.PromptTokensDetails.AudioTokens
 	info["audio_output"] = usage.CompletionTokenDetails.AudioTokens
@@ -87,3 +126,22 @@ func GenerateMjOtherInfo(priceData helper.PerCallPriceData) map[string]interface
 	}
 	return other
 }
+
+// appendGeminiCacheLatencyInfo 记录 Gemini 缓存查询与创建的耗时，并判断请求是否因此超出首字响应时间目标
+func appendGeminiCacheLatencyInfo(other map[string]interface{}, relayInfo *relaycommon.RelayInfo) {
+	overhead := relayInfo.GeminiCacheLookupLatency + relayInfo.GeminiCacheCreationLatency
+	other["gemini_cache_lookup_ms"] = relayInfo.GeminiCacheLookupLatency.Milliseconds()
+	other["gemini_cache_creation_ms"] = relayInfo.GeminiCacheCreationLatency.Milliseconds()
+	slo := int64(model_setting.GetGeminiSettings().CacheLatencySLOMs)
+	if slo <= 0 || relayInfo.FirstResponseTime.IsZero() {
+		return
+	}
+	frt := relayInfo.FirstResponseTime.Sub(relayInfo.StartTime).Milliseconds()
+	if frt > slo {
+		other["gemini_cache_slo_exceeded"] = true
+		// 扣除缓存耗时后仍满足目标，说明超时由缓存带来的额外请求导致
+		if frt-overhead.Milliseconds() <= slo {
+			other["gemini_cache_slo_caused_by_cache"] = true
+		}
+	}
+}
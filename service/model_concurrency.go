@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// 先清理租约已过期的占位，再在未达到上限时加入新的占位
+var modelConcurrencyAcquireScript = redis.NewScript(`
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', ARGV[1])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[4])
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+var (
+	modelConcurrency     = make(map[string]int)
+	modelConcurrencyLock sync.Mutex
+)
+
+func modelConcurrencyRedisKey(key string) string {
+	return "model_concurrency:" + key
+}
+
+// TryAcquireModelSlot 尝试占用模型的一个并发位，成功时返回释放函数。
+// 启用 Redis 时占位带租约，持有期间定期续约，节点异常退出后占位在租约到期后自动释放
+func TryAcquireModelSlot(key string, limit int, lease time.Duration) (func(), bool, error) {
+	if !common.RedisEnabled {
+		modelConcurrencyLock.Lock()
+		defer modelConcurrencyLock.Unlock()
+		if modelConcurrency[key] >= limit {
+			return nil, false, nil
+		}
+		modelConcurrency[key]++
+		return func() {
+			modelConcurrencyLock.Lock()
+			defer modelConcurrencyLock.Unlock()
+			if modelConcurrency[key] <= 1 {
+				delete(modelConcurrency, key)
+				return
+			}
+			modelConcurrency[key]--
+		}, true, nil
+	}
+
+	ctx := context.Background()
+	redisKey := modelConcurrencyRedisKey(key)
+	member := common.GetUUID()
+	now := time.Now()
+	acquired, err := modelConcurrencyAcquireScript.Run(ctx, common.RDB, []string{redisKey},
+		now.UnixMilli(), limit, now.Add(lease).UnixMilli(), member, int(lease.Seconds())*2).Int()
+	if err != nil {
+		return nil, false, fmt.Errorf("acquire model concurrency slot failed: %w", err)
+	}
+	if acquired == 0 {
+		return nil, false, nil
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				common.RDB.ZAddXX(ctx, redisKey, &redis.Z{
+					Score:  float64(time.Now().Add(lease).UnixMilli()),
+					Member: member,
+				})
+				common.RDB.Expire(ctx, redisKey, lease*2)
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			common.RDB.ZRem(ctx, redisKey, member)
+		})
+	}, true, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/constant"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pendingQuotaTTL Redis 中预扣额度记录的有效期，防止节点异常退出后记录无法释放
+const pendingQuotaTTL = time.Hour
+
+var (
+	pendingQuotas     = make(map[int]int)
+	pendingQuotasLock sync.Mutex
+)
+
+func pendingQuotaRedisKey(userId int) string {
+	return fmt.Sprintf("pending_quota:%d", userId)
+}
+
+func addPendingQuota(userId int, quota int) {
+	if !common.RedisEnabled {
+		pendingQuotasLock.Lock()
+		defer pendingQuotasLock.Unlock()
+		pendingQuotas[userId] += quota
+		if pendingQuotas[userId] <= 0 {
+			delete(pendingQuotas, userId)
+		}
+		return
+	}
+	ctx := context.Background()
+	key := pendingQuotaRedisKey(userId)
+	if err := common.RDB.IncrBy(ctx, key, int64(quota)).Err(); err != nil {
+		common.SysError("failed to update pending quota: " + err.Error())
+		return
+	}
+	common.RDB.Expire(ctx, key, pendingQuotaTTL)
+}
+
+// HoldPendingQuota 记录请求预扣的额度，请求结束时由 ReleasePendingQuota 释放
+func HoldPendingQuota(c *gin.Context, userId int, quota int) {
+	if quota <= 0 {
+		return
+	}
+	addPendingQuota(userId, quota)
+	common.SetContextKey(c, constant.ContextKeyPendingQuota, common.GetContextKeyInt(c, constant.ContextKeyPendingQuota)+quota)
+}
+
+// ReleasePendingQuota 释放本次请求记录的预扣额度
+func ReleasePendingQuota(c *gin.Context, userId int) {
+	quota := common.GetContextKeyInt(c, constant.ContextKeyPendingQuota)
+	if quota <= 0 {
+		return
+	}
+	common.SetContextKey(c, constant.ContextKeyPendingQuota, 0)
+	addPendingQuota(userId, -quota)
+}
+
+// GetPendingQuota 返回用户进行中的请求预扣且尚未结算的额度
+func GetPendingQuota(userId int) int {
+	if !common.RedisEnabled {
+		pendingQuotasLock.Lock()
+		defer pendingQuotasLock.Unlock()
+		return pendingQuotas[userId]
+	}
+	value, err := common.RDB.Get(context.Background(), pendingQuotaRedisKey(userId)).Result()
+	if err != nil {
+		return 0
+	}
+	quota, _ := strconv.Atoi(value)
+	if quota < 0 {
+		return 0
+	}
+	return quota
+}
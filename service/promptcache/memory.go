@@ -0,0 +1,124 @@
+package promptcache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DefaultMemoryCapacity 未启用 Redis 时进程内 LRU 最多保存的缓存记录数
+const DefaultMemoryCapacity = 1024
+
+type memoryItem struct {
+	hash     string
+	entry    Entry
+	expireAt time.Time
+}
+
+// memoryStore 进程内 LRU，未启用 Redis 的单实例部署也能复用上游缓存
+type memoryStore struct {
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List
+	lock     sync.Mutex
+}
+
+func newMemoryStore(capacity int) *memoryStore {
+	return &memoryStore{
+		capacity: capacity,
+		items:    make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (m *memoryStore) get(hash string) (*Entry, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	elem, ok := m.items[hash]
+	if !ok {
+		return nil, false
+	}
+	item := elem.Value.(*memoryItem)
+	if time.Now().After(item.expireAt) {
+		m.order.Remove(elem)
+		delete(m.items, hash)
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	entry := item.entry
+	return &entry, true
+}
+
+func (m *memoryStore) set(hash string, entry *Entry, ttl time.Duration) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if elem, ok := m.items[hash]; ok {
+		item := elem.Value.(*memoryItem)
+		item.entry = *entry
+		item.expireAt = time.Now().Add(ttl)
+		m.order.MoveToFront(elem)
+		return
+	}
+	m.items[hash] = m.order.PushFront(&memoryItem{
+		hash:     hash,
+		entry:    *entry,
+		expireAt: time.Now().Add(ttl),
+	})
+	for m.capacity > 0 && m.order.Len() > m.capacity {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.items, oldest.Value.(*memoryItem).hash)
+	}
+}
+
+// update 更新记录内容，保留原有的过期时间
+func (m *memoryStore) update(hash string, entry *Entry) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if elem, ok := m.items[hash]; ok {
+		elem.Value.(*memoryItem).entry = *entry
+	}
+}
+
+// deleteByChannel 删除绑定到该渠道的记录，返回被删除的记录
+func (m *memoryStore) deleteByChannel(channelID int) []Entry {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	entries := make([]Entry, 0)
+	for hash, elem := range m.items {
+		item := elem.Value.(*memoryItem)
+		if item.entry.ChannelID != channelID {
+			continue
+		}
+		entries = append(entries, item.entry)
+		m.order.Remove(elem)
+		delete(m.items, hash)
+	}
+	return entries
+}
+
+// snapshot 返回未过期记录的副本，遍历期间不持有锁
+func (m *memoryStore) snapshot() map[string]*Entry {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	entries := make(map[string]*Entry, len(m.items))
+	for hash, elem := range m.items {
+		item := elem.Value.(*memoryItem)
+		if now.After(item.expireAt) {
+			continue
+		}
+		entry := item.entry
+		entries[hash] = &entry
+	}
+	return entries
+}
+
+func (m *memoryStore) delete(hash string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if elem, ok := m.items[hash]; ok {
+		m.order.Remove(elem)
+		delete(m.items, hash)
+	}
+}
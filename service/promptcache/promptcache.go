@@ -0,0 +1,89 @@
+package promptcache
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	ProviderGemini    = "gemini"
+	ProviderAnthropic = "anthropic"
+)
+
+// Request 一次缓存查找/创建所需的信息
+type Request struct {
+	ApiKey     string
+	ChannelID  int
+	Model      string
+	Hashes     []string // 候选前缀 hash，按优先级排列，第一个用于新建缓存
+	TokenCount int
+	// PrefixLength 缓存覆盖的消息条数，随记录保存，命中时原样返回
+	PrefixLength int
+	// TTL 新建缓存记录的有效期，为 0 时使用默认有效期
+	TTL time.Duration
+	// Create 未命中时创建上游缓存，返回缓存名；为 nil 时直接以 hash 作为缓存名（仅做复用跟踪）
+	Create func() (string, error)
+}
+
+// Result 缓存查找结果，未命中且不满足缓存条件时 GetOrCreate 返回 nil
+type Result struct {
+	Name          string
+	Hash          string
+	Index         int // 命中的 Hashes 下标
+	IsJustCreated bool
+	Hits          int // 该缓存此前被复用的次数
+	PrefixLength  int // 缓存覆盖的消息条数
+}
+
+type Stats struct {
+	Provider      string `json:"provider"`
+	Hits          int64  `json:"hits"`
+	Misses        int64  `json:"misses"`
+	Creations     int64  `json:"creations"`
+	Errors        int64  `json:"errors"`
+	Expirations   int64  `json:"expirations"`
+	Invalidations int64  `json:"invalidations"`
+}
+
+// PromptCache 各供应商的提示词缓存实现
+type PromptCache interface {
+	ShouldCache(model string, tokenCount int) bool
+	GetOrCreate(req *Request) (*Result, error)
+	Invalidate(hash string) error
+	// InvalidateChannel 删除绑定到该渠道的全部缓存记录，返回被删除的记录
+	InvalidateChannel(channelID int) ([]Entry, error)
+	Stats() Stats
+}
+
+var (
+	registry     = make(map[string]PromptCache)
+	registryLock sync.RWMutex
+)
+
+func Register(provider string, cache PromptCache) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[provider] = cache
+}
+
+func Get(provider string) (PromptCache, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	cache, ok := registry[provider]
+	return cache, ok
+}
+
+// AllStats 返回所有已注册实现的统计，按 provider 排序
+func AllStats() []Stats {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	stats := make([]Stats, 0, len(registry))
+	for _, cache := range registry {
+		stats = append(stats, cache.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Provider < stats[j].Provider
+	})
+	return stats
+}
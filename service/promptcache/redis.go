@@ -0,0 +1,267 @@
+package promptcache
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Entry 保存在 Redis 中的缓存记录
+type Entry struct {
+	CacheName  string `json:"cache_name"`
+	ChannelID  int    `json:"channel_id"`
+	Hits       int    `json:"hits,omitempty"`
+	TokenCount int    `json:"token_count,omitempty"` // 缓存内容的 token 数，用于估算节省量
+	// PrefixLength 缓存覆盖的消息条数，自定义缓存 key 无法从 hash 推出前缀长度时使用
+	PrefixLength int   `json:"prefix_length,omitempty"`
+	TTL          int64 `json:"ttl,omitempty"` // 自定义有效期（秒），为 0 时使用默认有效期
+}
+
+// RedisPromptCache 基于 Redis 的通用实现，记录前缀 hash 与上游缓存、渠道的对应关系，未启用 Redis 时退回进程内 LRU。
+// 缓存与渠道的 API Key 绑定，记录的渠道与当前渠道不一致时视为未命中。
+type RedisPromptCache struct {
+	provider  string
+	keyPrefix string
+	ttl       time.Duration
+	memory    *memoryStore
+	// RefreshOnHit 命中后刷新过期时间，适用于上游命中即续期的缓存（如 Anthropic ephemeral）
+	RefreshOnHit bool
+	// ShouldCacheFunc 判断是否值得创建缓存，为 nil 时总是创建
+	ShouldCacheFunc func(model string, tokenCount int) bool
+	// VerifyFunc 命中后向上游确认缓存仍然存在，为 nil 时不校验；extended 表示上游缓存已续期，此时同步刷新本地记录的过期时间
+	VerifyFunc func(apiKey string, cacheName string) (exists bool, extended bool, err error)
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	creations     atomic.Int64
+	errors        atomic.Int64
+	expirations   atomic.Int64
+	invalidations atomic.Int64
+}
+
+func NewRedisPromptCache(provider string, keyPrefix string, ttl time.Duration) *RedisPromptCache {
+	return &RedisPromptCache{
+		provider:  provider,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+		memory:    newMemoryStore(DefaultMemoryCapacity),
+	}
+}
+
+func (r *RedisPromptCache) redisKey(hash string) string {
+	return fmt.Sprintf("%s:%s", r.keyPrefix, hash)
+}
+
+func (r *RedisPromptCache) ShouldCache(model string, tokenCount int) bool {
+	if r.ShouldCacheFunc == nil {
+		return true
+	}
+	return r.ShouldCacheFunc(model, tokenCount)
+}
+
+// Lookup 读取 hash 对应的缓存记录，不做上游校验
+func (r *RedisPromptCache) Lookup(hash string) (*Entry, bool) {
+	if hash == "" {
+		return nil, false
+	}
+	if !common.RedisEnabled {
+		return r.memory.get(hash)
+	}
+	val, err := common.RDB.Get(context.Background(), r.redisKey(hash)).Result()
+	if err != nil || val == "" {
+		return nil, false
+	}
+	var entry Entry
+	if err = common.Unmarshal([]byte(val), &entry); err != nil {
+		return nil, false
+	}
+	return &entry, true
+}
+
+func (r *RedisPromptCache) save(hash string, entry *Entry) {
+	ttl := r.ttl
+	if entry.TTL > 0 {
+		ttl = time.Duration(entry.TTL) * time.Second
+	}
+	r.Refresh(hash, entry, ttl)
+}
+
+// update 更新记录内容（如命中次数），保留原有的过期时间
+func (r *RedisPromptCache) update(hash string, entry *Entry) {
+	if !common.RedisEnabled {
+		r.memory.update(hash, entry)
+		return
+	}
+	ctx := context.Background()
+	ttl, err := common.RDB.PTTL(ctx, r.redisKey(hash)).Result()
+	if err != nil || ttl <= 0 {
+		return
+	}
+	jsonValue, _ := common.Marshal(entry)
+	if err = common.RDB.Set(ctx, r.redisKey(hash), jsonValue, ttl).Err(); err != nil {
+		common.SysError(fmt.Sprintf("%s prompt cache update failed: %s", r.provider, err.Error()))
+	}
+}
+
+// Refresh 以指定有效期重新保存缓存记录，用于与上游缓存的实际过期时间对齐
+func (r *RedisPromptCache) Refresh(hash string, entry *Entry, ttl time.Duration) {
+	if !common.RedisEnabled {
+		r.memory.set(hash, entry, ttl)
+		return
+	}
+	jsonValue, _ := common.Marshal(entry)
+	if err := common.RDB.Set(context.Background(), r.redisKey(hash), jsonValue, ttl).Err(); err != nil {
+		common.SysError(fmt.Sprintf("%s prompt cache save failed: %s", r.provider, err.Error()))
+	}
+}
+
+func (r *RedisPromptCache) GetOrCreate(req *Request) (*Result, error) {
+	if len(req.Hashes) == 0 {
+		return nil, nil
+	}
+	for i, hash := range req.Hashes {
+		entry, ok := r.Lookup(hash)
+		if !ok || entry.ChannelID != req.ChannelID {
+			continue
+		}
+		refresh := r.RefreshOnHit
+		if r.VerifyFunc != nil {
+			exists, extended, err := r.VerifyFunc(req.ApiKey, entry.CacheName)
+			if err != nil || !exists {
+				common.SysLog(fmt.Sprintf("%s prompt cache lookup failed, creating new cache: %s", r.provider, entry.CacheName))
+				r.expirations.Add(1)
+				r.recordStats(req.ChannelID, req.Model, map[string]int64{StatExpirations: 1})
+				_ = r.Invalidate(hash)
+				break
+			}
+			refresh = refresh || extended
+		}
+		r.hits.Add(1)
+		r.recordStats(req.ChannelID, req.Model, map[string]int64{
+			StatHits:        1,
+			StatTokensSaved: int64(entry.TokenCount),
+		})
+		result := &Result{
+			Name:         entry.CacheName,
+			Hash:         hash,
+			Index:        i,
+			Hits:         entry.Hits,
+			PrefixLength: entry.PrefixLength,
+		}
+		entry.Hits++
+		if refresh {
+			r.save(hash, entry)
+		} else {
+			r.update(hash, entry)
+		}
+		return result, nil
+	}
+	r.misses.Add(1)
+	r.recordStats(req.ChannelID, req.Model, map[string]int64{StatMisses: 1})
+
+	if !r.ShouldCache(req.Model, req.TokenCount) {
+		return nil, nil
+	}
+	hash := req.Hashes[0]
+	cacheName := hash
+	if req.Create != nil {
+		var err error
+		cacheName, err = req.Create()
+		if err != nil {
+			r.errors.Add(1)
+			return nil, err
+		}
+	}
+	r.creations.Add(1)
+	r.recordStats(req.ChannelID, req.Model, map[string]int64{StatCreations: 1})
+	r.save(hash, &Entry{
+		CacheName:    cacheName,
+		ChannelID:    req.ChannelID,
+		TokenCount:   req.TokenCount,
+		PrefixLength: req.PrefixLength,
+		TTL:          int64(req.TTL.Seconds()),
+	})
+	return &Result{
+		Name:          cacheName,
+		Hash:          hash,
+		IsJustCreated: true,
+		PrefixLength:  req.PrefixLength,
+	}, nil
+}
+
+func (r *RedisPromptCache) Invalidate(hash string) error {
+	r.invalidations.Add(1)
+	if !common.RedisEnabled {
+		r.memory.delete(hash)
+		return nil
+	}
+	return common.RDB.Del(context.Background(), r.redisKey(hash)).Err()
+}
+
+func (r *RedisPromptCache) InvalidateChannel(channelID int) ([]Entry, error) {
+	if !common.RedisEnabled {
+		entries := r.memory.deleteByChannel(channelID)
+		r.invalidations.Add(int64(len(entries)))
+		return entries, nil
+	}
+	ctx := context.Background()
+	entries := make([]Entry, 0)
+	iter := common.RDB.Scan(ctx, 0, r.keyPrefix+":*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		val, err := common.RDB.Get(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var entry Entry
+		if err = common.Unmarshal([]byte(val), &entry); err != nil || entry.ChannelID != channelID {
+			continue
+		}
+		if err = common.RDB.Del(ctx, key).Err(); err != nil {
+			return entries, err
+		}
+		r.invalidations.Add(1)
+		entries = append(entries, entry)
+	}
+	return entries, iter.Err()
+}
+
+// ForEach 遍历全部缓存记录，fn 中可以调用 Invalidate / Refresh
+func (r *RedisPromptCache) ForEach(fn func(hash string, entry *Entry)) error {
+	if !common.RedisEnabled {
+		for hash, entry := range r.memory.snapshot() {
+			fn(hash, entry)
+		}
+		return nil
+	}
+	ctx := context.Background()
+	iter := common.RDB.Scan(ctx, 0, r.keyPrefix+":*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		val, err := common.RDB.Get(ctx, key).Result()
+		if err != nil {
+			continue
+		}
+		var entry Entry
+		if err = common.Unmarshal([]byte(val), &entry); err != nil {
+			continue
+		}
+		fn(strings.TrimPrefix(key, r.keyPrefix+":"), &entry)
+	}
+	return iter.Err()
+}
+
+func (r *RedisPromptCache) Stats() Stats {
+	return Stats{
+		Provider:      r.provider,
+		Hits:          r.hits.Load(),
+		Misses:        r.misses.Load(),
+		Creations:     r.creations.Load(),
+		Errors:        r.errors.Load(),
+		Expirations:   r.expirations.Load(),
+		Invalidations: r.invalidations.Load(),
+	}
+}
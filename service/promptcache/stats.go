@@ -0,0 +1,135 @@
+package promptcache
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	StatHits        = "hits"
+	StatMisses      = "misses"
+	StatCreations   = "creations"
+	StatExpirations = "expirations"
+	StatTokensSaved = "tokens_saved"
+)
+
+// Counters 单个渠道或模型的缓存统计
+type Counters struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`
+	Creations   int64 `json:"creations"`
+	Expirations int64 `json:"expirations"`
+	TokensSaved int64 `json:"tokens_saved"` // 命中缓存估算节省的输入 token
+}
+
+type ChannelCounters struct {
+	ChannelID int `json:"channel_id"`
+	Counters
+}
+
+type ModelCounters struct {
+	ModelName string `json:"model_name"`
+	Counters
+}
+
+// DetailStats 保存在 Redis 中、跨实例汇总的统计
+type DetailStats struct {
+	Provider string             `json:"provider"`
+	Channels []*ChannelCounters `json:"channels"`
+	Models   []*ModelCounters   `json:"models"`
+}
+
+func (r *RedisPromptCache) statsKey(dimension string, id string) string {
+	return fmt.Sprintf("%s_stats:%s:%s", r.keyPrefix, dimension, id)
+}
+
+// recordStats 同时累加渠道与模型两个维度的计数
+func (r *RedisPromptCache) recordStats(channelID int, model string, deltas map[string]int64) {
+	if !common.RedisEnabled || len(deltas) == 0 {
+		return
+	}
+	ctx := context.Background()
+	pipe := common.RDB.Pipeline()
+	channelKey := r.statsKey("channel", strconv.Itoa(channelID))
+	modelKey := r.statsKey("model", model)
+	for field, delta := range deltas {
+		pipe.HIncrBy(ctx, channelKey, field, delta)
+		if model != "" {
+			pipe.HIncrBy(ctx, modelKey, field, delta)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		common.SysError(fmt.Sprintf("%s prompt cache stats record failed: %s", r.provider, err.Error()))
+	}
+}
+
+// DetailStats 读取按渠道、模型汇总的统计
+func (r *RedisPromptCache) DetailStats() (*DetailStats, error) {
+	stats := &DetailStats{
+		Provider: r.provider,
+		Channels: make([]*ChannelCounters, 0),
+		Models:   make([]*ModelCounters, 0),
+	}
+	if !common.RedisEnabled {
+		return stats, nil
+	}
+	ctx := context.Background()
+	prefix := fmt.Sprintf("%s_stats:", r.keyPrefix)
+	// 使用 SCAN 遍历，避免 KEYS 在大键空间下阻塞 Redis；SCAN 可能返回重复的 key
+	seen := make(map[string]bool)
+	iter := common.RDB.Scan(ctx, 0, prefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		values, err := common.RDB.HGetAll(ctx, key).Result()
+		if err != nil {
+			return nil, err
+		}
+		counters := parseCounters(values)
+		dimension, id, ok := strings.Cut(strings.TrimPrefix(key, prefix), ":")
+		if !ok {
+			continue
+		}
+		switch dimension {
+		case "channel":
+			channelID, err := strconv.Atoi(id)
+			if err != nil {
+				continue
+			}
+			stats.Channels = append(stats.Channels, &ChannelCounters{ChannelID: channelID, Counters: counters})
+		case "model":
+			stats.Models = append(stats.Models, &ModelCounters{ModelName: id, Counters: counters})
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(stats.Channels, func(i, j int) bool {
+		return stats.Channels[i].ChannelID < stats.Channels[j].ChannelID
+	})
+	sort.Slice(stats.Models, func(i, j int) bool {
+		return stats.Models[i].ModelName < stats.Models[j].ModelName
+	})
+	return stats, nil
+}
+
+func parseCounters(values map[string]string) Counters {
+	get := func(field string) int64 {
+		v, _ := strconv.ParseInt(values[field], 10, 64)
+		return v
+	}
+	return Counters{
+		Hits:        get(StatHits),
+		Misses:      get(StatMisses),
+		Creations:   get(StatCreations),
+		Expirations: get(StatExpirations),
+		TokensSaved: get(StatTokensSaved),
+	}
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"one-api/common"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"sort"
+	"strings"
+	"time"
+)
+
+// providerIncident 状态页上一条未结束的故障
+type providerIncident struct {
+	Name       string
+	Impact     string
+	Components []string
+}
+
+var providerImpactRank = map[string]int{
+	"none":     0,
+	"minor":    1,
+	"major":    2,
+	"critical": 3,
+}
+
+// googleSeverityImpact Google Cloud 故障级别与 Statuspage 影响级别的对应关系
+var googleSeverityImpact = map[string]string{
+	"low":    "minor",
+	"medium": "major",
+	"high":   "critical",
+}
+
+// AutomaticallyCheckProviderStatus 定期拉取服务商状态页，发生故障时降低相关渠道的权重，故障结束后恢复。
+// 降级状态保存在各节点内存中，每个节点都需要运行
+func AutomaticallyCheckProviderStatus() {
+	lastSummary := ""
+	for {
+		setting := operation_setting.GetProviderStatusSetting()
+		interval := time.Duration(setting.IntervalSeconds) * time.Second
+		if !setting.Enabled || interval <= 0 {
+			if lastSummary != "" {
+				model.SetDegradedProviders(nil, 1)
+				lastSummary = ""
+				common.SysLog("provider status check disabled, restored all channel weights")
+			}
+			time.Sleep(time.Minute)
+			continue
+		}
+		degraded := CheckProviderStatus(setting)
+		model.SetDegradedProviders(degraded, setting.WeightFactor)
+		if summary := summarizeDegradedProviders(degraded); summary != lastSummary {
+			if summary == "" {
+				common.SysLog("all provider incidents resolved, restored channel weights")
+			} else {
+				common.SysLog("provider incidents detected, degraded providers: " + summary)
+			}
+			lastSummary = summary
+		}
+		time.Sleep(interval)
+	}
+}
+
+// CheckProviderStatus 拉取所有状态页，返回正在发生故障的服务商及其影响的渠道范围。
+// 状态页拉取失败时不影响对应渠道
+func CheckProviderStatus(setting *operation_setting.ProviderStatusSetting) []model.DegradedProvider {
+	degraded := make([]model.DegradedProvider, 0)
+	minRank, ok := providerImpactRank[strings.ToLower(setting.MinImpact)]
+	if !ok || minRank == 0 {
+		minRank = providerImpactRank["major"]
+	}
+	for _, feed := range setting.Feeds {
+		incidents, err := fetchProviderIncidents(feed)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to fetch %s status: %s", feed.Name, err.Error()))
+			continue
+		}
+		var reasons []string
+		for _, incident := range incidents {
+			if providerImpactRank[incident.Impact] < minRank || !incidentMatchesComponents(incident, feed.Components) {
+				continue
+			}
+			reasons = append(reasons, incident.Name)
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		degraded = append(degraded, model.DegradedProvider{
+			ChannelTypes: feed.ChannelTypes,
+			Hosts:        feed.Hosts,
+			Reason:       fmt.Sprintf("%s: %s", feed.Name, strings.Join(reasons, "; ")),
+		})
+	}
+	return degraded
+}
+
+func incidentMatchesComponents(incident providerIncident, keywords []string) bool {
+	if len(keywords) == 0 {
+		return true
+	}
+	names := append([]string{incident.Name}, incident.Components...)
+	for _, keyword := range keywords {
+		for _, name := range names {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(keyword)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func summarizeDegradedProviders(degraded []model.DegradedProvider) string {
+	items := make([]string, 0, len(degraded))
+	for _, provider := range degraded {
+		items = append(items, fmt.Sprintf("%v%v(%s)", provider.ChannelTypes, provider.Hosts, provider.Reason))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ", ")
+}
+
+func fetchProviderIncidents(feed operation_setting.ProviderStatusFeed) ([]providerIncident, error) {
+	resp, err := GetHttpClient().Get(feed.Url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	switch feed.Format {
+	case operation_setting.ProviderStatusFormatGoogleCloud:
+		return parseGoogleCloudIncidents(body)
+	case operation_setting.ProviderStatusFormatStatuspage, "":
+		return parseStatuspageIncidents(body)
+	}
+	return nil, fmt.Errorf("unsupported status format: %s", feed.Format)
+}
+
+// parseStatuspageIncidents summary.json 中的 incidents 只包含未解决的故障
+func parseStatuspageIncidents(body []byte) ([]providerIncident, error) {
+	var summary struct {
+		Incidents []struct {
+			Name       string `json:"name"`
+			Status     string `json:"status"`
+			Impact     string `json:"impact"`
+			Components []struct {
+				Name string `json:"name"`
+			} `json:"components"`
+		} `json:"incidents"`
+	}
+	if err := common.Unmarshal(body, &summary); err != nil {
+		return nil, err
+	}
+	incidents := make([]providerIncident, 0, len(summary.Incidents))
+	for _, item := range summary.Incidents {
+		if item.Status == "resolved" || item.Status == "postmortem" {
+			continue
+		}
+		incident := providerIncident{Name: item.Name, Impact: strings.ToLower(item.Impact)}
+		for _, component := range item.Components {
+			incident.Components = append(incident.Components, component.Name)
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
+
+// parseGoogleCloudIncidents incidents.json 包含历史故障，没有结束时间的为进行中的故障
+func parseGoogleCloudIncidents(body []byte) ([]providerIncident, error) {
+	var items []struct {
+		ExternalDesc     string `json:"external_desc"`
+		End              string `json:"end"`
+		Severity         string `json:"severity"`
+		AffectedProducts []struct {
+			Title string `json:"title"`
+		} `json:"affected_products"`
+	}
+	if err := common.Unmarshal(body, &items); err != nil {
+		return nil, err
+	}
+	incidents := make([]providerIncident, 0)
+	for _, item := range items {
+		if item.End != "" {
+			continue
+		}
+		incident := providerIncident{Name: item.ExternalDesc, Impact: googleSeverityImpact[strings.ToLower(item.Severity)]}
+		for _, product := range item.AffectedProducts {
+			incident.Components = append(incident.Components, product.Title)
+		}
+		incidents = append(incidents, incident)
+	}
+	return incidents, nil
+}
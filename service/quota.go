@@ -30,6 +30,8 @@ type TokenDetails struct {
 type QuotaInfo struct {
 	InputDetails  TokenDetails
 	OutputDetails TokenDetails
+	CacheTokens   int     // 命中缓存的输入 token，不包含在 InputDetails 中
+	CacheRatio    float64 // CacheTokens 的倍率
 	ModelName     string
 	UsePrice      bool
 	ModelPrice    float64
@@ -64,6 +66,7 @@ func calculateAudioQuota(info QuotaInfo) int {
 	quota = quota.Add(inputTextTokens)
 	quota = quota.Add(outputTextTokens.Mul(completionRatio))
 	quota = quota.Add(inputAudioTokens.Mul(audioRatio))
+	quota = quota.Add(decimal.NewFromInt(int64(info.CacheTokens)).Mul(decimal.NewFromFloat(info.CacheRatio)))
 	quota = quota.Add(outputAudioTokens.Mul(audioRatio).Mul(audioCompletionRatio))
 
 	quota = quota.Mul(ratio)
@@ -200,7 +203,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 			"tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, modelName, preConsumedQuota))
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		UpdateChannelUsedQuota(relayInfo, quota)
 	}
 
 	logModel := modelName
@@ -286,7 +289,7 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			"tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, modelName, preConsumedQuota))
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		UpdateChannelUsedQuota(relayInfo, quota)
 	}
 
 	quotaDelta := quota - preConsumedQuota
@@ -338,6 +341,15 @@ func CalcOpenRouterCacheCreateTokens(usage dto.Usage, priceData helper.PriceData
 		(promptCacheCreatePrice - quotaPrice)))
 }
 
+// ShouldUseAudioQuota 是否按音频倍率结算：音频模型，或用量中出现音频 token。
+// Gemini 模型的音频输入按单独配置的音频价格在文本计费中处理
+func ShouldUseAudioQuota(modelName string, usage *dto.Usage) bool {
+	if ratio_setting.IsAudioChatModel(modelName) || usage.CompletionTokenDetails.AudioTokens > 0 {
+		return true
+	}
+	return usage.PromptTokensDetails.AudioTokens > 0 && !strings.HasPrefix(modelName, "gemini")
+}
+
 func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	usage *dto.Usage, preConsumedQuota int, userQuota int, priceData helper.PriceData, extraContent string) {
 
@@ -348,6 +360,17 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	audioInputTokens := usage.PromptTokensDetails.AudioTokens
 	audioOutTokens := usage.CompletionTokenDetails.AudioTokens
 
+	// chat completions 的用量只给出音频 token，其余按文本 token 计
+	if textInputTokens == 0 {
+		textInputTokens = max(usage.PromptTokens-audioInputTokens, 0)
+	}
+	if textOutTokens == 0 {
+		textOutTokens = max(usage.CompletionTokens-audioOutTokens, 0)
+	}
+	// 与文本计费一致，命中缓存的输入 token 按缓存倍率计费
+	cacheTokens := min(usage.PromptTokensDetails.CachedTokens, textInputTokens)
+	textInputTokens -= cacheTokens
+
 	tokenName := ctx.GetString("token_name")
 	completionRatio := decimal.NewFromFloat(ratio_setting.GetCompletionRatio(relayInfo.OriginModelName))
 	audioRatio := decimal.NewFromFloat(ratio_setting.GetAudioRatio(relayInfo.OriginModelName))
@@ -367,10 +390,13 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			TextTokens:  textOutTokens,
 			AudioTokens: audioOutTokens,
 		},
-		ModelName:  relayInfo.OriginModelName,
-		UsePrice:   usePrice,
-		ModelRatio: modelRatio,
-		GroupRatio: groupRatio,
+		CacheTokens: cacheTokens,
+		CacheRatio:  priceData.CacheRatio,
+		ModelName:   relayInfo.OriginModelName,
+		UsePrice:    usePrice,
+		ModelPrice:  modelPrice,
+		ModelRatio:  modelRatio,
+		GroupRatio:  groupRatio,
 	}
 
 	quota := calculateAudioQuota(quotaInfo)
@@ -394,7 +420,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 			"tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, relayInfo.OriginModelName, preConsumedQuota))
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		UpdateChannelUsedQuota(relayInfo, quota)
 	}
 
 	quotaDelta := quota - preConsumedQuota
@@ -410,7 +436,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 		logContent += ", " + extraContent
 	}
 	other := GenerateAudioOtherInfo(ctx, relayInfo, usage, modelRatio, groupRatio,
-		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), modelPrice, priceData.GroupRatioInfo.GroupSpecialRatio)
+		completionRatio.InexactFloat64(), audioRatio.InexactFloat64(), audioCompletionRatio.InexactFloat64(), priceData.CacheRatio, modelPrice, priceData.GroupRatioInfo.GroupSpecialRatio)
 	model.RecordConsumeLog(ctx, relayInfo.UserId, model.RecordConsumeLogParams{
 		ChannelId:        relayInfo.ChannelId,
 		PromptTokens:     usage.PromptTokens,
@@ -428,6 +454,14 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	})
 }
 
+// UpdateChannelUsedQuota 累计渠道用量，多 key 渠道同时按 key 记录
+func UpdateChannelUsedQuota(relayInfo *relaycommon.RelayInfo, quota int) {
+	model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+	if relayInfo.ChannelIsMultiKey {
+		model.UpdateChannelKeyUsedQuota(relayInfo.ChannelId, relayInfo.ApiKey, quota)
+	}
+}
+
 func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	if quota < 0 {
 		return errors.New("quota 不能为负数！")
@@ -480,6 +514,10 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		}
 	}
 
+	if consumeQuota := quota + preConsumedQuota; consumeQuota > 0 && !relayInfo.IsPlayground {
+		DispatchUserQuotaBelowEvent(relayInfo.UserId, relayInfo.UserQuota, relayInfo.UserQuota-consumeQuota)
+	}
+
 	return nil
 }
 
@@ -501,7 +539,9 @@ func checkAndSendQuotaNotify(relayInfo *relaycommon.RelayInfo, quota int, preCon
 			prompt := "您的额度即将用尽"
 			topUpLink := fmt.Sprintf("%s/topup", setting.ServerAddress)
 			content := "{{value}}，当前剩余额度为 {{value}}，为了不影响您的使用，请及时充值。<br/>充值链接：<a href='{{value}}'>{{value}}</a>"
-			err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, []interface{}{prompt, common.FormatQuota(relayInfo.UserQuota), topUpLink, topUpLink}))
+			notify := dto.NewNotify(dto.NotifyTypeQuotaExceed, prompt, content, []interface{}{prompt, common.FormatQuota(relayInfo.UserQuota), topUpLink, topUpLink})
+			notify.Metadata = relayInfo.RequestMetadata
+			err := NotifyUser(relayInfo.UserId, relayInfo.UserEmail, relayInfo.UserSetting, notify)
 			if err != nil {
 				common.SysError(fmt.Sprintf("failed to send quota notify to user %d: %s", relayInfo.UserId, err.Error()))
 			}
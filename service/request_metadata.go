@@ -0,0 +1,69 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/constant"
+	"one-api/setting/operation_setting"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExtractRequestMetadata 读取请求体中的 metadata 对象，仅保留标量值，开启回显时写入上下文
+func ExtractRequestMetadata(c *gin.Context) map[string]interface{} {
+	metadataSetting := operation_setting.GetRequestMetadataSetting()
+	if !metadataSetting.Enabled {
+		return nil
+	}
+	var request struct {
+		Metadata map[string]interface{} `json:"metadata"`
+	}
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil || len(request.Metadata) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(request.Metadata))
+	for key := range request.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	metadata := make(map[string]interface{})
+	for _, key := range keys {
+		if metadataSetting.MaxKeys > 0 && len(metadata) >= metadataSetting.MaxKeys {
+			break
+		}
+		switch value := request.Metadata[key].(type) {
+		case string:
+			metadata[key] = common.TruncateUTF8(value, metadataSetting.MaxValueLength)
+		case float64, bool:
+			metadata[key] = value
+		}
+	}
+	if len(metadata) == 0 {
+		return nil
+	}
+	// 回显需要重新序列化响应体，默认关闭，只记录到日志与通知
+	if metadataSetting.EchoEnabled {
+		common.SetContextKey(c, constant.ContextKeyRequestMetadata, metadata)
+	}
+	return metadata
+}
+
+// GetLogRequestMetadata 按配置筛选需要写入日志的 metadata
+func GetLogRequestMetadata(metadata map[string]interface{}) map[string]interface{} {
+	if len(metadata) == 0 {
+		return nil
+	}
+	logKeys := operation_setting.GetRequestMetadataSetting().LogKeys
+	if len(logKeys) == 0 {
+		return metadata
+	}
+	logMetadata := make(map[string]interface{})
+	for _, key := range logKeys {
+		if value, ok := metadata[key]; ok {
+			logMetadata[key] = value
+		}
+	}
+	return logMetadata
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"one-api/common"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// CachedResponse 缓存的上游响应
+type CachedResponse struct {
+	CreatedAt   int64  `json:"created_at"`
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type"`
+	Body        []byte `json:"body"`
+}
+
+type responseCacheMemoryEntry struct {
+	response  *CachedResponse
+	expiresAt time.Time
+}
+
+var (
+	responseCacheEntries     = make(map[string]*responseCacheMemoryEntry)
+	responseCacheEntriesLock sync.Mutex
+)
+
+func responseCacheRedisKey(key string) string {
+	return "response_cache:" + key
+}
+
+// GetCachedResponse 读取缓存的响应，未命中时返回 nil
+func GetCachedResponse(key string) (*CachedResponse, error) {
+	if !common.RedisEnabled {
+		responseCacheEntriesLock.Lock()
+		defer responseCacheEntriesLock.Unlock()
+		entry, ok := responseCacheEntries[key]
+		if !ok {
+			return nil, nil
+		}
+		if time.Now().After(entry.expiresAt) {
+			delete(responseCacheEntries, key)
+			return nil, nil
+		}
+		return entry.response, nil
+	}
+	data, err := common.RDB.Get(context.Background(), responseCacheRedisKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get cached response failed: %w", err)
+	}
+	response := &CachedResponse{}
+	if err = common.Unmarshal(data, response); err != nil {
+		return nil, fmt.Errorf("decode cached response failed: %w", err)
+	}
+	return response, nil
+}
+
+// SetCachedResponse 保存响应，ttl 到期后自动失效
+func SetCachedResponse(key string, response *CachedResponse, ttl time.Duration) error {
+	if !common.RedisEnabled {
+		responseCacheEntriesLock.Lock()
+		defer responseCacheEntriesLock.Unlock()
+		now := time.Now()
+		for k, entry := range responseCacheEntries {
+			if now.After(entry.expiresAt) {
+				delete(responseCacheEntries, k)
+			}
+		}
+		responseCacheEntries[key] = &responseCacheMemoryEntry{response: response, expiresAt: now.Add(ttl)}
+		return nil
+	}
+	data, err := common.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return common.RDB.Set(context.Background(), responseCacheRedisKey(key), data, ttl).Err()
+}
@@ -0,0 +1,205 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"one-api/common"
+	"sync"
+	"time"
+)
+
+const (
+	semanticEmbeddingTimeout = 10 * time.Second
+	// semanticCacheMaxIndexes 进程内最多保留的索引数，超出时淘汰最久未写入的索引
+	semanticCacheMaxIndexes = 10000
+	// semanticCacheSweepInterval 清理过期记录与空索引的间隔
+	semanticCacheSweepInterval = time.Minute
+)
+
+// semanticCacheEntry 语义索引中的一条记录，向量已归一化
+type semanticCacheEntry struct {
+	vector      []float32
+	responseKey string
+	expiresAt   time.Time
+}
+
+// semanticCacheIndex 一组请求参数对应的向量索引
+type semanticCacheIndex struct {
+	entries   []*semanticCacheEntry
+	updatedAt time.Time
+}
+
+var (
+	// semanticCacheIndexes 按请求参数分组的进程内向量索引，响应本身通过 SetCachedResponse 保存
+	semanticCacheIndexes     = make(map[string]*semanticCacheIndex)
+	semanticCacheIndexesLock sync.RWMutex
+	semanticCacheLastSweep   time.Time
+)
+
+// EmbedText 调用 OpenAI 兼容的 embeddings 接口，返回归一化后的向量
+func EmbedText(url string, apiKey string, model string, text string) ([]float32, error) {
+	body, err := common.Marshal(map[string]any{
+		"model": model,
+		"input": text,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), semanticEmbeddingTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	resp, err := GetHttpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embedding request failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	var embeddingResponse struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err = common.Unmarshal(respBody, &embeddingResponse); err != nil {
+		return nil, err
+	}
+	if len(embeddingResponse.Data) == 0 || len(embeddingResponse.Data[0].Embedding) == 0 {
+		return nil, fmt.Errorf("embedding response contains no data")
+	}
+	return normalizeVector(embeddingResponse.Data[0].Embedding), nil
+}
+
+func normalizeVector(vector []float32) []float32 {
+	var sum float64
+	for _, v := range vector {
+		sum += float64(v) * float64(v)
+	}
+	norm := math.Sqrt(sum)
+	if norm == 0 {
+		return vector
+	}
+	normalized := make([]float32, len(vector))
+	for i, v := range vector {
+		normalized[i] = float32(float64(v) / norm)
+	}
+	return normalized
+}
+
+// cosineSimilarity 两个归一化向量的余弦相似度，维度不同时返回 -1
+func cosineSimilarity(a []float32, b []float32) float64 {
+	if len(a) != len(b) {
+		return -1
+	}
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+// FindSimilarCachedResponse 在 indexKey 对应的索引中查找最相似的记录，相似度不低于 threshold 时返回其响应 key
+func FindSimilarCachedResponse(indexKey string, vector []float32, threshold float64) (string, float64) {
+	semanticCacheIndexesLock.RLock()
+	defer semanticCacheIndexesLock.RUnlock()
+	now := time.Now()
+	bestKey := ""
+	bestSimilarity := -1.0
+	index, ok := semanticCacheIndexes[indexKey]
+	if !ok {
+		return "", bestSimilarity
+	}
+	for _, entry := range index.entries {
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		if similarity := cosineSimilarity(vector, entry.vector); similarity > bestSimilarity {
+			bestKey = entry.responseKey
+			bestSimilarity = similarity
+		}
+	}
+	if bestKey == "" || bestSimilarity < threshold {
+		return "", bestSimilarity
+	}
+	return bestKey, bestSimilarity
+}
+
+// AddSemanticCacheEntry 将响应加入语义索引，超过 maxEntries 时淘汰最早加入的记录
+func AddSemanticCacheEntry(indexKey string, responseKey string, vector []float32, ttl time.Duration, maxEntries int) {
+	semanticCacheIndexesLock.Lock()
+	defer semanticCacheIndexesLock.Unlock()
+	now := time.Now()
+	if now.Sub(semanticCacheLastSweep) >= semanticCacheSweepInterval {
+		sweepSemanticCacheIndexes(now)
+	}
+	index, ok := semanticCacheIndexes[indexKey]
+	if !ok {
+		if len(semanticCacheIndexes) >= semanticCacheMaxIndexes {
+			evictOldestSemanticCacheIndex()
+		}
+		index = &semanticCacheIndex{}
+		semanticCacheIndexes[indexKey] = index
+	}
+	entries := make([]*semanticCacheEntry, 0, len(index.entries)+1)
+	for _, entry := range index.entries {
+		if now.Before(entry.expiresAt) && entry.responseKey != responseKey {
+			entries = append(entries, entry)
+		}
+	}
+	entries = append(entries, &semanticCacheEntry{
+		vector:      vector,
+		responseKey: responseKey,
+		expiresAt:   now.Add(ttl),
+	})
+	if maxEntries > 0 && len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+	index.entries = entries
+	index.updatedAt = now
+}
+
+// sweepSemanticCacheIndexes 清理所有索引中的过期记录，记录全部过期的索引一并删除。调用方需持有写锁
+func sweepSemanticCacheIndexes(now time.Time) {
+	semanticCacheLastSweep = now
+	for key, index := range semanticCacheIndexes {
+		entries := index.entries[:0]
+		for _, entry := range index.entries {
+			if now.Before(entry.expiresAt) {
+				entries = append(entries, entry)
+			}
+		}
+		if len(entries) == 0 {
+			delete(semanticCacheIndexes, key)
+			continue
+		}
+		index.entries = entries
+	}
+}
+
+// evictOldestSemanticCacheIndex 淘汰最久未写入的索引。调用方需持有写锁
+func evictOldestSemanticCacheIndex() {
+	oldestKey := ""
+	var oldest time.Time
+	for key, index := range semanticCacheIndexes {
+		if oldestKey == "" || index.updatedAt.Before(oldest) {
+			oldestKey = key
+			oldest = index.updatedAt
+		}
+	}
+	delete(semanticCacheIndexes, oldestKey)
+}
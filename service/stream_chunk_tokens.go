@@ -0,0 +1,56 @@
+package service
+
+import (
+	"one-api/common"
+	"one-api/relay/helper"
+	"strings"
+)
+
+func init() {
+	helper.StreamChunkTokenCounter = CountStreamChunkTokens
+}
+
+// streamChunkTextKeys 流式块中属于模型输出的字段，覆盖 OpenAI、Claude、Gemini 与 Responses 格式
+var streamChunkTextKeys = map[string]bool{
+	"content":           true,
+	"text":              true,
+	"delta":             true,
+	"reasoning_content": true,
+	"reasoning":         true,
+	"thinking":          true,
+	"arguments":         true,
+	"partial_json":      true,
+}
+
+// CountStreamChunkTokens 估算单个流式块输出的 token 数。
+// 汇总类事件（如 response.completed、*.done）会重复携带完整文本，不计入
+func CountStreamChunkTokens(data string, model string) int {
+	var chunk map[string]any
+	if err := common.UnmarshalJsonStr(data, &chunk); err != nil {
+		return 0
+	}
+	if eventType, ok := chunk["type"].(string); ok &&
+		(strings.HasSuffix(eventType, ".done") || strings.HasSuffix(eventType, ".completed")) {
+		return 0
+	}
+	var text strings.Builder
+	collectStreamChunkText(chunk, "", &text)
+	return CountTextToken(text.String(), model)
+}
+
+func collectStreamChunkText(value any, key string, text *strings.Builder) {
+	switch v := value.(type) {
+	case string:
+		if streamChunkTextKeys[key] {
+			text.WriteString(v)
+		}
+	case map[string]any:
+		for k, item := range v {
+			collectStreamChunkText(item, k, text)
+		}
+	case []any:
+		for _, item := range v {
+			collectStreamChunkText(item, key, text)
+		}
+	}
+}
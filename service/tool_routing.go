@@ -0,0 +1,47 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"one-api/common"
+	"one-api/constant"
+	"one-api/model"
+	"one-api/setting/operation_setting"
+	"one-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// toolRoutingRequest OpenAI、Claude 与 Gemini 请求中的工具定义
+type toolRoutingRequest struct {
+	Tools     json.RawMessage `json:"tools"`
+	Functions json.RawMessage `json:"functions"`
+}
+
+func hasToolDefinitions(raw json.RawMessage) bool {
+	var tools []json.RawMessage
+	return len(raw) > 0 && common.Unmarshal(raw, &tools) == nil && len(tools) > 0
+}
+
+// RequestHasTools 判断请求是否包含工具（或旧版 functions）定义
+func RequestHasTools(c *gin.Context) bool {
+	var request toolRoutingRequest
+	if err := common.UnmarshalBodyReusable(c, &request); err != nil {
+		return false
+	}
+	return hasToolDefinitions(request.Tools) || hasToolDefinitions(request.Functions)
+}
+
+// RecordToolCallResult 统计带工具请求在渠道上的结果，限流与鉴权失败与工具调用无关，不计入
+func RecordToolCallResult(c *gin.Context, channelId int, modelName string, err *types.NewAPIError) {
+	if !common.GetContextKeyBool(c, constant.ContextKeyRequestHasTools) {
+		return
+	}
+	if err != nil {
+		switch err.StatusCode {
+		case http.StatusTooManyRequests, http.StatusUnauthorized, http.StatusForbidden:
+			return
+		}
+	}
+	model.RecordChannelToolCallResult(channelId, modelName, err == nil, operation_setting.GetToolRoutingSetting().WindowSize)
+}
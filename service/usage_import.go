@@ -0,0 +1,198 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"one-api/common"
+	"one-api/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 各供应商导出文件中的列名别名，按优先级匹配
+var usageExportColumnAliases = map[string]map[string][]string{
+	model.UpstreamUsageProviderOpenAI: {
+		"day":        {"start_time", "date", "day", "timestamp"},
+		"model":      {"model", "snapshot_id", "model_name"},
+		"prompt":     {"input_tokens", "n_context_tokens_total", "prompt_tokens"},
+		"completion": {"output_tokens", "n_generated_tokens_total", "completion_tokens"},
+		"requests":   {"num_model_requests", "n_requests", "requests"},
+	},
+	model.UpstreamUsageProviderAnthropic: {
+		"day":        {"starting_at", "usage_date_utc", "date", "day"},
+		"model":      {"model", "model_name"},
+		"prompt":     {"uncached_input_tokens", "input_tokens", "prompt_tokens"},
+		"completion": {"output_tokens", "completion_tokens"},
+		"requests":   {"requests", "request_count"},
+		// uncached_input_tokens 不含缓存部分，需要把缓存读写 token 加回
+		"prompt_extra": {"cache_read_input_tokens", "cache_creation_input_tokens"},
+	},
+	model.UpstreamUsageProviderGoogle: {
+		"day":        {"usage_start_time", "date", "day"},
+		"model":      {"model", "model_name", "sku_description"},
+		"prompt":     {"input_token_count", "prompt_token_count", "input_tokens", "prompt_tokens"},
+		"completion": {"output_token_count", "candidates_token_count", "output_tokens", "completion_tokens"},
+		"requests":   {"request_count", "requests"},
+	},
+}
+
+// ParseUpstreamUsageExport 解析供应商控制台导出的用量文件（CSV 或 JSON 数组），按天、模型聚合
+func ParseUpstreamUsageExport(provider string, reader io.Reader) ([]*model.UpstreamUsage, error) {
+	aliases, ok := usageExportColumnAliases[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider: %s", provider)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, errors.New("usage export is empty")
+	}
+
+	var rows []map[string]string
+	if data[0] == '[' {
+		rows, err = parseUsageExportJson(data)
+	} else {
+		rows, err = parseUsageExportCsv(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	usageMap := make(map[string]*model.UpstreamUsage)
+	usages := make([]*model.UpstreamUsage, 0)
+	for i, row := range rows {
+		day, err := parseUsageExportDay(lookupUsageExportColumn(row, aliases["day"]))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		modelName := strings.TrimPrefix(lookupUsageExportColumn(row, aliases["model"]), "models/")
+		if modelName == "" {
+			continue
+		}
+		promptTokens := parseUsageExportInt(lookupUsageExportColumn(row, aliases["prompt"]))
+		for _, column := range aliases["prompt_extra"] {
+			promptTokens += parseUsageExportInt(row[column])
+		}
+		completionTokens := parseUsageExportInt(lookupUsageExportColumn(row, aliases["completion"]))
+		requests := parseUsageExportInt(lookupUsageExportColumn(row, aliases["requests"]))
+
+		key := fmt.Sprintf("%d|%s", day, modelName)
+		usage, ok := usageMap[key]
+		if !ok {
+			usage = &model.UpstreamUsage{
+				Provider:  provider,
+				Day:       day,
+				ModelName: modelName,
+			}
+			usageMap[key] = usage
+			usages = append(usages, usage)
+		}
+		usage.PromptTokens += promptTokens
+		usage.CompletionTokens += completionTokens
+		usage.Requests += requests
+	}
+	return usages, nil
+}
+
+func parseUsageExportCsv(data []byte) ([]map[string]string, error) {
+	csvReader := csv.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	csvReader.FieldsPerRecord = -1
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid csv: %w", err)
+	}
+	if len(records) < 2 {
+		return []map[string]string{}, nil
+	}
+	header := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		header[i] = normalizeUsageExportColumn(column)
+	}
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, record := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, value := range record {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(value)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseUsageExportJson(data []byte) ([]map[string]string, error) {
+	var items []map[string]interface{}
+	if err := common.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid json: %w", err)
+	}
+	rows := make([]map[string]string, 0, len(items))
+	for _, item := range items {
+		row := make(map[string]string, len(item))
+		for key, value := range item {
+			switch v := value.(type) {
+			case string:
+				row[normalizeUsageExportColumn(key)] = v
+			case float64:
+				row[normalizeUsageExportColumn(key)] = strconv.FormatFloat(v, 'f', -1, 64)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func normalizeUsageExportColumn(column string) string {
+	column = strings.ToLower(strings.TrimSpace(column))
+	column = strings.TrimPrefix(column, "\ufeff")
+	return strings.ReplaceAll(column, " ", "_")
+}
+
+func lookupUsageExportColumn(row map[string]string, aliases []string) string {
+	for _, alias := range aliases {
+		if value, ok := row[alias]; ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func parseUsageExportInt(value string) int {
+	if value == "" {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+	if err != nil {
+		return 0
+	}
+	return int(f)
+}
+
+// parseUsageExportDay 支持 unix 时间戳、RFC3339 与 yyyy-mm-dd，统一返回 UTC 当天零点
+func parseUsageExportDay(value string) (int64, error) {
+	if value == "" {
+		return 0, errors.New("missing date column")
+	}
+	var t time.Time
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		t = time.Unix(ts, 0)
+	} else if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		t = parsed
+	} else if parsed, err := time.Parse("2006-01-02 15:04:05", value); err == nil {
+		t = parsed
+	} else if parsed, err := time.Parse("2006-01-02", value); err == nil {
+		t = parsed
+	} else {
+		return 0, fmt.Errorf("invalid date: %s", value)
+	}
+	ts := t.UTC().Unix()
+	return ts - ts%86400, nil
+}
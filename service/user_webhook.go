@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"one-api/common"
+	"one-api/dto"
+	"one-api/model"
+	"sync"
+	"time"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// 用户 webhook 投递失败后的重试间隔，按指数退避
+var userWebhookRetryDelays = []time.Duration{
+	5 * time.Second,
+	30 * time.Second,
+	2 * time.Minute,
+}
+
+// DispatchUserWebhookEvent 将事件异步推送给用户订阅了该事件的所有 webhook
+func DispatchUserWebhookEvent(userId int, event string, title string, metadata map[string]interface{}) {
+	dispatchUserWebhookEvent(userId, event, title, metadata, nil)
+}
+
+// 同一令牌的过期事件在该时间内只推送一次，避免客户端持续使用过期令牌时反复查库
+const tokenExpiredEventTTL = 24 * time.Hour
+
+var (
+	tokenExpiredEvents     = make(map[int]time.Time)
+	tokenExpiredEventsLock sync.Mutex
+)
+
+// claimTokenExpiredEvent 返回本次是否应推送该令牌的过期事件
+func claimTokenExpiredEvent(tokenId int) bool {
+	if common.RedisEnabled {
+		claimed, err := common.RDB.SetNX(context.Background(), fmt.Sprintf("token_expired_event:%d", tokenId), 1, tokenExpiredEventTTL).Result()
+		return err == nil && claimed
+	}
+	tokenExpiredEventsLock.Lock()
+	defer tokenExpiredEventsLock.Unlock()
+	now := time.Now()
+	if expiresAt, ok := tokenExpiredEvents[tokenId]; ok && now.Before(expiresAt) {
+		return false
+	}
+	for id, expiresAt := range tokenExpiredEvents {
+		if now.After(expiresAt) {
+			delete(tokenExpiredEvents, id)
+		}
+	}
+	tokenExpiredEvents[tokenId] = now.Add(tokenExpiredEventTTL)
+	return true
+}
+
+// DispatchTokenExpiredEvent 推送令牌过期事件，每个令牌只推送一次
+func DispatchTokenExpiredEvent(token *model.Token) {
+	if !claimTokenExpiredEvent(token.Id) {
+		return
+	}
+	DispatchUserWebhookEvent(token.UserId, dto.UserWebhookEventTokenExpired, "令牌已过期", map[string]interface{}{
+		"token_id":     token.Id,
+		"token_name":   token.Name,
+		"expired_time": token.ExpiredTime,
+	})
+}
+
+// DispatchUserQuotaBelowEvent 本次消耗使剩余额度跌破 webhook 设定的阈值时推送 quota_below 事件
+func DispatchUserQuotaBelowEvent(userId int, quotaBefore int, quotaAfter int) {
+	metadata := map[string]interface{}{
+		"remain_quota": quotaAfter,
+		"remain":       common.FormatQuota(quotaAfter),
+	}
+	dispatchUserWebhookEvent(userId, dto.UserWebhookEventQuotaBelow, "额度低于阈值", metadata, func(webhook *model.UserWebhook) bool {
+		return quotaAfter < webhook.QuotaThreshold && quotaBefore >= webhook.QuotaThreshold
+	})
+}
+
+func dispatchUserWebhookEvent(userId int, event string, title string, metadata map[string]interface{}, filter func(webhook *model.UserWebhook) bool) {
+	gopool.Go(func() {
+		webhooks, err := model.GetEnabledUserWebhooksByEvent(userId, event)
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to get webhooks of user %d: %s", userId, err.Error()))
+			return
+		}
+		if filter != nil {
+			matched := make([]*model.UserWebhook, 0, len(webhooks))
+			for _, webhook := range webhooks {
+				if filter(webhook) {
+					matched = append(matched, webhook)
+				}
+			}
+			webhooks = matched
+		}
+		if len(webhooks) == 0 {
+			return
+		}
+		// 与其他通知共用频率限制，避免连续失败的请求刷屏
+		canSend, err := CheckNotificationLimit(userId, "webhook_"+event)
+		if err != nil || !canSend {
+			return
+		}
+		notify := dto.NewNotify(event, title, title, nil)
+		notify.Metadata = metadata
+		for _, webhook := range webhooks {
+			deliverUserWebhook(webhook, notify)
+		}
+	})
+}
+
+// SendUserWebhookTest 向 webhook 发送一次测试事件，不重试，直接返回结果
+func SendUserWebhookTest(webhook *model.UserWebhook) error {
+	notify := dto.NewNotify("test", "Webhook 测试", "这是一条测试消息", nil)
+	err := SendWebhookNotify(webhook.Url, webhook.Secret, notify)
+	model.UpdateUserWebhookDelivery(webhook.Id, err)
+	return err
+}
+
+func deliverUserWebhook(webhook *model.UserWebhook, notify dto.Notify) {
+	gopool.Go(func() {
+		err := SendWebhookNotify(webhook.Url, webhook.Secret, notify)
+		for _, delay := range userWebhookRetryDelays {
+			if err == nil {
+				break
+			}
+			time.Sleep(delay)
+			err = SendWebhookNotify(webhook.Url, webhook.Secret, notify)
+		}
+		if err != nil {
+			common.SysError(fmt.Sprintf("failed to deliver webhook %d of user %d: %s", webhook.Id, webhook.UserId, err.Error()))
+		}
+		model.UpdateUserWebhookDelivery(webhook.Id, err)
+	})
+}
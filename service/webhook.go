@@ -15,11 +15,12 @@ import (
 
 // WebhookPayload webhook 通知的负载数据
 type WebhookPayload struct {
-	Type      string        `json:"type"`
-	Title     string        `json:"title"`
-	Content   string        `json:"content"`
-	Values    []interface{} `json:"values,omitempty"`
-	Timestamp int64         `json:"timestamp"`
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Content   string                 `json:"content"`
+	Values    []interface{}          `json:"values,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Timestamp int64                  `json:"timestamp"`
 }
 
 // generateSignature 生成 webhook 签名
@@ -43,6 +44,7 @@ func SendWebhookNotify(webhookURL string, secret string, data dto.Notify) error
 		Title:     data.Title,
 		Content:   content,
 		Values:    data.Values,
+		Metadata:  data.Metadata,
 		Timestamp: time.Now().Unix(),
 	}
 
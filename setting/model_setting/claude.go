@@ -17,6 +17,8 @@ type ClaudeSettings struct {
 	DefaultMaxTokens                      map[string]int                 `json:"default_max_tokens"`
 	ThinkingAdapterEnabled                bool                           `json:"thinking_adapter_enabled"`
 	ThinkingAdapterBudgetTokensPercentage float64                        `json:"thinking_adapter_budget_tokens_percentage"`
+	PromptCacheEnabled                    bool                           `json:"prompt_cache_enabled"`    // 对 Anthropic、AWS Bedrock 与 Vertex AI 的 Claude 渠道生效
+	PromptCacheMinTokens                  int                            `json:"prompt_cache_min_tokens"` // system prompt / tools 达到该 token 数才注入 cache_control
 }
 
 // 默认配置
@@ -27,6 +29,8 @@ var defaultClaudeSettings = ClaudeSettings{
 		"default": 8192,
 	},
 	ThinkingAdapterBudgetTokensPercentage: 0.8,
+	PromptCacheEnabled:                    false,
+	PromptCacheMinTokens:                  1024,
 }
 
 // 全局实例
@@ -0,0 +1,58 @@
+package model_setting
+
+import (
+	"encoding/json"
+	"fmt"
+	"one-api/setting/config"
+)
+
+// ModelDefaultParams 模型的默认请求参数，客户端未传时使用；Force 为 true 时覆盖客户端传入的值
+type ModelDefaultParams struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	MaxTokens   *uint    `json:"max_tokens,omitempty"`
+	Force       bool     `json:"force"`
+}
+
+type DefaultParamsSettings struct {
+	Models map[string]ModelDefaultParams `json:"models"` // 以客户端请求的模型名为键
+}
+
+// 默认配置
+var defaultParamsSettings = DefaultParamsSettings{
+	Models: map[string]ModelDefaultParams{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("default_params", &defaultParamsSettings)
+}
+
+func GetDefaultParamsSettings() *DefaultParamsSettings {
+	return &defaultParamsSettings
+}
+
+// GetModelDefaultParams 返回模型的默认参数，未配置时返回 nil
+func GetModelDefaultParams(model string) *ModelDefaultParams {
+	params, ok := defaultParamsSettings.Models[model]
+	if !ok {
+		return nil
+	}
+	return &params
+}
+
+func CheckModelDefaultParams(jsonStr string) error {
+	models := make(map[string]ModelDefaultParams)
+	if err := json.Unmarshal([]byte(jsonStr), &models); err != nil {
+		return err
+	}
+	for name, params := range models {
+		if params.Temperature != nil && (*params.Temperature < 0 || *params.Temperature > 2) {
+			return fmt.Errorf("模型 %s 的 temperature 必须在 0 到 2 之间", name)
+		}
+		if params.TopP != nil && (*params.TopP < 0 || *params.TopP > 1) {
+			return fmt.Errorf("模型 %s 的 top_p 必须在 0 到 1 之间", name)
+		}
+	}
+	return nil
+}
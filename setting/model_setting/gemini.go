@@ -1,7 +1,17 @@
 package model_setting
 
 import (
+	"encoding/json"
+	"fmt"
 	"one-api/setting/config"
+	"strconv"
+	"strings"
+)
+
+const (
+	GeminiCacheIsolationNone  = "none"
+	GeminiCacheIsolationUser  = "user"
+	GeminiCacheIsolationToken = "token"
 )
 
 // GeminiSettings 定义Gemini模型的配置
@@ -12,6 +22,17 @@ type GeminiSettings struct {
 	ThinkingAdapterEnabled                bool              `json:"thinking_adapter_enabled"`
 	ThinkingAdapterBudgetTokensPercentage float64           `json:"thinking_adapter_budget_tokens_percentage"`
 	EnableCache                           bool              `json:"enable_cache"`
+	CacheHistoryEnabled                   bool              `json:"cache_history_enabled"`
+	CacheHistoryMinPrefix                 int               `json:"cache_history_min_prefix"` // 缓存历史消息的最少条数
+	CacheMinTokens                        map[string]int    `json:"cache_min_tokens"`         // 各模型创建缓存的最少 token 数，按最长前缀匹配
+	CacheMaxTTL                           int               `json:"cache_max_ttl"`            // 客户端通过 cache.ttl 可指定的缓存有效期上限（秒）
+	CacheIsolation                        string            `json:"cache_isolation"`          // 缓存隔离范围：none 不隔离，user 按用户隔离，token 按令牌隔离
+	CachePurgeUpstream                    bool              `json:"cache_purge_upstream"`     // 渠道 key 变更时同时删除旧 key 在上游创建的缓存
+	CacheJanitorInterval                  int               `json:"cache_janitor_interval"`   // 后台核对缓存记录与上游缓存的间隔（秒），0 表示关闭
+	CacheJanitorExtend                    bool              `json:"cache_janitor_extend"`     // 核对时为有命中记录且即将过期的上游缓存续期
+	EmbeddingDimensions                   map[string]int    `json:"embedding_dimensions"`     // 支持 outputDimensionality 的嵌入模型及其最大维度，按最长前缀匹配
+	CachedInputRatio                      float64           `json:"cached_input_ratio"`       // 命中上下文缓存的输入 token 计费倍率，模型未单独设置缓存倍率时使用
+	CacheLatencySLOMs                     int               `json:"cache_latency_slo_ms"`     // 首字响应时间目标（毫秒），用于统计缓存查询与创建导致超出目标的请求，0 表示不统计
 }
 
 // 默认配置
@@ -31,6 +52,24 @@ var defaultGeminiSettings = GeminiSettings{
 	ThinkingAdapterEnabled:                false,
 	ThinkingAdapterBudgetTokensPercentage: 0.6,
 	EnableCache:                           true,
+	CacheHistoryEnabled:                   false,
+	CacheHistoryMinPrefix:                 2,
+	CacheMinTokens: map[string]int{
+		"default":          4096,
+		"gemini-2.5-flash": 1024,
+		"gemini-2.5-pro":   4096,
+	},
+	CacheMaxTTL:          3600,
+	CacheIsolation:       GeminiCacheIsolationNone,
+	CachePurgeUpstream:   false,
+	CacheJanitorInterval: 300,
+	CacheJanitorExtend:   false,
+	EmbeddingDimensions: map[string]int{
+		"text-embedding-004": 768,
+		"gemini-embedding":   3072,
+	},
+	CachedInputRatio:  0.25,
+	CacheLatencySLOMs: 0,
 }
 
 // 全局实例
@@ -62,6 +101,112 @@ func GetGeminiVersionSetting(key string) string {
 	return geminiSettings.VersionSettings["default"]
 }
 
+// GetGeminiCacheMinTokens 获取模型创建缓存的最少 token 数，优先精确匹配，其次最长前缀匹配
+func GetGeminiCacheMinTokens(model string) int {
+	if value, ok := geminiSettings.CacheMinTokens[model]; ok {
+		return value
+	}
+	matched := ""
+	for prefix := range geminiSettings.CacheMinTokens {
+		if prefix != "default" && strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched != "" {
+		return geminiSettings.CacheMinTokens[matched]
+	}
+	if value, ok := geminiSettings.CacheMinTokens["default"]; ok {
+		return value
+	}
+	return defaultGeminiSettings.CacheMinTokens["default"]
+}
+
+// CheckGeminiCacheMinTokens 校验缓存最少 token 数配置
+func CheckGeminiCacheMinTokens(jsonStr string) error {
+	minTokens := make(map[string]int)
+	if err := json.Unmarshal([]byte(jsonStr), &minTokens); err != nil {
+		return err
+	}
+	for name, value := range minTokens {
+		if value < 0 {
+			return fmt.Errorf("模型 %s 的缓存最少 token 数不能为负数", name)
+		}
+	}
+	return nil
+}
+
+func CheckGeminiCachedInputRatio(value string) error {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	if ratio < 0 || ratio > 1 {
+		return fmt.Errorf("缓存输入倍率必须在 0 到 1 之间")
+	}
+	return nil
+}
+
+func CheckGeminiCacheJanitorInterval(value string) error {
+	interval, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if interval != 0 && interval < 60 {
+		return fmt.Errorf("缓存核对间隔不能小于 60 秒")
+	}
+	return nil
+}
+
+func CheckGeminiCacheLatencySLO(value string) error {
+	slo, err := strconv.Atoi(value)
+	if err != nil {
+		return err
+	}
+	if slo < 0 {
+		return fmt.Errorf("首字响应时间目标不能为负数")
+	}
+	return nil
+}
+
+func CheckGeminiCacheIsolation(value string) error {
+	switch value {
+	case GeminiCacheIsolationNone, GeminiCacheIsolationUser, GeminiCacheIsolationToken:
+		return nil
+	}
+	return fmt.Errorf("不支持的缓存隔离范围：%s", value)
+}
+
+// GetGeminiEmbeddingMaxDimensions 获取嵌入模型支持的最大输出维度，返回 0 表示不支持 outputDimensionality
+func GetGeminiEmbeddingMaxDimensions(model string) int {
+	if value, ok := geminiSettings.EmbeddingDimensions[model]; ok {
+		return value
+	}
+	matched := ""
+	for prefix := range geminiSettings.EmbeddingDimensions {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched != "" {
+		return geminiSettings.EmbeddingDimensions[matched]
+	}
+	return 0
+}
+
+// CheckGeminiEmbeddingDimensions 校验嵌入模型维度配置
+func CheckGeminiEmbeddingDimensions(jsonStr string) error {
+	dimensions := make(map[string]int)
+	if err := json.Unmarshal([]byte(jsonStr), &dimensions); err != nil {
+		return err
+	}
+	for name, value := range dimensions {
+		if value <= 0 {
+			return fmt.Errorf("模型 %s 的最大维度必须大于 0", name)
+		}
+	}
+	return nil
+}
+
 func IsGeminiModelSupportImagine(model string) bool {
 	for _, v := range geminiSettings.SupportedImagineModels {
 		if v == model {
@@ -5,12 +5,14 @@ import (
 )
 
 type GlobalSettings struct {
-	PassThroughRequestEnabled bool `json:"pass_through_request_enabled"`
+	PassThroughRequestEnabled  bool `json:"pass_through_request_enabled"`
+	ReasoningReclassifyEnabled bool `json:"reasoning_reclassify_enabled"` // 按实际是否产生思考 token 选择 -thinking / -nothinking 变体计费
 }
 
 // 默认配置
 var defaultOpenaiSettings = GlobalSettings{
-	PassThroughRequestEnabled: false,
+	PassThroughRequestEnabled:  false,
+	ReasoningReclassifyEnabled: false,
 }
 
 // 全局实例
@@ -0,0 +1,28 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// BenchmarkSetting 模型基准测试使用的标准提示词集
+type BenchmarkSetting struct {
+	Prompts   []string `json:"prompts"`
+	MaxTokens uint     `json:"max_tokens"` // 每次请求的最大输出 token 数
+}
+
+// 默认配置
+var benchmarkSetting = BenchmarkSetting{
+	Prompts: []string{
+		"Explain the difference between TCP and UDP in about 150 words.",
+		"Write a Python function that returns the n-th Fibonacci number iteratively, with a short docstring.",
+		"Summarize the main causes of the French Revolution in five bullet points.",
+	},
+	MaxTokens: 512,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("benchmark_setting", &benchmarkSetting)
+}
+
+func GetBenchmarkSetting() *BenchmarkSetting {
+	return &benchmarkSetting
+}
@@ -0,0 +1,41 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	BodyArchiveStorageDatabase = "database" // 保存在日志数据库中
+	BodyArchiveStorageS3       = "s3"       // 使用图片存储的 S3 配置上传
+)
+
+// BodyArchiveSetting 保存中继请求与响应的完整内容，按日志 id 查询，用于排查失败请求与计费争议
+type BodyArchiveSetting struct {
+	Enabled        bool     `json:"enabled"`
+	OnlyFailed     bool     `json:"only_failed"` // 只保存状态码不是 200 的请求
+	Storage        string   `json:"storage"`     // database 或 s3
+	S3Prefix       string   `json:"s3_prefix"`
+	MaxBodyKB      int      `json:"max_body_kb"`     // 请求体与响应体各自最多保存的大小，超出部分截断
+	RedactPatterns []string `json:"redact_patterns"` // 保存前替换为 [REDACTED] 的正则表达式
+}
+
+// 默认配置
+var bodyArchiveSetting = BodyArchiveSetting{
+	Enabled:    false,
+	OnlyFailed: false,
+	Storage:    BodyArchiveStorageDatabase,
+	S3Prefix:   "archives/",
+	MaxBodyKB:  256,
+	RedactPatterns: []string{
+		`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`, // 邮箱
+		`sk-[A-Za-z0-9_-]{20,}`,                          // API 密钥
+		`\b(?:\d[ -]?){13,19}\b`,                         // 银行卡号
+	},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("body_archive_setting", &bodyArchiveSetting)
+}
+
+func GetBodyArchiveSetting() *BodyArchiveSetting {
+	return &bodyArchiveSetting
+}
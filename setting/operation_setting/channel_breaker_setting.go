@@ -0,0 +1,26 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelBreakerSetting 按渠道与模型熔断的配置
+type ChannelBreakerSetting struct {
+	Enabled          bool `json:"enabled"`
+	FailureThreshold int  `json:"failure_threshold"` // 连续失败多少次后熔断
+	CooldownSeconds  int  `json:"cooldown_seconds"`  // 熔断持续时间，到期后放行请求试探上游是否恢复
+}
+
+// 默认配置
+var channelBreakerSetting = ChannelBreakerSetting{
+	Enabled:          false,
+	FailureThreshold: 5,
+	CooldownSeconds:  60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_breaker_setting", &channelBreakerSetting)
+}
+
+func GetChannelBreakerSetting() *ChannelBreakerSetting {
+	return &channelBreakerSetting
+}
@@ -0,0 +1,76 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	ChannelErrorCategoryChannel     = "channel"
+	ChannelErrorCategoryAuth        = "auth"
+	ChannelErrorCategoryQuota       = "quota"
+	ChannelErrorCategoryKeyword     = "keyword"
+	ChannelErrorCategoryRateLimit   = "rate_limit"
+	ChannelErrorCategoryOverloaded  = "overloaded"
+	ChannelErrorCategoryServerError = "server_error"
+)
+
+// ChannelDisableSetting 自动禁用渠道的加权滚动窗口配置
+type ChannelDisableSetting struct {
+	WeightedEnabled    bool                       `json:"weighted_enabled"`
+	WindowSeconds      int                        `json:"window_seconds"`
+	Threshold          float64                    `json:"threshold"`            // 窗口内错误权重之和达到该值时禁用
+	Weights            map[string]float64         `json:"weights"`              // 各错误类型的默认权重
+	ChannelTypeWeights map[int]map[string]float64 `json:"channel_type_weights"` // 按渠道类型覆盖权重
+	// QuarantineEnabled 开启后启用中的渠道触发禁用时先进入隔离状态，隔离中再次触发才禁用
+	QuarantineEnabled    bool    `json:"quarantine_enabled"`
+	QuarantineRetryRatio float64 `json:"quarantine_retry_ratio"` // 重试请求分配给隔离渠道的比例
+	// QuarantineRecoverSuccesses 隔离渠道连续成功处理该数量的探测请求后恢复启用
+	QuarantineRecoverSuccesses int `json:"quarantine_recover_successes"`
+	// LatencyPercentileEnabled 开启后按最近若干次测试与流式首字时间的 p95 判断响应超时，而不是单次测试耗时
+	LatencyPercentileEnabled bool `json:"latency_percentile_enabled"`
+	LatencyWindowSize        int  `json:"latency_window_size"` // 每个渠道保留的最近样本数
+	LatencyMinSamples        int  `json:"latency_min_samples"` // 样本数不足时不因响应时间禁用
+}
+
+// 默认配置：加权默认关闭，保持单次鉴权、额度等错误即禁用的行为；开启后上游过载等瞬时错误只有持续出现才会触发
+// 错误窗口只在当前进程内统计
+var channelDisableSetting = ChannelDisableSetting{
+	WeightedEnabled: false,
+	WindowSeconds:   300,
+	Threshold:       1,
+	Weights: map[string]float64{
+		ChannelErrorCategoryChannel:     1,
+		ChannelErrorCategoryAuth:        1,
+		ChannelErrorCategoryQuota:       1,
+		ChannelErrorCategoryKeyword:     1,
+		ChannelErrorCategoryRateLimit:   0.05,
+		ChannelErrorCategoryOverloaded:  0.05,
+		ChannelErrorCategoryServerError: 0,
+	},
+	ChannelTypeWeights:   map[int]map[string]float64{},
+	QuarantineEnabled:    false,
+	QuarantineRetryRatio: 0.01,
+
+	QuarantineRecoverSuccesses: 3,
+
+	LatencyPercentileEnabled: true,
+	LatencyWindowSize:        20,
+	LatencyMinSamples:        5,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_disable_setting", &channelDisableSetting)
+}
+
+func GetChannelDisableSetting() *ChannelDisableSetting {
+	return &channelDisableSetting
+}
+
+// GetWeight 获取错误类型权重，渠道类型单独配置的优先
+func (s *ChannelDisableSetting) GetWeight(channelType int, category string) float64 {
+	if weights, ok := s.ChannelTypeWeights[channelType]; ok {
+		if weight, ok := weights[category]; ok {
+			return weight
+		}
+	}
+	return s.Weights[category]
+}
@@ -0,0 +1,24 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelDrainSetting 渠道删除前的排空配置
+type ChannelDrainSetting struct {
+	TimeoutSeconds    int  `json:"timeout_seconds"`    // 等待处理中请求结束的最长时间，超时后直接删除
+	RetestReplacement bool `json:"retest_replacement"` // 删除前测试接替该渠道模型的其他渠道
+}
+
+// 默认配置
+var channelDrainSetting = ChannelDrainSetting{
+	TimeoutSeconds:    300,
+	RetestReplacement: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_drain_setting", &channelDrainSetting)
+}
+
+func GetChannelDrainSetting() *ChannelDrainSetting {
+	return &channelDrainSetting
+}
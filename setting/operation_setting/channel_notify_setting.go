@@ -0,0 +1,37 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	ChannelNotifyTypeWebhook  = "webhook"
+	ChannelNotifyTypeSlack    = "slack"
+	ChannelNotifyTypeDiscord  = "discord"
+	ChannelNotifyTypeTelegram = "telegram"
+)
+
+// ChannelNotifySetting 渠道被自动禁用或重新启用时的外部通知
+type ChannelNotifySetting struct {
+	Enabled          bool   `json:"enabled"`
+	Type             string `json:"type"`               // webhook、slack、discord、telegram
+	WebhookURL       string `json:"webhook_url"`        // webhook 地址，或 Slack / Discord 的 incoming webhook 地址
+	WebhookSecret    string `json:"webhook_secret"`     // 仅 webhook 类型使用，用于签名
+	TelegramBotToken string `json:"telegram_bot_token"` // 仅 telegram 类型使用
+	TelegramChatId   string `json:"telegram_chat_id"`
+	// 聚合窗口（秒），窗口内因相同错误被禁用的渠道合并为一条通知，0 表示不聚合
+	AggregateWindowSeconds int `json:"aggregate_window_seconds"`
+}
+
+// 默认配置
+var channelNotifySetting = ChannelNotifySetting{
+	Enabled: false,
+	Type:    ChannelNotifyTypeWebhook,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_notify_setting", &channelNotifySetting)
+}
+
+func GetChannelNotifySetting() *ChannelNotifySetting {
+	return &channelNotifySetting
+}
@@ -0,0 +1,46 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelPriorityTuningSetting 根据自动测试的延迟调整渠道优先级，持续偏慢的渠道逐步降为备用
+type ChannelPriorityTuningSetting struct {
+	Enabled       bool  `json:"enabled"`
+	SampleSize    int   `json:"sample_size"`     // 参考最近 N 次成功测试的平均延迟
+	SlowLatencyMs int64 `json:"slow_latency_ms"` // 平均延迟高于该值时降低优先级
+	FastLatencyMs int64 `json:"fast_latency_ms"` // 平均延迟低于该值时逐步恢复被下调的优先级
+	Step          int64 `json:"step"`            // 每次调整的幅度
+	MaxAdjustment int64 `json:"max_adjustment"`  // 累计下调的上限
+}
+
+// 默认配置
+var channelPriorityTuningSetting = ChannelPriorityTuningSetting{
+	Enabled:       false,
+	SampleSize:    5,
+	SlowLatencyMs: 10000,
+	FastLatencyMs: 3000,
+	Step:          1,
+	MaxAdjustment: 5,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_priority_tuning_setting", &channelPriorityTuningSetting)
+}
+
+func GetChannelPriorityTuningSetting() *ChannelPriorityTuningSetting {
+	return &channelPriorityTuningSetting
+}
+
+// GetAdjustment 根据平均延迟与当前累计调整量（非正数）返回本次的调整量，0 表示不调整
+func (s *ChannelPriorityTuningSetting) GetAdjustment(avgLatency int64, current int64) int64 {
+	if s.Step <= 0 {
+		return 0
+	}
+	if avgLatency > s.SlowLatencyMs && current > -s.MaxAdjustment {
+		return -min(s.Step, s.MaxAdjustment+current)
+	}
+	if avgLatency < s.FastLatencyMs && current < 0 {
+		return min(s.Step, -current)
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelSizeSetting 渠道请求/响应体积统计与突增告警
+type ChannelSizeSetting struct {
+	WindowSize      int     `json:"window_size"`      // 每个渠道保留的最近请求数
+	AlertEnabled    bool    `json:"alert_enabled"`    // 体积突增时通知管理员
+	SpikeFactor     float64 `json:"spike_factor"`     // 单次请求超过窗口 p95 的倍数视为突增
+	MinBytes        int64   `json:"min_bytes"`        // 小于该体积的请求不告警
+	MinSamples      int     `json:"min_samples"`      // 样本数不足时不告警
+	CooldownMinutes int     `json:"cooldown_minutes"` // 同一渠道两次告警的最小间隔
+}
+
+// 默认配置
+var channelSizeSetting = ChannelSizeSetting{
+	WindowSize:      1000,
+	AlertEnabled:    false,
+	SpikeFactor:     5,
+	MinBytes:        1 << 20,
+	MinSamples:      50,
+	CooldownMinutes: 30,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_size_setting", &channelSizeSetting)
+}
+
+func GetChannelSizeSetting() *ChannelSizeSetting {
+	return &channelSizeSetting
+}
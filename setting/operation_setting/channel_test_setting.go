@@ -0,0 +1,41 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"slices"
+)
+
+// ChannelTestSetting 按标签覆盖自动测试频率，渠道自身的设置优先于标签设置
+type ChannelTestSetting struct {
+	TagFrequency map[string]int `json:"tag_frequency"`  // 标签 -> 自动测试间隔（分钟）
+	ExcludedTags []string       `json:"excluded_tags"`  // 不参与自动测试的标签
+	TestOnUpdate bool           `json:"test_on_update"` // 修改渠道密钥、代理地址或模型后测试变更的模型，结果随保存结果返回（最多等待 10 秒）
+}
+
+// 默认配置
+var channelTestSetting = ChannelTestSetting{
+	TagFrequency: map[string]int{},
+	ExcludedTags: []string{},
+	TestOnUpdate: false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_test_setting", &channelTestSetting)
+}
+
+func GetChannelTestSetting() *ChannelTestSetting {
+	return &channelTestSetting
+}
+
+func (s *ChannelTestSetting) IsTagExcluded(tag string) bool {
+	return tag != "" && slices.Contains(s.ExcludedTags, tag)
+}
+
+// GetTagFrequency 返回标签的自动测试间隔（分钟），未配置时返回 0
+func (s *ChannelTestSetting) GetTagFrequency(tag string) int {
+	if tag == "" {
+		return 0
+	}
+	return s.TagFrequency[tag]
+}
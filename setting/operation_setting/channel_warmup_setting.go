@@ -0,0 +1,31 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ChannelWarmUpSetting 渠道启用前的预热配置
+type ChannelWarmUpSetting struct {
+	Enabled         bool `json:"enabled"`
+	Requests        int  `json:"requests"`         // 预热请求次数
+	IntervalSeconds int  `json:"interval_seconds"` // 两次预热请求之间的间隔
+}
+
+// 默认配置
+var channelWarmUpSetting = ChannelWarmUpSetting{
+	Enabled:         false,
+	Requests:        3,
+	IntervalSeconds: 1,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_warmup_setting", &channelWarmUpSetting)
+}
+
+func GetChannelWarmUpSetting() *ChannelWarmUpSetting {
+	return &channelWarmUpSetting
+}
+
+// ShouldWarmUp 开启预热且预热次数大于 0 时，渠道启用前需要先预热
+func (s *ChannelWarmUpSetting) ShouldWarmUp() bool {
+	return s.Enabled && s.Requests > 0
+}
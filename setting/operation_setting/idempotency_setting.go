@@ -0,0 +1,28 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// IdempotencySetting 非流式请求携带 Idempotency-Key 时保存响应，客户端重试时直接返回保存的响应，避免重复计费
+type IdempotencySetting struct {
+	Enabled       bool `json:"enabled"`
+	WindowSeconds int  `json:"window_seconds"`  // 响应保存时间
+	LockSeconds   int  `json:"lock_seconds"`    // 请求处理中的占用时间，超时后允许重新发起
+	MaxResponseKB int  `json:"max_response_kb"` // 超过该大小的响应不保存
+}
+
+// 默认配置
+var idempotencySetting = IdempotencySetting{
+	Enabled:       false,
+	WindowSeconds: 86400,
+	LockSeconds:   600,
+	MaxResponseKB: 1024,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("idempotency_setting", &idempotencySetting)
+}
+
+func GetIdempotencySetting() *IdempotencySetting {
+	return &idempotencySetting
+}
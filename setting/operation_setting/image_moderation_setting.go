@@ -0,0 +1,43 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	ImageModerationActionBlock = "block"
+	ImageModerationActionBlur  = "blur"
+)
+
+// ImageModerationSetting 生成图片的事后审核配置，由外部 webhook 分类器判定是否违规
+type ImageModerationSetting struct {
+	Enabled        bool    `json:"enabled"`
+	WebhookURL     string  `json:"webhook_url"`
+	WebhookSecret  string  `json:"webhook_secret"`
+	Action         string  `json:"action"`    // block 移除违规图片，blur 模糊处理（仅 b64_json 图片，url 图片退化为移除）
+	Threshold      float64 `json:"threshold"` // 分类器返回的 score 达到该值视为违规
+	TimeoutSeconds int     `json:"timeout_seconds"`
+	FailOpen       bool    `json:"fail_open"` // 分类器不可用时是否放行，关闭时返回 moderation_unavailable 错误
+}
+
+// 默认配置
+var imageModerationSetting = ImageModerationSetting{
+	Enabled:        false,
+	WebhookURL:     "",
+	WebhookSecret:  "",
+	Action:         ImageModerationActionBlock,
+	Threshold:      0.8,
+	TimeoutSeconds: 10,
+	FailOpen:       true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("image_moderation_setting", &imageModerationSetting)
+}
+
+func GetImageModerationSetting() *ImageModerationSetting {
+	return &imageModerationSetting
+}
+
+func (s *ImageModerationSetting) IsEnabled() bool {
+	return s.Enabled && s.WebhookURL != ""
+}
@@ -0,0 +1,47 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	ImageStorageTypeLocal = "local"
+	ImageStorageTypeS3    = "s3"
+)
+
+// ImageStorageSetting 生成图片以 URL 返回时使用的存储
+type ImageStorageSetting struct {
+	Type      string `json:"type"`       // local 或 s3，为空时不支持以 URL 返回
+	LocalDir  string `json:"local_dir"`  // 本地存储目录，通过 /api/image_storage/:name 访问
+	PublicURL string `json:"public_url"` // 图片访问地址前缀，为空时本地存储使用服务器地址，S3 使用 endpoint/bucket
+	// S3 兼容对象存储
+	S3Endpoint  string `json:"s3_endpoint"`
+	S3Region    string `json:"s3_region"`
+	S3Bucket    string `json:"s3_bucket"`
+	S3AccessKey string `json:"s3_access_key"`
+	S3SecretKey string `json:"s3_secret_key"`
+	S3Prefix    string `json:"s3_prefix"` // 对象键前缀
+	// 图片保留天数，超过后定期删除，0 表示不清理
+	RetentionDays int `json:"retention_days"`
+}
+
+// 默认配置
+var imageStorageSetting = ImageStorageSetting{
+	Type:     "",
+	LocalDir: "./data/images",
+	S3Region: "us-east-1",
+	S3Prefix: "images/",
+
+	RetentionDays: 30,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("image_storage_setting", &imageStorageSetting)
+}
+
+func GetImageStorageSetting() *ImageStorageSetting {
+	return &imageStorageSetting
+}
+
+func (s *ImageStorageSetting) IsEnabled() bool {
+	return s.Type == ImageStorageTypeLocal || s.Type == ImageStorageTypeS3
+}
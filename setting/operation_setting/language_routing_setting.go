@@ -0,0 +1,25 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// LanguageRoutingSetting 检测提示词语言，优先选择渠道设置中标注擅长该语言的渠道
+// 仅在同一优先级内筛选，没有匹配的渠道时按原权重选择；需开启内存缓存
+type LanguageRoutingSetting struct {
+	Enabled     bool `json:"enabled"`
+	MinTextRune int  `json:"min_text_rune"` // 参与检测的字符数少于该值时不做判断
+}
+
+// 默认配置
+var languageRoutingSetting = LanguageRoutingSetting{
+	Enabled:     false,
+	MinTextRune: 4,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("language_routing_setting", &languageRoutingSetting)
+}
+
+func GetLanguageRoutingSetting() *LanguageRoutingSetting {
+	return &languageRoutingSetting
+}
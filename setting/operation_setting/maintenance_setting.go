@@ -0,0 +1,36 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"slices"
+)
+
+// MaintenanceSetting 维护模式：中继请求统一返回 503，管理接口与白名单令牌不受影响
+type MaintenanceSetting struct {
+	Enabled           bool   `json:"enabled"`
+	Message           string `json:"message"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"` // 返回的 Retry-After，0 表示不返回
+	AllowedTokenIds   []int  `json:"allowed_token_ids"`
+}
+
+// 默认配置
+var maintenanceSetting = MaintenanceSetting{
+	Enabled:           false,
+	Message:           "服务维护中，请稍后再试",
+	RetryAfterSeconds: 300,
+	AllowedTokenIds:   []int{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("maintenance_setting", &maintenanceSetting)
+}
+
+func GetMaintenanceSetting() *MaintenanceSetting {
+	return &maintenanceSetting
+}
+
+// IsTokenAllowed 令牌是否在维护白名单中
+func (s *MaintenanceSetting) IsTokenAllowed(tokenId int) bool {
+	return slices.Contains(s.AllowedTokenIds, tokenId)
+}
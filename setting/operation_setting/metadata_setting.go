@@ -0,0 +1,30 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// RequestMetadataSetting 请求 metadata 回显配置
+type RequestMetadataSetting struct {
+	Enabled        bool     `json:"enabled"`
+	EchoEnabled    bool     `json:"echo_enabled"` // 是否将 metadata 回显到成功的 JSON 响应中
+	LogKeys        []string `json:"log_keys"`     // 需要记录到日志的 key，为空时记录全部
+	MaxKeys        int      `json:"max_keys"`
+	MaxValueLength int      `json:"max_value_length"`
+}
+
+// 默认配置
+var requestMetadataSetting = RequestMetadataSetting{
+	Enabled:        true,
+	EchoEnabled:    false,
+	LogKeys:        []string{},
+	MaxKeys:        16,
+	MaxValueLength: 512,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("request_metadata_setting", &requestMetadataSetting)
+}
+
+func GetRequestMetadataSetting() *RequestMetadataSetting {
+	return &requestMetadataSetting
+}
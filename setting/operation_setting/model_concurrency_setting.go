@@ -0,0 +1,55 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"strings"
+)
+
+const (
+	ModelConcurrencyModeReject = "reject" // 超出并发上限时直接返回 429
+	ModelConcurrencyModeQueue  = "queue"  // 超出并发上限时排队等待，超时后返回 429
+)
+
+// ModelConcurrencySetting 按模型限制同时处理中的请求数，多节点部署时通过 Redis 共享计数
+type ModelConcurrencySetting struct {
+	Enabled             bool           `json:"enabled"`
+	Limits              map[string]int `json:"limits"` // 模型最大并发数，按最长前缀匹配，0 表示不限制
+	Mode                string         `json:"mode"`   // reject 或 queue
+	QueueTimeoutSeconds int            `json:"queue_timeout_seconds"`
+	LeaseSeconds        int            `json:"lease_seconds"` // 并发占位的租约时间，节点异常退出时占位在租约到期后释放
+}
+
+// 默认配置
+var modelConcurrencySetting = ModelConcurrencySetting{
+	Enabled:             false,
+	Limits:              map[string]int{},
+	Mode:                ModelConcurrencyModeReject,
+	QueueTimeoutSeconds: 30,
+	LeaseSeconds:        60,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("model_concurrency_setting", &modelConcurrencySetting)
+}
+
+func GetModelConcurrencySetting() *ModelConcurrencySetting {
+	return &modelConcurrencySetting
+}
+
+// GetLimit 返回模型的最大并发数，优先精确匹配，其次最长前缀匹配；匹配到的前缀作为计数的 key，同一前缀下的模型共享并发数
+func (s *ModelConcurrencySetting) GetLimit(model string) (string, int) {
+	if limit, ok := s.Limits[model]; ok {
+		return model, limit
+	}
+	matched := ""
+	for prefix := range s.Limits {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(matched) {
+			matched = prefix
+		}
+	}
+	if matched == "" {
+		return "", 0
+	}
+	return matched, s.Limits[matched]
+}
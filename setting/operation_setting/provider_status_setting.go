@@ -0,0 +1,73 @@
+package operation_setting
+
+import (
+	"one-api/constant"
+	"one-api/setting/config"
+)
+
+const (
+	// ProviderStatusFormatStatuspage Atlassian Statuspage 的 /api/v2/summary.json
+	ProviderStatusFormatStatuspage = "statuspage"
+	// ProviderStatusFormatGoogleCloud Google Cloud 状态页的 incidents.json
+	ProviderStatusFormatGoogleCloud = "google_cloud"
+)
+
+// ProviderStatusFeed 一个服务商状态页，出现故障时影响类型在 ChannelTypes 中、且 base URL 主机名在 Hosts 中的渠道
+type ProviderStatusFeed struct {
+	Name         string   `json:"name"`
+	Url          string   `json:"url"`
+	Format       string   `json:"format"`
+	ChannelTypes []int    `json:"channel_types"`
+	Hosts        []string `json:"hosts,omitempty"`      // 渠道 base URL 的主机名（含子域名），为空表示不限，避免同类型的第三方渠道被一并降级
+	Components   []string `json:"components,omitempty"` // 仅关注名称包含这些关键字的组件或产品，为空表示全部
+}
+
+// ProviderStatusSetting 根据服务商状态页的故障公告提前降低相关渠道的权重
+type ProviderStatusSetting struct {
+	Enabled         bool                 `json:"enabled"`
+	IntervalSeconds int                  `json:"interval_seconds"`
+	MinImpact       string               `json:"min_impact"`    // 触发降级的最低影响级别：minor、major、critical
+	WeightFactor    float64              `json:"weight_factor"` // 降级渠道的权重系数，0 表示仅在同优先级没有其他渠道时使用
+	Feeds           []ProviderStatusFeed `json:"feeds"`
+}
+
+// 默认配置
+var providerStatusSetting = ProviderStatusSetting{
+	Enabled:         false,
+	IntervalSeconds: 120,
+	MinImpact:       "major",
+	WeightFactor:    0.1,
+	Feeds: []ProviderStatusFeed{
+		{
+			Name:         "OpenAI",
+			Url:          "https://status.openai.com/api/v2/summary.json",
+			Format:       ProviderStatusFormatStatuspage,
+			ChannelTypes: []int{constant.ChannelTypeOpenAI},
+			Hosts:        []string{"api.openai.com"},
+		},
+		{
+			Name:         "Anthropic",
+			Url:          "https://status.anthropic.com/api/v2/summary.json",
+			Format:       ProviderStatusFormatStatuspage,
+			ChannelTypes: []int{constant.ChannelTypeAnthropic},
+			Hosts:        []string{"api.anthropic.com"},
+		},
+		{
+			Name:         "Google",
+			Url:          "https://status.cloud.google.com/incidents.json",
+			Format:       ProviderStatusFormatGoogleCloud,
+			ChannelTypes: []int{constant.ChannelTypeGemini, constant.ChannelTypeVertexAi},
+			Hosts:        []string{"googleapis.com"},
+			Components:   []string{"Vertex", "Gemini"},
+		},
+	},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("provider_status_setting", &providerStatusSetting)
+}
+
+func GetProviderStatusSetting() *ProviderStatusSetting {
+	return &providerStatusSetting
+}
@@ -0,0 +1,24 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// RelayDedupSetting 同一令牌在时间窗口内发送完全相同的请求时合并为一次上游调用
+type RelayDedupSetting struct {
+	Enabled       bool `json:"enabled"`
+	WindowSeconds int  `json:"window_seconds"` // 请求完成后继续复用响应的时间
+}
+
+// 默认配置
+var relayDedupSetting = RelayDedupSetting{
+	Enabled:       false,
+	WindowSeconds: 5,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("relay_dedup_setting", &relayDedupSetting)
+}
+
+func GetRelayDedupSetting() *RelayDedupSetting {
+	return &relayDedupSetting
+}
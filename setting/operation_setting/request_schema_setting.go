@@ -0,0 +1,37 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+const (
+	RequestSchemaChatCompletions  = "chat_completions"
+	RequestSchemaEmbeddings       = "embeddings"
+	RequestSchemaImageGenerations = "image_generations"
+)
+
+// RequestSchemaSetting 在选择渠道之前按 JSON Schema 校验请求体，不合法的请求直接返回 400
+type RequestSchemaSetting struct {
+	Enabled bool            `json:"enabled"`
+	Routes  map[string]bool `json:"routes"` // 各接口是否校验，键为 RequestSchema* 常量
+	// Schemas 覆盖内置的 Schema，可通过 additionalProperties: false 拒绝未知字段
+	Schemas map[string]string `json:"schemas"`
+}
+
+// 默认配置
+var requestSchemaSetting = RequestSchemaSetting{
+	Enabled: false,
+	Routes: map[string]bool{
+		RequestSchemaChatCompletions:  true,
+		RequestSchemaEmbeddings:       true,
+		RequestSchemaImageGenerations: true,
+	},
+	Schemas: map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("request_schema_setting", &requestSchemaSetting)
+}
+
+func GetRequestSchemaSetting() *RequestSchemaSetting {
+	return &requestSchemaSetting
+}
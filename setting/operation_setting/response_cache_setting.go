@@ -0,0 +1,69 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"strings"
+)
+
+const (
+	ResponseCacheModeExact    = "exact"    // 请求完全相同时命中
+	ResponseCacheModeSemantic = "semantic" // 其余参数相同且提示词语义相似时命中
+)
+
+// ResponseCacheSetting temperature 为 0 且客户端显式要求缓存的非流式请求，直接返回相同请求的缓存响应，命中缓存不计费
+type ResponseCacheSetting struct {
+	Enabled          bool     `json:"enabled"`
+	TTLSeconds       int      `json:"ttl_seconds"`
+	Models           []string `json:"models"`             // 允许缓存的模型，以 * 结尾表示前缀匹配
+	ShareAcrossUsers bool     `json:"share_across_users"` // 不同用户之间共享完全相同请求的缓存，语义匹配始终按用户隔离
+	MaxResponseKB    int      `json:"max_response_kb"`    // 超过该大小的响应不缓存
+
+	Mode                string  `json:"mode"`                 // exact 或 semantic
+	SimilarityThreshold float64 `json:"similarity_threshold"` // 语义缓存命中所需的最低余弦相似度
+	EmbeddingUrl        string  `json:"embedding_url"`        // OpenAI 兼容的 embeddings 接口地址
+	EmbeddingApiKey     string  `json:"embedding_api_key"`
+	EmbeddingModel      string  `json:"embedding_model"`
+	SemanticMaxEntries  int     `json:"semantic_max_entries"` // 每组相同参数下最多保留的语义索引条目，索引只保存在当前进程内存中
+}
+
+// 默认配置
+var responseCacheSetting = ResponseCacheSetting{
+	Enabled:             false,
+	TTLSeconds:          3600,
+	Models:              []string{},
+	ShareAcrossUsers:    false,
+	MaxResponseKB:       512,
+	Mode:                ResponseCacheModeExact,
+	SimilarityThreshold: 0.95,
+	EmbeddingUrl:        "https://api.openai.com/v1/embeddings",
+	EmbeddingModel:      "text-embedding-3-small",
+	SemanticMaxEntries:  1000,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
+
+// IsModelAllowed 模型是否在允许缓存的列表中
+func (s *ResponseCacheSetting) IsModelAllowed(model string) bool {
+	for _, allowed := range s.Models {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok {
+			if strings.HasPrefix(model, prefix) {
+				return true
+			}
+		} else if allowed == model {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSemantic 是否启用语义缓存，需要配置 embeddings 接口
+func (s *ResponseCacheSetting) IsSemantic() bool {
+	return s.Mode == ResponseCacheModeSemantic && s.EmbeddingUrl != "" && s.EmbeddingModel != ""
+}
@@ -0,0 +1,49 @@
+package operation_setting
+
+import (
+	"one-api/setting/config"
+	"strings"
+)
+
+// ResponseHeaderSetting 透传给客户端的上游响应头白名单，支持以 * 结尾的前缀匹配，不区分大小写
+type ResponseHeaderSetting struct {
+	PassThroughHeaders []string `json:"pass_through_headers"`
+}
+
+// 默认配置
+var responseHeaderSetting = ResponseHeaderSetting{
+	PassThroughHeaders: []string{
+		"anthropic-ratelimit-*",
+		"openai-processing-ms",
+		"x-ratelimit-*",
+		"retry-after",
+	},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_header_setting", &responseHeaderSetting)
+}
+
+func GetResponseHeaderSetting() *ResponseHeaderSetting {
+	return &responseHeaderSetting
+}
+
+// ShouldPassThroughHeader 判断上游响应头是否在白名单中
+func ShouldPassThroughHeader(name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range responseHeaderSetting.PassThroughHeaders {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
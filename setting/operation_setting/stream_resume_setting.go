@@ -0,0 +1,26 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// StreamResumeSetting 流式响应断线续传：缓存最近的事件，客户端携带 Last-Event-ID 重连时补发而不是重新请求上游
+type StreamResumeSetting struct {
+	Enabled    bool `json:"enabled"`
+	TTLSeconds int  `json:"ttl_seconds"` // 流结束后缓存保留的时间
+	MaxBytes   int  `json:"max_bytes"`   // 单个流最多缓存的字节数，超出后该流不可续传
+}
+
+// 默认配置
+var streamResumeSetting = StreamResumeSetting{
+	Enabled:    false,
+	TTLSeconds: 60,
+	MaxBytes:   4 << 20,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("stream_resume_setting", &streamResumeSetting)
+}
+
+func GetStreamResumeSetting() *StreamResumeSetting {
+	return &streamResumeSetting
+}
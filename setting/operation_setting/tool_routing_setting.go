@@ -0,0 +1,29 @@
+package operation_setting
+
+import "one-api/setting/config"
+
+// ToolRoutingSetting 请求包含工具定义时，优先选择工具调用可靠的渠道
+// 仅在同一优先级内筛选，没有可靠的渠道时按原权重选择；需开启内存缓存
+type ToolRoutingSetting struct {
+	Enabled        bool    `json:"enabled"`
+	WindowSize     int     `json:"window_size"`      // 按渠道与模型统计最近多少次带工具的请求
+	MinSamples     int     `json:"min_samples"`      // 样本数少于该值时不按成功率筛选
+	MinSuccessRate float64 `json:"min_success_rate"` // 成功率低于该值的渠道在有其他渠道时不被选择
+}
+
+// 默认配置
+var toolRoutingSetting = ToolRoutingSetting{
+	Enabled:        false,
+	WindowSize:     50,
+	MinSamples:     10,
+	MinSuccessRate: 0.8,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("tool_routing_setting", &toolRoutingSetting)
+}
+
+func GetToolRoutingSetting() *ToolRoutingSetting {
+	return &toolRoutingSetting
+}
@@ -44,6 +44,9 @@ var defaultModelRatio = map[string]float64{
 	"gpt-4o":                                  1.25, // $2.5 / 1M tokens
 	"gpt-4o-audio-preview":                    1.25, // $2.5 / 1M tokens
 	"gpt-4o-audio-preview-2024-10-01":         1.25, // $2.5 / 1M tokens
+	"gpt-4o-mini-audio-preview":               0.075,
+	"gpt-audio":                               1.25, // $2.5 / 1M tokens
+	"gpt-audio-mini":                          0.3,  // $0.6 / 1M tokens
 	"gpt-4o-2024-05-13":                       2.5,  // $5 / 1M tokens
 	"gpt-4o-2024-08-06":                       1.25, // $2.5 / 1M tokens
 	"gpt-4o-2024-11-20":                       1.25, // $2.5 / 1M tokens
@@ -160,6 +163,8 @@ var defaultModelRatio = map[string]float64{
 	"gemini-2.5-flash-lite-preview-thinking-*":  0.05,
 	"gemini-2.5-flash-lite-preview-06-17":       0.05,
 	"gemini-2.5-flash":                          0.15,
+	"gemini-2.5-flash-preview-tts":              0.25, // 文本输入 $0.5 / 1M tokens，音频输出 $10 / 1M tokens
+	"gemini-2.5-pro-preview-tts":                0.5,  // 文本输入 $1 / 1M tokens，音频输出 $20 / 1M tokens
 	"text-embedding-004":                        0.001,
 	"chatglm_turbo":                             0.3572,     // ￥0.005 / 1k tokens
 	"chatglm_pro":                               0.7143,     // ￥0.01 / 1k tokens
@@ -343,7 +348,10 @@ func GetModelPrice(name string, printErr bool) (float64, bool) {
 	modelPriceMapMutex.RLock()
 	defer modelPriceMapMutex.RUnlock()
 
-	name = FormatMatchingModelName(name)
+	name, _ = MatchModelVariant(name, func(name string) bool {
+		_, ok := modelPriceMap[name]
+		return ok
+	})
 
 	price, ok := modelPriceMap[name]
 	if !ok {
@@ -366,19 +374,14 @@ func UpdateModelRatioByJSONString(jsonStr string) error {
 	return err
 }
 
-// 处理带有思考预算的模型名称，方便统一定价
-func handleThinkingBudgetModel(name, prefix, wildcard string) string {
-	if strings.HasPrefix(name, prefix) && strings.Contains(name, "-thinking-") {
-		return wildcard
-	}
-	return name
-}
-
 func GetModelRatio(name string) (float64, bool, string) {
 	modelRatioMapMutex.RLock()
 	defer modelRatioMapMutex.RUnlock()
 
-	name = FormatMatchingModelName(name)
+	name, _ = MatchModelVariant(name, func(name string) bool {
+		_, ok := modelRatioMap[name]
+		return ok
+	})
 
 	ratio, ok := modelRatioMap[name]
 	if !ok {
@@ -387,6 +390,18 @@ func GetModelRatio(name string) (float64, bool, string) {
 	return ratio, true, name
 }
 
+// HasModelRatio 模型（含变体匹配）是否设置了倍率，不受自用模式影响
+func HasModelRatio(name string) bool {
+	modelRatioMapMutex.RLock()
+	defer modelRatioMapMutex.RUnlock()
+
+	_, ok := MatchModelVariant(name, func(name string) bool {
+		_, ok := modelRatioMap[name]
+		return ok
+	})
+	return ok
+}
+
 func DefaultModelRatio2JSONString() string {
 	jsonBytes, err := json.Marshal(defaultModelRatio)
 	if err != nil {
@@ -431,7 +446,10 @@ func GetCompletionRatio(name string) float64 {
 	CompletionRatioMutex.RLock()
 	defer CompletionRatioMutex.RUnlock()
 
-	name = FormatMatchingModelName(name)
+	name, _ = MatchModelVariant(name, func(name string) bool {
+		_, ok := CompletionRatio[name]
+		return ok
+	})
 
 	if strings.Contains(name, "/") {
 		if ratio, ok := CompletionRatio[name]; ok {
@@ -474,6 +492,9 @@ func getHardcodedCompletionModelRatio(name string) (float64, bool) {
 	if strings.HasPrefix(name, "o1") || strings.HasPrefix(name, "o3") {
 		return 4, true
 	}
+	if strings.HasPrefix(name, "gpt-audio") {
+		return 4, true
+	}
 	if name == "chatgpt-4o-latest" {
 		return 3, true
 	}
@@ -501,7 +522,9 @@ func getHardcodedCompletionModelRatio(name string) (float64, bool) {
 		return 3, true
 	}
 	if strings.HasPrefix(name, "gemini-") {
-		if strings.HasPrefix(name, "gemini-1.5") {
+		if strings.HasSuffix(name, "-tts") {
+			return 20, true
+		} else if strings.HasPrefix(name, "gemini-1.5") {
 			return 4, true
 		} else if strings.HasPrefix(name, "gemini-2.0") {
 			return 4, true
@@ -559,6 +582,13 @@ func getHardcodedCompletionModelRatio(name string) (float64, bool) {
 	return 1, false
 }
 
+// IsAudioChatModel 是否为支持音频输入输出的 chat completions 模型，按音频倍率计费
+func IsAudioChatModel(name string) bool {
+	return strings.HasPrefix(name, "gpt-4o-audio") ||
+		strings.HasPrefix(name, "gpt-4o-mini-audio") ||
+		strings.HasPrefix(name, "gpt-audio")
+}
+
 func GetAudioRatio(name string) float64 {
 	if strings.Contains(name, "-realtime") {
 		if strings.HasSuffix(name, "gpt-4o-realtime-preview") {
@@ -574,6 +604,10 @@ func GetAudioRatio(name string) float64 {
 			return 40 / 2.5
 		} else if strings.HasPrefix(name, "gpt-4o-mini-audio-preview") {
 			return 10 / 0.15
+		} else if strings.HasPrefix(name, "gpt-audio-mini") {
+			return 10 / 0.6
+		} else if strings.HasPrefix(name, "gpt-audio") {
+			return 32 / 2.5
 		} else {
 			return 40
 		}
@@ -663,23 +697,3 @@ func GetCompletionRatioCopy() map[string]float64 {
 	}
 	return copyMap
 }
-
-// 转换模型名，减少渠道必须配置各种带参数模型
-func FormatMatchingModelName(name string) string {
-
-	if strings.HasPrefix(name, "gemini-2.5-flash-lite") {
-		name = handleThinkingBudgetModel(name, "gemini-2.5-flash-lite", "gemini-2.5-flash-lite-thinking-*")
-	} else if strings.HasPrefix(name, "gemini-2.5-flash") {
-		name = handleThinkingBudgetModel(name, "gemini-2.5-flash", "gemini-2.5-flash-thinking-*")
-	} else if strings.HasPrefix(name, "gemini-2.5-pro") {
-		name = handleThinkingBudgetModel(name, "gemini-2.5-pro", "gemini-2.5-pro-thinking-*")
-	}
-
-	if strings.HasPrefix(name, "gpt-4-gizmo") {
-		name = "gpt-4-gizmo-*"
-	}
-	if strings.HasPrefix(name, "gpt-4o-gizmo") {
-		name = "gpt-4o-gizmo-*"
-	}
-	return name
-}
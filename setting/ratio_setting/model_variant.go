@@ -0,0 +1,110 @@
+package ratio_setting
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	ModelVariantNone       = ""
+	ModelVariantThinking   = "thinking"        // <base>-thinking
+	ModelVariantNoThinking = "nothinking"      // <base>-nothinking
+	ModelVariantBudget     = "thinking-budget" // <base>-thinking-<budget>
+)
+
+// ModelVariant 模型名称中的思考后缀，定价、日志、渠道匹配和上游请求都以此为准
+type ModelVariant struct {
+	Name      string
+	BaseModel string // 去掉思考后缀后发送给上游的模型名
+	Variant   string
+	Budget    int // 仅 ModelVariantBudget 有效
+}
+
+func ParseModelVariant(name string) ModelVariant {
+	variant := ModelVariant{Name: name, BaseModel: name, Variant: ModelVariantNone}
+	if idx := strings.LastIndex(name, "-thinking-"); idx > 0 {
+		// 只有数字预算才视为变体，避免误伤 gemini-2.0-flash-thinking-exp 这类模型
+		if budget, err := strconv.Atoi(name[idx+len("-thinking-"):]); err == nil && budget >= 0 {
+			variant.BaseModel = name[:idx]
+			variant.Variant = ModelVariantBudget
+			variant.Budget = budget
+		}
+		return variant
+	}
+	if base, ok := strings.CutSuffix(name, "-nothinking"); ok && base != "" {
+		variant.BaseModel = base
+		variant.Variant = ModelVariantNoThinking
+	} else if base, ok := strings.CutSuffix(name, "-thinking"); ok && base != "" {
+		variant.BaseModel = base
+		variant.Variant = ModelVariantThinking
+	}
+	return variant
+}
+
+// ModelVariantCandidates 返回匹配定价、渠道时依次尝试的名称。
+// 带思考预算的模型依次尝试完整名称、<base>-thinking-*（base 逐段缩短，如 gemini-2.5-flash-preview-05-20 → gemini-2.5-flash）、<base>-thinking；
+// 不带思考后缀的模型最后尝试 <name>-thinking-*，与模型列表中把 <base>-thinking-* 展示为 <base> 保持一致
+func ModelVariantCandidates(name string) []string {
+	if strings.HasPrefix(name, "gpt-4-gizmo") {
+		return []string{"gpt-4-gizmo-*"}
+	}
+	if strings.HasPrefix(name, "gpt-4o-gizmo") {
+		return []string{"gpt-4o-gizmo-*"}
+	}
+	candidates := []string{name}
+	variant := ParseModelVariant(name)
+	if variant.Variant == ModelVariantBudget {
+		base := variant.BaseModel
+		for {
+			candidates = append(candidates, base+"-thinking-*")
+			idx := strings.LastIndex(base, "-")
+			if idx <= 0 {
+				break
+			}
+			base = base[:idx]
+		}
+		candidates = append(candidates, variant.BaseModel+"-thinking")
+	} else if variant.Variant == ModelVariantNone && !strings.HasSuffix(name, "-thinking-*") {
+		candidates = append(candidates, name+"-thinking-*")
+	}
+	return candidates
+}
+
+// ListedModelName 返回模型列表与定价页展示的名称：<base>-thinking-* 无法直接调用，展示为基础模型，
+// 客户端可直接调用基础模型，或自行加上 -thinking-<budget> 后缀指定思考预算
+func ListedModelName(name string) string {
+	if base, ok := strings.CutSuffix(name, "-thinking-*"); ok && base != "" {
+		return base
+	}
+	return name
+}
+
+// MatchModelVariant 返回第一个 exists 为 true 的候选名称，都不满足时返回首个候选名称和 false
+func MatchModelVariant(name string, exists func(name string) bool) (string, bool) {
+	candidates := ModelVariantCandidates(name)
+	for _, candidate := range candidates {
+		if exists(candidate) {
+			return candidate, true
+		}
+	}
+	return candidates[0], false
+}
+
+// GetReasoningVariant 按实际产生的思考 token 选择计费变体：未声明思考的请求产生了思考 token 时按 <base>-thinking 计费，
+// -thinking 请求未产生思考 token 时按 <base>-nothinking 计费，目标变体未配置倍率时返回 false
+func GetReasoningVariant(name string, reasoningTokens int) (string, bool) {
+	variant := ParseModelVariant(name)
+	var target string
+	switch {
+	case reasoningTokens > 0 && (variant.Variant == ModelVariantNone || variant.Variant == ModelVariantNoThinking):
+		target = variant.BaseModel + "-thinking"
+	case reasoningTokens == 0 && variant.Variant == ModelVariantThinking:
+		target = variant.BaseModel + "-nothinking"
+	default:
+		return "", false
+	}
+	modelRatioMapMutex.RLock()
+	defer modelRatioMapMutex.RUnlock()
+	_, ok := modelRatioMap[target]
+	return target, ok
+}
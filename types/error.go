@@ -37,6 +37,7 @@ type ErrorCode string
 const (
 	ErrorCodeInvalidRequest         ErrorCode = "invalid_request"
 	ErrorCodeSensitiveWordsDetected ErrorCode = "sensitive_words_detected"
+	ErrorCodeModerationUnavailable  ErrorCode = "moderation_unavailable"
 
 	// new api error
 	ErrorCodeCountTokenFailed  ErrorCode = "count_token_failed"
@@ -0,0 +1,9 @@
+package types
+
+// RelayAttempt 一次失败的上游请求，重试时记录在日志中
+type RelayAttempt struct {
+	ChannelId  int       `json:"channel_id"`
+	StatusCode int       `json:"status_code"`
+	ErrorCode  ErrorCode `json:"error_code"`
+	Duration   int64     `json:"duration_ms"`
+}